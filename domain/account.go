@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// AccountType is the kind of place money is held
+type AccountType string
+
+const (
+	AccountTypeBank   AccountType = "bank"
+	AccountTypeCash   AccountType = "cash"
+	AccountTypeWallet AccountType = "wallet"
+	AccountTypeCard   AccountType = "card"
+)
+
+// IsValid checks if the account type is supported
+func (t AccountType) IsValid() bool {
+	switch t {
+	case AccountTypeBank, AccountTypeCash, AccountTypeWallet, AccountTypeCard:
+		return true
+	}
+	return false
+}
+
+// Account is somewhere money is held, such as a bank account or the cash in a wallet.
+// OpeningBalance is the balance at the start of OpeningDate; expenses, incomes and
+// transfers dated from then on move it. Amounts are in the base currency, and a card
+// account usually runs a negative balance.
+type Account struct {
+	ID             int         `json:"id"`
+	UserID         int         `json:"-"`
+	Name           string      `json:"name"`
+	Type           AccountType `json:"type"`
+	OpeningBalance Money       `json:"opening_balance"`
+	OpeningDate    time.Time   `json:"opening_date"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+}
+
+// AccountBalance is an account's balance at the end of AsOf with the movements behind it
+type AccountBalance struct {
+	AccountID      int       `json:"account_id"`
+	AsOf           time.Time `json:"as_of"`
+	OpeningBalance Money     `json:"opening_balance"`
+	Income         Money     `json:"income"`
+	Expenses       Money     `json:"expenses"`
+	TransfersIn    Money     `json:"transfers_in"`
+	TransfersOut   Money     `json:"transfers_out"`
+	Balance        Money     `json:"balance"`
+}
+
+// Reconciliation records a statement balance checked against the computed balance.
+// Discrepancy is the statement balance minus the computed one.
+type Reconciliation struct {
+	ID               int       `json:"id"`
+	UserID           int       `json:"-"`
+	AccountID        int       `json:"account_id"`
+	StatementDate    time.Time `json:"statement_date"`
+	StatementBalance Money     `json:"statement_balance"`
+	ComputedBalance  Money     `json:"computed_balance"`
+	Discrepancy      Money     `json:"discrepancy"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// Transfer moves money between two of a user's accounts, such as an ATM withdrawal
+// from a bank account into cash
+type Transfer struct {
+	ID            int       `json:"id"`
+	UserID        int       `json:"-"`
+	FromAccountID int       `json:"from_account_id"`
+	ToAccountID   int       `json:"to_account_id"`
+	Amount        Money     `json:"amount"`
+	Description   string    `json:"description"`
+	TransferDate  time.Time `json:"transfer_date"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// AccountRepository defines the interface for account data operations.
+// Every method is scoped to the owning user.
+type AccountRepository interface {
+	// WithTx returns the repository bound to a transaction from a TxManager
+	WithTx(tx Tx) AccountRepository
+	Create(ctx context.Context, account *Account) error
+	GetByID(ctx context.Context, userID, id int) (*Account, error)
+	GetAll(ctx context.Context, userID int) ([]*Account, error)
+	Update(ctx context.Context, account *Account) error
+	// Delete returns ErrInUse while expenses, incomes or transfers refer to the account
+	Delete(ctx context.Context, userID, id int) error
+	// GetBalance sums the movements dated from the opening date through asOf; Balance is left unset
+	GetBalance(ctx context.Context, userID, id int, asOf time.Time) (*AccountBalance, error)
+	CreateReconciliation(ctx context.Context, reconciliation *Reconciliation) error
+	GetReconciliations(ctx context.Context, userID, accountID int) ([]*Reconciliation, error)
+}
+
+// TransferRepository defines the interface for transfer data operations.
+// Every method is scoped to the owning user.
+type TransferRepository interface {
+	// WithTx returns the repository bound to a transaction from a TxManager
+	WithTx(tx Tx) TransferRepository
+	Create(ctx context.Context, transfer *Transfer) error
+	GetByID(ctx context.Context, userID, id int) (*Transfer, error)
+	// GetAll lists transfers newest first, only those into or out of accountID when it is set
+	GetAll(ctx context.Context, userID int, accountID *int) ([]*Transfer, error)
+	Delete(ctx context.Context, userID, id int) error
+}
@@ -0,0 +1,148 @@
+package domain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// AuditEntity names a kind of record whose changes are kept in the audit log
+type AuditEntity string
+
+const (
+	AuditEntityExpense          AuditEntity = "expense"
+	AuditEntityCategory         AuditEntity = "category"
+	AuditEntityBudget           AuditEntity = "budget"
+	AuditEntityIncome           AuditEntity = "income"
+	AuditEntityAccount          AuditEntity = "account"
+	AuditEntityTransfer         AuditEntity = "transfer"
+	AuditEntityRecurringExpense AuditEntity = "recurring_expense"
+	AuditEntityPaymentMethod    AuditEntity = "payment_method"
+	AuditEntityTag              AuditEntity = "tag"
+)
+
+// IsValid reports whether the entity is one of the audited kinds
+func (e AuditEntity) IsValid() bool {
+	switch e {
+	case AuditEntityExpense, AuditEntityCategory, AuditEntityBudget, AuditEntityIncome, AuditEntityAccount,
+		AuditEntityTransfer, AuditEntityRecurringExpense, AuditEntityPaymentMethod, AuditEntityTag:
+		return true
+	}
+	return false
+}
+
+// AuditAction names what happened to the record
+type AuditAction string
+
+const (
+	AuditActionCreate  AuditAction = "create"
+	AuditActionUpdate  AuditAction = "update"
+	AuditActionDelete  AuditAction = "delete"
+	AuditActionRestore AuditAction = "restore"
+	AuditActionPurge   AuditAction = "purge"
+	AuditActionMerge   AuditAction = "merge"
+)
+
+// AuditChange holds the JSON values of one field before and after a change.
+// From is left out for a new record and To for a deleted one.
+type AuditChange struct {
+	From json.RawMessage `json:"from,omitempty"`
+	To   json.RawMessage `json:"to,omitempty"`
+}
+
+// AuditEvent records one change to a user's record. ActorID is the user who made
+// it, or nil when the server made it on its own, like the recurring expense
+// scheduler or the trash purge. Events are only ever appended.
+type AuditEvent struct {
+	ID        int64                  `json:"id"`
+	UserID    int                    `json:"-"`
+	ActorID   *int                   `json:"actor_id"`
+	Entity    AuditEntity            `json:"entity"`
+	EntityID  int                    `json:"entity_id"`
+	Action    AuditAction            `json:"action"`
+	Changes   map[string]AuditChange `json:"changes"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// NewAuditEvent builds the event for a change the user made to their own record.
+// before is nil for a new record and after is nil for a deleted one.
+func NewAuditEvent(userID int, entity AuditEntity, entityID int, action AuditAction, before, after interface{}) (*AuditEvent, error) {
+	changes, err := DiffSnapshots(before, after)
+	if err != nil {
+		return nil, err
+	}
+	actorID := userID
+	return &AuditEvent{
+		UserID:   userID,
+		ActorID:  &actorID,
+		Entity:   entity,
+		EntityID: entityID,
+		Action:   action,
+		Changes:  changes,
+	}, nil
+}
+
+// DiffSnapshots compares the JSON forms of two versions of a record field by field
+// and returns the fields that differ. Either version may be nil.
+func DiffSnapshots(before, after interface{}) (map[string]AuditChange, error) {
+	beforeFields, err := snapshotFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := snapshotFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]AuditChange{}
+	for name, from := range beforeFields {
+		to, ok := afterFields[name]
+		if !ok && afterFields != nil {
+			// Fields left out of the JSON when empty were cleared
+			to = json.RawMessage("null")
+		}
+		if !bytes.Equal(from, to) {
+			changes[name] = AuditChange{From: from, To: to}
+		}
+	}
+	for name, to := range afterFields {
+		if _, ok := beforeFields[name]; ok {
+			continue
+		}
+		change := AuditChange{To: to}
+		if beforeFields != nil {
+			change.From = json.RawMessage("null")
+		}
+		changes[name] = change
+	}
+	return changes, nil
+}
+
+// snapshotFields returns the JSON value of each field of the record, or nil for no record
+func snapshotFields(record interface{}) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// AuditFilter narrows the audit log to one kind of record, and optionally to one
+// record of that kind. Limit caps the number of events returned.
+type AuditFilter struct {
+	Entity   AuditEntity
+	EntityID int
+	Limit    int
+}
+
+// AuditRepository defines the interface for reading the audit log. Events are
+// written by the repositories making each change, in the same transaction.
+type AuditRepository interface {
+	// GetAll returns the user's events matching the filter, newest first
+	GetAll(ctx context.Context, userID int, filter *AuditFilter) ([]*AuditEvent, error)
+}
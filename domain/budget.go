@@ -1,31 +1,48 @@
 package domain
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
-// Budget represents a monthly budget
+// Budget represents a monthly budget. A nil CategoryID is the overall budget
+// for the month; otherwise it caps spending in that category.
 type Budget struct {
-	ID           int       `json:"id"`
-	Month        int       `json:"month"`
-	Year         int       `json:"year"`
-	BudgetAmount float64   `json:"budget_amount"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           int        `json:"id"`
+	UserID       int        `json:"-"`
+	CategoryID   *int       `json:"category_id,omitempty"`
+	Month        int        `json:"month"`
+	Year         int        `json:"year"`
+	BudgetAmount Money      `json:"budget_amount"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
 }
 
-// BudgetStatus represents the status of a budget with spending information
+// BudgetStatus represents the status of a budget with spending information.
+// Income and SpentPercentOfIncome are only filled in when asked for, and the
+// percentage is left out when the month has no income.
 type BudgetStatus struct {
-	Budget      *Budget `json:"budget"`
-	SpentAmount float64 `json:"spent_amount"`
-	Remaining   float64 `json:"remaining"`
-	Status      string  `json:"status"` // "within_budget" or "exceeded"
+	Budget               *Budget         `json:"budget"`
+	SpentAmount          Money           `json:"spent_amount"`
+	Remaining            Money           `json:"remaining"`
+	Status               string          `json:"status"` // "within_budget", "exceeded" or "no_budget"
+	Income               *Money          `json:"income,omitempty"`
+	SpentPercentOfIncome *float64        `json:"spent_percent_of_income,omitempty"`
+	Categories           []*BudgetStatus `json:"categories,omitempty"`
 }
 
-// BudgetRepository defines the interface for budget data operations
+// BudgetRepository defines the interface for budget data operations.
+// Every method is scoped to the owning user.
 type BudgetRepository interface {
-	Create(budget *Budget) error
-	GetByID(id int) (*Budget, error)
-	GetAll() ([]*Budget, error)
-	GetByMonth(month, year int) (*Budget, error)
-	Update(budget *Budget) error
-	Delete(id int) error
+	// WithTx returns the repository bound to a transaction from a TxManager
+	WithTx(tx Tx) BudgetRepository
+	Create(ctx context.Context, budget *Budget) error
+	GetByID(ctx context.Context, userID, id int) (*Budget, error)
+	GetAll(ctx context.Context, userID int) ([]*Budget, error)
+	GetByMonth(ctx context.Context, userID, month, year int) (*Budget, error)
+	GetByMonthAndCategory(ctx context.Context, userID, month, year, categoryID int) (*Budget, error)
+	GetCategoryBudgetsByMonth(ctx context.Context, userID, month, year int) ([]*Budget, error)
+	Update(ctx context.Context, budget *Budget) error
+	Delete(ctx context.Context, userID, id int) error
 }
@@ -1,19 +1,84 @@
 package domain
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
-// Category represents an expense category
+// Category represents an expense category. ParentID nests it under another
+// category, as in "Food > Groceries"; top-level categories have none.
+// Archived categories keep their expenses but can't be picked for new ones.
 type Category struct {
-	ID        int       `json:"id"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        int        `json:"id"`
+	UserID    int        `json:"-"`
+	ParentID  *int       `json:"parent_id"`
+	Name      string     `json:"name"`
+	Archived  bool       `json:"archived"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
-// CategoryRepository defines the interface for category data operations
+// CategoryNode is a category with its subcategories, as returned by the tree endpoint
+type CategoryNode struct {
+	*Category
+	Children []*CategoryNode `json:"children"`
+}
+
+// BuildCategoryTree nests the categories under their parents. Categories whose
+// parent is not in the list become roots; children keep the order of the list.
+func BuildCategoryTree(categories []*Category) []*CategoryNode {
+	nodes := make(map[int]*CategoryNode, len(categories))
+	for _, category := range categories {
+		nodes[category.ID] = &CategoryNode{Category: category, Children: []*CategoryNode{}}
+	}
+
+	roots := []*CategoryNode{}
+	for _, category := range categories {
+		node := nodes[category.ID]
+		if category.ParentID != nil {
+			if parent, ok := nodes[*category.ParentID]; ok && parent != node {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
+
+// AncestorIDs returns the parents of the category id, nearest first, following
+// ParentID through categories. It stops at a parent that is missing or already seen.
+func AncestorIDs(categories []*Category, id int) []int {
+	parents := make(map[int]*int, len(categories))
+	for _, category := range categories {
+		parents[category.ID] = category.ParentID
+	}
+
+	ancestors := []int{}
+	seen := map[int]bool{id: true}
+	for parent := parents[id]; parent != nil && !seen[*parent]; parent = parents[*parent] {
+		ancestors = append(ancestors, *parent)
+		seen[*parent] = true
+	}
+	return ancestors
+}
+
+// CategoryRepository defines the interface for category data operations.
+// Every method is scoped to the owning user.
 type CategoryRepository interface {
-	Create(category *Category) error
-	GetByID(id int) (*Category, error)
-	GetAll() ([]*Category, error)
-	Update(category *Category) error
-	Delete(id int) error
+	// WithTx returns the repository bound to a transaction from a TxManager
+	WithTx(tx Tx) CategoryRepository
+	Create(ctx context.Context, category *Category) error
+	GetByID(ctx context.Context, userID, id int) (*Category, error)
+	GetAll(ctx context.Context, userID int) ([]*Category, error)
+	Update(ctx context.Context, category *Category) error
+	// SetArchived archives or restores a category together with its subcategories
+	SetArchived(ctx context.Context, userID, id int, archived bool) error
+	// Delete moves the category and its budgets to the trash. It refuses with ErrInUse
+	// while expenses, recurring expenses or subcategories outside the trash use it.
+	Delete(ctx context.Context, userID, id int) error
+	// Merge moves the expenses, recurring expenses, incomes and subcategories of
+	// sourceID to targetID, along with budgets for months targetID has none, and
+	// deletes sourceID, all in one transaction
+	Merge(ctx context.Context, userID, sourceID, targetID int) error
 }
@@ -0,0 +1,154 @@
+package domain
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBaseCurrency is the currency totals are kept in unless configured otherwise
+const DefaultBaseCurrency = "INR"
+
+// rateScale is the number of rate units in one, giving rates eight decimal places
+const rateScale = 100000000
+
+// NormalizeCurrency uppercases and checks an ISO 4217 code such as "usd"
+func NormalizeCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 {
+		return "", ErrInvalidCurrency
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return "", ErrInvalidCurrency
+		}
+	}
+	return code, nil
+}
+
+// Rate is an exchange rate with eight decimal places: the amount of the base
+// currency one unit of a foreign currency buys. Like Money it is exact.
+type Rate int64
+
+// RateOne is the rate of the base currency against itself
+const RateOne = Rate(rateScale)
+
+// ParseRate parses a positive rate such as "83.125" with at most eight decimal places
+func ParseRate(s string) (Rate, error) {
+	s = strings.TrimSpace(s)
+	whole, fraction, hasPoint := strings.Cut(s, ".")
+	if whole == "" || len(whole) > 10 || !isDigits(whole) {
+		return 0, ErrInvalidRate
+	}
+	if hasPoint && (fraction == "" || len(fraction) > 8 || !isDigits(fraction)) {
+		return 0, ErrInvalidRate
+	}
+
+	units, _ := strconv.ParseInt(whole, 10, 64)
+	units *= rateScale
+	if fraction != "" {
+		fractionUnits, _ := strconv.ParseInt((fraction + "0000000")[:8], 10, 64)
+		units += fractionUnits
+	}
+	if units <= 0 {
+		return 0, ErrInvalidRate
+	}
+	return Rate(units), nil
+}
+
+// String formats the rate without trailing zeros, e.g. "83.125"
+func (r Rate) String() string {
+	s := fmt.Sprintf("%d.%08d", int64(r)/rateScale, int64(r)%rateScale)
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
+
+// MarshalJSON encodes the rate as a number
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalJSON accepts a number or a numeric string
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	text := string(data)
+	if strings.HasPrefix(text, `"`) {
+		unquoted, err := strconv.Unquote(text)
+		if err != nil {
+			return ErrInvalidRate
+		}
+		text = unquoted
+	}
+
+	parsed, err := ParseRate(text)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// Value stores the rate as a decimal string
+func (r Rate) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+// Scan reads a NUMERIC rate column
+func (r *Rate) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		*r = Rate(v * rateScale)
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Rate", src)
+	}
+
+	parsed, err := ParseRate(s)
+	if err != nil {
+		return fmt.Errorf("cannot scan %q into Rate: %w", s, err)
+	}
+	*r = parsed
+	return nil
+}
+
+// Convert multiplies the amount by the rate, rounding half away from zero to the minor unit
+func (m Money) Convert(rate Rate) Money {
+	product := new(big.Int).Mul(big.NewInt(int64(m)), big.NewInt(int64(rate)))
+	negative := product.Sign() < 0
+	product.Abs(product)
+	product.Add(product, big.NewInt(rateScale/2))
+	product.Quo(product, big.NewInt(rateScale))
+	if negative {
+		product.Neg(product)
+	}
+	return Money(product.Int64())
+}
+
+// ExchangeRate is the rate of a foreign currency against the base currency on a date
+type ExchangeRate struct {
+	Currency string    `json:"currency"`
+	Date     time.Time `json:"date"`
+	Rate     Rate      `json:"rate"`
+}
+
+// ExchangeRateRepository defines the interface for exchange rate data operations.
+// Rates are shared by all users.
+type ExchangeRateRepository interface {
+	// Upsert saves the rates in a single transaction, replacing any rate for the same currency and date
+	Upsert(ctx context.Context, rates []*ExchangeRate) error
+	// GetOnOrBefore returns the latest rate for the currency dated on or before date, or ErrNotFound
+	GetOnOrBefore(ctx context.Context, currency string, date time.Time) (*ExchangeRate, error)
+	// GetAll lists the rates for a currency, or for every currency when it is empty, newest first
+	GetAll(ctx context.Context, currency string) ([]*ExchangeRate, error)
+}
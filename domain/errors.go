@@ -3,11 +3,22 @@ package domain
 import "errors"
 
 var (
-	ErrNotFound            = errors.New("resource not found")
-	ErrInvalidCredentials  = errors.New("invalid credentials")
-	ErrEmailAlreadyExists  = errors.New("email already exists")
-	ErrInvalidPaymentMode  = errors.New("invalid payment mode")
-	ErrInvalidCategory     = errors.New("invalid category")
-	ErrUnauthorized        = errors.New("unauthorized")
-	ErrInvalidInput        = errors.New("invalid input")
+	ErrNotFound           = errors.New("resource not found")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrEmailAlreadyExists = errors.New("email already exists")
+	ErrInvalidPaymentMode = errors.New("invalid payment mode")
+	ErrInvalidCategory    = errors.New("invalid category")
+	ErrCategoryCycle      = errors.New("a category cannot be moved under itself or one of its subcategories")
+	ErrInvalidAccount     = errors.New("invalid account")
+	ErrUnauthorized       = errors.New("unauthorized")
+	ErrInvalidInput       = errors.New("invalid input")
+	ErrInvalidSchedule    = errors.New("invalid schedule")
+	ErrAlreadyExists      = errors.New("resource already exists")
+	ErrInUse              = errors.New("resource is still in use")
+	ErrInvalidReference   = errors.New("a referenced record does not exist")
+	ErrInvalidAmount      = errors.New("invalid amount: expected a number with at most two decimal places")
+	ErrAmountTooLarge     = errors.New("invalid amount: must be at most 99999999.99")
+	ErrInvalidCurrency    = errors.New("invalid currency: expected a three-letter ISO 4217 code")
+	ErrInvalidRate        = errors.New("invalid exchange rate: expected a positive number with at most eight decimal places")
+	ErrNoExchangeRate     = errors.New("no exchange rate for the currency on or before the expense date")
 )
@@ -1,33 +1,86 @@
 package domain
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
-// Expense represents an expense entry
+// MaxDescriptionLength caps the description of expenses, recurring expenses, incomes and transfers
+const MaxDescriptionLength = 500
+
+// Dates outside EarliestDate and LatestDate are refused, which catches typos such as a five-digit year
+var (
+	EarliestDate = time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)
+	LatestDate   = time.Date(2100, time.December, 31, 0, 0, 0, 0, time.UTC)
+)
+
+// Expense represents an expense entry. Amount is always in the base currency;
+// OriginalAmount is what was spent in Currency and ExchangeRate the rate used to
+// convert it, recorded so totals don't change when rates are loaded later.
+// AccountID is the account the expense is paid from, if one is chosen.
 type Expense struct {
-	ID          int         `json:"id"`
-	CategoryID  int         `json:"category_id"`
-	Amount      float64     `json:"amount"`
-	Description string      `json:"description"`
-	PaymentMode PaymentMode `json:"payment_mode"`
-	ExpenseDate time.Time   `json:"expense_date"`
-	CreatedAt   time.Time   `json:"created_at"`
-	Warning     string      `json:"warning,omitempty"`
+	ID                 int         `json:"id"`
+	UserID             int         `json:"-"`
+	CategoryID         int         `json:"category_id"`
+	Amount             Money       `json:"amount"`
+	Currency           string      `json:"currency"`
+	OriginalAmount     Money       `json:"original_amount"`
+	ExchangeRate       Rate        `json:"exchange_rate"`
+	Description        string      `json:"description"`
+	PaymentMode        PaymentMode `json:"payment_mode"`
+	AccountID          *int        `json:"account_id,omitempty"`
+	ExpenseDate        time.Time   `json:"expense_date"`
+	RecurringExpenseID *int        `json:"recurring_expense_id,omitempty"`
+	Tags               []string    `json:"tags"`
+	CreatedAt          time.Time   `json:"created_at"`
+	DeletedAt          *time.Time  `json:"deleted_at,omitempty"`
+	Warning            string      `json:"warning,omitempty"`
+	// Rank and Highlight are only set on search results
+	Rank      float64 `json:"rank,omitempty"`
+	Highlight string  `json:"highlight,omitempty"`
+}
+
+// ExpenseExportRow is an expense with its category name resolved, as written by exports
+type ExpenseExportRow struct {
+	*Expense
+	CategoryName string `json:"category_name"`
 }
 
-// ExpenseFilter represents filters for querying expenses
+// ExpenseFilter represents filters for querying expenses.
+// Tags keeps expenses carrying any of the tags, or all of them when AllTags is set.
+// Query is a full-text search over the description and category name.
+// Sort, Descending, Limit and After control paging and are ignored by counts and exports.
 type ExpenseFilter struct {
-	CategoryID  *int
-	PaymentMode *PaymentMode
-	StartDate   *time.Time
-	EndDate     *time.Time
+	CategoryID *int
+	// IncludeSubcategories widens CategoryID to every category below it
+	IncludeSubcategories bool
+	PaymentMode          *PaymentMode
+	AccountID            *int
+	StartDate            *time.Time
+	EndDate              *time.Time
+	Query                string
+	Tags                 []string
+	AllTags              bool
+	Sort                 ExpenseSort
+	Descending           bool
+	Limit                int
+	After                *ExpenseCursor
 }
 
-// ExpenseRepository defines the interface for expense data operations
+// ExpenseRepository defines the interface for expense data operations.
+// Every method is scoped to the owning user.
 type ExpenseRepository interface {
-	Create(expense *Expense) error
-	GetByID(id int) (*Expense, error)
-	GetAll(filter *ExpenseFilter) ([]*Expense, error)
-	Update(expense *Expense) error
-	Delete(id int) error
-	GetTotalByMonth(month, year int) (float64, error)
+	// WithTx returns the repository bound to a transaction from a TxManager
+	WithTx(tx Tx) ExpenseRepository
+	Create(ctx context.Context, expense *Expense) error
+	CreateBatch(ctx context.Context, expenses []*Expense) error
+	GetByID(ctx context.Context, userID, id int) (*Expense, error)
+	GetAll(ctx context.Context, userID int, filter *ExpenseFilter) ([]*Expense, error)
+	Count(ctx context.Context, userID int, filter *ExpenseFilter) (int, error)
+	StreamAll(ctx context.Context, userID int, filter *ExpenseFilter, fn func(row *ExpenseExportRow) error) error
+	Update(ctx context.Context, expense *Expense) error
+	Delete(ctx context.Context, userID, id int) error
+	GetTotalByMonth(ctx context.Context, userID, month, year int) (Money, error)
+	// GetTotalsByCategoryForMonth rolls subcategory spending up into every parent's total
+	GetTotalsByCategoryForMonth(ctx context.Context, userID, month, year int) (map[int]Money, error)
 }
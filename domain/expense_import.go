@@ -0,0 +1,52 @@
+package domain
+
+// ImportRowStatus is the outcome of importing a single CSV row
+type ImportRowStatus string
+
+const (
+	ImportRowCreated ImportRowStatus = "created"
+	ImportRowSkipped ImportRowStatus = "skipped"
+	ImportRowFailed  ImportRowStatus = "failed"
+)
+
+// ImportMapping maps expense fields to CSV header names
+type ImportMapping struct {
+	Date        string
+	Amount      string
+	Currency    string
+	Description string
+	Category    string
+	PaymentMode string
+	DateFormat  string
+}
+
+// DefaultImportMapping returns the mapping used when the caller does not override a column
+func DefaultImportMapping() ImportMapping {
+	return ImportMapping{
+		Date:        "date",
+		Amount:      "amount",
+		Currency:    "currency",
+		Description: "description",
+		Category:    "category",
+		PaymentMode: "payment_mode",
+		DateFormat:  "2006-01-02",
+	}
+}
+
+// ImportRowResult reports what happened to one CSV row.
+// Row is the line number in the file, counting the header as line 1.
+type ImportRowResult struct {
+	Row     int             `json:"row"`
+	Status  ImportRowStatus `json:"status"`
+	Reason  string          `json:"reason,omitempty"`
+	Expense *Expense        `json:"expense,omitempty"`
+}
+
+// ImportReport summarises a CSV import
+type ImportReport struct {
+	DryRun  bool               `json:"dry_run"`
+	Created int                `json:"created"`
+	Skipped int                `json:"skipped"`
+	Failed  int                `json:"failed"`
+	Rows    []*ImportRowResult `json:"rows"`
+}
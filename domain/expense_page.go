@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// ExpenseSort is a field expenses can be ordered by
+type ExpenseSort string
+
+const (
+	ExpenseSortDate      ExpenseSort = "date"
+	ExpenseSortAmount    ExpenseSort = "amount"
+	ExpenseSortCreatedAt ExpenseSort = "created_at"
+	// ExpenseSortRelevance orders search results by rank and requires a query
+	ExpenseSortRelevance ExpenseSort = "relevance"
+)
+
+// IsValid checks if the sort field is supported
+func (s ExpenseSort) IsValid() bool {
+	return s == ExpenseSortDate || s == ExpenseSortAmount || s == ExpenseSortCreatedAt || s == ExpenseSortRelevance
+}
+
+// ExpensePage is one page of expenses. NextCursor is nil on the last page.
+type ExpensePage struct {
+	Items      []*Expense `json:"items"`
+	NextCursor *string    `json:"next_cursor"`
+	TotalCount int        `json:"total_count"`
+}
+
+// ExpenseCursor marks the last expense of a page. Value is that expense's
+// sort key and ID breaks ties, so a page resumes exactly where the previous one ended.
+type ExpenseCursor struct {
+	Sort       ExpenseSort `json:"s"`
+	Descending bool        `json:"d"`
+	Value      string      `json:"v"`
+	ID         int         `json:"id"`
+}
+
+// NewExpenseCursor builds the cursor that resumes after expense
+func NewExpenseCursor(sort ExpenseSort, descending bool, expense *Expense) *ExpenseCursor {
+	cursor := &ExpenseCursor{Sort: sort, Descending: descending, ID: expense.ID}
+	switch sort {
+	case ExpenseSortAmount:
+		cursor.Value = expense.Amount.String()
+	case ExpenseSortCreatedAt:
+		cursor.Value = expense.CreatedAt.Format("2006-01-02T15:04:05.999999")
+	case ExpenseSortRelevance:
+		// Ranks are single precision in Postgres; format them so they round-trip exactly
+		cursor.Value = strconv.FormatFloat(expense.Rank, 'g', -1, 32)
+	default:
+		cursor.Value = expense.ExpenseDate.Format("2006-01-02")
+	}
+	return cursor
+}
+
+// Encode turns the cursor into an opaque URL-safe token
+func (c *ExpenseCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseExpenseCursor decodes a token produced by Encode
+func ParseExpenseCursor(token string) (*ExpenseCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidInput
+	}
+
+	cursor := &ExpenseCursor{}
+	if err := json.Unmarshal(data, cursor); err != nil || !cursor.Sort.IsValid() || cursor.ID < 1 {
+		return nil, ErrInvalidInput
+	}
+
+	// The value ends up in a query, so it must parse as the sort key's type
+	switch cursor.Sort {
+	case ExpenseSortAmount:
+		_, err = ParseMoney(cursor.Value)
+	case ExpenseSortRelevance:
+		_, err = strconv.ParseFloat(cursor.Value, 64)
+	case ExpenseSortCreatedAt:
+		_, err = time.Parse("2006-01-02T15:04:05.999999", cursor.Value)
+	default:
+		_, err = time.Parse("2006-01-02", cursor.Value)
+	}
+	if err != nil {
+		return nil, ErrInvalidInput
+	}
+	return cursor, nil
+}
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Income represents money received, such as salary or a refund. Amounts are in the
+// base currency. PaymentMode names the payment method it was received by and AccountID
+// the account it credits; both are optional, like CategoryID.
+type Income struct {
+	ID          int          `json:"id"`
+	UserID      int          `json:"-"`
+	CategoryID  *int         `json:"category_id,omitempty"`
+	Source      string       `json:"source"`
+	Amount      Money        `json:"amount"`
+	Description string       `json:"description"`
+	PaymentMode *PaymentMode `json:"payment_mode,omitempty"`
+	AccountID   *int         `json:"account_id,omitempty"`
+	IncomeDate  time.Time    `json:"income_date"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// IncomeFilter limits incomes to a date range; both ends are inclusive and optional
+type IncomeFilter struct {
+	StartDate *time.Time
+	EndDate   *time.Time
+}
+
+// IncomeRepository defines the interface for income data operations.
+// Every method is scoped to the owning user.
+type IncomeRepository interface {
+	// WithTx returns the repository bound to a transaction from a TxManager
+	WithTx(tx Tx) IncomeRepository
+	Create(ctx context.Context, income *Income) error
+	GetByID(ctx context.Context, userID, id int) (*Income, error)
+	GetAll(ctx context.Context, userID int, filter *IncomeFilter) ([]*Income, error)
+	Update(ctx context.Context, income *Income) error
+	Delete(ctx context.Context, userID, id int) error
+	GetTotalByMonth(ctx context.Context, userID, month, year int) (Money, error)
+}
@@ -0,0 +1,155 @@
+package domain
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// maxMoneyDigits bounds the integer part so every amount, and every sum of them the
+// database returns, fits in an int64 of minor units
+const maxMoneyDigits = 15
+
+// MaxAmount is the largest amount the DECIMAL(10,2) columns hold, 99999999.99.
+// Larger amounts are refused with ErrAmountTooLarge before they reach the database.
+const MaxAmount Money = 99_999_999_99
+
+// Money is an exact amount in minor units (paise, cents), so sums never drift the way
+// float64 does. It reads and writes the DECIMAL(10,2) columns directly and is encoded
+// in JSON as a number with exactly two decimal places, e.g. 1250.50.
+type Money int64
+
+// ParseMoney parses an amount such as "1250.5" or "-20.00". Amounts with more than
+// two decimal places, exponents or anything other than digits fail with ErrInvalidAmount.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	whole, fraction, hasPoint := strings.Cut(s, ".")
+	if whole == "" || len(whole) > maxMoneyDigits || !isDigits(whole) {
+		return 0, ErrInvalidAmount
+	}
+	if hasPoint && (fraction == "" || len(fraction) > 2 || !isDigits(fraction)) {
+		return 0, ErrInvalidAmount
+	}
+
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidAmount
+	}
+	units *= 100
+	if fraction != "" {
+		cents, _ := strconv.ParseInt((fraction + "0")[:2], 10, 64)
+		units += cents
+	}
+
+	if negative {
+		units = -units
+	}
+	return Money(units), nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// String formats the amount with two decimal places
+func (m Money) String() string {
+	units := int64(m)
+	sign := ""
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, units/100, units%100)
+}
+
+// Div splits the amount n ways, rounding half away from zero
+func (m Money) Div(n int) Money {
+	units, divisor := int64(m), int64(n)
+	if units < 0 {
+		return -Money((-units*2 + divisor) / (2 * divisor))
+	}
+	return Money((units*2 + divisor) / (2 * divisor))
+}
+
+// Percent returns the amount as a percentage of total, rounded half away from zero
+// to two decimal places. total must be positive.
+func (m Money) Percent(total Money) float64 {
+	basisPoints := new(big.Int).Mul(big.NewInt(int64(m)), big.NewInt(10000))
+	negative := basisPoints.Sign() < 0
+	basisPoints.Abs(basisPoints)
+	basisPoints.Add(basisPoints, big.NewInt(int64(total)/2))
+	basisPoints.Quo(basisPoints, big.NewInt(int64(total)))
+	if negative {
+		basisPoints.Neg(basisPoints)
+	}
+	return float64(basisPoints.Int64()) / 100
+}
+
+// MarshalJSON encodes the amount as a fixed-scale number
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a number or a numeric string with at most two decimal places
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	text := string(data)
+	if strings.HasPrefix(text, `"`) {
+		unquoted, err := strconv.Unquote(text)
+		if err != nil {
+			return ErrInvalidAmount
+		}
+		text = unquoted
+	}
+
+	parsed, err := ParseMoney(text)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value stores the amount as a decimal string
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan reads a DECIMAL column, including SUM aggregates
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return m.scanDecimal(string(v))
+	case string:
+		return m.scanDecimal(v)
+	case int64:
+		*m = Money(v * 100)
+		return nil
+	case nil:
+		*m = 0
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into Money", src)
+}
+
+func (m *Money) scanDecimal(s string) error {
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return fmt.Errorf("cannot scan %q into Money: %w", s, err)
+	}
+	*m = parsed
+	return nil
+}
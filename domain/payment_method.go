@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// PaymentMethodType is the kind of instrument behind a payment method
+type PaymentMethodType string
+
+const (
+	PaymentMethodTypeCard   PaymentMethodType = "card"
+	PaymentMethodTypeBank   PaymentMethodType = "bank"
+	PaymentMethodTypeWallet PaymentMethodType = "wallet"
+	PaymentMethodTypeCash   PaymentMethodType = "cash"
+	PaymentMethodTypeUPI    PaymentMethodType = "upi"
+)
+
+// IsValid checks if the payment method type is supported
+func (t PaymentMethodType) IsValid() bool {
+	switch t {
+	case PaymentMethodTypeCard, PaymentMethodTypeBank, PaymentMethodTypeWallet, PaymentMethodTypeCash, PaymentMethodTypeUPI:
+		return true
+	}
+	return false
+}
+
+// PaymentMethod is a way a user pays, such as a card or a UPI ID. Expenses refer
+// to it by name through their PaymentMode. Last4 applies to cards and bank accounts,
+// VPA to UPI. Inactive methods stay on old expenses but can't be used for new ones.
+type PaymentMethod struct {
+	ID        int               `json:"id"`
+	UserID    int               `json:"-"`
+	Name      string            `json:"name"`
+	Type      PaymentMethodType `json:"type"`
+	Last4     *string           `json:"last4,omitempty"`
+	VPA       *string           `json:"vpa,omitempty"`
+	Active    bool              `json:"active"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// DefaultPaymentMethods returns the payment methods created for every new user
+func DefaultPaymentMethods() []*PaymentMethod {
+	return []*PaymentMethod{
+		{Name: string(PaymentModeUPI), Type: PaymentMethodTypeUPI, Active: true},
+		{Name: string(PaymentModeCash), Type: PaymentMethodTypeCash, Active: true},
+	}
+}
+
+// PaymentMethodRepository defines the interface for payment method data operations.
+// Every method is scoped to the owning user.
+type PaymentMethodRepository interface {
+	// WithTx returns the repository bound to a transaction from a TxManager
+	WithTx(tx Tx) PaymentMethodRepository
+	Create(ctx context.Context, method *PaymentMethod) error
+	GetByID(ctx context.Context, userID, id int) (*PaymentMethod, error)
+	GetByName(ctx context.Context, userID int, name string) (*PaymentMethod, error)
+	GetAll(ctx context.Context, userID int) ([]*PaymentMethod, error)
+	Update(ctx context.Context, method *PaymentMethod) error
+	Delete(ctx context.Context, userID, id int) error
+}
@@ -1,14 +1,10 @@
 package domain
 
-// PaymentMode represents the mode of payment for an expense
+// PaymentMode is the name of one of the user's payment methods, as stored on expenses
 type PaymentMode string
 
+// Names of the payment methods every new user starts with
 const (
 	PaymentModeUPI  PaymentMode = "UPI"
 	PaymentModeCash PaymentMode = "Cash"
 )
-
-// IsValid checks if the payment mode is valid
-func (pm PaymentMode) IsValid() bool {
-	return pm == PaymentModeUPI || pm == PaymentModeCash
-}
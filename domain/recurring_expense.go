@@ -0,0 +1,120 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Frequency represents how often a recurring expense repeats
+type Frequency string
+
+const (
+	FrequencyDaily   Frequency = "daily"
+	FrequencyWeekly  Frequency = "weekly"
+	FrequencyMonthly Frequency = "monthly"
+	FrequencyYearly  Frequency = "yearly"
+)
+
+// IsValid checks if the frequency is valid
+func (f Frequency) IsValid() bool {
+	switch f {
+	case FrequencyDaily, FrequencyWeekly, FrequencyMonthly, FrequencyYearly:
+		return true
+	}
+	return false
+}
+
+// RecurringExpense is a template the scheduler turns into expenses on a schedule.
+// Monthly templates repeat on DayOfMonth when set, otherwise on the day of StartDate;
+// days past the end of a short month fall on its last day.
+type RecurringExpense struct {
+	ID          int         `json:"id"`
+	UserID      int         `json:"-"`
+	CategoryID  int         `json:"category_id"`
+	Amount      Money       `json:"amount"`
+	Description string      `json:"description"`
+	PaymentMode PaymentMode `json:"payment_mode"`
+	Frequency   Frequency   `json:"frequency"`
+	DayOfMonth  *int        `json:"day_of_month,omitempty"`
+	StartDate   time.Time   `json:"start_date"`
+	EndDate     *time.Time  `json:"end_date,omitempty"`
+	NextRunDate time.Time   `json:"next_run_date"`
+	Active      bool        `json:"active"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+// OccurrenceOnOrAfter returns the first scheduled date on or after t
+func (r *RecurringExpense) OccurrenceOnOrAfter(t time.Time) time.Time {
+	start := TruncateToDate(r.StartDate)
+	t = TruncateToDate(t)
+	if t.Before(start) {
+		t = start
+	}
+
+	switch r.Frequency {
+	case FrequencyWeekly:
+		days := int(t.Sub(start).Hours() / 24)
+		if rem := days % 7; rem != 0 {
+			t = t.AddDate(0, 0, 7-rem)
+		}
+		return t
+	case FrequencyMonthly:
+		day := start.Day()
+		if r.DayOfMonth != nil {
+			day = *r.DayOfMonth
+		}
+		candidate := dateInMonth(t.Year(), t.Month(), day)
+		if candidate.Before(t) {
+			candidate = dateInMonth(t.Year(), t.Month()+1, day)
+		}
+		return candidate
+	case FrequencyYearly:
+		candidate := dateInMonth(t.Year(), start.Month(), start.Day())
+		if candidate.Before(t) {
+			candidate = dateInMonth(t.Year()+1, start.Month(), start.Day())
+		}
+		return candidate
+	default:
+		return t
+	}
+}
+
+// NextOccurrence returns the first scheduled date after t
+func (r *RecurringExpense) NextOccurrence(t time.Time) time.Time {
+	return r.OccurrenceOnOrAfter(TruncateToDate(t).AddDate(0, 0, 1))
+}
+
+// IsDue reports whether an occurrence on date should still be created
+func (r *RecurringExpense) IsDue(date time.Time) bool {
+	return r.Active && (r.EndDate == nil || !date.After(*r.EndDate))
+}
+
+// TruncateToDate drops the time of day, keeping the calendar date in UTC
+func TruncateToDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// dateInMonth returns the given day of a month, clamped to the month's last day
+func dateInMonth(year int, month time.Month, day int) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1).Day()
+	if day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// RecurringExpenseRepository defines the interface for recurring expense data operations.
+// Every method except those used by the scheduler is scoped to the owning user.
+type RecurringExpenseRepository interface {
+	// WithTx returns the repository bound to a transaction from a TxManager
+	WithTx(tx Tx) RecurringExpenseRepository
+	Create(ctx context.Context, recurring *RecurringExpense) error
+	GetByID(ctx context.Context, userID, id int) (*RecurringExpense, error)
+	GetAll(ctx context.Context, userID int) ([]*RecurringExpense, error)
+	GetDue(ctx context.Context, asOf time.Time) ([]*RecurringExpense, error)
+	Update(ctx context.Context, recurring *RecurringExpense) error
+	UpdateNextRunDate(ctx context.Context, id int, nextRunDate time.Time) error
+	Delete(ctx context.Context, userID, id int) error
+}
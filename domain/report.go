@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// MonthlyReport summarises a user's spending for one calendar month
+type MonthlyReport struct {
+	Month          int                     `json:"month"`
+	Year           int                     `json:"year"`
+	Total          Money                   `json:"total"`
+	Count          int                     `json:"count"`
+	AveragePerDay  Money                   `json:"average_per_day"`
+	LargestExpense *Expense                `json:"largest_expense"`
+	ByCategory     []*CategoryBreakdown    `json:"by_category"`
+	ByPaymentMode  []*PaymentModeBreakdown `json:"by_payment_mode"`
+	Daily          []*DailyTotal           `json:"daily"`
+}
+
+// SpendingSummary holds the headline aggregates for a date range
+type SpendingSummary struct {
+	Total Money
+	Count int
+}
+
+// CategoryBreakdown is the spending in one category. Amount, Count and Share, a percentage
+// of the total, cover the category's own expenses; the rolled-up figures add its subcategories.
+type CategoryBreakdown struct {
+	CategoryID     int     `json:"category_id"`
+	ParentID       *int    `json:"parent_id"`
+	CategoryName   string  `json:"category_name"`
+	Amount         Money   `json:"amount"`
+	Share          float64 `json:"share"`
+	Count          int     `json:"count"`
+	RolledUpAmount Money   `json:"rolled_up_amount"`
+	RolledUpCount  int     `json:"rolled_up_count"`
+}
+
+// PaymentModeBreakdown is the spending with one payment mode. Share is a percentage of the total.
+type PaymentModeBreakdown struct {
+	PaymentMode PaymentMode `json:"payment_mode"`
+	Amount      Money       `json:"amount"`
+	Share       float64     `json:"share"`
+	Count       int         `json:"count"`
+}
+
+// DailyTotal is the spending on a single day
+type DailyTotal struct {
+	Date   time.Time `json:"date"`
+	Amount Money     `json:"amount"`
+	Count  int       `json:"count"`
+}
+
+// CashflowMonth is the money in and out during one calendar month, formatted YYYY-MM.
+// SavingsRate is the net as a percentage of income and is nil for a month without income.
+type CashflowMonth struct {
+	Month       string   `json:"month"`
+	Income      Money    `json:"income"`
+	Expenses    Money    `json:"expenses"`
+	Net         Money    `json:"net"`
+	SavingsRate *float64 `json:"savings_rate"`
+}
+
+// CashflowReport is the cash flow for each month in a date range, with totals over the range
+type CashflowReport struct {
+	From        time.Time        `json:"from"`
+	To          time.Time        `json:"to"`
+	Income      Money            `json:"income"`
+	Expenses    Money            `json:"expenses"`
+	Net         Money            `json:"net"`
+	SavingsRate *float64         `json:"savings_rate"`
+	Months      []*CashflowMonth `json:"months"`
+}
+
+// ReportRepository defines aggregate queries over expenses.
+// Ranges include from and exclude to; every method is scoped to the owning user.
+type ReportRepository interface {
+	GetSummary(ctx context.Context, userID int, from, to time.Time) (*SpendingSummary, error)
+	GetLargestExpense(ctx context.Context, userID int, from, to time.Time) (*Expense, error)
+	GetCategoryBreakdown(ctx context.Context, userID int, from, to time.Time) ([]*CategoryBreakdown, error)
+	GetPaymentModeBreakdown(ctx context.Context, userID int, from, to time.Time) ([]*PaymentModeBreakdown, error)
+	GetDailyTotals(ctx context.Context, userID int, from, to time.Time) ([]*DailyTotal, error)
+	// GetMonthlyCashflow returns income and expenses for every month the range touches, including empty months
+	GetMonthlyCashflow(ctx context.Context, userID int, from, to time.Time) ([]*CashflowMonth, error)
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// maxTagLength caps the length of a tag name
+const maxTagLength = 50
+
+// Tag is a free-form label that can be attached to any number of expenses
+type Tag struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"-"`
+	Name       string    `json:"name"`
+	UsageCount int       `json:"usage_count"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// NormalizeTagName trims and lowercases a tag name so "Trip-Goa" and "trip-goa" are the same tag
+func NormalizeTagName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || len(name) > maxTagLength || strings.Contains(name, ",") {
+		return "", ErrInvalidInput
+	}
+	return name, nil
+}
+
+// NormalizeTagNames normalizes every name and drops duplicates, keeping the first occurrence.
+// The result is never nil, so an empty list clears an expense's tags.
+func NormalizeTagNames(names []string) ([]string, error) {
+	normalized := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name, err := NormalizeTagName(name)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[name] {
+			seen[name] = true
+			normalized = append(normalized, name)
+		}
+	}
+	return normalized, nil
+}
+
+// TagRepository defines the interface for tag data operations.
+// Tags are attached to expenses by the expense repository; every method is scoped to the owning user.
+type TagRepository interface {
+	GetAll(ctx context.Context, userID int) ([]*Tag, error)
+	GetByID(ctx context.Context, userID, id int) (*Tag, error)
+	Rename(ctx context.Context, userID, id int, name string) error
+	Merge(ctx context.Context, userID, sourceID, targetID int) error
+	// WithTx returns the repository bound to a transaction from a TxManager
+	WithTx(tx Tx) TagRepository
+}
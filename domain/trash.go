@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// TrashItemType names a kind of record that can be deleted to the trash and restored
+type TrashItemType string
+
+const (
+	TrashItemExpense  TrashItemType = "expense"
+	TrashItemCategory TrashItemType = "category"
+	TrashItemBudget   TrashItemType = "budget"
+)
+
+// IsValid reports whether the item type is one of the known kinds
+func (t TrashItemType) IsValid() bool {
+	switch t {
+	case TrashItemExpense, TrashItemCategory, TrashItemBudget:
+		return true
+	}
+	return false
+}
+
+// Trash holds a user's deleted records, most recently deleted first
+type Trash struct {
+	Expenses   []*Expense  `json:"expenses"`
+	Categories []*Category `json:"categories"`
+	Budgets    []*Budget   `json:"budgets"`
+}
+
+// TrashRepository defines the interface for listing, restoring and purging deleted records
+type TrashRepository interface {
+	GetAll(ctx context.Context, userID int) (*Trash, error)
+	// Restore returns ErrNotFound when the item isn't in the user's trash and
+	// ErrInvalidCategory when the category it belongs under is in the trash too.
+	// Restoring a category also restores the budgets deleted with it.
+	Restore(ctx context.Context, userID int, itemType TrashItemType, id int) error
+	// Purge permanently removes every user's records deleted before the cutoff
+	// and returns how many were removed
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,16 @@
+package domain
+
+import "context"
+
+// Tx is an open transaction handed out by a TxManager. Only the repositories
+// of the storage that began it know what it holds.
+type Tx interface{}
+
+// TxManager runs a unit of work in a single transaction. Inside the work,
+// repositories bound to the transaction with their WithTx method see each other's
+// changes, and GetByID locks the record it returns until the transaction ends.
+type TxManager interface {
+	// WithinTx commits the transaction when fn returns nil and otherwise rolls it
+	// back and returns fn's error. Cancelling ctx rolls the transaction back.
+	WithinTx(ctx context.Context, fn func(tx Tx) error) error
+}
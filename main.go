@@ -2,13 +2,20 @@ package main
 
 import (
 	"context"
-	"expense-tracker-api/repository"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"expense-tracker-api/domain"
+	"expense-tracker-api/repository/memory"
 	"expense-tracker-api/services"
 	"expense-tracker-api/transport"
+	"flag"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -16,39 +23,170 @@ import (
 )
 
 func main() {
+	demo := flag.Bool("demo", false, "keep records in memory, preloaded with the demo data, instead of a database")
+	flag.Parse()
+	args := flag.Args()
+
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using environment variables")
 	}
 
-	// Initialize database
-	if err := repository.InitDB(); err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+	// Initialize the database of the STORAGE_DRIVER, Postgres unless set to sqlite,
+	// or the in-memory storage of the demo mode
+	var store *storage
+	if *demo {
+		store = openDemoStorage()
+	} else {
+		var err error
+		store, err = openStorage(os.Getenv("STORAGE_DRIVER"))
+		if err != nil {
+			log.Fatalf("Failed to initialize database: %v", err)
+		}
+	}
+	defer store.close()
+
+	// The migrate subcommand manages the schema and exits
+	if len(args) > 0 && args[0] == "migrate" {
+		if store.migrator == nil {
+			log.Fatal("The demo mode keeps no schema to migrate")
+		}
+		if err := runMigrate(context.Background(), store.migrator, args[1:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	// Apply pending migrations. A dirty schema, or one a newer build migrated, is refused.
+	if store.migrator != nil {
+		if err := store.migrator.Up(context.Background()); err != nil {
+			log.Fatalf("Failed to migrate schema: %v", err)
+		}
+	}
+
+	// The adopt subcommand hands the data of databases created before user accounts
+	// existed to the user with the email, once they have registered, and exits
+	if len(args) > 0 && args[0] == "adopt" {
+		if len(args) != 2 {
+			log.Fatal("Usage: adopt <email>")
+		}
+		if store.adoptLegacyData == nil {
+			log.Fatal("This storage holds no data without an owner")
+		}
+		adopted, err := store.adoptLegacyData(context.Background(), args[1])
+		if errors.Is(err, domain.ErrNotFound) {
+			log.Fatalf("No user has registered with %s", args[1])
+		}
+		if err != nil {
+			log.Fatalf("Adopting data failed: %v", err)
+		}
+		log.Printf("%d records now belong to %s", adopted, args[1])
+		return
 	}
-	defer repository.CloseDB()
 
-	// Migrate existing schema (remove user_id columns if they exist)
-	if err := repository.MigrateSchema(); err != nil {
-		log.Printf("Warning: Migration failed (may not be needed): %v", err)
+	// Amounts are kept in the base currency, which can't change once expenses exist
+	baseCurrency := domain.DefaultBaseCurrency
+	if currencyStr := os.Getenv("BASE_CURRENCY"); currencyStr != "" {
+		currency, err := domain.NormalizeCurrency(currencyStr)
+		if err != nil {
+			log.Fatalf("Invalid BASE_CURRENCY: %q", currencyStr)
+		}
+		baseCurrency = currency
+	}
+	if err := store.initBaseCurrency(baseCurrency); err != nil {
+		log.Fatalf("Failed to set base currency: %v", err)
+	}
+	if store.seed != nil {
+		if err := store.seed(context.Background()); err != nil {
+			log.Fatalf("Failed to load demo data: %v", err)
+		}
+		log.Printf("Demo mode: records are kept in memory; sign in as %s with password %s", memory.DemoEmail, memory.DemoPassword)
 	}
 
-	// Create database schema
-	if err := repository.CreateSchema(); err != nil {
-		log.Fatalf("Failed to create schema: %v", err)
+	// Tokens are signed with a shared secret that must be configured. The demo mode
+	// makes one up, as its tokens can't outlive its records anyway.
+	jwtSecret := os.Getenv("JWT_SECRET")
+	if jwtSecret == "" && *demo {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatalf("Failed to generate JWT secret: %v", err)
+		}
+		jwtSecret = hex.EncodeToString(secret)
+	}
+	if jwtSecret == "" {
+		log.Fatal("JWT_SECRET must be set")
 	}
 
 	// Initialize repositories
-	categoryRepo := repository.NewCategoryRepository()
-	expenseRepo := repository.NewExpenseRepository()
-	budgetRepo := repository.NewBudgetRepository()
+	userRepo := store.users
+	categoryRepo := store.categories
+	expenseRepo := store.expenses
+	budgetRepo := store.budgets
+	recurringExpenseRepo := store.recurringExpenses
+	reportRepo := store.reports
+	tagRepo := store.tags
+	paymentMethodRepo := store.paymentMethods
+	exchangeRateRepo := store.exchangeRates
+	incomeRepo := store.incomes
+	accountRepo := store.accounts
+	transferRepo := store.transfers
+	trashRepo := store.trash
+	auditRepo := store.audit
+	txManager := store.txManager
 
 	// Initialize services
-	categoryService := services.NewCategoryService(categoryRepo)
-	expenseService := services.NewExpenseService(expenseRepo, categoryRepo, budgetRepo)
-	budgetService := services.NewBudgetService(budgetRepo, expenseRepo)
+	authService := services.NewAuthService(userRepo, []byte(jwtSecret))
+	categoryService := services.NewCategoryService(txManager, categoryRepo)
+	exchangeRateService := services.NewExchangeRateService(exchangeRateRepo, baseCurrency)
+	expenseService := services.NewExpenseService(txManager, expenseRepo, categoryRepo, budgetRepo, paymentMethodRepo, accountRepo, exchangeRateService)
+	budgetService := services.NewBudgetService(txManager, budgetRepo, expenseRepo, categoryRepo, incomeRepo)
+	recurringExpenseService := services.NewRecurringExpenseService(txManager, recurringExpenseRepo, categoryRepo, expenseService)
+	reportService := services.NewReportService(reportRepo)
+	tagService := services.NewTagService(txManager, tagRepo)
+	paymentMethodService := services.NewPaymentMethodService(txManager, paymentMethodRepo)
+	accountService := services.NewAccountService(txManager, accountRepo, transferRepo)
+	incomeService := services.NewIncomeService(txManager, incomeRepo, categoryRepo, paymentMethodRepo, accountRepo)
+
+	// Deleted records stay restorable for TRASH_RETENTION_DAYS days before the purger removes them
+	trashRetention := services.DefaultTrashRetention
+	if retentionStr := os.Getenv("TRASH_RETENTION_DAYS"); retentionStr != "" {
+		days, err := strconv.Atoi(retentionStr)
+		if err != nil || days < 0 {
+			log.Fatalf("Invalid TRASH_RETENTION_DAYS: %q", retentionStr)
+		}
+		trashRetention = time.Duration(days) * 24 * time.Hour
+	}
+	trashService := services.NewTrashService(trashRepo, trashRetention)
+	auditService := services.NewAuditService(auditRepo)
+
+	// A request's queries are cancelled once it has run for QUERY_TIMEOUT; 0 disables the limit
+	queryTimeout := 30 * time.Second
+	if timeoutStr := os.Getenv("QUERY_TIMEOUT"); timeoutStr != "" {
+		timeout, err := time.ParseDuration(timeoutStr)
+		if err != nil || timeout < 0 {
+			log.Fatalf("Invalid QUERY_TIMEOUT: %q", timeoutStr)
+		}
+		queryTimeout = timeout
+	}
 
 	// Setup router
-	router := transport.SetupRouter(categoryService, expenseService, budgetService)
+	router := transport.SetupRouter(authService, categoryService, expenseService, budgetService, recurringExpenseService, reportService, tagService,
+		paymentMethodService, exchangeRateService, incomeService, accountService, trashService, auditService, os.Getenv("ADMIN_TOKEN"),
+		queryTimeout)
+
+	// Start the recurring expense scheduler; it catches up on missed runs at startup
+	schedulerInterval := time.Hour
+	if intervalStr := os.Getenv("RECURRING_SCHEDULER_INTERVAL"); intervalStr != "" {
+		interval, err := time.ParseDuration(intervalStr)
+		if err != nil || interval <= 0 {
+			log.Fatalf("Invalid RECURRING_SCHEDULER_INTERVAL: %q", intervalStr)
+		}
+		schedulerInterval = interval
+	}
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+	go recurringExpenseService.StartScheduler(schedulerCtx, schedulerInterval)
+	go trashService.StartPurger(schedulerCtx, 24*time.Hour)
 
 	// Get port from environment or use default
 	port := os.Getenv("PORT")
@@ -56,10 +194,16 @@ func main() {
 		port = "8080"
 	}
 
+	// Every request context derives from requestsCtx, so cancelling it stops the
+	// queries of requests still running when the server shuts down
+	requestsCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+
 	// Create HTTP server
 	srv := &http.Server{
-		Addr:    ":" + port,
-		Handler: router,
+		Addr:        ":" + port,
+		Handler:     router,
+		BaseContext: func(net.Listener) context.Context { return requestsCtx },
 	}
 
 	// Start server in a goroutine
@@ -76,13 +220,17 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopScheduler()
 
 	// Graceful shutdown with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		// Requests still running after the grace period are cut off, along with their queries
+		log.Printf("Server forced to shutdown: %v", err)
+		cancelRequests()
+		srv.Close()
 	}
 
 	log.Println("Server exited")
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"expense-tracker-api/repository"
+	"fmt"
+	"strconv"
+)
+
+const migrateUsage = "usage: migrate up|down|status|to N|force N"
+
+// runMigrate runs the migrate subcommand against the schema of the storage driver:
+//
+//	migrate up        apply every pending migration
+//	migrate down      revert the latest migration
+//	migrate status    list the migrations and the version the database is at
+//	migrate to N      apply or revert migrations until the database is at version N
+//	migrate force N   record the database as being at version N after an interrupted migration
+func runMigrate(ctx context.Context, migrator *repository.Migrator, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up", "down", "status":
+		if len(args) != 1 {
+			return errors.New(migrateUsage)
+		}
+	case "to", "force":
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	var err error
+	switch args[0] {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		err = migrator.Down(ctx)
+	case "status":
+		return printSchemaStatus(ctx, migrator)
+	case "to", "force":
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil || version < 0 {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		if args[0] == "to" {
+			err = migrator.To(ctx, version)
+		} else {
+			err = migrator.Force(ctx, version)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return printSchemaStatus(ctx, migrator)
+}
+
+func printSchemaStatus(ctx context.Context, migrator *repository.Migrator) error {
+	status, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	state := "clean"
+	if status.Dirty {
+		state = "dirty"
+	}
+	fmt.Printf("Schema version %d of %d (%s)\n", status.Version, status.Latest(), state)
+	for _, migration := range status.Migrations {
+		applied := "pending"
+		if migration.Version <= status.Version {
+			applied = "applied"
+		}
+		fmt.Printf("  %04d_%s  %s\n", migration.Version, migration.Name, applied)
+	}
+	return nil
+}
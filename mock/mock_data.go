@@ -15,7 +15,7 @@ func GenerateMockCategory(id int, name string) *domain.Category {
 }
 
 // GenerateMockExpense creates a mock expense
-func GenerateMockExpense(id, categoryID int, amount float64, paymentMode domain.PaymentMode) *domain.Expense {
+func GenerateMockExpense(id, categoryID int, amount domain.Money, paymentMode domain.PaymentMode) *domain.Expense {
 	return &domain.Expense{
 		ID:          id,
 		CategoryID:  categoryID,
@@ -28,7 +28,7 @@ func GenerateMockExpense(id, categoryID int, amount float64, paymentMode domain.
 }
 
 // GenerateMockBudget creates a mock budget
-func GenerateMockBudget(id, month, year int, budgetAmount float64) *domain.Budget {
+func GenerateMockBudget(id, month, year int, budgetAmount domain.Money) *domain.Budget {
 	return &domain.Budget{
 		ID:           id,
 		Month:        month,
@@ -53,7 +53,7 @@ func GenerateMockExpenses(categoryID int, count int) []*domain.Expense {
 	expenses := make([]*domain.Expense, count)
 	paymentModes := []domain.PaymentMode{domain.PaymentModeUPI, domain.PaymentModeCash}
 	for i := 0; i < count; i++ {
-		expenses[i] = GenerateMockExpense(i+1, categoryID, float64((i+1)*100), paymentModes[i%2])
+		expenses[i] = GenerateMockExpense(i+1, categoryID, domain.Money((i+1)*10000), paymentModes[i%2])
 	}
 	return expenses
 }
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"expense-tracker-api/repository/repositorytest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestConformance runs against the Postgres database named by TEST_DB_NAME, which it
+// empties before every test, and is skipped when that isn't set
+func TestConformance(t *testing.T) {
+	dbName := os.Getenv("TEST_DB_NAME")
+	if dbName == "" {
+		t.Skip("TEST_DB_NAME is not set")
+	}
+	t.Setenv("DB_NAME", dbName)
+	require.NoError(t, InitDB())
+	t.Cleanup(func() { CloseDB() })
+
+	repositorytest.Run(t, func(t *testing.T) *repositorytest.Backend {
+		migrator := NewSchemaMigrator()
+		require.NoError(t, migrator.To(context.Background(), 0))
+		require.NoError(t, migrator.Up(context.Background()))
+		return &repositorytest.Backend{
+			Users:          NewUserRepository(),
+			Categories:     NewCategoryRepository(),
+			Expenses:       NewExpenseRepository(),
+			Budgets:        NewBudgetRepository(),
+			Tags:           NewTagRepository(),
+			Audit:          NewAuditRepository(),
+			PaymentMethods: NewPaymentMethodRepository(),
+			Accounts:       NewAccountRepository(),
+			Incomes:        NewIncomeRepository(),
+			ExchangeRates:  NewExchangeRateRepository(),
+			TxManager:      NewTxManager(),
+		}
+	})
+}
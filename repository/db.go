@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"os"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // DB holds the database connection
@@ -50,43 +50,41 @@ func InitDB() error {
 	return nil
 }
 
-// CreateSchema creates all necessary tables
-func CreateSchema() error {
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS categories (
-			id SERIAL PRIMARY KEY,
-			name VARCHAR(255) NOT NULL UNIQUE,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-		`CREATE TABLE IF NOT EXISTS expenses (
-			id SERIAL PRIMARY KEY,
-			category_id INTEGER NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
-			amount DECIMAL(10, 2) NOT NULL,
-			description TEXT,
-			payment_mode VARCHAR(10) NOT NULL CHECK (payment_mode IN ('UPI', 'Cash')),
-			expense_date DATE NOT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-		`CREATE TABLE IF NOT EXISTS budgets (
-			id SERIAL PRIMARY KEY,
-			month INTEGER NOT NULL CHECK (month >= 1 AND month <= 12),
-			year INTEGER NOT NULL,
-			budget_amount DECIMAL(10, 2) NOT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			UNIQUE(month, year)
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_expenses_category_id ON expenses(category_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_expenses_expense_date ON expenses(expense_date)`,
+// InitBaseCurrency records the base currency the first time the server starts.
+// Amounts are stored in the base currency, so starting with a different one later is an error.
+func InitBaseCurrency(currency string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
 
-	for _, query := range queries {
-		if _, err := DB.Exec(query); err != nil {
-			return fmt.Errorf("failed to execute schema query: %w", err)
+	var stored string
+	err = tx.QueryRow(`SELECT value FROM app_settings WHERE key = 'base_currency'`).Scan(&stored)
+	switch {
+	case err == sql.ErrNoRows:
+		if _, err := tx.Exec(`INSERT INTO app_settings (key, value) VALUES ('base_currency', $1)`, currency); err != nil {
+			return err
 		}
+	case err != nil:
+		return err
+	case stored != currency:
+		return fmt.Errorf("amounts are stored in %s and can't be switched to base currency %s", stored, currency)
 	}
 
-	return nil
+	return tx.Commit()
+}
+
+// isUniqueViolation reports whether err is a Postgres unique constraint violation
+func isUniqueViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23505"
+}
+
+// isForeignKeyViolation reports whether err is a Postgres foreign key violation
+func isForeignKeyViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23503"
 }
 
 // CloseDB closes the database connection
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"expense-tracker-api/domain"
+	"time"
+)
+
+type exchangeRateRepository struct{}
+
+// NewExchangeRateRepository creates a new exchange rate repository
+func NewExchangeRateRepository() domain.ExchangeRateRepository {
+	return &exchangeRateRepository{}
+}
+
+// Upsert saves the rates in a single transaction so a bad batch leaves the table untouched
+func (r *exchangeRateRepository) Upsert(ctx context.Context, rates []*domain.ExchangeRate) error {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO exchange_rates (currency, rate_date, rate, updated_at)
+			  VALUES ($1, $2, $3, $4)
+			  ON CONFLICT (currency, rate_date) DO UPDATE SET rate = EXCLUDED.rate, updated_at = EXCLUDED.updated_at`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for _, rate := range rates {
+		if _, err := stmt.ExecContext(ctx, rate.Currency, rate.Date, rate.Rate, now); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetOnOrBefore falls back to the latest earlier rate, so days without a published rate use the last one
+func (r *exchangeRateRepository) GetOnOrBefore(ctx context.Context, currency string, date time.Time) (*domain.ExchangeRate, error) {
+	rate := &domain.ExchangeRate{}
+	query := `SELECT currency, rate_date, rate FROM exchange_rates
+			  WHERE currency = $1 AND rate_date <= $2::date
+			  ORDER BY rate_date DESC LIMIT 1`
+	err := DB.QueryRowContext(ctx, query, currency, date).Scan(&rate.Currency, &rate.Date, &rate.Rate)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rate, nil
+}
+
+func (r *exchangeRateRepository) GetAll(ctx context.Context, currency string) ([]*domain.ExchangeRate, error) {
+	query := `SELECT currency, rate_date, rate FROM exchange_rates
+			  WHERE $1 = '' OR currency = $1
+			  ORDER BY rate_date DESC, currency`
+	rows, err := DB.QueryContext(ctx, query, currency)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []*domain.ExchangeRate{}
+	for rows.Next() {
+		rate := &domain.ExchangeRate{}
+		if err := rows.Scan(&rate.Currency, &rate.Date, &rate.Rate); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	return rates, rows.Err()
+}
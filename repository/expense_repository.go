@@ -1,113 +1,450 @@
 package repository
 
 import (
+	"context"
+	"database/sql"
 	"expense-tracker-api/domain"
 	"fmt"
 	"time"
+
+	"github.com/lib/pq"
 )
 
-type expenseRepository struct{}
+type expenseRepository struct {
+	tx *sql.Tx
+}
 
 // NewExpenseRepository creates a new expense repository
 func NewExpenseRepository() domain.ExpenseRepository {
 	return &expenseRepository{}
 }
 
-func (r *expenseRepository) Create(expense *domain.Expense) error {
-	query := `INSERT INTO expenses (category_id, amount, description, payment_mode, expense_date, created_at) 
-			  VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
-	err := DB.QueryRow(query, expense.CategoryID, expense.Amount, expense.Description,
-		expense.PaymentMode, expense.ExpenseDate, time.Now()).Scan(&expense.ID)
+// WithTx returns the repository bound to a transaction from NewTxManager
+func (r *expenseRepository) WithTx(tx domain.Tx) domain.ExpenseRepository {
+	return &expenseRepository{tx: boundTx(tx)}
+}
+
+// expenseColumns lists the expense columns in the order expenseFields scans them
+const expenseColumns = `id, user_id, category_id, amount, currency, original_amount, exchange_rate,
+			  description, payment_mode, account_id, expense_date, recurring_expense_id, created_at`
+
+// expenseFields returns the scan destinations for expenseColumns
+func expenseFields(expense *domain.Expense) []interface{} {
+	return []interface{}{&expense.ID, &expense.UserID, &expense.CategoryID, &expense.Amount, &expense.Currency,
+		&expense.OriginalAmount, &expense.ExchangeRate, &expense.Description, &expense.PaymentMode, &expense.AccountID,
+		&expense.ExpenseDate, &expense.RecurringExpenseID, &expense.CreatedAt}
+}
+
+// expenseTagsColumn selects the sorted tag names of the expense identified by idColumn
+func expenseTagsColumn(idColumn string) string {
+	return fmt.Sprintf(`ARRAY(SELECT t.name FROM expense_tags et JOIN tags t ON t.id = et.tag_id
+			  WHERE et.expense_id = %s ORDER BY t.name)`, idColumn)
+}
+
+// Create inserts the expense and attaches its tags in a single transaction
+func (r *expenseRepository) Create(ctx context.Context, expense *domain.Expense) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO expenses (user_id, category_id, amount, currency, original_amount, exchange_rate,
+			  description, payment_mode, account_id, expense_date, recurring_expense_id, created_at) 
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id`
+	err = tx.QueryRowContext(ctx, query, expense.UserID, expense.CategoryID, expense.Amount, expense.Currency,
+		expense.OriginalAmount, expense.ExchangeRate, expense.Description, expense.PaymentMode, expense.AccountID, expense.ExpenseDate, expense.RecurringExpenseID, time.Now()).Scan(&expense.ID)
+	if err != nil {
+		// A recurring occurrence can only be created once per date
+		if isUniqueViolation(err) {
+			return domain.ErrAlreadyExists
+		}
+		return err
+	}
+
+	if err := setExpenseTags(ctx, tx, expense); err != nil {
+		return err
+	}
+	if err := recordExpenseCreated(ctx, tx, expense); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// recordExpenseCreated logs a new expense as it was saved. Occurrences of a recurring
+// expense are created by the scheduler rather than by the user.
+func recordExpenseCreated(ctx context.Context, tx executor, expense *domain.Expense) error {
+	after, err := lockExpense(ctx, tx, expense.UserID, expense.ID)
+	if err != nil {
+		return err
+	}
+	event, err := domain.NewAuditEvent(expense.UserID, domain.AuditEntityExpense, expense.ID, domain.AuditActionCreate, nil, after)
+	if err != nil {
+		return err
+	}
+	if expense.RecurringExpenseID != nil {
+		event.ActorID = nil
+	}
+	return recordAudit(ctx, tx, event)
+}
+
+// setExpenseTags replaces the tags of the expense, creating tags the user doesn't have yet
+func setExpenseTags(ctx context.Context, tx executor, expense *domain.Expense) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM expense_tags WHERE expense_id = $1`, expense.ID); err != nil {
+		return err
+	}
+	if len(expense.Tags) == 0 {
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx, `INSERT INTO tags (user_id, name, created_at) SELECT $1, unnest($2::text[]), $3
+			  ON CONFLICT (user_id, name) DO NOTHING`, expense.UserID, pq.Array(expense.Tags), time.Now())
 	if err != nil {
 		return err
 	}
-	return nil
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO expense_tags (expense_id, tag_id)
+			  SELECT $1, id FROM tags WHERE user_id = $2 AND name = ANY($3)`,
+		expense.ID, expense.UserID, pq.Array(expense.Tags))
+	return err
 }
 
-func (r *expenseRepository) GetByID(id int) (*domain.Expense, error) {
+// CreateBatch inserts all expenses in a single transaction
+func (r *expenseRepository) CreateBatch(ctx context.Context, expenses []*domain.Expense) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO expenses (user_id, category_id, amount, currency, original_amount, exchange_rate,
+			  description, payment_mode, account_id, expense_date, recurring_expense_id, created_at) 
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for _, expense := range expenses {
+		err := stmt.QueryRowContext(ctx, expense.UserID, expense.CategoryID, expense.Amount, expense.Currency,
+			expense.OriginalAmount, expense.ExchangeRate, expense.Description, expense.PaymentMode, expense.AccountID, expense.ExpenseDate, expense.RecurringExpenseID, now).Scan(&expense.ID)
+		if err != nil {
+			return err
+		}
+		expense.CreatedAt = now
+
+		if err := setExpenseTags(ctx, tx, expense); err != nil {
+			return err
+		}
+		if err := recordExpenseCreated(ctx, tx, expense); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// expenseByIDQuery selects one of the user's ($2) live expenses with its tags
+var expenseByIDQuery = `SELECT ` + expenseColumns + `, ` + expenseTagsColumn("expenses.id") + ` FROM expenses
+			  WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`
+
+func (r *expenseRepository) GetByID(ctx context.Context, userID, id int) (*domain.Expense, error) {
 	expense := &domain.Expense{}
-	query := `SELECT id, category_id, amount, description, payment_mode, expense_date, created_at 
-			  FROM expenses WHERE id = $1`
-	err := DB.QueryRow(query, id).Scan(&expense.ID, &expense.CategoryID, &expense.Amount,
-		&expense.Description, &expense.PaymentMode, &expense.ExpenseDate, &expense.CreatedAt)
+	err := conn(r.tx).QueryRowContext(ctx, expenseByIDQuery+lockClause(r.tx), id, userID).Scan(append(expenseFields(expense), pq.Array(&expense.Tags))...)
 	if err != nil {
 		return nil, err
 	}
 	return expense, nil
 }
 
-func (r *expenseRepository) GetAll(filter *domain.ExpenseFilter) ([]*domain.Expense, error) {
-	query := `SELECT id, category_id, amount, description, payment_mode, expense_date, created_at 
-			  FROM expenses WHERE 1=1`
-	args := []interface{}{}
-	argIndex := 1
+// lockExpense reads the expense within tx and locks it until tx ends
+func lockExpense(ctx context.Context, tx executor, userID, id int) (*domain.Expense, error) {
+	expense := &domain.Expense{}
+	err := tx.QueryRowContext(ctx, expenseByIDQuery+` FOR UPDATE`, id, userID).Scan(append(expenseFields(expense), pq.Array(&expense.Tags))...)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return expense, nil
+}
+
+// expenseSortColumns maps each sort field to its column and the type its cursor value is cast to
+var expenseSortColumns = map[domain.ExpenseSort][2]string{
+	domain.ExpenseSortDate:      {"expense_date", "date"},
+	domain.ExpenseSortAmount:    {"amount", "numeric"},
+	domain.ExpenseSortCreatedAt: {"created_at", "timestamp"},
+	domain.ExpenseSortRelevance: {"rank", "real"},
+}
+
+// searchHighlightOptions wraps matched words in <mark> tags
+const searchHighlightOptions = `StartSel=<mark>, StopSel=</mark>, HighlightAll=true`
+
+// GetAll returns one page of matching expenses. The ID breaks ties in the sort
+// order so that keyset pagination with filter.After never skips or repeats a row.
+// With a search query each expense also carries its rank and a highlighted description.
+func (r *expenseRepository) GetAll(ctx context.Context, userID int, filter *domain.ExpenseFilter) ([]*domain.Expense, error) {
+	where, args := buildExpenseFilter(userID, filter, "")
+
+	columns := expenseColumns + `, ` + expenseTagsColumn("expenses.id") + ` AS tags`
+	searching := filter.Query != ""
+	if searching {
+		args = append(args, filter.Query)
+		tsQuery := fmt.Sprintf(`websearch_to_tsquery('english', $%d)`, len(args))
+		columns += fmt.Sprintf(`, ts_rank(search_vector, %s) AS rank, ts_headline('english', COALESCE(description, ''), %s, '%s')`,
+			tsQuery, tsQuery, searchHighlightOptions)
+	}
+
+	sort, ok := expenseSortColumns[filter.Sort]
+	if !ok || (filter.Sort == domain.ExpenseSortRelevance && !searching) {
+		sort = expenseSortColumns[domain.ExpenseSortDate]
+	}
+	direction, comparison := "ASC", ">"
+	if filter.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	// The page condition goes on an outer query so it can refer to the computed rank
+	query := `SELECT * FROM (SELECT ` + columns + ` FROM expenses WHERE ` + where + `) AS e`
+	if filter.After != nil {
+		query += fmt.Sprintf(` WHERE (%s, id) %s ($%d::%s, $%d)`, sort[0], comparison, len(args)+1, sort[1], len(args)+2)
+		args = append(args, filter.After.Value, filter.After.ID)
+	}
+	query += fmt.Sprintf(` ORDER BY %s %s, id %s`, sort[0], direction, direction)
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(` LIMIT $%d`, len(args)+1)
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := conn(r.tx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	expenses := []*domain.Expense{}
+	for rows.Next() {
+		expense := &domain.Expense{}
+		dest := append(expenseFields(expense), pq.Array(&expense.Tags))
+		if searching {
+			dest = append(dest, &expense.Rank, &expense.Highlight)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		expenses = append(expenses, expense)
+	}
+	return expenses, rows.Err()
+}
+
+// Count returns the number of expenses matching the filter, ignoring paging
+func (r *expenseRepository) Count(ctx context.Context, userID int, filter *domain.ExpenseFilter) (int, error) {
+	where, args := buildExpenseFilter(userID, filter, "")
+	var count int
+	err := conn(r.tx).QueryRowContext(ctx, `SELECT COUNT(*) FROM expenses WHERE `+where, args...).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// StreamAll calls fn for each matching expense as it is read from the database
+// cursor, so large exports are never held in memory. It stops at the first error fn returns.
+func (r *expenseRepository) StreamAll(ctx context.Context, userID int, filter *domain.ExpenseFilter, fn func(row *domain.ExpenseExportRow) error) error {
+	where, args := buildExpenseFilter(userID, filter, "e.")
+	query := `SELECT e.id, e.user_id, e.category_id, e.amount, e.currency, e.original_amount, e.exchange_rate,
+			  e.description, e.payment_mode, e.account_id, e.expense_date, e.recurring_expense_id, e.created_at, ` +
+		expenseTagsColumn("e.id") + `, c.name
+			  FROM expenses e JOIN categories c ON c.id = e.category_id
+			  WHERE ` + where + ` ORDER BY e.expense_date DESC, e.created_at DESC`
+
+	rows, err := conn(r.tx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row := &domain.ExpenseExportRow{Expense: &domain.Expense{}}
+		err := rows.Scan(append(expenseFields(row.Expense), pq.Array(&row.Tags), &row.CategoryName)...)
+		if err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// buildExpenseFilter turns the owner and filter into conditions on the expenses table.
+// prefix qualifies the column names when the query joins other tables.
+func buildExpenseFilter(userID int, filter *domain.ExpenseFilter, prefix string) (string, []interface{}) {
+	where := fmt.Sprintf(`%suser_id = $1 AND %sdeleted_at IS NULL`, prefix, prefix)
+	args := []interface{}{userID}
+	argIndex := 2
 
 	if filter.CategoryID != nil {
-		query += fmt.Sprintf(` AND category_id = $%d`, argIndex)
+		if filter.IncludeSubcategories {
+			where += fmt.Sprintf(` AND %scategory_id IN (WITH RECURSIVE `+subcategoriesCTE+` SELECT id FROM subcategories)`,
+				prefix, argIndex)
+		} else {
+			where += fmt.Sprintf(` AND %scategory_id = $%d`, prefix, argIndex)
+		}
 		args = append(args, *filter.CategoryID)
 		argIndex++
 	}
 
 	if filter.PaymentMode != nil {
-		query += fmt.Sprintf(` AND payment_mode = $%d`, argIndex)
+		where += fmt.Sprintf(` AND %spayment_mode = $%d`, prefix, argIndex)
 		args = append(args, string(*filter.PaymentMode))
 		argIndex++
 	}
 
+	if filter.AccountID != nil {
+		where += fmt.Sprintf(` AND %saccount_id = $%d`, prefix, argIndex)
+		args = append(args, *filter.AccountID)
+		argIndex++
+	}
+
 	if filter.StartDate != nil {
-		query += fmt.Sprintf(` AND expense_date >= $%d`, argIndex)
+		where += fmt.Sprintf(` AND %sexpense_date >= $%d`, prefix, argIndex)
 		args = append(args, *filter.StartDate)
 		argIndex++
 	}
 
 	if filter.EndDate != nil {
-		query += fmt.Sprintf(` AND expense_date <= $%d`, argIndex)
+		where += fmt.Sprintf(` AND %sexpense_date <= $%d`, prefix, argIndex)
 		args = append(args, *filter.EndDate)
 		argIndex++
 	}
 
-	query += ` ORDER BY expense_date DESC, created_at DESC`
-
-	rows, err := DB.Query(query, args...)
-	if err != nil {
-		return nil, err
+	if filter.Query != "" {
+		where += fmt.Sprintf(` AND %ssearch_vector @@ websearch_to_tsquery('english', $%d)`, prefix, argIndex)
+		args = append(args, filter.Query)
+		argIndex++
 	}
-	defer rows.Close()
 
-	var expenses []*domain.Expense
-	for rows.Next() {
-		expense := &domain.Expense{}
-		err := rows.Scan(&expense.ID, &expense.CategoryID, &expense.Amount,
-			&expense.Description, &expense.PaymentMode, &expense.ExpenseDate, &expense.CreatedAt)
-		if err != nil {
-			return nil, err
+	if len(filter.Tags) > 0 {
+		idColumn := prefix + "id"
+		if prefix == "" {
+			idColumn = "expenses.id"
 		}
-		expenses = append(expenses, expense)
+		// Counting matched tags covers both modes: at least one for any, every one for all
+		minMatches := "1"
+		if filter.AllTags {
+			minMatches = fmt.Sprintf(`cardinality($%d::text[])`, argIndex)
+		}
+		where += fmt.Sprintf(` AND (SELECT COUNT(*) FROM expense_tags et JOIN tags t ON t.id = et.tag_id
+			  WHERE et.expense_id = %s AND t.name = ANY($%d::text[])) >= %s`, idColumn, argIndex, minMatches)
+		args = append(args, pq.Array(filter.Tags))
+		argIndex++
 	}
-	return expenses, nil
+
+	return where, args
 }
 
-func (r *expenseRepository) Update(expense *domain.Expense) error {
-	query := `UPDATE expenses SET category_id = $1, amount = $2, description = $3, 
-			  payment_mode = $4, expense_date = $5 WHERE id = $6`
-	_, err := DB.Exec(query, expense.CategoryID, expense.Amount, expense.Description,
-		expense.PaymentMode, expense.ExpenseDate, expense.ID)
-	return err
+// Update saves the expense and replaces its tags in a single transaction
+func (r *expenseRepository) Update(ctx context.Context, expense *domain.Expense) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Never attach tags to an expense the user doesn't own
+	before, err := lockExpense(ctx, tx, expense.UserID, expense.ID)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE expenses SET category_id = $1, amount = $2, currency = $3, original_amount = $4, exchange_rate = $5,
+			  description = $6, payment_mode = $7, account_id = $8, expense_date = $9
+			  WHERE id = $10 AND user_id = $11`
+	_, err = tx.ExecContext(ctx, query, expense.CategoryID, expense.Amount, expense.Currency, expense.OriginalAmount,
+		expense.ExchangeRate, expense.Description, expense.PaymentMode, expense.AccountID, expense.ExpenseDate,
+		expense.ID, expense.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := setExpenseTags(ctx, tx, expense); err != nil {
+		return err
+	}
+
+	after, err := lockExpense(ctx, tx, expense.UserID, expense.ID)
+	if err != nil {
+		return err
+	}
+	if err := recordChange(ctx, tx, expense.UserID, domain.AuditEntityExpense, expense.ID, domain.AuditActionUpdate, before, after); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (r *expenseRepository) Delete(id int) error {
-	query := `DELETE FROM expenses WHERE id = $1`
-	_, err := DB.Exec(query, id)
-	return err
+// Delete moves the expense to the trash; the purge job removes it for good
+func (r *expenseRepository) Delete(ctx context.Context, userID, id int) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := lockExpense(ctx, tx, userID, id)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE expenses SET deleted_at = NOW() WHERE id = $1`, id); err != nil {
+		return err
+	}
+	if err := recordChange(ctx, tx, userID, domain.AuditEntityExpense, id, domain.AuditActionDelete, before, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (r *expenseRepository) GetTotalByMonth(month, year int) (float64, error) {
-	var total float64
+func (r *expenseRepository) GetTotalByMonth(ctx context.Context, userID, month, year int) (domain.Money, error) {
+	var total domain.Money
 	query := `SELECT COALESCE(SUM(amount), 0) FROM expenses 
-			  WHERE EXTRACT(MONTH FROM expense_date) = $1 AND EXTRACT(YEAR FROM expense_date) = $2`
-	err := DB.QueryRow(query, month, year).Scan(&total)
+			  WHERE user_id = $1 AND deleted_at IS NULL
+			  AND EXTRACT(MONTH FROM expense_date) = $2 AND EXTRACT(YEAR FROM expense_date) = $3`
+	err := conn(r.tx).QueryRowContext(ctx, query, userID, month, year).Scan(&total)
 	if err != nil {
 		return 0, err
 	}
 	return total, nil
 }
+
+// GetTotalsByCategoryForMonth returns each category's spending including its subcategories
+func (r *expenseRepository) GetTotalsByCategoryForMonth(ctx context.Context, userID, month, year int) (map[int]domain.Money, error) {
+	query := `WITH RECURSIVE ` + categoryClosure + `,
+			  spent AS (
+				  SELECT category_id, SUM(amount) AS amount FROM expenses
+				  WHERE user_id = $1 AND deleted_at IS NULL AND EXTRACT(MONTH FROM expense_date) = $2 AND EXTRACT(YEAR FROM expense_date) = $3
+				  GROUP BY category_id
+			  )
+			  SELECT closure.ancestor_id, SUM(spent.amount)
+			  FROM closure JOIN spent ON spent.category_id = closure.category_id
+			  GROUP BY closure.ancestor_id`
+	rows, err := conn(r.tx).QueryContext(ctx, query, userID, month, year)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := make(map[int]domain.Money)
+	for rows.Next() {
+		var categoryID int
+		var total domain.Money
+		if err := rows.Scan(&categoryID, &total); err != nil {
+			return nil, err
+		}
+		totals[categoryID] = total
+	}
+	return totals, rows.Err()
+}
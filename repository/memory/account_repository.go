@@ -0,0 +1,227 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"expense-tracker-api/domain"
+	"slices"
+	"strings"
+	"time"
+)
+
+type accountRepository struct {
+	binding
+}
+
+// NewAccountRepository creates an account repository on the store
+func NewAccountRepository(store *Store) domain.AccountRepository {
+	return &accountRepository{binding{store: store}}
+}
+
+// WithTx returns the repository bound to a transaction from NewTxManager
+func (r *accountRepository) WithTx(tx domain.Tx) domain.AccountRepository {
+	return &accountRepository{r.bind(tx)}
+}
+
+// account returns the user's account
+func (t *tables) account(userID, id int) (*domain.Account, error) {
+	account, ok := t.accounts[id]
+	if !ok || account.UserID != userID {
+		return nil, domain.ErrNotFound
+	}
+	return account, nil
+}
+
+// accountNameTaken reports whether another account of the user has the name
+func (t *tables) accountNameTaken(account *domain.Account) bool {
+	for _, other := range t.accounts {
+		if other.ID != account.ID && other.UserID == account.UserID && other.Name == account.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// accountInUse reports whether any expense, in the trash or not, income or transfer refers to the account
+func (t *tables) accountInUse(id int) bool {
+	for _, record := range t.expenses {
+		if record.expense.AccountID != nil && *record.expense.AccountID == id {
+			return true
+		}
+	}
+	for _, income := range t.incomes {
+		if income.AccountID != nil && *income.AccountID == id {
+			return true
+		}
+	}
+	for _, transfer := range t.transfers {
+		if transfer.FromAccountID == id || transfer.ToAccountID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *accountRepository) Create(ctx context.Context, account *domain.Account) error {
+	return r.update(ctx, func(t *tables) error {
+		if t.accountNameTaken(account) {
+			return domain.ErrAlreadyExists
+		}
+		account.ID = t.nextID("accounts")
+		account.CreatedAt = now()
+		account.UpdatedAt = account.CreatedAt
+		stored := *account
+		stored.OpeningDate = domain.TruncateToDate(account.OpeningDate)
+		t.accounts[account.ID] = &stored
+		return recordChange(t, account.UserID, domain.AuditEntityAccount, account.ID, domain.AuditActionCreate, nil, &stored)
+	})
+}
+
+func (r *accountRepository) GetByID(ctx context.Context, userID, id int) (*domain.Account, error) {
+	var account domain.Account
+	err := r.view(func(t *tables) error {
+		stored, err := t.account(userID, id)
+		if err != nil {
+			return err
+		}
+		account = *stored
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *accountRepository) GetAll(ctx context.Context, userID int) ([]*domain.Account, error) {
+	accounts := []*domain.Account{}
+	err := r.view(func(t *tables) error {
+		for _, id := range sortedIDs(t.accounts) {
+			if account := *t.accounts[id]; account.UserID == userID {
+				accounts = append(accounts, &account)
+			}
+		}
+		return nil
+	})
+	slices.SortStableFunc(accounts, func(a, b *domain.Account) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return accounts, err
+}
+
+func (r *accountRepository) Update(ctx context.Context, account *domain.Account) error {
+	return r.update(ctx, func(t *tables) error {
+		before, err := t.account(account.UserID, account.ID)
+		if err != nil {
+			return err
+		}
+		if t.accountNameTaken(account) {
+			return domain.ErrAlreadyExists
+		}
+
+		account.UpdatedAt = now()
+		after := *before
+		after.Name = account.Name
+		after.Type = account.Type
+		after.OpeningBalance = account.OpeningBalance
+		after.OpeningDate = domain.TruncateToDate(account.OpeningDate)
+		after.UpdatedAt = account.UpdatedAt
+		t.accounts[account.ID] = &after
+		return recordChange(t, account.UserID, domain.AuditEntityAccount, account.ID, domain.AuditActionUpdate, before, &after)
+	})
+}
+
+// Delete removes an account that no expense, income or transfer refers to.
+// Its reconciliations go with it.
+func (r *accountRepository) Delete(ctx context.Context, userID, id int) error {
+	return r.update(ctx, func(t *tables) error {
+		before, err := t.account(userID, id)
+		if err != nil {
+			return err
+		}
+		if t.accountInUse(id) {
+			return domain.ErrInUse
+		}
+
+		delete(t.accounts, id)
+		for reconciliationID, reconciliation := range t.reconciliations {
+			if reconciliation.AccountID == id {
+				delete(t.reconciliations, reconciliationID)
+			}
+		}
+		return recordChange(t, userID, domain.AuditEntityAccount, id, domain.AuditActionDelete, before, nil)
+	})
+}
+
+// GetBalance sums each kind of movement dated from the account's opening date through asOf
+func (r *accountRepository) GetBalance(ctx context.Context, userID, id int, asOf time.Time) (*domain.AccountBalance, error) {
+	balance := &domain.AccountBalance{AccountID: id, AsOf: asOf}
+	err := r.view(func(t *tables) error {
+		account, err := t.account(userID, id)
+		if err != nil {
+			return err
+		}
+		balance.OpeningBalance = account.OpeningBalance
+
+		// Both ends are included, so the range runs to the day after asOf
+		from, to := account.OpeningDate, asOf.AddDate(0, 0, 1)
+		for _, income := range t.incomes {
+			if income.AccountID != nil && *income.AccountID == id && inRange(income.IncomeDate, from, to) {
+				balance.Income += income.Amount
+			}
+		}
+		for _, record := range t.expenses {
+			expense := &record.expense
+			if expense.AccountID != nil && *expense.AccountID == id && expense.DeletedAt == nil && inRange(expense.ExpenseDate, from, to) {
+				balance.Expenses += expense.Amount
+			}
+		}
+		for _, transfer := range t.transfers {
+			if !inRange(transfer.TransferDate, from, to) {
+				continue
+			}
+			if transfer.ToAccountID == id {
+				balance.TransfersIn += transfer.Amount
+			}
+			if transfer.FromAccountID == id {
+				balance.TransfersOut += transfer.Amount
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return balance, nil
+}
+
+func (r *accountRepository) CreateReconciliation(ctx context.Context, reconciliation *domain.Reconciliation) error {
+	return r.update(ctx, func(t *tables) error {
+		if _, err := t.account(reconciliation.UserID, reconciliation.AccountID); err != nil {
+			return err
+		}
+		reconciliation.ID = t.nextID("reconciliations")
+		reconciliation.CreatedAt = now()
+		stored := *reconciliation
+		stored.StatementDate = domain.TruncateToDate(reconciliation.StatementDate)
+		t.reconciliations[reconciliation.ID] = &stored
+		return nil
+	})
+}
+
+// GetReconciliations lists an account's reconciliations, latest statement first
+func (r *accountRepository) GetReconciliations(ctx context.Context, userID, accountID int) ([]*domain.Reconciliation, error) {
+	reconciliations := []*domain.Reconciliation{}
+	err := r.view(func(t *tables) error {
+		for _, id := range sortedIDs(t.reconciliations) {
+			if reconciliation := *t.reconciliations[id]; reconciliation.UserID == userID && reconciliation.AccountID == accountID {
+				reconciliations = append(reconciliations, &reconciliation)
+			}
+		}
+		return nil
+	})
+	slices.SortFunc(reconciliations, func(a, b *domain.Reconciliation) int {
+		return cmp.Or(b.StatementDate.Compare(a.StatementDate), cmp.Compare(b.ID, a.ID))
+	})
+	return reconciliations, err
+}
@@ -0,0 +1,31 @@
+package memory
+
+import (
+	"context"
+	"expense-tracker-api/domain"
+)
+
+type auditRepository struct {
+	store *Store
+}
+
+// NewAuditRepository creates an audit log repository on the store
+func NewAuditRepository(store *Store) domain.AuditRepository {
+	return &auditRepository{store: store}
+}
+
+func (r *auditRepository) GetAll(ctx context.Context, userID int, filter *domain.AuditFilter) ([]*domain.AuditEvent, error) {
+	events := []*domain.AuditEvent{}
+	err := r.store.view(func(t *tables) error {
+		for i := len(t.auditEvents) - 1; i >= 0 && len(events) < filter.Limit; i-- {
+			event := *t.auditEvents[i]
+			if event.UserID != userID || (filter.Entity != "" && event.Entity != filter.Entity) ||
+				(filter.EntityID != 0 && event.EntityID != filter.EntityID) {
+				continue
+			}
+			events = append(events, &event)
+		}
+		return nil
+	})
+	return events, err
+}
@@ -0,0 +1,181 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"expense-tracker-api/domain"
+	"slices"
+)
+
+type budgetRepository struct {
+	binding
+}
+
+// NewBudgetRepository creates a budget repository on the store
+func NewBudgetRepository(store *Store) domain.BudgetRepository {
+	return &budgetRepository{binding{store: store}}
+}
+
+// WithTx returns the repository bound to a transaction from NewTxManager
+func (r *budgetRepository) WithTx(tx domain.Tx) domain.BudgetRepository {
+	return &budgetRepository{r.bind(tx)}
+}
+
+// liveBudget returns the user's budget unless it is missing or in the trash
+func (t *tables) liveBudget(userID, id int) (*domain.Budget, error) {
+	budget, ok := t.budgets[id]
+	if !ok || budget.UserID != userID || budget.DeletedAt != nil {
+		return nil, domain.ErrNotFound
+	}
+	return budget, nil
+}
+
+// budgetFor returns the user's live budget for the month and category, nil for
+// the overall budget, or nil when there is none
+func (t *tables) budgetFor(userID, month, year int, categoryID *int) *domain.Budget {
+	for _, id := range sortedIDs(t.budgets) {
+		budget := t.budgets[id]
+		if budget.UserID == userID && budget.Month == month && budget.Year == year && budget.DeletedAt == nil &&
+			sameID(budget.CategoryID, categoryID) {
+			return budget
+		}
+	}
+	return nil
+}
+
+// sameID reports whether two optional IDs are both unset or equal
+func sameID(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func (r *budgetRepository) Create(ctx context.Context, budget *domain.Budget) error {
+	return r.update(ctx, func(t *tables) error {
+		if t.budgetFor(budget.UserID, budget.Month, budget.Year, budget.CategoryID) != nil {
+			return domain.ErrAlreadyExists
+		}
+		budget.ID = t.nextID("budgets")
+		budget.CreatedAt = now()
+		budget.UpdatedAt = budget.CreatedAt
+		stored := *budget
+		stored.DeletedAt = nil
+		t.budgets[budget.ID] = &stored
+		return recordChange(t, budget.UserID, domain.AuditEntityBudget, budget.ID, domain.AuditActionCreate, nil, &stored)
+	})
+}
+
+// findBudget returns a copy of the first of the user's live budgets for which match is true
+func (r *budgetRepository) findBudget(match func(budget *domain.Budget) bool) (*domain.Budget, error) {
+	var found domain.Budget
+	err := r.view(func(t *tables) error {
+		for _, id := range sortedIDs(t.budgets) {
+			if budget := t.budgets[id]; budget.DeletedAt == nil && match(budget) {
+				found = *budget
+				return nil
+			}
+		}
+		return domain.ErrNotFound
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &found, nil
+}
+
+// listBudgets returns copies of the user's live budgets for which match is true
+func (r *budgetRepository) listBudgets(userID int, match func(budget *domain.Budget) bool) ([]*domain.Budget, error) {
+	var budgets []*domain.Budget
+	err := r.view(func(t *tables) error {
+		for _, id := range sortedIDs(t.budgets) {
+			if budget := *t.budgets[id]; budget.UserID == userID && budget.DeletedAt == nil && match(&budget) {
+				budgets = append(budgets, &budget)
+			}
+		}
+		return nil
+	})
+	return budgets, err
+}
+
+func (r *budgetRepository) GetByID(ctx context.Context, userID, id int) (*domain.Budget, error) {
+	return r.findBudget(func(budget *domain.Budget) bool {
+		return budget.ID == id && budget.UserID == userID
+	})
+}
+
+// GetAll lists the budgets newest month first, the overall budget ahead of the category budgets
+func (r *budgetRepository) GetAll(ctx context.Context, userID int) ([]*domain.Budget, error) {
+	budgets, err := r.listBudgets(userID, func(budget *domain.Budget) bool { return true })
+	slices.SortStableFunc(budgets, func(a, b *domain.Budget) int {
+		return cmp.Or(cmp.Compare(b.Year, a.Year), cmp.Compare(b.Month, a.Month), compareOptionalIDs(a.CategoryID, b.CategoryID))
+	})
+	return budgets, err
+}
+
+// compareOptionalIDs orders optional IDs with unset ones first
+func compareOptionalIDs(a, b *int) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	return cmp.Compare(*a, *b)
+}
+
+func (r *budgetRepository) GetByMonth(ctx context.Context, userID, month, year int) (*domain.Budget, error) {
+	return r.findBudget(func(budget *domain.Budget) bool {
+		return budget.UserID == userID && budget.Month == month && budget.Year == year && budget.CategoryID == nil
+	})
+}
+
+func (r *budgetRepository) GetByMonthAndCategory(ctx context.Context, userID, month, year, categoryID int) (*domain.Budget, error) {
+	return r.findBudget(func(budget *domain.Budget) bool {
+		return budget.UserID == userID && budget.Month == month && budget.Year == year && sameID(budget.CategoryID, &categoryID)
+	})
+}
+
+func (r *budgetRepository) GetCategoryBudgetsByMonth(ctx context.Context, userID, month, year int) ([]*domain.Budget, error) {
+	budgets, err := r.listBudgets(userID, func(budget *domain.Budget) bool {
+		return budget.Month == month && budget.Year == year && budget.CategoryID != nil
+	})
+	slices.SortStableFunc(budgets, func(a, b *domain.Budget) int {
+		return cmp.Compare(*a.CategoryID, *b.CategoryID)
+	})
+	return budgets, err
+}
+
+func (r *budgetRepository) Update(ctx context.Context, budget *domain.Budget) error {
+	return r.update(ctx, func(t *tables) error {
+		before, err := t.liveBudget(budget.UserID, budget.ID)
+		if err != nil {
+			return err
+		}
+
+		budget.UpdatedAt = now()
+		after := *before
+		after.BudgetAmount = budget.BudgetAmount
+		after.UpdatedAt = budget.UpdatedAt
+		t.budgets[budget.ID] = &after
+		return recordChange(t, budget.UserID, domain.AuditEntityBudget, budget.ID, domain.AuditActionUpdate, before, &after)
+	})
+}
+
+// Delete moves the budget to the trash; the purge job removes it for good
+func (r *budgetRepository) Delete(ctx context.Context, userID, id int) error {
+	return r.update(ctx, func(t *tables) error {
+		before, err := t.liveBudget(userID, id)
+		if err != nil {
+			return err
+		}
+
+		deletedAt := now()
+		deleted := *before
+		deleted.DeletedAt = &deletedAt
+		t.budgets[id] = &deleted
+		return recordChange(t, userID, domain.AuditEntityBudget, id, domain.AuditActionDelete, before, nil)
+	})
+}
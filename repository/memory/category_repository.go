@@ -0,0 +1,329 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"expense-tracker-api/domain"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type categoryRepository struct {
+	binding
+}
+
+// NewCategoryRepository creates a category repository on the store
+func NewCategoryRepository(store *Store) domain.CategoryRepository {
+	return &categoryRepository{binding{store: store}}
+}
+
+// WithTx returns the repository bound to a transaction from NewTxManager
+func (r *categoryRepository) WithTx(tx domain.Tx) domain.CategoryRepository {
+	return &categoryRepository{r.bind(tx)}
+}
+
+// liveCategory returns the user's category unless it is missing or in the trash
+func (t *tables) liveCategory(userID, id int) (*domain.Category, error) {
+	category, ok := t.categories[id]
+	if !ok || category.UserID != userID || category.DeletedAt != nil {
+		return nil, domain.ErrNotFound
+	}
+	return category, nil
+}
+
+// categoryName returns the name of the category, in the trash or not
+func (t *tables) categoryName(id int) string {
+	if category, ok := t.categories[id]; ok {
+		return category.Name
+	}
+	return ""
+}
+
+// categoryNameTaken reports whether another live category of the user has the name
+func (t *tables) categoryNameTaken(category *domain.Category) bool {
+	for _, other := range t.categories {
+		if other.ID != category.ID && other.UserID == category.UserID && other.DeletedAt == nil && other.Name == category.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// subcategories returns the ID of one of the user's live categories and of every
+// live category below it
+func (t *tables) subcategories(userID, id int) map[int]bool {
+	ids := map[int]bool{}
+	if _, err := t.liveCategory(userID, id); err != nil {
+		return ids
+	}
+	ids[id] = true
+	for queue := []int{id}; len(queue) > 0; queue = queue[1:] {
+		for _, childID := range sortedIDs(t.categories) {
+			child := t.categories[childID]
+			if child.ParentID != nil && *child.ParentID == queue[0] && child.DeletedAt == nil && !ids[childID] {
+				ids[childID] = true
+				queue = append(queue, childID)
+			}
+		}
+	}
+	return ids
+}
+
+// liveAncestors returns the user's live category and the parents its spending rolls
+// up into, nearest first. Like the closure of the SQL repositories, the chain ends
+// at a parent in the trash.
+func (t *tables) liveAncestors(userID, id int) []int {
+	var ids []int
+	seen := map[int]bool{}
+	for {
+		category, err := t.liveCategory(userID, id)
+		if err != nil || seen[id] {
+			return ids
+		}
+		ids = append(ids, id)
+		seen[id] = true
+		if category.ParentID == nil {
+			return ids
+		}
+		id = *category.ParentID
+	}
+}
+
+func (r *categoryRepository) Create(ctx context.Context, category *domain.Category) error {
+	return r.update(ctx, func(t *tables) error {
+		if t.categoryNameTaken(category) {
+			return domain.ErrAlreadyExists
+		}
+		category.ID = t.nextID("categories")
+		category.CreatedAt = now()
+		stored := *category
+		stored.Archived = false
+		stored.DeletedAt = nil
+		t.categories[category.ID] = &stored
+		return recordChange(t, category.UserID, domain.AuditEntityCategory, category.ID, domain.AuditActionCreate, nil, &stored)
+	})
+}
+
+func (r *categoryRepository) GetByID(ctx context.Context, userID, id int) (*domain.Category, error) {
+	var category domain.Category
+	err := r.view(func(t *tables) error {
+		stored, err := t.liveCategory(userID, id)
+		if err != nil {
+			return err
+		}
+		category = *stored
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (r *categoryRepository) GetAll(ctx context.Context, userID int) ([]*domain.Category, error) {
+	var categories []*domain.Category
+	err := r.view(func(t *tables) error {
+		for _, id := range sortedIDs(t.categories) {
+			if category := *t.categories[id]; category.UserID == userID && category.DeletedAt == nil {
+				categories = append(categories, &category)
+			}
+		}
+		return nil
+	})
+	slices.SortStableFunc(categories, func(a, b *domain.Category) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return categories, err
+}
+
+func (r *categoryRepository) Update(ctx context.Context, category *domain.Category) error {
+	return r.update(ctx, func(t *tables) error {
+		before, err := t.liveCategory(category.UserID, category.ID)
+		if err != nil {
+			return err
+		}
+		if t.categoryNameTaken(category) {
+			return domain.ErrAlreadyExists
+		}
+
+		after := *before
+		after.Name = category.Name
+		after.ParentID = category.ParentID
+		t.categories[category.ID] = &after
+		return recordChange(t, category.UserID, domain.AuditEntityCategory, category.ID, domain.AuditActionUpdate, before, &after)
+	})
+}
+
+// SetArchived archives or unarchives the category together with its subcategories,
+// logging a change for each one whose flag flips
+func (r *categoryRepository) SetArchived(ctx context.Context, userID, id int, archived bool) error {
+	return r.update(ctx, func(t *tables) error {
+		for _, categoryID := range sortedIDs(t.subcategories(userID, id)) {
+			before := t.categories[categoryID]
+			if before.Archived == archived {
+				continue
+			}
+			after := *before
+			after.Archived = archived
+			t.categories[categoryID] = &after
+			if err := recordChange(t, userID, domain.AuditEntityCategory, categoryID, domain.AuditActionUpdate, before, &after); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Delete moves the category and its budgets to the trash together, so restoring it
+// brings them back. It refuses with ErrInUse while the category still has expenses,
+// recurring expenses or subcategories that aren't in the trash.
+func (r *categoryRepository) Delete(ctx context.Context, userID, id int) error {
+	return r.update(ctx, func(t *tables) error {
+		before, err := t.liveCategory(userID, id)
+		if err != nil {
+			return err
+		}
+		if t.categoryInUse(id) {
+			return domain.ErrInUse
+		}
+
+		// The same deletion time ties the budgets to the category
+		deletedAt := now()
+		deleted := *before
+		deleted.DeletedAt = &deletedAt
+		t.categories[id] = &deleted
+		if err := recordChange(t, userID, domain.AuditEntityCategory, id, domain.AuditActionDelete, before, nil); err != nil {
+			return err
+		}
+
+		for _, budgetID := range sortedIDs(t.budgets) {
+			budget := t.budgets[budgetID]
+			if budget.CategoryID == nil || *budget.CategoryID != id || budget.DeletedAt != nil {
+				continue
+			}
+			deletedBudget := *budget
+			deletedBudget.DeletedAt = &deletedAt
+			t.budgets[budgetID] = &deletedBudget
+			if err := recordChange(t, userID, domain.AuditEntityBudget, budgetID, domain.AuditActionDelete, budget, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// categoryInUse reports whether live expenses, recurring expenses or live subcategories use the category
+func (t *tables) categoryInUse(id int) bool {
+	for _, record := range t.expenses {
+		if record.expense.CategoryID == id && record.expense.DeletedAt == nil {
+			return true
+		}
+	}
+	for _, recurring := range t.recurringExpenses {
+		if recurring.CategoryID == id {
+			return true
+		}
+	}
+	for _, category := range t.categories {
+		if category.ParentID != nil && *category.ParentID == id && category.DeletedAt == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Merge moves everything filed under the source category to the target and deletes
+// the source. A budget only moves when the target has none for its month; the
+// others are deleted with the source.
+func (r *categoryRepository) Merge(ctx context.Context, userID, sourceID, targetID int) error {
+	return r.update(ctx, func(t *tables) error {
+		source, err := t.liveCategory(userID, sourceID)
+		if err != nil {
+			return err
+		}
+
+		var moves []struct {
+			entity domain.AuditEntity
+			column string
+			id     int
+		}
+		moved := func(entity domain.AuditEntity, column string, id int) {
+			moves = append(moves, struct {
+				entity domain.AuditEntity
+				column string
+				id     int
+			}{entity, column, id})
+		}
+
+		for _, id := range sortedIDs(t.expenses) {
+			if record := *t.expenses[id]; record.expense.UserID == userID && record.expense.CategoryID == sourceID {
+				record.expense.CategoryID = targetID
+				t.expenses[id] = &record
+				moved(domain.AuditEntityExpense, "category_id", id)
+			}
+		}
+		for _, id := range sortedIDs(t.recurringExpenses) {
+			if recurring := *t.recurringExpenses[id]; recurring.UserID == userID && recurring.CategoryID == sourceID {
+				recurring.CategoryID = targetID
+				t.recurringExpenses[id] = &recurring
+				moved(domain.AuditEntityRecurringExpense, "category_id", id)
+			}
+		}
+		for _, id := range sortedIDs(t.incomes) {
+			if income := *t.incomes[id]; income.UserID == userID && income.CategoryID != nil && *income.CategoryID == sourceID {
+				income.CategoryID = &targetID
+				t.incomes[id] = &income
+				moved(domain.AuditEntityIncome, "category_id", id)
+			}
+		}
+		for _, id := range sortedIDs(t.categories) {
+			if category := *t.categories[id]; category.UserID == userID && category.ParentID != nil && *category.ParentID == sourceID {
+				category.ParentID = &targetID
+				t.categories[id] = &category
+				moved(domain.AuditEntityCategory, "parent_id", id)
+			}
+		}
+
+		// The budgets that don't move go with the category
+		var dropped []*domain.Budget
+		updatedAt := now()
+		for _, id := range sortedIDs(t.budgets) {
+			budget := *t.budgets[id]
+			if budget.UserID != userID || budget.CategoryID == nil || *budget.CategoryID != sourceID {
+				continue
+			}
+			if budget.DeletedAt != nil || t.budgetFor(userID, budget.Month, budget.Year, &targetID) != nil {
+				dropped = append(dropped, t.budgets[id])
+				continue
+			}
+			budget.CategoryID = &targetID
+			budget.UpdatedAt = updatedAt
+			t.budgets[id] = &budget
+			moved(domain.AuditEntityBudget, "category_id", id)
+		}
+
+		for _, move := range moves {
+			before := map[string]int{move.column: sourceID}
+			after := map[string]int{move.column: targetID}
+			if err := recordChange(t, userID, move.entity, move.id, domain.AuditActionUpdate, before, after); err != nil {
+				return err
+			}
+		}
+		for _, budget := range dropped {
+			delete(t.budgets, budget.ID)
+			if err := recordChange(t, userID, domain.AuditEntityBudget, budget.ID, domain.AuditActionDelete, budget, nil); err != nil {
+				return err
+			}
+		}
+
+		delete(t.categories, sourceID)
+		event, err := domain.NewAuditEvent(userID, domain.AuditEntityCategory, sourceID, domain.AuditActionMerge, source, nil)
+		if err != nil {
+			return err
+		}
+		event.Changes["merged_into"] = domain.AuditChange{To: json.RawMessage(strconv.Itoa(targetID))}
+		recordAudit(t, event)
+		return nil
+	})
+}
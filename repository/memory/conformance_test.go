@@ -0,0 +1,25 @@
+package memory
+
+import (
+	"expense-tracker-api/repository/repositorytest"
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) *repositorytest.Backend {
+		store := NewStore()
+		return &repositorytest.Backend{
+			Users:          NewUserRepository(store),
+			Categories:     NewCategoryRepository(store),
+			Expenses:       NewExpenseRepository(store),
+			Budgets:        NewBudgetRepository(store),
+			Tags:           NewTagRepository(store),
+			Audit:          NewAuditRepository(store),
+			PaymentMethods: NewPaymentMethodRepository(store),
+			Accounts:       NewAccountRepository(store),
+			Incomes:        NewIncomeRepository(store),
+			ExchangeRates:  NewExchangeRateRepository(store),
+			TxManager:      NewTxManager(store),
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"expense-tracker-api/domain"
+	"slices"
+	"strings"
+	"time"
+)
+
+// exchangeRateKey identifies a rate: one per currency and date
+type exchangeRateKey struct {
+	currency string
+	date     string
+}
+
+type exchangeRateRepository struct {
+	store *Store
+}
+
+// NewExchangeRateRepository creates an exchange rate repository on the store
+func NewExchangeRateRepository(store *Store) domain.ExchangeRateRepository {
+	return &exchangeRateRepository{store: store}
+}
+
+// Upsert saves all the rates at once, replacing any rate for the same currency and date
+func (r *exchangeRateRepository) Upsert(ctx context.Context, rates []*domain.ExchangeRate) error {
+	return r.store.update(ctx, func(t *tables) error {
+		for _, rate := range rates {
+			stored := *rate
+			stored.Date = domain.TruncateToDate(rate.Date)
+			t.exchangeRates[exchangeRateKey{currency: rate.Currency, date: dateKey(rate.Date)}] = &stored
+		}
+		return nil
+	})
+}
+
+// GetOnOrBefore falls back to the latest earlier rate, so days without a published rate use the last one
+func (r *exchangeRateRepository) GetOnOrBefore(ctx context.Context, currency string, date time.Time) (*domain.ExchangeRate, error) {
+	var latest *domain.ExchangeRate
+	err := r.store.view(func(t *tables) error {
+		for key, rate := range t.exchangeRates {
+			if key.currency == currency && key.date <= dateKey(date) && (latest == nil || key.date > dateKey(latest.Date)) {
+				latest = rate
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if latest == nil {
+		return nil, domain.ErrNotFound
+	}
+	rate := *latest
+	return &rate, nil
+}
+
+func (r *exchangeRateRepository) GetAll(ctx context.Context, currency string) ([]*domain.ExchangeRate, error) {
+	rates := []*domain.ExchangeRate{}
+	err := r.store.view(func(t *tables) error {
+		for key, rate := range t.exchangeRates {
+			if currency == "" || key.currency == currency {
+				rate := *rate
+				rates = append(rates, &rate)
+			}
+		}
+		return nil
+	})
+	slices.SortFunc(rates, func(a, b *domain.ExchangeRate) int {
+		return cmp.Or(b.Date.Compare(a.Date), strings.Compare(a.Currency, b.Currency))
+	})
+	return rates, err
+}
@@ -0,0 +1,447 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"expense-tracker-api/domain"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// expenseRecord is a stored expense. Tags are kept by ID, so renaming or merging
+// a tag shows on every expense carrying it.
+type expenseRecord struct {
+	expense domain.Expense
+	tagIDs  []int
+}
+
+type expenseRepository struct {
+	binding
+}
+
+// NewExpenseRepository creates an expense repository on the store
+func NewExpenseRepository(store *Store) domain.ExpenseRepository {
+	return &expenseRepository{binding{store: store}}
+}
+
+// WithTx returns the repository bound to a transaction from NewTxManager
+func (r *expenseRepository) WithTx(tx domain.Tx) domain.ExpenseRepository {
+	return &expenseRepository{r.bind(tx)}
+}
+
+// expense returns a copy of the stored expense with its tag names sorted
+func (t *tables) expense(record *expenseRecord) *domain.Expense {
+	expense := record.expense
+	expense.Tags = make([]string, 0, len(record.tagIDs))
+	for _, id := range record.tagIDs {
+		expense.Tags = append(expense.Tags, t.tags[id].Name)
+	}
+	slices.Sort(expense.Tags)
+	return &expense
+}
+
+// liveExpense returns the user's expense unless it is missing or in the trash
+func (t *tables) liveExpense(userID, id int) (*expenseRecord, error) {
+	record, ok := t.expenses[id]
+	if !ok || record.expense.UserID != userID || record.expense.DeletedAt != nil {
+		return nil, domain.ErrNotFound
+	}
+	return record, nil
+}
+
+// tagIDs returns the IDs of the user's tags with the names, creating tags the user doesn't have yet
+func (t *tables) tagIDs(userID int, names []string) []int {
+	ids := make([]int, 0, len(names))
+	for _, name := range names {
+		id, ok := t.tagByName(userID, name)
+		if !ok {
+			id = t.nextID("tags")
+			t.tags[id] = &domain.Tag{ID: id, UserID: userID, Name: name, CreatedAt: now()}
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// insertExpense stores a new expense and logs it. A recurring expense can only
+// create one occurrence per date, even when an earlier one is in the trash.
+func (t *tables) insertExpense(expense *domain.Expense, createdAt time.Time) error {
+	if expense.RecurringExpenseID != nil {
+		for _, record := range t.expenses {
+			other := record.expense
+			if other.RecurringExpenseID != nil && *other.RecurringExpenseID == *expense.RecurringExpenseID &&
+				dateKey(other.ExpenseDate) == dateKey(expense.ExpenseDate) {
+				return domain.ErrAlreadyExists
+			}
+		}
+	}
+
+	expense.ID = t.nextID("expenses")
+	expense.CreatedAt = createdAt
+	record := &expenseRecord{expense: *expense, tagIDs: t.tagIDs(expense.UserID, expense.Tags)}
+	record.expense.ExpenseDate = domain.TruncateToDate(expense.ExpenseDate)
+	record.expense.Tags = nil
+	record.expense.DeletedAt = nil
+	record.expense.Warning = ""
+	t.expenses[expense.ID] = record
+
+	// Occurrences of a recurring expense are created by the scheduler rather than by the user
+	event, err := domain.NewAuditEvent(expense.UserID, domain.AuditEntityExpense, expense.ID, domain.AuditActionCreate, nil, t.expense(record))
+	if err != nil {
+		return err
+	}
+	if expense.RecurringExpenseID != nil {
+		event.ActorID = nil
+	}
+	recordAudit(t, event)
+	return nil
+}
+
+func (r *expenseRepository) Create(ctx context.Context, expense *domain.Expense) error {
+	return r.update(ctx, func(t *tables) error {
+		return t.insertExpense(expense, now())
+	})
+}
+
+// CreateBatch inserts all expenses or, when one fails, none of them
+func (r *expenseRepository) CreateBatch(ctx context.Context, expenses []*domain.Expense) error {
+	return r.update(ctx, func(t *tables) error {
+		createdAt := now()
+		for _, expense := range expenses {
+			if err := t.insertExpense(expense, createdAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *expenseRepository) GetByID(ctx context.Context, userID, id int) (*domain.Expense, error) {
+	var expense *domain.Expense
+	err := r.view(func(t *tables) error {
+		record, err := t.liveExpense(userID, id)
+		if err != nil {
+			return err
+		}
+		expense = t.expense(record)
+		return nil
+	})
+	return expense, err
+}
+
+// expenseMatcher holds a filter prepared for testing expenses against it
+type expenseMatcher struct {
+	userID     int
+	filter     *domain.ExpenseFilter
+	categories map[int]bool
+	words      []string
+}
+
+// searchWords splits a search query into the lowercase words every match must contain
+func searchWords(query string) []string {
+	return strings.Fields(strings.ToLower(query))
+}
+
+func (t *tables) expenseMatcher(userID int, filter *domain.ExpenseFilter) *expenseMatcher {
+	m := &expenseMatcher{userID: userID, filter: filter, words: searchWords(filter.Query)}
+	if filter.CategoryID != nil && filter.IncludeSubcategories {
+		m.categories = t.subcategories(userID, *filter.CategoryID)
+	}
+	return m
+}
+
+// matches reports whether the expense is one of the user's live expenses passing the filter
+func (m *expenseMatcher) matches(t *tables, expense *domain.Expense) bool {
+	filter := m.filter
+	if expense.UserID != m.userID || expense.DeletedAt != nil {
+		return false
+	}
+	if filter.CategoryID != nil {
+		if filter.IncludeSubcategories {
+			if !m.categories[expense.CategoryID] {
+				return false
+			}
+		} else if expense.CategoryID != *filter.CategoryID {
+			return false
+		}
+	}
+	if filter.PaymentMode != nil && expense.PaymentMode != *filter.PaymentMode {
+		return false
+	}
+	if filter.AccountID != nil && (expense.AccountID == nil || *expense.AccountID != *filter.AccountID) {
+		return false
+	}
+	if filter.StartDate != nil && dateKey(expense.ExpenseDate) < dateKey(*filter.StartDate) {
+		return false
+	}
+	if filter.EndDate != nil && dateKey(expense.ExpenseDate) > dateKey(*filter.EndDate) {
+		return false
+	}
+
+	// Every word has to appear in the description or the category name
+	description, category := strings.ToLower(expense.Description), strings.ToLower(t.categoryName(expense.CategoryID))
+	for _, word := range m.words {
+		if !strings.Contains(description, word) && !strings.Contains(category, word) {
+			return false
+		}
+	}
+
+	if len(filter.Tags) > 0 {
+		// Counting matched tags covers both modes: at least one for any, every one for all
+		matched := 0
+		for _, tag := range expense.Tags {
+			if slices.Contains(filter.Tags, tag) {
+				matched++
+			}
+		}
+		if matched == 0 || (filter.AllTags && matched < len(filter.Tags)) {
+			return false
+		}
+	}
+	return true
+}
+
+// rank scores an expense by the words found in its description (1 each) and
+// category name (0.5 each), like the SQLite repositories
+func (m *expenseMatcher) rank(t *tables, expense *domain.Expense) float64 {
+	description, category := strings.ToLower(expense.Description), strings.ToLower(t.categoryName(expense.CategoryID))
+	var rank float64
+	for _, word := range m.words {
+		if strings.Contains(description, word) {
+			rank++
+		}
+		if strings.Contains(category, word) {
+			rank += 0.5
+		}
+	}
+	return rank
+}
+
+// findExpenses returns copies of the user's expenses matching the filter in ID order
+func (t *tables) findExpenses(userID int, filter *domain.ExpenseFilter) ([]*domain.Expense, *expenseMatcher) {
+	m := t.expenseMatcher(userID, filter)
+	expenses := []*domain.Expense{}
+	for _, id := range sortedIDs(t.expenses) {
+		expense := t.expense(t.expenses[id])
+		if m.matches(t, expense) {
+			expenses = append(expenses, expense)
+		}
+	}
+	return expenses, m
+}
+
+// highlight wraps every occurrence of the words in <mark> tags, ignoring case
+func highlight(text string, words []string) string {
+	quoted := make([]string, 0, len(words))
+	for _, word := range words {
+		quoted = append(quoted, regexp.QuoteMeta(word))
+	}
+	pattern := regexp.MustCompile(`(?i)` + strings.Join(quoted, "|"))
+	return pattern.ReplaceAllString(text, `<mark>$0</mark>`)
+}
+
+// compareExpenses orders two expenses by the sort field
+func compareExpenses(sort domain.ExpenseSort, a, b *domain.Expense) int {
+	switch sort {
+	case domain.ExpenseSortAmount:
+		return cmp.Compare(a.Amount, b.Amount)
+	case domain.ExpenseSortCreatedAt:
+		return a.CreatedAt.Compare(b.CreatedAt)
+	case domain.ExpenseSortRelevance:
+		return cmp.Compare(a.Rank, b.Rank)
+	}
+	return strings.Compare(dateKey(a.ExpenseDate), dateKey(b.ExpenseDate))
+}
+
+// cursorExpense turns the cursor into an expense with the sort key and ID it resumes after
+func cursorExpense(cursor *domain.ExpenseCursor) (*domain.Expense, error) {
+	expense := &domain.Expense{ID: cursor.ID}
+	var err error
+	switch cursor.Sort {
+	case domain.ExpenseSortAmount:
+		expense.Amount, err = domain.ParseMoney(cursor.Value)
+	case domain.ExpenseSortCreatedAt:
+		expense.CreatedAt, err = time.Parse("2006-01-02T15:04:05.999999", cursor.Value)
+	case domain.ExpenseSortRelevance:
+		expense.Rank, err = strconv.ParseFloat(cursor.Value, 64)
+	default:
+		expense.ExpenseDate, err = time.Parse("2006-01-02", cursor.Value)
+	}
+	return expense, err
+}
+
+// GetAll returns one page of matching expenses. The ID breaks ties in the sort
+// order so that keyset pagination with filter.After never skips or repeats a row.
+// With a search query each expense also carries its rank and a highlighted description.
+func (r *expenseRepository) GetAll(ctx context.Context, userID int, filter *domain.ExpenseFilter) ([]*domain.Expense, error) {
+	var expenses []*domain.Expense
+	err := r.view(func(t *tables) error {
+		var m *expenseMatcher
+		expenses, m = t.findExpenses(userID, filter)
+		searching := len(m.words) > 0
+		if searching {
+			for _, expense := range expenses {
+				expense.Rank = m.rank(t, expense)
+				expense.Highlight = highlight(expense.Description, m.words)
+			}
+		}
+
+		sort := filter.Sort
+		if !sort.IsValid() || (sort == domain.ExpenseSortRelevance && !searching) {
+			sort = domain.ExpenseSortDate
+		}
+		compare := func(a, b *domain.Expense) int {
+			order := cmp.Or(compareExpenses(sort, a, b), cmp.Compare(a.ID, b.ID))
+			if filter.Descending {
+				return -order
+			}
+			return order
+		}
+		slices.SortFunc(expenses, compare)
+
+		if filter.After != nil {
+			after, err := cursorExpense(filter.After)
+			if err != nil {
+				return domain.ErrInvalidInput
+			}
+			expenses = slices.DeleteFunc(expenses, func(expense *domain.Expense) bool {
+				return compare(expense, after) <= 0
+			})
+		}
+		if filter.Limit > 0 && len(expenses) > filter.Limit {
+			expenses = expenses[:filter.Limit]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expenses, nil
+}
+
+// Count returns the number of expenses matching the filter, ignoring paging
+func (r *expenseRepository) Count(ctx context.Context, userID int, filter *domain.ExpenseFilter) (int, error) {
+	var count int
+	err := r.view(func(t *tables) error {
+		expenses, _ := t.findExpenses(userID, filter)
+		count = len(expenses)
+		return nil
+	})
+	return count, err
+}
+
+// StreamAll calls fn for each matching expense, newest first. The expenses are
+// copied out before fn is called, so fn may use the store. It stops at the first error fn returns.
+func (r *expenseRepository) StreamAll(ctx context.Context, userID int, filter *domain.ExpenseFilter, fn func(row *domain.ExpenseExportRow) error) error {
+	var rows []*domain.ExpenseExportRow
+	err := r.view(func(t *tables) error {
+		expenses, _ := t.findExpenses(userID, filter)
+		slices.SortStableFunc(expenses, func(a, b *domain.Expense) int {
+			return cmp.Or(strings.Compare(dateKey(b.ExpenseDate), dateKey(a.ExpenseDate)), b.CreatedAt.Compare(a.CreatedAt))
+		})
+		for _, expense := range expenses {
+			rows = append(rows, &domain.ExpenseExportRow{Expense: expense, CategoryName: t.categoryName(expense.CategoryID)})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Update saves the expense and replaces its tags
+func (r *expenseRepository) Update(ctx context.Context, expense *domain.Expense) error {
+	return r.update(ctx, func(t *tables) error {
+		// Never attach tags to an expense the user doesn't own
+		record, err := t.liveExpense(expense.UserID, expense.ID)
+		if err != nil {
+			return err
+		}
+		before := t.expense(record)
+
+		updated := *record
+		updated.expense.CategoryID = expense.CategoryID
+		updated.expense.Amount = expense.Amount
+		updated.expense.Currency = expense.Currency
+		updated.expense.OriginalAmount = expense.OriginalAmount
+		updated.expense.ExchangeRate = expense.ExchangeRate
+		updated.expense.Description = expense.Description
+		updated.expense.PaymentMode = expense.PaymentMode
+		updated.expense.AccountID = expense.AccountID
+		updated.expense.ExpenseDate = domain.TruncateToDate(expense.ExpenseDate)
+		updated.tagIDs = t.tagIDs(expense.UserID, expense.Tags)
+		t.expenses[expense.ID] = &updated
+
+		return recordChange(t, expense.UserID, domain.AuditEntityExpense, expense.ID, domain.AuditActionUpdate, before, t.expense(&updated))
+	})
+}
+
+// Delete moves the expense to the trash; the purge job removes it for good
+func (r *expenseRepository) Delete(ctx context.Context, userID, id int) error {
+	return r.update(ctx, func(t *tables) error {
+		record, err := t.liveExpense(userID, id)
+		if err != nil {
+			return err
+		}
+		before := t.expense(record)
+
+		deleted := *record
+		deletedAt := now()
+		deleted.expense.DeletedAt = &deletedAt
+		t.expenses[id] = &deleted
+
+		return recordChange(t, userID, domain.AuditEntityExpense, id, domain.AuditActionDelete, before, nil)
+	})
+}
+
+// spentIn calls fn for each of the user's live expenses dated in the range
+func (t *tables) spentIn(userID int, from, to time.Time, fn func(expense *domain.Expense)) {
+	for _, id := range sortedIDs(t.expenses) {
+		expense := &t.expenses[id].expense
+		if expense.UserID == userID && expense.DeletedAt == nil && inRange(expense.ExpenseDate, from, to) {
+			fn(expense)
+		}
+	}
+}
+
+func (r *expenseRepository) GetTotalByMonth(ctx context.Context, userID, month, year int) (domain.Money, error) {
+	var total domain.Money
+	from, to := monthRange(month, year)
+	err := r.view(func(t *tables) error {
+		t.spentIn(userID, from, to, func(expense *domain.Expense) {
+			total += expense.Amount
+		})
+		return nil
+	})
+	return total, err
+}
+
+// GetTotalsByCategoryForMonth returns each category's spending including its subcategories
+func (r *expenseRepository) GetTotalsByCategoryForMonth(ctx context.Context, userID, month, year int) (map[int]domain.Money, error) {
+	totals := make(map[int]domain.Money)
+	from, to := monthRange(month, year)
+	err := r.view(func(t *tables) error {
+		t.spentIn(userID, from, to, func(expense *domain.Expense) {
+			for _, id := range t.liveAncestors(userID, expense.CategoryID) {
+				totals[id] += expense.Amount
+			}
+		})
+		return nil
+	})
+	return totals, err
+}
@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"expense-tracker-api/domain"
+	"slices"
+)
+
+type incomeRepository struct {
+	binding
+}
+
+// NewIncomeRepository creates an income repository on the store
+func NewIncomeRepository(store *Store) domain.IncomeRepository {
+	return &incomeRepository{binding{store: store}}
+}
+
+// WithTx returns the repository bound to a transaction from NewTxManager
+func (r *incomeRepository) WithTx(tx domain.Tx) domain.IncomeRepository {
+	return &incomeRepository{r.bind(tx)}
+}
+
+// income returns the user's income
+func (t *tables) income(userID, id int) (*domain.Income, error) {
+	income, ok := t.incomes[id]
+	if !ok || income.UserID != userID {
+		return nil, domain.ErrNotFound
+	}
+	return income, nil
+}
+
+func (r *incomeRepository) Create(ctx context.Context, income *domain.Income) error {
+	return r.update(ctx, func(t *tables) error {
+		income.ID = t.nextID("incomes")
+		income.CreatedAt = now()
+		income.UpdatedAt = income.CreatedAt
+		stored := *income
+		stored.IncomeDate = domain.TruncateToDate(income.IncomeDate)
+		t.incomes[income.ID] = &stored
+		return recordChange(t, income.UserID, domain.AuditEntityIncome, income.ID, domain.AuditActionCreate, nil, &stored)
+	})
+}
+
+func (r *incomeRepository) GetByID(ctx context.Context, userID, id int) (*domain.Income, error) {
+	var income domain.Income
+	err := r.view(func(t *tables) error {
+		stored, err := t.income(userID, id)
+		if err != nil {
+			return err
+		}
+		income = *stored
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &income, nil
+}
+
+// GetAll returns the user's incomes in the filter's date range, newest first
+func (r *incomeRepository) GetAll(ctx context.Context, userID int, filter *domain.IncomeFilter) ([]*domain.Income, error) {
+	incomes := []*domain.Income{}
+	err := r.view(func(t *tables) error {
+		for _, id := range sortedIDs(t.incomes) {
+			income := *t.incomes[id]
+			if income.UserID != userID {
+				continue
+			}
+			if filter.StartDate != nil && dateKey(income.IncomeDate) < dateKey(*filter.StartDate) {
+				continue
+			}
+			if filter.EndDate != nil && dateKey(income.IncomeDate) > dateKey(*filter.EndDate) {
+				continue
+			}
+			incomes = append(incomes, &income)
+		}
+		return nil
+	})
+	slices.SortFunc(incomes, func(a, b *domain.Income) int {
+		return cmp.Or(b.IncomeDate.Compare(a.IncomeDate), cmp.Compare(b.ID, a.ID))
+	})
+	return incomes, err
+}
+
+func (r *incomeRepository) Update(ctx context.Context, income *domain.Income) error {
+	return r.update(ctx, func(t *tables) error {
+		before, err := t.income(income.UserID, income.ID)
+		if err != nil {
+			return err
+		}
+
+		income.UpdatedAt = now()
+		after := *before
+		after.CategoryID = income.CategoryID
+		after.Source = income.Source
+		after.Amount = income.Amount
+		after.Description = income.Description
+		after.PaymentMode = income.PaymentMode
+		after.AccountID = income.AccountID
+		after.IncomeDate = domain.TruncateToDate(income.IncomeDate)
+		after.UpdatedAt = income.UpdatedAt
+		t.incomes[income.ID] = &after
+		return recordChange(t, income.UserID, domain.AuditEntityIncome, income.ID, domain.AuditActionUpdate, before, &after)
+	})
+}
+
+func (r *incomeRepository) Delete(ctx context.Context, userID, id int) error {
+	return r.update(ctx, func(t *tables) error {
+		before, err := t.income(userID, id)
+		if err != nil {
+			return err
+		}
+		delete(t.incomes, id)
+		return recordChange(t, userID, domain.AuditEntityIncome, id, domain.AuditActionDelete, before, nil)
+	})
+}
+
+func (r *incomeRepository) GetTotalByMonth(ctx context.Context, userID, month, year int) (domain.Money, error) {
+	var total domain.Money
+	from, to := monthRange(month, year)
+	err := r.view(func(t *tables) error {
+		for _, income := range t.incomes {
+			if income.UserID == userID && inRange(income.IncomeDate, from, to) {
+				total += income.Amount
+			}
+		}
+		return nil
+	})
+	return total, err
+}
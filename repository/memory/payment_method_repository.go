@@ -0,0 +1,193 @@
+package memory
+
+import (
+	"context"
+	"expense-tracker-api/domain"
+	"slices"
+	"strings"
+)
+
+type paymentMethodRepository struct {
+	binding
+}
+
+// NewPaymentMethodRepository creates a payment method repository on the store
+func NewPaymentMethodRepository(store *Store) domain.PaymentMethodRepository {
+	return &paymentMethodRepository{binding{store: store}}
+}
+
+// WithTx returns the repository bound to a transaction from NewTxManager
+func (r *paymentMethodRepository) WithTx(tx domain.Tx) domain.PaymentMethodRepository {
+	return &paymentMethodRepository{r.bind(tx)}
+}
+
+// paymentMethod returns the user's payment method
+func (t *tables) paymentMethod(userID, id int) (*domain.PaymentMethod, error) {
+	method, ok := t.paymentMethods[id]
+	if !ok || method.UserID != userID {
+		return nil, domain.ErrNotFound
+	}
+	return method, nil
+}
+
+// paymentMethodNameTaken reports whether another payment method of the user has the name
+func (t *tables) paymentMethodNameTaken(method *domain.PaymentMethod) bool {
+	for _, other := range t.paymentMethods {
+		if other.ID != method.ID && other.UserID == method.UserID && other.Name == method.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// paymentModeInUse reports whether an expense, recurring expense or income of the user
+// refers to the payment method by name
+func (t *tables) paymentModeInUse(userID int, name string) bool {
+	mode := domain.PaymentMode(name)
+	for _, record := range t.expenses {
+		if record.expense.UserID == userID && record.expense.PaymentMode == mode {
+			return true
+		}
+	}
+	for _, recurring := range t.recurringExpenses {
+		if recurring.UserID == userID && recurring.PaymentMode == mode {
+			return true
+		}
+	}
+	for _, income := range t.incomes {
+		if income.UserID == userID && income.PaymentMode != nil && *income.PaymentMode == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// renamePaymentMode carries a payment method's new name over to the records using it,
+// as the ON UPDATE CASCADE keys of the SQL repositories do
+func (t *tables) renamePaymentMode(userID int, from, to string) {
+	oldMode, newMode := domain.PaymentMode(from), domain.PaymentMode(to)
+	for id, record := range t.expenses {
+		if record.expense.UserID == userID && record.expense.PaymentMode == oldMode {
+			renamed := *record
+			renamed.expense.PaymentMode = newMode
+			t.expenses[id] = &renamed
+		}
+	}
+	for id, recurring := range t.recurringExpenses {
+		if recurring.UserID == userID && recurring.PaymentMode == oldMode {
+			renamed := *recurring
+			renamed.PaymentMode = newMode
+			t.recurringExpenses[id] = &renamed
+		}
+	}
+	for id, income := range t.incomes {
+		if income.UserID == userID && income.PaymentMode != nil && *income.PaymentMode == oldMode {
+			renamed := *income
+			renamed.PaymentMode = &newMode
+			t.incomes[id] = &renamed
+		}
+	}
+}
+
+func (r *paymentMethodRepository) Create(ctx context.Context, method *domain.PaymentMethod) error {
+	return r.update(ctx, func(t *tables) error {
+		if t.paymentMethodNameTaken(method) {
+			return domain.ErrAlreadyExists
+		}
+		method.ID = t.nextID("payment_methods")
+		method.CreatedAt = now()
+		method.UpdatedAt = method.CreatedAt
+		stored := *method
+		t.paymentMethods[method.ID] = &stored
+		return recordChange(t, method.UserID, domain.AuditEntityPaymentMethod, method.ID, domain.AuditActionCreate, nil, &stored)
+	})
+}
+
+func (r *paymentMethodRepository) GetByID(ctx context.Context, userID, id int) (*domain.PaymentMethod, error) {
+	return r.find(func(method *domain.PaymentMethod) bool {
+		return method.ID == id && method.UserID == userID
+	})
+}
+
+func (r *paymentMethodRepository) GetByName(ctx context.Context, userID int, name string) (*domain.PaymentMethod, error) {
+	return r.find(func(method *domain.PaymentMethod) bool {
+		return method.UserID == userID && method.Name == name
+	})
+}
+
+// find returns a copy of the payment method for which match is true
+func (r *paymentMethodRepository) find(match func(method *domain.PaymentMethod) bool) (*domain.PaymentMethod, error) {
+	var found domain.PaymentMethod
+	err := r.view(func(t *tables) error {
+		for _, method := range t.paymentMethods {
+			if match(method) {
+				found = *method
+				return nil
+			}
+		}
+		return domain.ErrNotFound
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &found, nil
+}
+
+func (r *paymentMethodRepository) GetAll(ctx context.Context, userID int) ([]*domain.PaymentMethod, error) {
+	methods := []*domain.PaymentMethod{}
+	err := r.view(func(t *tables) error {
+		for _, id := range sortedIDs(t.paymentMethods) {
+			if method := *t.paymentMethods[id]; method.UserID == userID {
+				methods = append(methods, &method)
+			}
+		}
+		return nil
+	})
+	slices.SortStableFunc(methods, func(a, b *domain.PaymentMethod) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return methods, err
+}
+
+// Update saves the payment method. A new name is carried over to the
+// expenses, recurring expenses and incomes that use it.
+func (r *paymentMethodRepository) Update(ctx context.Context, method *domain.PaymentMethod) error {
+	return r.update(ctx, func(t *tables) error {
+		before, err := t.paymentMethod(method.UserID, method.ID)
+		if err != nil {
+			return err
+		}
+		if t.paymentMethodNameTaken(method) {
+			return domain.ErrAlreadyExists
+		}
+
+		method.UpdatedAt = now()
+		after := *before
+		after.Name = method.Name
+		after.Type = method.Type
+		after.Last4 = method.Last4
+		after.VPA = method.VPA
+		after.Active = method.Active
+		after.UpdatedAt = method.UpdatedAt
+		t.paymentMethods[method.ID] = &after
+		if after.Name != before.Name {
+			t.renamePaymentMode(method.UserID, before.Name, after.Name)
+		}
+		return recordChange(t, method.UserID, domain.AuditEntityPaymentMethod, method.ID, domain.AuditActionUpdate, before, &after)
+	})
+}
+
+// Delete removes a payment method that nothing refers to
+func (r *paymentMethodRepository) Delete(ctx context.Context, userID, id int) error {
+	return r.update(ctx, func(t *tables) error {
+		before, err := t.paymentMethod(userID, id)
+		if err != nil {
+			return err
+		}
+		if t.paymentModeInUse(userID, before.Name) {
+			return domain.ErrInUse
+		}
+		delete(t.paymentMethods, id)
+		return recordChange(t, userID, domain.AuditEntityPaymentMethod, id, domain.AuditActionDelete, before, nil)
+	})
+}
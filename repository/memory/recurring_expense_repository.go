@@ -0,0 +1,160 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"expense-tracker-api/domain"
+	"slices"
+	"time"
+)
+
+type recurringExpenseRepository struct {
+	binding
+}
+
+// NewRecurringExpenseRepository creates a recurring expense repository on the store
+func NewRecurringExpenseRepository(store *Store) domain.RecurringExpenseRepository {
+	return &recurringExpenseRepository{binding{store: store}}
+}
+
+// WithTx returns the repository bound to a transaction from NewTxManager
+func (r *recurringExpenseRepository) WithTx(tx domain.Tx) domain.RecurringExpenseRepository {
+	return &recurringExpenseRepository{r.bind(tx)}
+}
+
+// recurringExpense returns the user's recurring expense
+func (t *tables) recurringExpense(userID, id int) (*domain.RecurringExpense, error) {
+	recurring, ok := t.recurringExpenses[id]
+	if !ok || recurring.UserID != userID {
+		return nil, domain.ErrNotFound
+	}
+	return recurring, nil
+}
+
+// storedRecurringExpense returns a copy of the recurring expense with its dates
+// cut to the day, as DATE columns keep them
+func storedRecurringExpense(recurring *domain.RecurringExpense) *domain.RecurringExpense {
+	stored := *recurring
+	stored.StartDate = domain.TruncateToDate(recurring.StartDate)
+	stored.NextRunDate = domain.TruncateToDate(recurring.NextRunDate)
+	if recurring.EndDate != nil {
+		endDate := domain.TruncateToDate(*recurring.EndDate)
+		stored.EndDate = &endDate
+	}
+	return &stored
+}
+
+// sortByNextRun orders recurring expenses by their next run, breaking ties by ID
+func sortByNextRun(recurringExpenses []*domain.RecurringExpense) {
+	slices.SortFunc(recurringExpenses, func(a, b *domain.RecurringExpense) int {
+		return cmp.Or(a.NextRunDate.Compare(b.NextRunDate), cmp.Compare(a.ID, b.ID))
+	})
+}
+
+func (r *recurringExpenseRepository) Create(ctx context.Context, recurring *domain.RecurringExpense) error {
+	return r.update(ctx, func(t *tables) error {
+		recurring.ID = t.nextID("recurring_expenses")
+		recurring.CreatedAt = now()
+		recurring.UpdatedAt = recurring.CreatedAt
+		stored := storedRecurringExpense(recurring)
+		t.recurringExpenses[recurring.ID] = stored
+		return recordChange(t, recurring.UserID, domain.AuditEntityRecurringExpense, recurring.ID, domain.AuditActionCreate, nil, stored)
+	})
+}
+
+func (r *recurringExpenseRepository) GetByID(ctx context.Context, userID, id int) (*domain.RecurringExpense, error) {
+	var recurring domain.RecurringExpense
+	err := r.view(func(t *tables) error {
+		stored, err := t.recurringExpense(userID, id)
+		if err != nil {
+			return err
+		}
+		recurring = *stored
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &recurring, nil
+}
+
+func (r *recurringExpenseRepository) GetAll(ctx context.Context, userID int) ([]*domain.RecurringExpense, error) {
+	var recurringExpenses []*domain.RecurringExpense
+	err := r.view(func(t *tables) error {
+		for _, recurring := range t.recurringExpenses {
+			if recurring := *recurring; recurring.UserID == userID {
+				recurringExpenses = append(recurringExpenses, &recurring)
+			}
+		}
+		return nil
+	})
+	sortByNextRun(recurringExpenses)
+	return recurringExpenses, err
+}
+
+// GetDue returns every user's active recurring expenses whose next run is on or
+// before asOf and not past their end date
+func (r *recurringExpenseRepository) GetDue(ctx context.Context, asOf time.Time) ([]*domain.RecurringExpense, error) {
+	var recurringExpenses []*domain.RecurringExpense
+	err := r.view(func(t *tables) error {
+		for _, recurring := range t.recurringExpenses {
+			recurring := *recurring
+			nextRun := dateKey(recurring.NextRunDate)
+			if recurring.Active && nextRun <= dateKey(asOf) && (recurring.EndDate == nil || nextRun <= dateKey(*recurring.EndDate)) {
+				recurringExpenses = append(recurringExpenses, &recurring)
+			}
+		}
+		return nil
+	})
+	sortByNextRun(recurringExpenses)
+	return recurringExpenses, err
+}
+
+func (r *recurringExpenseRepository) Update(ctx context.Context, recurring *domain.RecurringExpense) error {
+	return r.update(ctx, func(t *tables) error {
+		before, err := t.recurringExpense(recurring.UserID, recurring.ID)
+		if err != nil {
+			return err
+		}
+
+		recurring.UpdatedAt = now()
+		after := storedRecurringExpense(recurring)
+		after.CreatedAt = before.CreatedAt
+		t.recurringExpenses[recurring.ID] = after
+		return recordChange(t, recurring.UserID, domain.AuditEntityRecurringExpense, recurring.ID, domain.AuditActionUpdate, before, after)
+	})
+}
+
+// UpdateNextRunDate moves the next run of any user's recurring expense; it does nothing for an unknown ID
+func (r *recurringExpenseRepository) UpdateNextRunDate(ctx context.Context, id int, nextRunDate time.Time) error {
+	return r.update(ctx, func(t *tables) error {
+		stored, ok := t.recurringExpenses[id]
+		if !ok {
+			return nil
+		}
+		updated := *stored
+		updated.NextRunDate = domain.TruncateToDate(nextRunDate)
+		updated.UpdatedAt = now()
+		t.recurringExpenses[id] = &updated
+		return nil
+	})
+}
+
+// Delete removes the recurring expense. The expenses it created stay, no longer linked to it.
+func (r *recurringExpenseRepository) Delete(ctx context.Context, userID, id int) error {
+	return r.update(ctx, func(t *tables) error {
+		before, err := t.recurringExpense(userID, id)
+		if err != nil {
+			return err
+		}
+		delete(t.recurringExpenses, id)
+		for expenseID, record := range t.expenses {
+			if record.expense.RecurringExpenseID != nil && *record.expense.RecurringExpenseID == id {
+				unlinked := *record
+				unlinked.expense.RecurringExpenseID = nil
+				t.expenses[expenseID] = &unlinked
+			}
+		}
+		return recordChange(t, userID, domain.AuditEntityRecurringExpense, id, domain.AuditActionDelete, before, nil)
+	})
+}
@@ -0,0 +1,178 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"expense-tracker-api/domain"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+type reportRepository struct {
+	store *Store
+}
+
+// NewReportRepository creates a report repository on the store
+func NewReportRepository(store *Store) domain.ReportRepository {
+	return &reportRepository{store: store}
+}
+
+// share returns amount as a percentage of total rounded to two decimals, or 0 without a total
+func share(amount, total domain.Money) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(amount)*100*100/float64(total)) / 100
+}
+
+func (r *reportRepository) GetSummary(ctx context.Context, userID int, from, to time.Time) (*domain.SpendingSummary, error) {
+	summary := &domain.SpendingSummary{}
+	err := r.store.view(func(t *tables) error {
+		t.spentIn(userID, from, to, func(expense *domain.Expense) {
+			summary.Total += expense.Amount
+			summary.Count++
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+func (r *reportRepository) GetLargestExpense(ctx context.Context, userID int, from, to time.Time) (*domain.Expense, error) {
+	var largest *domain.Expense
+	err := r.store.view(func(t *tables) error {
+		t.spentIn(userID, from, to, func(expense *domain.Expense) {
+			// Expenses come in ID order, so the earliest of equal amounts on a day is kept
+			if largest == nil || expense.Amount > largest.Amount ||
+				(expense.Amount == largest.Amount && dateKey(expense.ExpenseDate) < dateKey(largest.ExpenseDate)) {
+				largest = expense
+			}
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if largest == nil {
+		return nil, domain.ErrNotFound
+	}
+	expense := *largest
+	return &expense, nil
+}
+
+// GetCategoryBreakdown returns spending per category, largest rolled-up amount first.
+// Every category with spending of its own or below it is listed, so parents appear
+// even when all their expenses are in subcategories.
+func (r *reportRepository) GetCategoryBreakdown(ctx context.Context, userID int, from, to time.Time) ([]*domain.CategoryBreakdown, error) {
+	breakdown := []*domain.CategoryBreakdown{}
+	err := r.store.view(func(t *tables) error {
+		items := map[int]*domain.CategoryBreakdown{}
+		var total domain.Money
+		t.spentIn(userID, from, to, func(expense *domain.Expense) {
+			total += expense.Amount
+			for _, id := range t.liveAncestors(userID, expense.CategoryID) {
+				item, ok := items[id]
+				if !ok {
+					category := t.categories[id]
+					item = &domain.CategoryBreakdown{CategoryID: id, ParentID: category.ParentID, CategoryName: category.Name}
+					items[id] = item
+					breakdown = append(breakdown, item)
+				}
+				if id == expense.CategoryID {
+					item.Amount += expense.Amount
+					item.Count++
+				}
+				item.RolledUpAmount += expense.Amount
+				item.RolledUpCount++
+			}
+		})
+		for _, item := range breakdown {
+			item.Share = share(item.Amount, total)
+		}
+		return nil
+	})
+	slices.SortStableFunc(breakdown, func(a, b *domain.CategoryBreakdown) int {
+		return cmp.Or(cmp.Compare(b.RolledUpAmount, a.RolledUpAmount), strings.Compare(a.CategoryName, b.CategoryName))
+	})
+	return breakdown, err
+}
+
+// GetPaymentModeBreakdown returns spending per payment mode, largest first
+func (r *reportRepository) GetPaymentModeBreakdown(ctx context.Context, userID int, from, to time.Time) ([]*domain.PaymentModeBreakdown, error) {
+	breakdown := []*domain.PaymentModeBreakdown{}
+	err := r.store.view(func(t *tables) error {
+		items := map[domain.PaymentMode]*domain.PaymentModeBreakdown{}
+		var total domain.Money
+		t.spentIn(userID, from, to, func(expense *domain.Expense) {
+			item, ok := items[expense.PaymentMode]
+			if !ok {
+				item = &domain.PaymentModeBreakdown{PaymentMode: expense.PaymentMode}
+				items[expense.PaymentMode] = item
+				breakdown = append(breakdown, item)
+			}
+			item.Amount += expense.Amount
+			item.Count++
+			total += expense.Amount
+		})
+		for _, item := range breakdown {
+			item.Share = share(item.Amount, total)
+		}
+		return nil
+	})
+	slices.SortStableFunc(breakdown, func(a, b *domain.PaymentModeBreakdown) int {
+		return cmp.Or(cmp.Compare(b.Amount, a.Amount), strings.Compare(string(a.PaymentMode), string(b.PaymentMode)))
+	})
+	return breakdown, err
+}
+
+// GetDailyTotals returns one entry per day in the range, including days without spending
+func (r *reportRepository) GetDailyTotals(ctx context.Context, userID int, from, to time.Time) ([]*domain.DailyTotal, error) {
+	totals := []*domain.DailyTotal{}
+	err := r.store.view(func(t *tables) error {
+		days := map[string]*domain.DailyTotal{}
+		for day := domain.TruncateToDate(from); dateKey(day) < dateKey(to); day = day.AddDate(0, 0, 1) {
+			total := &domain.DailyTotal{Date: day}
+			days[dateKey(day)] = total
+			totals = append(totals, total)
+		}
+		t.spentIn(userID, from, to, func(expense *domain.Expense) {
+			total := days[dateKey(expense.ExpenseDate)]
+			total.Amount += expense.Amount
+			total.Count++
+		})
+		return nil
+	})
+	return totals, err
+}
+
+// GetMonthlyCashflow returns one entry per calendar month the range touches. Only
+// income and expenses inside the range are counted, so partial months are partial.
+func (r *reportRepository) GetMonthlyCashflow(ctx context.Context, userID int, from, to time.Time) ([]*domain.CashflowMonth, error) {
+	months := []*domain.CashflowMonth{}
+	err := r.store.view(func(t *tables) error {
+		byMonth := map[string]*domain.CashflowMonth{}
+		first := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+		if dateKey(from) < dateKey(to) {
+			for month := first; dateKey(month) < dateKey(to); month = month.AddDate(0, 1, 0) {
+				cashflow := &domain.CashflowMonth{Month: month.Format("2006-01")}
+				byMonth[cashflow.Month] = cashflow
+				months = append(months, cashflow)
+			}
+		}
+
+		for _, income := range t.incomes {
+			if income.UserID == userID && inRange(income.IncomeDate, from, to) {
+				byMonth[income.IncomeDate.Format("2006-01")].Income += income.Amount
+			}
+		}
+		t.spentIn(userID, from, to, func(expense *domain.Expense) {
+			byMonth[expense.ExpenseDate.Format("2006-01")].Expenses += expense.Amount
+		})
+		return nil
+	})
+	return months, err
+}
@@ -0,0 +1,123 @@
+package memory
+
+import (
+	"context"
+	"expense-tracker-api/domain"
+	"time"
+)
+
+// DemoEmail and DemoPassword sign in to the demo user that Seed creates
+const (
+	DemoEmail    = "demo@example.com"
+	DemoPassword = "password123"
+)
+
+// demoPasswordHash is the bcrypt hash of DemoPassword, as in seed.sql
+const demoPasswordHash = "$2a$10$5vx0ISMgU.nZFC8PiGZ/d.G5Zs6nXWPotfp9TV51ZR/dch.VznwHa"
+
+// Seed loads the records of seed.sql into the store: a demo user with payment methods,
+// accounts, categories, budgets, expenses, a transfer and a salary for February 2026.
+// Expenses are in the base currency, so call it after InitBaseCurrency.
+func Seed(ctx context.Context, store *Store) error {
+	return store.update(ctx, func(t *tables) error {
+		created := now()
+		baseCurrency := t.settings["base_currency"]
+		if baseCurrency == "" {
+			baseCurrency = domain.DefaultBaseCurrency
+		}
+		february := func(day int) time.Time {
+			return time.Date(2026, time.February, day, 0, 0, 0, 0, time.UTC)
+		}
+
+		userID := t.nextID("users")
+		t.users[userID] = &domain.User{ID: userID, Email: DemoEmail, PasswordHash: demoPasswordHash, CreatedAt: created}
+
+		for _, method := range []struct {
+			name       string
+			methodType domain.PaymentMethodType
+		}{
+			{"UPI", domain.PaymentMethodTypeUPI},
+			{"Cash", domain.PaymentMethodTypeCash},
+			{"Bank Transfer", domain.PaymentMethodTypeBank},
+		} {
+			id := t.nextID("payment_methods")
+			t.paymentMethods[id] = &domain.PaymentMethod{ID: id, UserID: userID, Name: method.name, Type: method.methodType,
+				Active: true, CreatedAt: created, UpdatedAt: created}
+		}
+
+		accounts := map[string]int{}
+		for _, account := range []struct {
+			name        string
+			accountType domain.AccountType
+			opening     domain.Money
+		}{
+			{"HDFC Bank", domain.AccountTypeBank, 2500000},
+			{"Cash", domain.AccountTypeCash, 50000},
+		} {
+			id := t.nextID("accounts")
+			t.accounts[id] = &domain.Account{ID: id, UserID: userID, Name: account.name, Type: account.accountType,
+				OpeningBalance: account.opening, OpeningDate: february(1), CreatedAt: created, UpdatedAt: created}
+			accounts[account.name] = id
+		}
+
+		categories := map[string]int{}
+		addCategory := func(name string, parentID *int) {
+			id := t.nextID("categories")
+			t.categories[id] = &domain.Category{ID: id, UserID: userID, ParentID: parentID, Name: name, CreatedAt: created}
+			categories[name] = id
+		}
+		for _, name := range []string{"Food", "Transport", "Rent", "Entertainment"} {
+			addCategory(name, nil)
+		}
+		// Spending on groceries counts towards the Food budget
+		foodID := categories["Food"]
+		addCategory("Groceries", &foodID)
+
+		for _, budget := range []struct {
+			categoryID *int
+			amount     domain.Money
+		}{
+			{nil, 1200000},
+			{&foodID, 600000},
+		} {
+			id := t.nextID("budgets")
+			t.budgets[id] = &domain.Budget{ID: id, UserID: userID, CategoryID: budget.categoryID, Month: 2, Year: 2026,
+				BudgetAmount: budget.amount, CreatedAt: created, UpdatedAt: created}
+		}
+
+		// 8000.00 in all, within the monthly budget of 12000.00
+		for _, expense := range []struct {
+			category    string
+			amount      domain.Money
+			description string
+			paymentMode domain.PaymentMode
+			account     string
+			day         int
+		}{
+			{"Rent", 500000, "Monthly Rent", "Bank Transfer", "HDFC Bank", 1},
+			{"Groceries", 200000, "Grocery shopping", "UPI", "HDFC Bank", 5},
+			{"Transport", 100000, "Fuel", "Cash", "Cash", 10},
+		} {
+			id := t.nextID("expenses")
+			accountID := accounts[expense.account]
+			t.expenses[id] = &expenseRecord{expense: domain.Expense{ID: id, UserID: userID, CategoryID: categories[expense.category],
+				Amount: expense.amount, Currency: baseCurrency, OriginalAmount: expense.amount, ExchangeRate: domain.RateOne,
+				Description: expense.description, PaymentMode: expense.paymentMode, AccountID: &accountID,
+				ExpenseDate: february(expense.day), CreatedAt: created}}
+		}
+
+		// An ATM withdrawal from the bank into cash
+		transferID := t.nextID("transfers")
+		t.transfers[transferID] = &domain.Transfer{ID: transferID, UserID: userID, FromAccountID: accounts["HDFC Bank"],
+			ToAccountID: accounts["Cash"], Amount: 200000, Description: "ATM withdrawal", TransferDate: february(9), CreatedAt: created}
+
+		// The salary is paid into the bank account
+		incomeID := t.nextID("incomes")
+		salaryMode := domain.PaymentMode("Bank Transfer")
+		bankID := accounts["HDFC Bank"]
+		t.incomes[incomeID] = &domain.Income{ID: incomeID, UserID: userID, Source: "Salary", Amount: 6000000,
+			Description: "February salary", PaymentMode: &salaryMode, AccountID: &bankID, IncomeDate: february(1),
+			CreatedAt: created, UpdatedAt: created}
+		return nil
+	})
+}
@@ -0,0 +1,243 @@
+// Package memory keeps every repository's records in memory. It behaves like the
+// SQL repositories, filters, ordering and error values included, so it serves
+// as a fast stand-in in tests and as the storage of the demo mode.
+package memory
+
+import (
+	"context"
+	"expense-tracker-api/domain"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Store holds the records of all repositories created on it. It is safe for
+// concurrent use: reads see the last committed state, and changes are made one
+// transaction at a time on a copy that replaces the state when they succeed.
+type Store struct {
+	// txMu is held by the transaction making changes
+	txMu sync.Mutex
+	// mu guards data
+	mu   sync.RWMutex
+	data *tables
+}
+
+// NewStore creates an empty store
+func NewStore() *Store {
+	return &Store{data: newTables()}
+}
+
+// tables are the records of a store. Records are never changed in place, only
+// replaced, so copying the maps is enough to copy the tables.
+type tables struct {
+	nextIDs           map[string]int
+	settings          map[string]string
+	users             map[int]*domain.User
+	categories        map[int]*domain.Category
+	expenses          map[int]*expenseRecord
+	tags              map[int]*domain.Tag
+	budgets           map[int]*domain.Budget
+	paymentMethods    map[int]*domain.PaymentMethod
+	accounts          map[int]*domain.Account
+	reconciliations   map[int]*domain.Reconciliation
+	transfers         map[int]*domain.Transfer
+	incomes           map[int]*domain.Income
+	recurringExpenses map[int]*domain.RecurringExpense
+	exchangeRates     map[exchangeRateKey]*domain.ExchangeRate
+	auditEvents       []*domain.AuditEvent
+}
+
+func newTables() *tables {
+	return &tables{
+		nextIDs:           map[string]int{},
+		settings:          map[string]string{},
+		users:             map[int]*domain.User{},
+		categories:        map[int]*domain.Category{},
+		expenses:          map[int]*expenseRecord{},
+		tags:              map[int]*domain.Tag{},
+		budgets:           map[int]*domain.Budget{},
+		paymentMethods:    map[int]*domain.PaymentMethod{},
+		accounts:          map[int]*domain.Account{},
+		reconciliations:   map[int]*domain.Reconciliation{},
+		transfers:         map[int]*domain.Transfer{},
+		incomes:           map[int]*domain.Income{},
+		recurringExpenses: map[int]*domain.RecurringExpense{},
+		exchangeRates:     map[exchangeRateKey]*domain.ExchangeRate{},
+	}
+}
+
+func (t *tables) clone() *tables {
+	return &tables{
+		nextIDs:           maps.Clone(t.nextIDs),
+		settings:          maps.Clone(t.settings),
+		users:             maps.Clone(t.users),
+		categories:        maps.Clone(t.categories),
+		expenses:          maps.Clone(t.expenses),
+		tags:              maps.Clone(t.tags),
+		budgets:           maps.Clone(t.budgets),
+		paymentMethods:    maps.Clone(t.paymentMethods),
+		accounts:          maps.Clone(t.accounts),
+		reconciliations:   maps.Clone(t.reconciliations),
+		transfers:         maps.Clone(t.transfers),
+		incomes:           maps.Clone(t.incomes),
+		recurringExpenses: maps.Clone(t.recurringExpenses),
+		exchangeRates:     maps.Clone(t.exchangeRates),
+		auditEvents:       slices.Clone(t.auditEvents),
+	}
+}
+
+// nextID returns the next ID of the table, starting at 1 like a SERIAL column
+func (t *tables) nextID(table string) int {
+	t.nextIDs[table]++
+	return t.nextIDs[table]
+}
+
+// update runs fn on a copy of the tables and keeps the copy when fn succeeds
+// and ctx is still live, the way a database commits a transaction
+func (s *Store) update(ctx context.Context, fn func(t *tables) error) error {
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.RLock()
+	work := s.data.clone()
+	s.mu.RUnlock()
+
+	if err := fn(work); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.data = work
+	s.mu.Unlock()
+	return nil
+}
+
+// view runs fn on the committed tables
+func (s *Store) view(fn func(t *tables) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return fn(s.data)
+}
+
+// InitBaseCurrency records the base currency the first time it is called.
+// Amounts are stored in the base currency, so a different one later is an error.
+func (s *Store) InitBaseCurrency(currency string) error {
+	return s.update(context.Background(), func(t *tables) error {
+		stored, ok := t.settings["base_currency"]
+		if ok && stored != currency {
+			return fmt.Errorf("amounts are stored in %s and can't be switched to base currency %s", stored, currency)
+		}
+		t.settings["base_currency"] = currency
+		return nil
+	})
+}
+
+// transaction is the domain.Tx handed out by the store's TxManager
+type transaction struct {
+	data *tables
+}
+
+type txManager struct {
+	store *Store
+}
+
+// NewTxManager creates a transaction manager for the repositories of the store
+func NewTxManager(store *Store) domain.TxManager {
+	return &txManager{store: store}
+}
+
+// WithinTx runs fn on a copy of the store's records that replaces them when fn
+// succeeds. Transactions run one at a time, so a read-modify-write can't lose a
+// concurrent update, while reads outside them carry on against the committed records.
+func (m *txManager) WithinTx(ctx context.Context, fn func(tx domain.Tx) error) error {
+	return m.store.update(ctx, func(t *tables) error {
+		return fn(&transaction{data: t})
+	})
+}
+
+// binding is the store a repository works on and the transaction it is bound to, if any
+type binding struct {
+	store *Store
+	tx    *transaction
+}
+
+// bind returns the binding for a transaction from NewTxManager
+func (b binding) bind(tx domain.Tx) binding {
+	return binding{store: b.store, tx: tx.(*transaction)}
+}
+
+// view reads the bound transaction's records, or the committed ones
+func (b binding) view(fn func(t *tables) error) error {
+	if b.tx != nil {
+		return fn(b.tx.data)
+	}
+	return b.store.view(fn)
+}
+
+// update changes the records within the bound transaction, or in a transaction of its own
+func (b binding) update(ctx context.Context, fn func(t *tables) error) error {
+	if b.tx != nil {
+		return fn(b.tx.data)
+	}
+	return b.store.update(ctx, fn)
+}
+
+// now returns the current time to the microsecond, as the SQL databases keep timestamps
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// dateKey returns the calendar date of t, so dates compare as text the way the SQL repositories compare them
+func dateKey(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// inRange reports whether the date of t is on or after from and before to
+func inRange(t, from, to time.Time) bool {
+	day := dateKey(t)
+	return day >= dateKey(from) && day < dateKey(to)
+}
+
+// monthRange returns the first day of the month and of the month after it
+func monthRange(month, year int) (time.Time, time.Time) {
+	first := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	return first, first.AddDate(0, 1, 0)
+}
+
+// recordChange appends an event for a change the user made, comparing the record
+// before and after it. before is nil for a new record and after for a deleted one.
+func recordChange(t *tables, userID int, entity domain.AuditEntity, entityID int, action domain.AuditAction,
+	before, after interface{}) error {
+	event, err := domain.NewAuditEvent(userID, entity, entityID, action, before, after)
+	if err != nil {
+		return err
+	}
+	recordAudit(t, event)
+	return nil
+}
+
+// recordAudit appends the event to the log
+func recordAudit(t *tables, event *domain.AuditEvent) {
+	event.ID = int64(t.nextID("audit_events"))
+	event.CreatedAt = now()
+	t.auditEvents = append(t.auditEvents, event)
+}
+
+// sortedIDs returns the keys of a table in ascending order, so results come out
+// in a stable order before they are sorted
+func sortedIDs[V any](table map[int]V) []int {
+	ids := make([]int, 0, len(table))
+	for id := range table {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"expense-tracker-api/domain"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreConcurrentUse(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	users, categories, expenses := NewUserRepository(store), NewCategoryRepository(store), NewExpenseRepository(store)
+	txManager := NewTxManager(store)
+
+	user := &domain.User{Email: "concurrent@example.com", PasswordHash: "hash"}
+	require.NoError(t, users.Create(ctx, user))
+	category := &domain.Category{UserID: user.ID, Name: "Food"}
+	require.NoError(t, categories.Create(ctx, category))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			err := txManager.WithinTx(ctx, func(tx domain.Tx) error {
+				return expenses.WithTx(tx).Create(ctx, &domain.Expense{UserID: user.ID, CategoryID: category.ID, Amount: 100,
+					Currency: "INR", OriginalAmount: 100, ExchangeRate: domain.RateOne, PaymentMode: domain.PaymentModeCash,
+					ExpenseDate: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Tags: []string{"shared"}})
+			})
+			assert.NoError(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := expenses.GetAll(ctx, user.ID, &domain.ExpenseFilter{Tags: []string{"shared"}})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	total, err := expenses.GetTotalByMonth(ctx, user.ID, 3, 2024)
+	require.NoError(t, err)
+	assert.Equal(t, domain.Money(2000), total)
+}
+
+func TestStoreTransactionRollback(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	categories := NewCategoryRepository(store)
+	txManager := NewTxManager(store)
+
+	t.Run("Error discards the changes", func(t *testing.T) {
+		errStop := errors.New("stop")
+		err := txManager.WithinTx(ctx, func(tx domain.Tx) error {
+			require.NoError(t, categories.WithTx(tx).Create(ctx, &domain.Category{UserID: 1, Name: "Travel"}))
+			return errStop
+		})
+		assert.ErrorIs(t, err, errStop)
+	})
+
+	t.Run("Cancelled context discards the changes", func(t *testing.T) {
+		cancelled, cancel := context.WithCancel(ctx)
+		err := txManager.WithinTx(cancelled, func(tx domain.Tx) error {
+			require.NoError(t, categories.WithTx(tx).Create(ctx, &domain.Category{UserID: 1, Name: "Travel"}))
+			cancel()
+			return nil
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	all, err := categories.GetAll(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, all)
+}
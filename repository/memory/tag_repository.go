@@ -0,0 +1,179 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"expense-tracker-api/domain"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type tagRepository struct {
+	binding
+}
+
+// NewTagRepository creates a tag repository on the store
+func NewTagRepository(store *Store) domain.TagRepository {
+	return &tagRepository{binding{store: store}}
+}
+
+// WithTx returns the repository bound to a transaction from NewTxManager
+func (r *tagRepository) WithTx(tx domain.Tx) domain.TagRepository {
+	return &tagRepository{r.bind(tx)}
+}
+
+// tagByName returns the ID of the user's tag with the name
+func (t *tables) tagByName(userID int, name string) (int, bool) {
+	for id, tag := range t.tags {
+		if tag.UserID == userID && tag.Name == name {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// tagUsage returns the number of live expenses carrying the tag
+func (t *tables) tagUsage(id int) int {
+	count := 0
+	for _, record := range t.expenses {
+		if record.expense.DeletedAt == nil && slices.Contains(record.tagIDs, id) {
+			count++
+		}
+	}
+	return count
+}
+
+// GetAll returns the user's tags with the number of expenses carrying each one
+func (r *tagRepository) GetAll(ctx context.Context, userID int) ([]*domain.Tag, error) {
+	tags := []*domain.Tag{}
+	err := r.view(func(t *tables) error {
+		for _, id := range sortedIDs(t.tags) {
+			if tag := *t.tags[id]; tag.UserID == userID {
+				tag.UsageCount = t.tagUsage(id)
+				tags = append(tags, &tag)
+			}
+		}
+		return nil
+	})
+	slices.SortStableFunc(tags, func(a, b *domain.Tag) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return tags, err
+}
+
+func (r *tagRepository) GetByID(ctx context.Context, userID, id int) (*domain.Tag, error) {
+	var tag *domain.Tag
+	err := r.view(func(t *tables) error {
+		var err error
+		tag, err = t.tag(userID, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// taggedExpense is an expense carrying a tag, with the tags it carried before a change
+type taggedExpense struct {
+	id   int
+	tags []string
+}
+
+// taggedExpenses returns the expenses carrying the tag, in the trash or not, with their tags
+func (t *tables) taggedExpenses(tagID int) []taggedExpense {
+	var expenses []taggedExpense
+	for _, id := range sortedIDs(t.expenses) {
+		if record := t.expenses[id]; slices.Contains(record.tagIDs, tagID) {
+			expenses = append(expenses, taggedExpense{id: id, tags: t.expense(record).Tags})
+		}
+	}
+	return expenses
+}
+
+// recordRetagged logs the change a tag rename or merge made to the tags of each expense
+func (t *tables) recordRetagged(userID int, expenses []taggedExpense) error {
+	for _, expense := range expenses {
+		before := map[string][]string{"tags": expense.tags}
+		after := map[string][]string{"tags": t.expense(t.expenses[expense.id]).Tags}
+		if err := recordChange(t, userID, domain.AuditEntityExpense, expense.id, domain.AuditActionUpdate, before, after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tag returns a copy of the user's tag with its usage count
+func (t *tables) tag(userID, id int) (*domain.Tag, error) {
+	stored, ok := t.tags[id]
+	if !ok || stored.UserID != userID {
+		return nil, domain.ErrNotFound
+	}
+	tag := *stored
+	tag.UsageCount = t.tagUsage(id)
+	return &tag, nil
+}
+
+// Rename renames the tag, which renames it on every expense carrying it
+func (r *tagRepository) Rename(ctx context.Context, userID, id int, name string) error {
+	return r.update(ctx, func(t *tables) error {
+		before, err := t.tag(userID, id)
+		if err != nil {
+			return err
+		}
+		if other, ok := t.tagByName(userID, name); ok && other != id {
+			return domain.ErrAlreadyExists
+		}
+		expenses := t.taggedExpenses(id)
+
+		renamed := *t.tags[id]
+		renamed.Name = name
+		t.tags[id] = &renamed
+
+		after := *before
+		after.Name = name
+		if err := recordChange(t, userID, domain.AuditEntityTag, id, domain.AuditActionUpdate, before, &after); err != nil {
+			return err
+		}
+		return t.recordRetagged(userID, expenses)
+	})
+}
+
+// Merge moves every expense from the source tag to the target tag and deletes the source.
+// Expenses that already carry both tags keep a single link to the target.
+func (r *tagRepository) Merge(ctx context.Context, userID, sourceID, targetID int) error {
+	return r.update(ctx, func(t *tables) error {
+		source, err := t.tag(userID, sourceID)
+		if err != nil {
+			return err
+		}
+		if _, err := t.tag(userID, targetID); err != nil {
+			return err
+		}
+		expenses := t.taggedExpenses(sourceID)
+
+		for _, expense := range expenses {
+			record := t.expenses[expense.id]
+			index := slices.Index(record.tagIDs, sourceID)
+			merged := *record
+			merged.tagIDs = slices.Delete(slices.Clone(record.tagIDs), index, index+1)
+			if !slices.Contains(merged.tagIDs, targetID) {
+				merged.tagIDs = append(merged.tagIDs, targetID)
+			}
+			t.expenses[expense.id] = &merged
+		}
+		delete(t.tags, sourceID)
+
+		if err := t.recordRetagged(userID, expenses); err != nil {
+			return err
+		}
+		event, err := domain.NewAuditEvent(userID, domain.AuditEntityTag, sourceID, domain.AuditActionMerge, source, nil)
+		if err != nil {
+			return err
+		}
+		event.Changes["merged_into"] = domain.AuditChange{To: json.RawMessage(strconv.Itoa(targetID))}
+		recordAudit(t, event)
+		return nil
+	})
+}
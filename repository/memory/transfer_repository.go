@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"expense-tracker-api/domain"
+	"slices"
+)
+
+type transferRepository struct {
+	binding
+}
+
+// NewTransferRepository creates a transfer repository on the store
+func NewTransferRepository(store *Store) domain.TransferRepository {
+	return &transferRepository{binding{store: store}}
+}
+
+// WithTx returns the repository bound to a transaction from NewTxManager
+func (r *transferRepository) WithTx(tx domain.Tx) domain.TransferRepository {
+	return &transferRepository{r.bind(tx)}
+}
+
+// transfer returns the user's transfer
+func (t *tables) transfer(userID, id int) (*domain.Transfer, error) {
+	transfer, ok := t.transfers[id]
+	if !ok || transfer.UserID != userID {
+		return nil, domain.ErrNotFound
+	}
+	return transfer, nil
+}
+
+func (r *transferRepository) Create(ctx context.Context, transfer *domain.Transfer) error {
+	return r.update(ctx, func(t *tables) error {
+		// Both accounts must exist, as their foreign keys require
+		for _, accountID := range []int{transfer.FromAccountID, transfer.ToAccountID} {
+			if _, ok := t.accounts[accountID]; !ok {
+				return domain.ErrInvalidAccount
+			}
+		}
+		transfer.ID = t.nextID("transfers")
+		transfer.CreatedAt = now()
+		stored := *transfer
+		stored.TransferDate = domain.TruncateToDate(transfer.TransferDate)
+		t.transfers[transfer.ID] = &stored
+		return recordChange(t, transfer.UserID, domain.AuditEntityTransfer, transfer.ID, domain.AuditActionCreate, nil, &stored)
+	})
+}
+
+func (r *transferRepository) GetByID(ctx context.Context, userID, id int) (*domain.Transfer, error) {
+	var transfer domain.Transfer
+	err := r.view(func(t *tables) error {
+		stored, err := t.transfer(userID, id)
+		if err != nil {
+			return err
+		}
+		transfer = *stored
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+func (r *transferRepository) GetAll(ctx context.Context, userID int, accountID *int) ([]*domain.Transfer, error) {
+	transfers := []*domain.Transfer{}
+	err := r.view(func(t *tables) error {
+		for _, id := range sortedIDs(t.transfers) {
+			transfer := *t.transfers[id]
+			if transfer.UserID != userID {
+				continue
+			}
+			if accountID != nil && transfer.FromAccountID != *accountID && transfer.ToAccountID != *accountID {
+				continue
+			}
+			transfers = append(transfers, &transfer)
+		}
+		return nil
+	})
+	slices.SortFunc(transfers, func(a, b *domain.Transfer) int {
+		return cmp.Or(b.TransferDate.Compare(a.TransferDate), cmp.Compare(b.ID, a.ID))
+	})
+	return transfers, err
+}
+
+func (r *transferRepository) Delete(ctx context.Context, userID, id int) error {
+	return r.update(ctx, func(t *tables) error {
+		before, err := t.transfer(userID, id)
+		if err != nil {
+			return err
+		}
+		delete(t.transfers, id)
+		return recordChange(t, userID, domain.AuditEntityTransfer, id, domain.AuditActionDelete, before, nil)
+	})
+}
@@ -0,0 +1,189 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"expense-tracker-api/domain"
+	"slices"
+	"time"
+)
+
+type trashRepository struct {
+	store *Store
+}
+
+// NewTrashRepository creates a trash repository on the store
+func NewTrashRepository(store *Store) domain.TrashRepository {
+	return &trashRepository{store: store}
+}
+
+// compareDeleted orders records most recently deleted first, breaking ties by ID
+func compareDeleted(aDeletedAt, bDeletedAt *time.Time, aID, bID int) int {
+	return cmp.Or(bDeletedAt.Compare(*aDeletedAt), cmp.Compare(bID, aID))
+}
+
+func (r *trashRepository) GetAll(ctx context.Context, userID int) (*domain.Trash, error) {
+	trash := &domain.Trash{Expenses: []*domain.Expense{}, Categories: []*domain.Category{}, Budgets: []*domain.Budget{}}
+	err := r.store.view(func(t *tables) error {
+		for _, id := range sortedIDs(t.expenses) {
+			if record := t.expenses[id]; record.expense.UserID == userID && record.expense.DeletedAt != nil {
+				trash.Expenses = append(trash.Expenses, t.expense(record))
+			}
+		}
+		for _, id := range sortedIDs(t.categories) {
+			if category := *t.categories[id]; category.UserID == userID && category.DeletedAt != nil {
+				trash.Categories = append(trash.Categories, &category)
+			}
+		}
+		for _, id := range sortedIDs(t.budgets) {
+			if budget := *t.budgets[id]; budget.UserID == userID && budget.DeletedAt != nil {
+				trash.Budgets = append(trash.Budgets, &budget)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(trash.Expenses, func(a, b *domain.Expense) int { return compareDeleted(a.DeletedAt, b.DeletedAt, a.ID, b.ID) })
+	slices.SortFunc(trash.Categories, func(a, b *domain.Category) int { return compareDeleted(a.DeletedAt, b.DeletedAt, a.ID, b.ID) })
+	slices.SortFunc(trash.Budgets, func(a, b *domain.Budget) int { return compareDeleted(a.DeletedAt, b.DeletedAt, a.ID, b.ID) })
+	return trash, nil
+}
+
+// deletedCategory reports whether the category exists and is in the trash
+func (t *tables) deletedCategory(id *int) bool {
+	if id == nil {
+		return false
+	}
+	category, ok := t.categories[*id]
+	return ok && category.DeletedAt != nil
+}
+
+func (r *trashRepository) Restore(ctx context.Context, userID int, itemType domain.TrashItemType, id int) error {
+	return r.store.update(ctx, func(t *tables) error {
+		switch itemType {
+		case domain.TrashItemExpense:
+			record, ok := t.expenses[id]
+			if !ok || record.expense.UserID != userID || record.expense.DeletedAt == nil {
+				return domain.ErrNotFound
+			}
+			if t.deletedCategory(&record.expense.CategoryID) {
+				return domain.ErrInvalidCategory
+			}
+			restored := *record
+			restored.expense.DeletedAt = nil
+			t.expenses[id] = &restored
+			return recordChange(t, userID, domain.AuditEntityExpense, id, domain.AuditActionRestore, nil, t.expense(&restored))
+
+		case domain.TrashItemCategory:
+			category, ok := t.categories[id]
+			if !ok || category.UserID != userID || category.DeletedAt == nil {
+				return domain.ErrNotFound
+			}
+			if t.deletedCategory(category.ParentID) {
+				return domain.ErrInvalidCategory
+			}
+			// The budgets deleted together with the category share its deletion time
+			for _, budgetID := range sortedIDs(t.budgets) {
+				budget := t.budgets[budgetID]
+				if budget.CategoryID == nil || *budget.CategoryID != id || budget.DeletedAt == nil || !budget.DeletedAt.Equal(*category.DeletedAt) {
+					continue
+				}
+				if err := t.restoreBudget(userID, budget); err != nil {
+					return err
+				}
+			}
+			restored := *category
+			restored.DeletedAt = nil
+			if t.categoryNameTaken(&restored) {
+				return domain.ErrAlreadyExists
+			}
+			t.categories[id] = &restored
+			return recordChange(t, userID, domain.AuditEntityCategory, id, domain.AuditActionRestore, nil, &restored)
+
+		case domain.TrashItemBudget:
+			budget, ok := t.budgets[id]
+			if !ok || budget.UserID != userID || budget.DeletedAt == nil {
+				return domain.ErrNotFound
+			}
+			if t.deletedCategory(budget.CategoryID) {
+				return domain.ErrInvalidCategory
+			}
+			return t.restoreBudget(userID, budget)
+		}
+		return domain.ErrInvalidInput
+	})
+}
+
+// restoreBudget takes the budget out of the trash unless a live budget holds its month
+func (t *tables) restoreBudget(userID int, budget *domain.Budget) error {
+	if t.budgetFor(budget.UserID, budget.Month, budget.Year, budget.CategoryID) != nil {
+		return domain.ErrAlreadyExists
+	}
+	restored := *budget
+	restored.DeletedAt = nil
+	t.budgets[budget.ID] = &restored
+	return recordChange(t, userID, domain.AuditEntityBudget, budget.ID, domain.AuditActionRestore, nil, &restored)
+}
+
+// Purge removes the records that have been in the trash since before the cutoff,
+// logging each one with no actor. Expenses and budgets go first so the categories
+// they used can go in the same pass. A category still referenced by anything, such
+// as a newer expense in the trash, is kept until a later purge.
+func (r *trashRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	var purged int64
+	err := r.store.update(ctx, func(t *tables) error {
+		purge := func(entity domain.AuditEntity, userID, id int) {
+			recordAudit(t, &domain.AuditEvent{UserID: userID, Entity: entity, EntityID: id, Action: domain.AuditActionPurge,
+				Changes: map[string]domain.AuditChange{}})
+			purged++
+		}
+
+		for _, id := range sortedIDs(t.expenses) {
+			if expense := t.expenses[id].expense; expense.DeletedAt != nil && expense.DeletedAt.Before(before) {
+				delete(t.expenses, id)
+				purge(domain.AuditEntityExpense, expense.UserID, id)
+			}
+		}
+		for _, id := range sortedIDs(t.budgets) {
+			if budget := t.budgets[id]; budget.DeletedAt != nil && budget.DeletedAt.Before(before) {
+				delete(t.budgets, id)
+				purge(domain.AuditEntityBudget, budget.UserID, id)
+			}
+		}
+		for _, id := range sortedIDs(t.categories) {
+			if category := t.categories[id]; category.DeletedAt != nil && category.DeletedAt.Before(before) && !t.categoryReferenced(id) {
+				delete(t.categories, id)
+				purge(domain.AuditEntityCategory, category.UserID, id)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
+
+// categoryReferenced reports whether any expense, in the trash or not, recurring
+// expense or subcategory refers to the category
+func (t *tables) categoryReferenced(id int) bool {
+	for _, record := range t.expenses {
+		if record.expense.CategoryID == id {
+			return true
+		}
+	}
+	for _, recurring := range t.recurringExpenses {
+		if recurring.CategoryID == id {
+			return true
+		}
+	}
+	for _, category := range t.categories {
+		if category.ParentID != nil && *category.ParentID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"context"
+	"expense-tracker-api/domain"
+)
+
+type userRepository struct {
+	store *Store
+}
+
+// NewUserRepository creates a user repository on the store
+func NewUserRepository(store *Store) domain.UserRepository {
+	return &userRepository{store: store}
+}
+
+// Create stores the user with the default payment methods
+func (r *userRepository) Create(ctx context.Context, user *domain.User) error {
+	return r.store.update(ctx, func(t *tables) error {
+		for _, other := range t.users {
+			if other.Email == user.Email {
+				return domain.ErrEmailAlreadyExists
+			}
+		}
+
+		user.ID = t.nextID("users")
+		user.CreatedAt = now()
+		stored := *user
+		t.users[user.ID] = &stored
+
+		for _, method := range domain.DefaultPaymentMethods() {
+			method.ID = t.nextID("payment_methods")
+			method.UserID = user.ID
+			method.CreatedAt = user.CreatedAt
+			method.UpdatedAt = user.CreatedAt
+			t.paymentMethods[method.ID] = method
+		}
+		return nil
+	})
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id int) (*domain.User, error) {
+	return r.find(func(user *domain.User) bool { return user.ID == id })
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	return r.find(func(user *domain.User) bool { return user.Email == email })
+}
+
+// find returns a copy of the user for which match is true
+func (r *userRepository) find(match func(user *domain.User) bool) (*domain.User, error) {
+	var found domain.User
+	err := r.store.view(func(t *tables) error {
+		for _, user := range t.users {
+			if match(user) {
+				found = *user
+				return nil
+			}
+		}
+		return domain.ErrNotFound
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &found, nil
+}
@@ -0,0 +1,357 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID is the Postgres advisory lock held while migrating, so two
+// servers starting at once apply each migration a single time
+const migrationLockID int64 = 0x6578705f6d6967 // "exp_mig"
+
+// MigrationLock keeps other servers from migrating the same database until unlock is
+// called. It is taken on the connection the migrations then run on.
+type MigrationLock func(ctx context.Context, conn *sql.Conn) (unlock func(), err error)
+
+// Migrator applies numbered migrations to a database, keeping the version it is at
+// in the schema_migrations table
+type Migrator struct {
+	db         *sql.DB
+	migrations fs.FS
+	lock       MigrationLock
+}
+
+// NewMigrator creates a migrator for the NNNN_name.up.sql and NNNN_name.down.sql files
+// at the root of migrations. lock may be nil for a database only one server uses.
+func NewMigrator(db *sql.DB, migrations fs.FS, lock MigrationLock) *Migrator {
+	return &Migrator{db: db, migrations: migrations, lock: lock}
+}
+
+// NewSchemaMigrator creates the migrator for the Postgres schema, which holds an
+// advisory lock while it runs
+func NewSchemaMigrator() *Migrator {
+	migrations, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		panic(err)
+	}
+	return NewMigrator(DB, migrations, advisoryLock)
+}
+
+// advisoryLock takes the Postgres advisory lock. It belongs to the session, which is
+// why the migrations run on the connection that took it.
+func advisoryLock(ctx context.Context, conn *sql.Conn) (func(), error) {
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return nil, err
+	}
+	return func() {
+		conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+	}, nil
+}
+
+var (
+	// ErrSchemaDirty is returned when a migration was interrupted part way. The
+	// database has to be checked by hand and its version set with Migrator.Force.
+	ErrSchemaDirty = errors.New("schema is dirty: a migration was interrupted")
+	// ErrSchemaTooNew is returned when the database was migrated by a newer build
+	ErrSchemaTooNew = errors.New("schema is newer than this build's migrations")
+)
+
+// Migration is one numbered schema change, with the statements that undo it
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// SchemaStatus is the version the database is at and every migration this build knows
+type SchemaStatus struct {
+	Version    int
+	Dirty      bool
+	Migrations []*Migration
+}
+
+// Latest returns the version of the newest migration, or 0 when there are none
+func (s *SchemaStatus) Latest() int {
+	return latestVersion(s.Migrations)
+}
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migrations returns the migrator's migrations in version order
+func (m *Migrator) Migrations() ([]*Migration, error) {
+	return loadMigrations(m.migrations)
+}
+
+// loadMigrations reads the NNNN_name.up.sql and NNNN_name.down.sql pairs at the root
+// of fsys. Every version needs both halves.
+func loadMigrations(fsys fs.FS) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration %s: name must look like 0001_name.up.sql", entry.Name())
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: version must be a positive number", entry.Name())
+		}
+		contents, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d: named both %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(contents)
+		} else {
+			migration.Down = string(contents)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d: needs both an up and a down file", migration.Version)
+		}
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func latestVersion(migrations []*Migration) int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// Up applies every migration the database hasn't had yet
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn, migrations []*Migration) error {
+		return migrateTo(ctx, conn, migrations, latestVersion(migrations))
+	})
+}
+
+// Down reverts the latest migration the database has had
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn, migrations []*Migration) error {
+		version, _, err := schemaVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if version == 0 {
+			return nil
+		}
+		return migrateTo(ctx, conn, migrations, previousVersion(migrations, version))
+	})
+}
+
+// To applies or reverts migrations until the database is at version. Version 0
+// reverts every migration.
+func (m *Migrator) To(ctx context.Context, version int) error {
+	return m.withLock(ctx, func(conn *sql.Conn, migrations []*Migration) error {
+		if err := checkKnownVersion(migrations, version); err != nil {
+			return err
+		}
+		return migrateTo(ctx, conn, migrations, version)
+	})
+}
+
+// Force records the database as being at version and clears the dirty flag without
+// running anything. It is for recovering from an interrupted migration once the
+// database has been checked by hand.
+func (m *Migrator) Force(ctx context.Context, version int) error {
+	return m.withLock(ctx, func(conn *sql.Conn, migrations []*Migration) error {
+		if err := checkKnownVersion(migrations, version); err != nil {
+			return err
+		}
+		return setSchemaVersion(ctx, conn, version, false)
+	})
+}
+
+// Status returns the version the database is at and the migrations this build knows
+func (m *Migrator) Status(ctx context.Context) (*SchemaStatus, error) {
+	status := &SchemaStatus{}
+	err := m.withLock(ctx, func(conn *sql.Conn, migrations []*Migration) error {
+		version, dirty, err := schemaVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		status.Version, status.Dirty, status.Migrations = version, dirty, migrations
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// withLock runs fn on a connection holding the migration lock, so migrations from
+// different servers run one after another
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn, migrations []*Migration) error) error {
+	migrations, err := m.Migrations()
+	if err != nil {
+		return err
+	}
+
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.lock != nil {
+		unlock, err := m.lock(ctx, conn)
+		if err != nil {
+			return fmt.Errorf("failed to take the migration lock: %w", err)
+		}
+		defer unlock()
+	}
+
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER NOT NULL,
+			dirty BOOLEAN NOT NULL
+		)`,
+		// The table holds a single row, so moving to a new version is one UPDATE
+		`INSERT INTO schema_migrations (version, dirty)
+		 SELECT 0, FALSE WHERE NOT EXISTS (SELECT 1 FROM schema_migrations)`,
+	}
+	for _, query := range queries {
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("failed to create schema_migrations: %w", err)
+		}
+	}
+
+	return fn(conn, migrations)
+}
+
+// migrateTo runs the up halves of the migrations after the database's version through
+// target, or the down halves back to target, each in its own transaction
+func migrateTo(ctx context.Context, conn *sql.Conn, migrations []*Migration, target int) error {
+	version, dirty, err := schemaVersion(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("%w at version %d", ErrSchemaDirty, version)
+	}
+	if latest := latestVersion(migrations); version > latest {
+		return fmt.Errorf("%w: database is at version %d, latest known is %d", ErrSchemaTooNew, version, latest)
+	}
+
+	for _, migration := range migrations {
+		if migration.Version <= version || migration.Version > target {
+			continue
+		}
+		if err := applyMigration(ctx, conn, migration.Up, version, migration.Version); err != nil {
+			return fmt.Errorf("migration %d_%s up: %w", migration.Version, migration.Name, err)
+		}
+		version = migration.Version
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		migration := migrations[i]
+		if migration.Version > version || migration.Version <= target {
+			continue
+		}
+		previous := previousVersion(migrations, migration.Version)
+		if err := applyMigration(ctx, conn, migration.Down, version, previous); err != nil {
+			return fmt.Errorf("migration %d_%s down: %w", migration.Version, migration.Name, err)
+		}
+		version = previous
+	}
+	return nil
+}
+
+// applyMigration runs statements to move the database from one version to another.
+// The database is marked dirty at the new version first, so a server that dies part
+// way leaves a record of it. When the statements fail they are rolled back, and the
+// database is put back at the old version.
+func applyMigration(ctx context.Context, conn *sql.Conn, statements string, from, to int) error {
+	if err := setSchemaVersion(ctx, conn, to, true); err != nil {
+		return err
+	}
+
+	if err := runMigration(ctx, conn, statements, to); err != nil {
+		if resetErr := setSchemaVersion(context.WithoutCancel(ctx), conn, from, false); resetErr != nil {
+			return fmt.Errorf("%w (and clearing the dirty flag failed: %v)", err, resetErr)
+		}
+		return err
+	}
+	return nil
+}
+
+func runMigration(ctx context.Context, conn *sql.Conn, statements string, to int) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, statements); err != nil {
+		return err
+	}
+	if err := setSchemaVersion(ctx, tx, to, false); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func schemaVersion(ctx context.Context, conn executor) (int, bool, error) {
+	var version int
+	var dirty bool
+	err := conn.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations`).Scan(&version, &dirty)
+	return version, dirty, err
+}
+
+func setSchemaVersion(ctx context.Context, conn executor, version int, dirty bool) error {
+	_, err := conn.ExecContext(ctx, `UPDATE schema_migrations SET version = $1, dirty = $2`, version, dirty)
+	return err
+}
+
+// previousVersion returns the version of the migration before version, or 0 for the first
+func previousVersion(migrations []*Migration, version int) int {
+	previous := 0
+	for _, migration := range migrations {
+		if migration.Version >= version {
+			break
+		}
+		previous = migration.Version
+	}
+	return previous
+}
+
+func checkKnownVersion(migrations []*Migration, version int) error {
+	if version == 0 {
+		return nil
+	}
+	for _, migration := range migrations {
+		if migration.Version == version {
+			return nil
+		}
+	}
+	return fmt.Errorf("no migration has version %d", version)
+}
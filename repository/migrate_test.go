@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrations(t *testing.T) {
+	t.Run("Embedded migrations load in order", func(t *testing.T) {
+		migrations, err := NewSchemaMigrator().Migrations()
+		require.NoError(t, err)
+		require.NotEmpty(t, migrations)
+		assert.Equal(t, 1, migrations[0].Version)
+		for i := 1; i < len(migrations); i++ {
+			assert.Greater(t, migrations[i].Version, migrations[i-1].Version)
+		}
+	})
+
+	t.Run("Orders by version and pairs up and down files", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"0010_add_notes.up.sql":   {Data: []byte("ALTER TABLE expenses ADD COLUMN notes TEXT;")},
+			"0010_add_notes.down.sql": {Data: []byte("ALTER TABLE expenses DROP COLUMN notes;")},
+			"0002_initial.up.sql":     {Data: []byte("CREATE TABLE expenses (id SERIAL);")},
+			"0002_initial.down.sql":   {Data: []byte("DROP TABLE expenses;")},
+		}
+
+		migrations, err := loadMigrations(fsys)
+
+		require.NoError(t, err)
+		require.Len(t, migrations, 2)
+		assert.Equal(t, 2, migrations[0].Version)
+		assert.Equal(t, "initial", migrations[0].Name)
+		assert.Equal(t, "DROP TABLE expenses;", migrations[0].Down)
+		assert.Equal(t, 10, migrations[1].Version)
+		assert.Equal(t, "ALTER TABLE expenses ADD COLUMN notes TEXT;", migrations[1].Up)
+		assert.Equal(t, 2, previousVersion(migrations, 10))
+		assert.Equal(t, 0, previousVersion(migrations, 2))
+	})
+
+	t.Run("Missing down file", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"0001_initial.up.sql": {Data: []byte("CREATE TABLE expenses (id SERIAL);")},
+		}
+
+		_, err := loadMigrations(fsys)
+
+		assert.ErrorContains(t, err, "needs both an up and a down file")
+	})
+
+	t.Run("Badly named file", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"initial.sql": {Data: []byte("CREATE TABLE expenses (id SERIAL);")},
+		}
+
+		_, err := loadMigrations(fsys)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Two names for one version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"0001_initial.up.sql": {Data: []byte("CREATE TABLE expenses (id SERIAL);")},
+			"0001_other.down.sql": {Data: []byte("DROP TABLE expenses;")},
+		}
+
+		_, err := loadMigrations(fsys)
+
+		assert.ErrorContains(t, err, "named both")
+	})
+}
@@ -0,0 +1,22 @@
+-- Drops every table the initial schema created, and all the data in them
+DROP TABLE IF EXISTS audit_events;
+DROP FUNCTION IF EXISTS audit_events_append_only();
+
+DROP TABLE IF EXISTS reconciliations;
+DROP TABLE IF EXISTS transfers;
+DROP TABLE IF EXISTS incomes;
+DROP TABLE IF EXISTS budgets;
+DROP TABLE IF EXISTS app_settings;
+DROP TABLE IF EXISTS exchange_rates;
+DROP TABLE IF EXISTS expense_tags;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS expenses;
+DROP TABLE IF EXISTS recurring_expenses;
+DROP TABLE IF EXISTS accounts;
+DROP TABLE IF EXISTS payment_methods;
+DROP TABLE IF EXISTS categories;
+DROP TABLE IF EXISTS users;
+
+DROP FUNCTION IF EXISTS categories_search_vector_trigger();
+DROP FUNCTION IF EXISTS expenses_search_vector_trigger();
+DROP FUNCTION IF EXISTS expenses_search_vector(TEXT, INTEGER);
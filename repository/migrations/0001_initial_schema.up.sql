@@ -0,0 +1,382 @@
+-- The schema as of the first versioned release. Every statement is idempotent, so a
+-- database created before migrations were versioned is brought up to date and adopted.
+
+-- Category names and monthly budgets are unique per user, not globally
+ALTER TABLE IF EXISTS categories DROP CONSTRAINT IF EXISTS categories_name_key;
+
+ALTER TABLE IF EXISTS budgets DROP CONSTRAINT IF EXISTS budgets_month_year_key;
+
+DROP INDEX IF EXISTS idx_budgets_month_year_category;
+
+-- Budgets can be scoped to a category; uniqueness moves to idx_budgets_user_month_year_category_live
+ALTER TABLE IF EXISTS budgets ADD COLUMN IF NOT EXISTS category_id INTEGER REFERENCES categories(id) ON DELETE CASCADE;
+
+-- Names and months only need to be unique among rows that aren't in the trash;
+-- partial unique indexes below take their place
+ALTER TABLE IF EXISTS categories DROP CONSTRAINT IF EXISTS categories_user_id_name_key;
+
+DROP INDEX IF EXISTS idx_categories_user_name;
+
+DROP INDEX IF EXISTS idx_budgets_user_month_year_category;
+
+-- Payment modes are no longer limited to UPI and Cash; they are linked to payment_methods below
+ALTER TABLE IF EXISTS expenses DROP CONSTRAINT IF EXISTS expenses_payment_mode_check;
+
+ALTER TABLE IF EXISTS recurring_expenses DROP CONSTRAINT IF EXISTS recurring_expenses_payment_mode_check;
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'expenses' AND column_name = 'payment_mode' AND character_maximum_length < 50) THEN
+        ALTER TABLE expenses ALTER COLUMN payment_mode TYPE VARCHAR(50);
+        ALTER TABLE recurring_expenses ALTER COLUMN payment_mode TYPE VARCHAR(50);
+    END IF;
+END
+$$;
+
+CREATE TABLE IF NOT EXISTS users (
+    id SERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    password_hash VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS categories (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS payment_methods (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    type VARCHAR(10) NOT NULL CHECK (type IN ('card', 'bank', 'wallet', 'cash', 'upi')),
+    last4 CHAR(4),
+    vpa VARCHAR(255),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, name)
+);
+
+-- Accounts hold money in the base currency; the opening balance applies from opening_date
+CREATE TABLE IF NOT EXISTS accounts (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    type VARCHAR(10) NOT NULL CHECK (type IN ('bank', 'cash', 'wallet', 'card')),
+    opening_balance DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    opening_date DATE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS recurring_expenses (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category_id INTEGER NOT NULL REFERENCES categories(id),
+    amount DECIMAL(10, 2) NOT NULL,
+    description TEXT,
+    payment_mode VARCHAR(50) NOT NULL,
+    frequency VARCHAR(10) NOT NULL CHECK (frequency IN ('daily', 'weekly', 'monthly', 'yearly')),
+    day_of_month INTEGER CHECK (day_of_month >= 1 AND day_of_month <= 31),
+    start_date DATE NOT NULL,
+    end_date DATE,
+    next_run_date DATE NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS expenses (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category_id INTEGER NOT NULL REFERENCES categories(id),
+    amount DECIMAL(10, 2) NOT NULL,
+    description TEXT,
+    payment_mode VARCHAR(50) NOT NULL,
+    expense_date DATE NOT NULL,
+    recurring_expense_id INTEGER REFERENCES recurring_expenses(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS tags (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS expense_tags (
+    expense_id INTEGER NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (expense_id, tag_id)
+);
+
+-- Rates are shared by all users: units of the base currency per unit of the foreign currency
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    currency CHAR(3) NOT NULL,
+    rate_date DATE NOT NULL,
+    rate NUMERIC(18, 8) NOT NULL CHECK (rate > 0),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (currency, rate_date)
+);
+
+CREATE TABLE IF NOT EXISTS app_settings (
+    key VARCHAR(50) PRIMARY KEY,
+    value TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS budgets (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category_id INTEGER REFERENCES categories(id) ON DELETE CASCADE,
+    month INTEGER NOT NULL CHECK (month >= 1 AND month <= 12),
+    year INTEGER NOT NULL,
+    budget_amount DECIMAL(10, 2) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Incomes are in the base currency. The payment method they were received into is optional.
+CREATE TABLE IF NOT EXISTS incomes (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category_id INTEGER REFERENCES categories(id) ON DELETE SET NULL,
+    source VARCHAR(255) NOT NULL,
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+    description TEXT,
+    payment_mode VARCHAR(50),
+    income_date DATE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT incomes_payment_method_fkey FOREIGN KEY (user_id, payment_mode)
+        REFERENCES payment_methods(user_id, name) ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS transfers (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    from_account_id INTEGER NOT NULL REFERENCES accounts(id),
+    to_account_id INTEGER NOT NULL REFERENCES accounts(id),
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+    description TEXT,
+    transfer_date DATE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (from_account_id <> to_account_id)
+);
+
+CREATE TABLE IF NOT EXISTS reconciliations (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    statement_date DATE NOT NULL,
+    statement_balance DECIMAL(12, 2) NOT NULL,
+    computed_balance DECIMAL(12, 2) NOT NULL,
+    discrepancy DECIMAL(12, 2) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Databases created before recurring expenses and user accounts need the columns added.
+-- Rows that predate user accounts keep a NULL owner until the first user registers.
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS recurring_expense_id INTEGER
+    REFERENCES recurring_expenses(id) ON DELETE SET NULL;
+
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS user_id INTEGER REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE recurring_expenses ADD COLUMN IF NOT EXISTS user_id INTEGER REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS user_id INTEGER REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE budgets ADD COLUMN IF NOT EXISTS user_id INTEGER REFERENCES users(id) ON DELETE CASCADE;
+
+-- Expenses keep the amount as entered and the rate used to convert it to the base currency.
+-- Migration 0002 fills these in for older rows and then makes them required.
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS currency CHAR(3);
+
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS original_amount DECIMAL(10, 2);
+
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS exchange_rate NUMERIC(18, 8);
+
+-- Expenses and incomes may debit or credit an account. Accounts in use can't be deleted.
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS account_id INTEGER REFERENCES accounts(id);
+
+ALTER TABLE incomes ADD COLUMN IF NOT EXISTS account_id INTEGER REFERENCES accounts(id);
+
+-- Categories may nest under a parent. A parent with subcategories can't be deleted.
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS parent_id INTEGER REFERENCES categories(id);
+
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS archived BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Deleting a category used to delete its expenses and recurring expenses with it.
+-- Older databases get the cascading foreign keys replaced so categories in use can't be deleted.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'expenses_category_id_fkey' AND confdeltype = 'c') THEN
+        ALTER TABLE expenses DROP CONSTRAINT expenses_category_id_fkey;
+        ALTER TABLE expenses ADD CONSTRAINT expenses_category_id_fkey
+            FOREIGN KEY (category_id) REFERENCES categories(id);
+    END IF;
+    IF EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'recurring_expenses_category_id_fkey' AND confdeltype = 'c') THEN
+        ALTER TABLE recurring_expenses DROP CONSTRAINT recurring_expenses_category_id_fkey;
+        ALTER TABLE recurring_expenses ADD CONSTRAINT recurring_expenses_category_id_fkey
+            FOREIGN KEY (category_id) REFERENCES categories(id);
+    END IF;
+END
+$$;
+
+-- Deleted expenses, categories and budgets stay in the trash until restored or purged
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+ALTER TABLE budgets ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_expenses_deleted_at ON expenses(deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_categories_deleted_at ON categories(deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_budgets_deleted_at ON budgets(deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_categories_user_id ON categories(user_id);
+
+CREATE INDEX IF NOT EXISTS idx_categories_parent_id ON categories(parent_id) WHERE parent_id IS NOT NULL;
+
+-- A name or month in the trash can be used again; restoring it then fails with a conflict
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_user_name_live ON categories(user_id, name) WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_expenses_user_id ON expenses(user_id);
+
+CREATE INDEX IF NOT EXISTS idx_expenses_category_id ON expenses(category_id);
+
+CREATE INDEX IF NOT EXISTS idx_expenses_expense_date ON expenses(expense_date);
+
+-- Serves the default newest-first page of a user's expenses
+CREATE INDEX IF NOT EXISTS idx_expenses_user_date_id ON expenses(user_id, expense_date, id);
+
+-- Each recurring occurrence is created at most once, even when catching up after downtime
+CREATE UNIQUE INDEX IF NOT EXISTS idx_expenses_recurring_occurrence
+    ON expenses(recurring_expense_id, expense_date) WHERE recurring_expense_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_expense_tags_tag_id ON expense_tags(tag_id);
+
+-- Expenses name their payment method. The first time this runs it registers a payment
+-- method for every name already in use plus the defaults for every user, then links
+-- the tables so renames cascade and methods in use can't be deleted.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'expenses_payment_method_fkey') THEN
+        INSERT INTO payment_methods (user_id, name, type)
+        SELECT DISTINCT used.user_id, used.payment_mode,
+            CASE
+                WHEN lower(used.payment_mode) = 'upi' THEN 'upi'
+                WHEN lower(used.payment_mode) = 'cash' THEN 'cash'
+                WHEN lower(used.payment_mode) LIKE '%card%' THEN 'card'
+                WHEN lower(used.payment_mode) LIKE '%bank%' OR lower(used.payment_mode) LIKE '%transfer%' THEN 'bank'
+                ELSE 'wallet'
+            END
+        FROM (SELECT user_id, payment_mode FROM expenses
+            UNION SELECT user_id, payment_mode FROM recurring_expenses) AS used
+        WHERE NOT EXISTS (SELECT 1 FROM payment_methods pm
+            WHERE pm.user_id IS NOT DISTINCT FROM used.user_id AND pm.name = used.payment_mode);
+
+        INSERT INTO payment_methods (user_id, name, type)
+        SELECT u.id, d.name, d.type FROM users u
+        CROSS JOIN (VALUES ('UPI', 'upi'), ('Cash', 'cash')) AS d(name, type)
+        ON CONFLICT (user_id, name) DO NOTHING;
+
+        ALTER TABLE expenses ADD CONSTRAINT expenses_payment_method_fkey
+            FOREIGN KEY (user_id, payment_mode) REFERENCES payment_methods(user_id, name) ON UPDATE CASCADE;
+        ALTER TABLE recurring_expenses ADD CONSTRAINT recurring_expenses_payment_method_fkey
+            FOREIGN KEY (user_id, payment_mode) REFERENCES payment_methods(user_id, name) ON UPDATE CASCADE;
+    END IF;
+END
+$$;
+
+CREATE INDEX IF NOT EXISTS idx_incomes_user_date ON incomes(user_id, income_date);
+
+CREATE INDEX IF NOT EXISTS idx_expenses_account_id ON expenses(account_id, expense_date) WHERE account_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_incomes_account_id ON incomes(account_id, income_date) WHERE account_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_transfers_from_account_id ON transfers(from_account_id, transfer_date);
+
+CREATE INDEX IF NOT EXISTS idx_transfers_to_account_id ON transfers(to_account_id, transfer_date);
+
+CREATE INDEX IF NOT EXISTS idx_reconciliations_account_id ON reconciliations(account_id, statement_date);
+
+CREATE INDEX IF NOT EXISTS idx_recurring_expenses_user_id ON recurring_expenses(user_id);
+
+CREATE INDEX IF NOT EXISTS idx_recurring_expenses_next_run_date ON recurring_expenses(next_run_date);
+
+-- One overall budget (category_id NULL) and one budget per category for each user and month
+CREATE UNIQUE INDEX IF NOT EXISTS idx_budgets_user_month_year_category_live
+    ON budgets(user_id, month, year, COALESCE(category_id, 0)) WHERE deleted_at IS NULL;
+
+-- Full-text search over the description (weight A) and category name (weight B).
+-- Triggers keep the vector current when either side changes.
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+CREATE OR REPLACE FUNCTION expenses_search_vector(description TEXT, category_id INTEGER) RETURNS tsvector AS $$
+    SELECT setweight(to_tsvector('english', COALESCE($1, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE((SELECT name FROM categories WHERE id = $2), '')), 'B')
+$$ LANGUAGE SQL STABLE;
+
+CREATE OR REPLACE FUNCTION expenses_search_vector_trigger() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector := expenses_search_vector(NEW.description, NEW.category_id);
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS expenses_search_vector_update ON expenses;
+
+CREATE TRIGGER expenses_search_vector_update BEFORE INSERT OR UPDATE OF description, category_id
+    ON expenses FOR EACH ROW EXECUTE FUNCTION expenses_search_vector_trigger();
+
+CREATE OR REPLACE FUNCTION categories_search_vector_trigger() RETURNS trigger AS $$
+BEGIN
+    UPDATE expenses SET search_vector = expenses_search_vector(description, category_id)
+        WHERE category_id = NEW.id;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS categories_search_vector_update ON categories;
+
+CREATE TRIGGER categories_search_vector_update AFTER UPDATE OF name
+    ON categories FOR EACH ROW EXECUTE FUNCTION categories_search_vector_trigger();
+
+UPDATE expenses SET search_vector = expenses_search_vector(description, category_id) WHERE search_vector IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_expenses_search_vector ON expenses USING GIN(search_vector);
+
+-- Every change to a user's records is logged in the transaction that makes it. The log
+-- outlives the records it describes and is append-only: rows can't be changed or removed.
+CREATE TABLE IF NOT EXISTS audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    actor_id INTEGER,
+    entity VARCHAR(30) NOT NULL,
+    entity_id INTEGER NOT NULL,
+    action VARCHAR(10) NOT NULL,
+    changes JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_user_entity ON audit_events(user_id, entity, entity_id, id);
+
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events;
+
+CREATE TRIGGER audit_events_append_only BEFORE UPDATE OR DELETE
+    ON audit_events FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
@@ -0,0 +1,4 @@
+-- Makes the currency columns optional again; the values filled in are kept
+ALTER TABLE expenses ALTER COLUMN currency DROP NOT NULL,
+    ALTER COLUMN original_amount DROP NOT NULL,
+    ALTER COLUMN exchange_rate DROP NOT NULL;
@@ -0,0 +1,17 @@
+-- Expenses saved before currencies were tracked are in the base currency. A database
+-- that has such expenses and no base currency recorded yet used the default, INR, and
+-- keeps it: the server refuses to start with another one rather than mislabel them.
+INSERT INTO app_settings (key, value)
+SELECT 'base_currency', 'INR'
+WHERE EXISTS (SELECT 1 FROM expenses WHERE currency IS NULL)
+ON CONFLICT (key) DO NOTHING;
+
+UPDATE expenses
+SET currency = (SELECT value FROM app_settings WHERE key = 'base_currency'),
+    original_amount = amount,
+    exchange_rate = 1
+WHERE currency IS NULL;
+
+ALTER TABLE expenses ALTER COLUMN currency SET NOT NULL,
+    ALTER COLUMN original_amount SET NOT NULL,
+    ALTER COLUMN exchange_rate SET NOT NULL;
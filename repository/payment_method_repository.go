@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"expense-tracker-api/domain"
+	"time"
+)
+
+type paymentMethodRepository struct {
+	tx *sql.Tx
+}
+
+// NewPaymentMethodRepository creates a new payment method repository
+func NewPaymentMethodRepository() domain.PaymentMethodRepository {
+	return &paymentMethodRepository{}
+}
+
+// WithTx returns the repository bound to a transaction from NewTxManager
+func (r *paymentMethodRepository) WithTx(tx domain.Tx) domain.PaymentMethodRepository {
+	return &paymentMethodRepository{tx: boundTx(tx)}
+}
+
+const paymentMethodColumns = `id, user_id, name, type, last4, vpa, active, created_at, updated_at`
+
+func (r *paymentMethodRepository) Create(ctx context.Context, method *domain.PaymentMethod) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO payment_methods (user_id, name, type, last4, vpa, active, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+	now := time.Now()
+	err = tx.QueryRowContext(ctx, query, method.UserID, method.Name, method.Type, method.Last4, method.VPA,
+		method.Active, now, now).Scan(&method.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.ErrAlreadyExists
+		}
+		return err
+	}
+	method.CreatedAt = now
+	method.UpdatedAt = now
+
+	if err := recordChange(ctx, tx, method.UserID, domain.AuditEntityPaymentMethod, method.ID, domain.AuditActionCreate, nil, method); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const paymentMethodByIDQuery = `SELECT ` + paymentMethodColumns + ` FROM payment_methods WHERE id = $1 AND user_id = $2`
+
+func (r *paymentMethodRepository) GetByID(ctx context.Context, userID, id int) (*domain.PaymentMethod, error) {
+	method, err := scanPaymentMethod(conn(r.tx).QueryRowContext(ctx, paymentMethodByIDQuery+lockClause(r.tx), id, userID))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	return method, err
+}
+
+// lockPaymentMethod reads the payment method within tx and locks it until tx ends
+func lockPaymentMethod(ctx context.Context, tx executor, userID, id int) (*domain.PaymentMethod, error) {
+	method, err := scanPaymentMethod(tx.QueryRowContext(ctx, paymentMethodByIDQuery+` FOR UPDATE`, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	return method, err
+}
+
+func (r *paymentMethodRepository) GetByName(ctx context.Context, userID int, name string) (*domain.PaymentMethod, error) {
+	query := `SELECT ` + paymentMethodColumns + ` FROM payment_methods WHERE user_id = $1 AND name = $2`
+	method, err := scanPaymentMethod(conn(r.tx).QueryRowContext(ctx, query, userID, name))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	return method, err
+}
+
+func (r *paymentMethodRepository) GetAll(ctx context.Context, userID int) ([]*domain.PaymentMethod, error) {
+	query := `SELECT ` + paymentMethodColumns + ` FROM payment_methods WHERE user_id = $1 ORDER BY name`
+	rows, err := conn(r.tx).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	methods := []*domain.PaymentMethod{}
+	for rows.Next() {
+		method, err := scanPaymentMethod(rows)
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, method)
+	}
+	return methods, rows.Err()
+}
+
+// Update saves the payment method. A new name is carried over to the
+// expenses and recurring expenses that use it by the ON UPDATE CASCADE keys.
+func (r *paymentMethodRepository) Update(ctx context.Context, method *domain.PaymentMethod) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := lockPaymentMethod(ctx, tx, method.UserID, method.ID)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE payment_methods SET name = $1, type = $2, last4 = $3, vpa = $4, active = $5, updated_at = $6
+			  WHERE id = $7`
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, query, method.Name, method.Type, method.Last4, method.VPA, method.Active, now, method.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.ErrAlreadyExists
+		}
+		return err
+	}
+	method.UpdatedAt = now
+
+	after, err := lockPaymentMethod(ctx, tx, method.UserID, method.ID)
+	if err != nil {
+		return err
+	}
+	if err := recordChange(ctx, tx, method.UserID, domain.AuditEntityPaymentMethod, method.ID, domain.AuditActionUpdate, before, after); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete removes a payment method that no expense refers to
+func (r *paymentMethodRepository) Delete(ctx context.Context, userID, id int) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := lockPaymentMethod(ctx, tx, userID, id)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM payment_methods WHERE id = $1`, id); err != nil {
+		if isForeignKeyViolation(err) {
+			return domain.ErrInUse
+		}
+		return err
+	}
+	if err := recordChange(ctx, tx, userID, domain.AuditEntityPaymentMethod, id, domain.AuditActionDelete, before, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func scanPaymentMethod(row rowScanner) (*domain.PaymentMethod, error) {
+	method := &domain.PaymentMethod{}
+	err := row.Scan(&method.ID, &method.UserID, &method.Name, &method.Type, &method.Last4, &method.VPA,
+		&method.Active, &method.CreatedAt, &method.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return method, nil
+}
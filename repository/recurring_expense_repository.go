@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"expense-tracker-api/domain"
+	"time"
+)
+
+type recurringExpenseRepository struct {
+	tx *sql.Tx
+}
+
+// NewRecurringExpenseRepository creates a new recurring expense repository
+func NewRecurringExpenseRepository() domain.RecurringExpenseRepository {
+	return &recurringExpenseRepository{}
+}
+
+// WithTx returns the repository bound to a transaction from NewTxManager
+func (r *recurringExpenseRepository) WithTx(tx domain.Tx) domain.RecurringExpenseRepository {
+	return &recurringExpenseRepository{tx: boundTx(tx)}
+}
+
+const recurringExpenseColumns = `id, user_id, category_id, amount, description, payment_mode, frequency, day_of_month,
+			  start_date, end_date, next_run_date, active, created_at, updated_at`
+
+func (r *recurringExpenseRepository) Create(ctx context.Context, recurring *domain.RecurringExpense) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO recurring_expenses (user_id, category_id, amount, description, payment_mode, frequency, day_of_month,
+			  start_date, end_date, next_run_date, active, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id`
+	now := time.Now()
+	err = tx.QueryRowContext(ctx, query, recurring.UserID, recurring.CategoryID, recurring.Amount, recurring.Description, recurring.PaymentMode,
+		recurring.Frequency, recurring.DayOfMonth, recurring.StartDate, recurring.EndDate, recurring.NextRunDate,
+		recurring.Active, now, now).Scan(&recurring.ID)
+	if err != nil {
+		return err
+	}
+	recurring.CreatedAt = now
+	recurring.UpdatedAt = now
+
+	if err := recordChange(ctx, tx, recurring.UserID, domain.AuditEntityRecurringExpense, recurring.ID, domain.AuditActionCreate, nil, recurring); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const recurringExpenseByIDQuery = `SELECT ` + recurringExpenseColumns + ` FROM recurring_expenses WHERE id = $1 AND user_id = $2`
+
+func (r *recurringExpenseRepository) GetByID(ctx context.Context, userID, id int) (*domain.RecurringExpense, error) {
+	return scanRecurringExpense(conn(r.tx).QueryRowContext(ctx, recurringExpenseByIDQuery+lockClause(r.tx), id, userID))
+}
+
+// lockRecurringExpense reads the recurring expense within tx and locks it until tx ends
+func lockRecurringExpense(ctx context.Context, tx executor, userID, id int) (*domain.RecurringExpense, error) {
+	recurring, err := scanRecurringExpense(tx.QueryRowContext(ctx, recurringExpenseByIDQuery+` FOR UPDATE`, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	return recurring, err
+}
+
+func (r *recurringExpenseRepository) GetAll(ctx context.Context, userID int) ([]*domain.RecurringExpense, error) {
+	query := `SELECT ` + recurringExpenseColumns + ` FROM recurring_expenses WHERE user_id = $1 ORDER BY next_run_date, id`
+	rows, err := conn(r.tx).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanRecurringExpenses(rows)
+}
+
+func (r *recurringExpenseRepository) GetDue(ctx context.Context, asOf time.Time) ([]*domain.RecurringExpense, error) {
+	query := `SELECT ` + recurringExpenseColumns + ` FROM recurring_expenses
+			  WHERE active AND next_run_date <= $1 AND (end_date IS NULL OR next_run_date <= end_date)
+			  ORDER BY next_run_date, id`
+	rows, err := conn(r.tx).QueryContext(ctx, query, asOf)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanRecurringExpenses(rows)
+}
+
+func (r *recurringExpenseRepository) Update(ctx context.Context, recurring *domain.RecurringExpense) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := lockRecurringExpense(ctx, tx, recurring.UserID, recurring.ID)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE recurring_expenses SET category_id = $1, amount = $2, description = $3, payment_mode = $4,
+			  frequency = $5, day_of_month = $6, start_date = $7, end_date = $8, next_run_date = $9,
+			  active = $10, updated_at = $11 WHERE id = $12`
+	recurring.UpdatedAt = time.Now()
+	_, err = tx.ExecContext(ctx, query, recurring.CategoryID, recurring.Amount, recurring.Description, recurring.PaymentMode,
+		recurring.Frequency, recurring.DayOfMonth, recurring.StartDate, recurring.EndDate, recurring.NextRunDate,
+		recurring.Active, recurring.UpdatedAt, recurring.ID)
+	if err != nil {
+		return err
+	}
+
+	after, err := lockRecurringExpense(ctx, tx, recurring.UserID, recurring.ID)
+	if err != nil {
+		return err
+	}
+	if err := recordChange(ctx, tx, recurring.UserID, domain.AuditEntityRecurringExpense, recurring.ID, domain.AuditActionUpdate, before, after); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *recurringExpenseRepository) UpdateNextRunDate(ctx context.Context, id int, nextRunDate time.Time) error {
+	query := `UPDATE recurring_expenses SET next_run_date = $1, updated_at = $2 WHERE id = $3`
+	_, err := conn(r.tx).ExecContext(ctx, query, nextRunDate, time.Now(), id)
+	return err
+}
+
+func (r *recurringExpenseRepository) Delete(ctx context.Context, userID, id int) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := lockRecurringExpense(ctx, tx, userID, id)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recurring_expenses WHERE id = $1`, id); err != nil {
+		return err
+	}
+	if err := recordChange(ctx, tx, userID, domain.AuditEntityRecurringExpense, id, domain.AuditActionDelete, before, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRecurringExpense(row rowScanner) (*domain.RecurringExpense, error) {
+	recurring := &domain.RecurringExpense{}
+	err := row.Scan(&recurring.ID, &recurring.UserID, &recurring.CategoryID, &recurring.Amount, &recurring.Description,
+		&recurring.PaymentMode, &recurring.Frequency, &recurring.DayOfMonth, &recurring.StartDate,
+		&recurring.EndDate, &recurring.NextRunDate, &recurring.Active, &recurring.CreatedAt, &recurring.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return recurring, nil
+}
+
+func scanRecurringExpenses(rows *sql.Rows) ([]*domain.RecurringExpense, error) {
+	var recurringExpenses []*domain.RecurringExpense
+	for rows.Next() {
+		recurring, err := scanRecurringExpense(rows)
+		if err != nil {
+			return nil, err
+		}
+		recurringExpenses = append(recurringExpenses, recurring)
+	}
+	return recurringExpenses, rows.Err()
+}
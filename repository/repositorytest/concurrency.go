@@ -0,0 +1,147 @@
+package repositorytest
+
+import (
+	"context"
+	"expense-tracker-api/domain"
+	"expense-tracker-api/services"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The tests below run the services against the backend, so they show that its
+// transactions and locks, rather than the services, keep concurrent requests apart
+
+// readDelay holds back the reads a request decides on, widening the window in
+// which a concurrent request could change what was read
+const readDelay = 20 * time.Millisecond
+
+type slowBudgetReads struct {
+	domain.BudgetRepository
+}
+
+func (r slowBudgetReads) WithTx(tx domain.Tx) domain.BudgetRepository {
+	return slowBudgetReads{r.BudgetRepository.WithTx(tx)}
+}
+
+func (r slowBudgetReads) GetByMonth(ctx context.Context, userID, month, year int) (*domain.Budget, error) {
+	budget, err := r.BudgetRepository.GetByMonth(ctx, userID, month, year)
+	time.Sleep(readDelay)
+	return budget, err
+}
+
+func (r slowBudgetReads) GetByMonthAndCategory(ctx context.Context, userID, month, year, categoryID int) (*domain.Budget, error) {
+	budget, err := r.BudgetRepository.GetByMonthAndCategory(ctx, userID, month, year, categoryID)
+	time.Sleep(readDelay)
+	return budget, err
+}
+
+type slowExpenseReads struct {
+	domain.ExpenseRepository
+}
+
+func (r slowExpenseReads) WithTx(tx domain.Tx) domain.ExpenseRepository {
+	return slowExpenseReads{r.ExpenseRepository.WithTx(tx)}
+}
+
+func (r slowExpenseReads) GetByID(ctx context.Context, userID, id int) (*domain.Expense, error) {
+	expense, err := r.ExpenseRepository.GetByID(ctx, userID, id)
+	time.Sleep(readDelay)
+	return expense, err
+}
+
+func newBudgetService(b *Backend) *services.BudgetService {
+	return services.NewBudgetService(b.TxManager, slowBudgetReads{b.Budgets}, b.Expenses, b.Categories, b.Incomes)
+}
+
+func newExpenseService(b *Backend) *services.ExpenseService {
+	return services.NewExpenseService(b.TxManager, slowExpenseReads{b.Expenses}, b.Categories, b.Budgets, b.PaymentMethods,
+		b.Accounts, services.NewExchangeRateService(b.ExchangeRates, "INR"))
+}
+
+// runConcurrently calls fn from n goroutines at once and returns the error of each call
+func runConcurrently(n int, fn func(i int) error) []error {
+	var ready, done sync.WaitGroup
+	ready.Add(1)
+	errs := make([]error, n)
+	for i := range n {
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			ready.Wait()
+			errs[i] = fn(i)
+		}()
+	}
+	ready.Done()
+	done.Wait()
+	return errs
+}
+
+func testConcurrentBudgetCreates(t *testing.T, b *Backend) {
+	ctx := context.Background()
+	userID := createUser(t, b, "concurrent-budgets@example.com")
+	foodID := createCategory(t, b, userID, "Food", nil)
+	budgetService := newBudgetService(b)
+	amount := domain.Money(500000)
+
+	// Every request misses the budget of the new month, and all but one of them
+	// clash with the budget the first one creates
+	errs := runConcurrently(20, func(i int) error {
+		categoryID := &foodID
+		if i%2 == 0 {
+			categoryID = nil
+		}
+		_, err := budgetService.CreateOrUpdateBudget(ctx, userID, 6, 2024, categoryID, amount)
+		return err
+	})
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+
+	budgets, err := b.Budgets.GetAll(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, budgets, 2)
+	for _, budget := range budgets {
+		assert.Equal(t, amount, budget.BudgetAmount)
+	}
+}
+
+func testConcurrentExpenseEdits(t *testing.T, b *Backend) {
+	ctx := context.Background()
+	userID := createUser(t, b, "concurrent-edits@example.com")
+	foodID := createCategory(t, b, userID, "Food", nil)
+	travelID := createCategory(t, b, userID, "Travel", nil)
+	expense := createExpense(t, b, userID, foodID, "100.00", date(2024, time.January, 15), "Lunch")
+	expenseService := newExpenseService(b)
+
+	// Each request reads the expense and writes all of it back with one field
+	// changed; without a lock on the read, a later write undoes an earlier one
+	edits := []*domain.Expense{
+		{Description: "Team lunch"},
+		{Amount: 25000},
+		{CategoryID: travelID},
+		{PaymentMode: domain.PaymentModeUPI},
+		{Tags: []string{"work"}},
+	}
+	errs := runConcurrently(len(edits), func(i int) error {
+		edit := *edits[i]
+		edit.ID = expense.ID
+		edit.UserID = userID
+		_, err := expenseService.UpdateExpense(ctx, &edit)
+		return err
+	})
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+
+	got, err := b.Expenses.GetByID(ctx, userID, expense.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Team lunch", got.Description)
+	assert.Equal(t, "250.00", got.Amount.String())
+	assert.Equal(t, travelID, got.CategoryID)
+	assert.Equal(t, domain.PaymentModeUPI, got.PaymentMode)
+	assert.Equal(t, []string{"work"}, got.Tags)
+}
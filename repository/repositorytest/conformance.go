@@ -0,0 +1,717 @@
+// Package repositorytest holds the tests every storage backend of the expense,
+// category, budget, tag and payment method repositories has to pass, so the
+// backends behave the same behind the services. The concurrency tests run the
+// services themselves on the backend.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"expense-tracker-api/domain"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Backend is one storage backend's repositories, all on the same empty database
+type Backend struct {
+	Users          domain.UserRepository
+	Categories     domain.CategoryRepository
+	Expenses       domain.ExpenseRepository
+	Budgets        domain.BudgetRepository
+	Tags           domain.TagRepository
+	Audit          domain.AuditRepository
+	PaymentMethods domain.PaymentMethodRepository
+	Accounts       domain.AccountRepository
+	Incomes        domain.IncomeRepository
+	ExchangeRates  domain.ExchangeRateRepository
+	TxManager      domain.TxManager
+}
+
+// Run runs the conformance tests, calling newBackend for a backend on an empty database in each one
+func Run(t *testing.T, newBackend func(t *testing.T) *Backend) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, b *Backend)
+	}{
+		{"ExpenseRoundTrip", testExpenseRoundTrip},
+		{"ExpenseUpdateAndDelete", testExpenseUpdateAndDelete},
+		{"ExpenseFilters", testExpenseFilters},
+		{"ExpensePaging", testExpensePaging},
+		{"ExpenseSearch", testExpenseSearch},
+		{"ExpenseTotals", testExpenseTotals},
+		{"ExpenseStream", testExpenseStream},
+		{"CategoryRoundTrip", testCategoryRoundTrip},
+		{"CategoryDelete", testCategoryDelete},
+		{"CategorySetArchived", testCategorySetArchived},
+		{"CategoryMerge", testCategoryMerge},
+		{"TagRenameAndMerge", testTagRenameAndMerge},
+		{"PaymentMethodLookup", testPaymentMethodLookup},
+		{"BudgetRoundTrip", testBudgetRoundTrip},
+		{"BudgetUniqueness", testBudgetUniqueness},
+		{"TxRollback", testTxRollback},
+		{"ConcurrentBudgetCreates", testConcurrentBudgetCreates},
+		{"ConcurrentExpenseEdits", testConcurrentExpenseEdits},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newBackend(t))
+		})
+	}
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func createUser(t *testing.T, b *Backend, email string) int {
+	t.Helper()
+	user := &domain.User{Email: email, PasswordHash: "hash"}
+	require.NoError(t, b.Users.Create(context.Background(), user))
+	return user.ID
+}
+
+func createCategory(t *testing.T, b *Backend, userID int, name string, parentID *int) int {
+	t.Helper()
+	category := &domain.Category{UserID: userID, Name: name, ParentID: parentID}
+	require.NoError(t, b.Categories.Create(context.Background(), category))
+	return category.ID
+}
+
+func createExpense(t *testing.T, b *Backend, userID, categoryID int, amount string, day time.Time, description string, tags ...string) *domain.Expense {
+	t.Helper()
+	money, err := domain.ParseMoney(amount)
+	require.NoError(t, err)
+	expense := &domain.Expense{
+		UserID:         userID,
+		CategoryID:     categoryID,
+		Amount:         money,
+		Currency:       "INR",
+		OriginalAmount: money,
+		ExchangeRate:   domain.RateOne,
+		Description:    description,
+		PaymentMode:    domain.PaymentModeCash,
+		ExpenseDate:    day,
+		Tags:           tags,
+	}
+	require.NoError(t, b.Expenses.Create(context.Background(), expense))
+	return expense
+}
+
+func expenseIDs(expenses []*domain.Expense) []int {
+	ids := make([]int, 0, len(expenses))
+	for _, expense := range expenses {
+		ids = append(ids, expense.ID)
+	}
+	return ids
+}
+
+func testExpenseRoundTrip(t *testing.T, b *Backend) {
+	ctx := context.Background()
+	userID := createUser(t, b, "round@example.com")
+	categoryID := createCategory(t, b, userID, "Food", nil)
+	created := createExpense(t, b, userID, categoryID, "1234.56", date(2024, time.March, 15), "Groceries", "weekly", "essentials")
+
+	got, err := b.Expenses.GetByID(ctx, userID, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, got.ID)
+	assert.Equal(t, userID, got.UserID)
+	assert.Equal(t, categoryID, got.CategoryID)
+	assert.Equal(t, "1234.56", got.Amount.String())
+	assert.Equal(t, "INR", got.Currency)
+	assert.Equal(t, created.OriginalAmount, got.OriginalAmount)
+	assert.Equal(t, domain.RateOne, got.ExchangeRate)
+	assert.Equal(t, "Groceries", got.Description)
+	assert.Equal(t, domain.PaymentModeCash, got.PaymentMode)
+	assert.Nil(t, got.AccountID)
+	assert.Equal(t, "2024-03-15", got.ExpenseDate.Format("2006-01-02"))
+	assert.Equal(t, []string{"essentials", "weekly"}, got.Tags)
+	assert.WithinDuration(t, time.Now(), got.CreatedAt, time.Minute)
+
+	// Other users can't see the expense
+	otherID := createUser(t, b, "other@example.com")
+	_, err = b.Expenses.GetByID(ctx, otherID, created.ID)
+	assert.Error(t, err)
+
+	batch := []*domain.Expense{
+		{UserID: userID, CategoryID: categoryID, Amount: 100, Currency: "INR", OriginalAmount: 100, ExchangeRate: domain.RateOne,
+			PaymentMode: domain.PaymentModeUPI, ExpenseDate: date(2024, time.March, 16), Tags: []string{"weekly"}},
+		{UserID: userID, CategoryID: categoryID, Amount: 200, Currency: "INR", OriginalAmount: 200, ExchangeRate: domain.RateOne,
+			PaymentMode: domain.PaymentModeUPI, ExpenseDate: date(2024, time.March, 17)},
+	}
+	require.NoError(t, b.Expenses.CreateBatch(ctx, batch))
+	for _, expense := range batch {
+		assert.NotZero(t, expense.ID)
+	}
+	got, err = b.Expenses.GetByID(ctx, userID, batch[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"weekly"}, got.Tags)
+	got, err = b.Expenses.GetByID(ctx, userID, batch[1].ID)
+	require.NoError(t, err)
+	assert.Empty(t, got.Tags)
+}
+
+func testExpenseUpdateAndDelete(t *testing.T, b *Backend) {
+	ctx := context.Background()
+	userID := createUser(t, b, "update@example.com")
+	foodID := createCategory(t, b, userID, "Food", nil)
+	travelID := createCategory(t, b, userID, "Travel", nil)
+	expense := createExpense(t, b, userID, foodID, "10.00", date(2024, time.May, 1), "Lunch", "work")
+
+	expense.CategoryID = travelID
+	expense.Amount = 2500
+	expense.OriginalAmount = 2500
+	expense.Description = "Taxi"
+	expense.ExpenseDate = date(2024, time.May, 2)
+	expense.Tags = []string{"trip"}
+	require.NoError(t, b.Expenses.Update(ctx, expense))
+
+	got, err := b.Expenses.GetByID(ctx, userID, expense.ID)
+	require.NoError(t, err)
+	assert.Equal(t, travelID, got.CategoryID)
+	assert.Equal(t, "25.00", got.Amount.String())
+	assert.Equal(t, "Taxi", got.Description)
+	assert.Equal(t, "2024-05-02", got.ExpenseDate.Format("2006-01-02"))
+	assert.Equal(t, []string{"trip"}, got.Tags)
+
+	// Another user's update is refused
+	otherID := createUser(t, b, "intruder@example.com")
+	stolen := *expense
+	stolen.UserID = otherID
+	assert.True(t, errors.Is(b.Expenses.Update(ctx, &stolen), domain.ErrNotFound))
+
+	require.NoError(t, b.Expenses.Delete(ctx, userID, expense.ID))
+	_, err = b.Expenses.GetByID(ctx, userID, expense.ID)
+	assert.Error(t, err)
+	assert.True(t, errors.Is(b.Expenses.Delete(ctx, userID, expense.ID), domain.ErrNotFound))
+
+	count, err := b.Expenses.Count(ctx, userID, &domain.ExpenseFilter{})
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func testExpenseFilters(t *testing.T, b *Backend) {
+	ctx := context.Background()
+	userID := createUser(t, b, "filters@example.com")
+	foodID := createCategory(t, b, userID, "Food", nil)
+	snacksID := createCategory(t, b, userID, "Snacks", &foodID)
+	travelID := createCategory(t, b, userID, "Travel", nil)
+
+	lunch := createExpense(t, b, userID, foodID, "12.00", date(2024, time.January, 5), "Lunch", "work", "meal")
+	chips := createExpense(t, b, userID, snacksID, "3.50", date(2024, time.January, 20), "Chips", "meal")
+	train := createExpense(t, b, userID, travelID, "40.00", date(2024, time.February, 2), "Train", "work")
+
+	tests := []struct {
+		name   string
+		filter *domain.ExpenseFilter
+		want   []int
+	}{
+		{"no filter", &domain.ExpenseFilter{}, []int{lunch.ID, chips.ID, train.ID}},
+		{"category", &domain.ExpenseFilter{CategoryID: &foodID}, []int{lunch.ID}},
+		{"category with subcategories", &domain.ExpenseFilter{CategoryID: &foodID, IncludeSubcategories: true},
+			[]int{lunch.ID, chips.ID}},
+		{"date range", &domain.ExpenseFilter{StartDate: ptr(date(2024, time.January, 20)), EndDate: ptr(date(2024, time.February, 2))},
+			[]int{chips.ID, train.ID}},
+		{"any tag", &domain.ExpenseFilter{Tags: []string{"work", "meal"}}, []int{lunch.ID, chips.ID, train.ID}},
+		{"all tags", &domain.ExpenseFilter{Tags: []string{"work", "meal"}, AllTags: true}, []int{lunch.ID}},
+		{"payment mode", &domain.ExpenseFilter{PaymentMode: ptr(domain.PaymentModeUPI)}, []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expenses, err := b.Expenses.GetAll(ctx, userID, tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, expenseIDs(expenses))
+
+			count, err := b.Expenses.Count(ctx, userID, tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, len(tt.want), count)
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func testExpensePaging(t *testing.T, b *Backend) {
+	ctx := context.Background()
+	userID := createUser(t, b, "paging@example.com")
+	categoryID := createCategory(t, b, userID, "Food", nil)
+
+	// Equal dates and amounts make the ID break ties
+	var all []*domain.Expense
+	for i, amount := range []string{"5.00", "1.00", "5.00", "3.25", "1.00"} {
+		all = append(all, createExpense(t, b, userID, categoryID, amount, date(2024, time.June, 1+i/2), fmt.Sprintf("Expense %d", i)))
+	}
+
+	for _, sort := range []domain.ExpenseSort{domain.ExpenseSortDate, domain.ExpenseSortAmount, domain.ExpenseSortCreatedAt} {
+		for _, descending := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s descending=%v", sort, descending), func(t *testing.T) {
+				filter := &domain.ExpenseFilter{Sort: sort, Descending: descending}
+				unpaged, err := b.Expenses.GetAll(ctx, userID, filter)
+				require.NoError(t, err)
+				require.Len(t, unpaged, len(all))
+
+				var paged []int
+				filter.Limit = 2
+				for {
+					page, err := b.Expenses.GetAll(ctx, userID, filter)
+					require.NoError(t, err)
+					paged = append(paged, expenseIDs(page)...)
+					if len(page) < filter.Limit {
+						break
+					}
+					filter.After = domain.NewExpenseCursor(sort, descending, page[len(page)-1])
+				}
+				assert.Equal(t, expenseIDs(unpaged), paged)
+			})
+		}
+	}
+
+	expenses, err := b.Expenses.GetAll(ctx, userID, &domain.ExpenseFilter{Sort: domain.ExpenseSortAmount, Descending: true})
+	require.NoError(t, err)
+	assert.Equal(t, []int{all[2].ID, all[0].ID, all[3].ID, all[4].ID, all[1].ID}, expenseIDs(expenses))
+}
+
+func testExpenseSearch(t *testing.T, b *Backend) {
+	ctx := context.Background()
+	userID := createUser(t, b, "search@example.com")
+	foodID := createCategory(t, b, userID, "Coffee", nil)
+	otherID := createCategory(t, b, userID, "Books", nil)
+
+	inDescription := createExpense(t, b, userID, otherID, "8.00", date(2024, time.July, 1), "Coffee beans")
+	inBoth := createExpense(t, b, userID, foodID, "4.00", date(2024, time.July, 2), "Morning coffee")
+	inCategory := createExpense(t, b, userID, foodID, "3.00", date(2024, time.July, 3), "Espresso")
+	createExpense(t, b, userID, otherID, "20.00", date(2024, time.July, 4), "Novel")
+
+	expenses, err := b.Expenses.GetAll(ctx, userID, &domain.ExpenseFilter{Query: "coffee"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int{inDescription.ID, inBoth.ID, inCategory.ID}, expenseIDs(expenses))
+	for _, expense := range expenses {
+		assert.Positive(t, expense.Rank)
+		if expense.ID != inCategory.ID {
+			assert.Contains(t, expense.Highlight, "<mark>")
+		}
+	}
+
+	count, err := b.Expenses.Count(ctx, userID, &domain.ExpenseFilter{Query: "coffee beans"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	// Paging by relevance resumes after the last expense of the page
+	filter := &domain.ExpenseFilter{Query: "coffee", Sort: domain.ExpenseSortRelevance, Descending: true}
+	ranked, err := b.Expenses.GetAll(ctx, userID, filter)
+	require.NoError(t, err)
+	require.Len(t, ranked, 3)
+	assert.GreaterOrEqual(t, ranked[0].Rank, ranked[1].Rank)
+	assert.GreaterOrEqual(t, ranked[1].Rank, ranked[2].Rank)
+
+	filter.Limit = 1
+	filter.After = domain.NewExpenseCursor(domain.ExpenseSortRelevance, true, ranked[0])
+	page, err := b.Expenses.GetAll(ctx, userID, filter)
+	require.NoError(t, err)
+	assert.Equal(t, []int{ranked[1].ID}, expenseIDs(page))
+}
+
+func testExpenseTotals(t *testing.T, b *Backend) {
+	ctx := context.Background()
+	userID := createUser(t, b, "totals@example.com")
+	foodID := createCategory(t, b, userID, "Food", nil)
+	snacksID := createCategory(t, b, userID, "Snacks", &foodID)
+	travelID := createCategory(t, b, userID, "Travel", nil)
+
+	createExpense(t, b, userID, foodID, "10.10", date(2024, time.March, 1), "")
+	createExpense(t, b, userID, snacksID, "2.20", date(2024, time.March, 31), "")
+	createExpense(t, b, userID, travelID, "30.00", date(2024, time.March, 15), "")
+	createExpense(t, b, userID, travelID, "99.00", date(2024, time.April, 1), "")
+	deleted := createExpense(t, b, userID, travelID, "50.00", date(2024, time.March, 2), "")
+	require.NoError(t, b.Expenses.Delete(ctx, userID, deleted.ID))
+
+	total, err := b.Expenses.GetTotalByMonth(ctx, userID, 3, 2024)
+	require.NoError(t, err)
+	assert.Equal(t, "42.30", total.String())
+
+	totals, err := b.Expenses.GetTotalsByCategoryForMonth(ctx, userID, 3, 2024)
+	require.NoError(t, err)
+	assert.Equal(t, map[int]domain.Money{foodID: 1230, snacksID: 220, travelID: 3000}, totals)
+
+	total, err = b.Expenses.GetTotalByMonth(ctx, userID, 5, 2024)
+	require.NoError(t, err)
+	assert.Zero(t, total)
+}
+
+func testExpenseStream(t *testing.T, b *Backend) {
+	ctx := context.Background()
+	userID := createUser(t, b, "stream@example.com")
+	categoryID := createCategory(t, b, userID, "Food", nil)
+	older := createExpense(t, b, userID, categoryID, "1.00", date(2024, time.August, 1), "Older", "a")
+	newer := createExpense(t, b, userID, categoryID, "2.00", date(2024, time.August, 2), "Newer")
+
+	var rows []*domain.ExpenseExportRow
+	err := b.Expenses.StreamAll(ctx, userID, &domain.ExpenseFilter{}, func(row *domain.ExpenseExportRow) error {
+		rows = append(rows, row)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, newer.ID, rows[0].Expense.ID)
+	assert.Equal(t, older.ID, rows[1].Expense.ID)
+	assert.Equal(t, "Food", rows[1].CategoryName)
+	assert.Equal(t, []string{"a"}, rows[1].Tags)
+
+	// An error from fn stops the stream
+	stop := errors.New("stop")
+	calls := 0
+	err = b.Expenses.StreamAll(ctx, userID, &domain.ExpenseFilter{}, func(row *domain.ExpenseExportRow) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+}
+
+func testCategoryRoundTrip(t *testing.T, b *Backend) {
+	ctx := context.Background()
+	userID := createUser(t, b, "categories@example.com")
+	foodID := createCategory(t, b, userID, "Food", nil)
+	snacksID := createCategory(t, b, userID, "Snacks", &foodID)
+
+	got, err := b.Categories.GetByID(ctx, userID, snacksID)
+	require.NoError(t, err)
+	assert.Equal(t, "Snacks", got.Name)
+	assert.Equal(t, &foodID, got.ParentID)
+	assert.False(t, got.Archived)
+	_, err = b.Categories.GetByID(ctx, userID, snacksID+100)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	// Names are unique per user
+	err = b.Categories.Create(ctx, &domain.Category{UserID: userID, Name: "Food"})
+	assert.ErrorIs(t, err, domain.ErrAlreadyExists)
+	otherID := createUser(t, b, "other-categories@example.com")
+	createCategory(t, b, otherID, "Food", nil)
+	_, err = b.Categories.GetByID(ctx, otherID, foodID)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	got.Name = "Treats"
+	got.ParentID = nil
+	require.NoError(t, b.Categories.Update(ctx, got))
+	err = b.Categories.Update(ctx, &domain.Category{ID: snacksID, UserID: userID, Name: "Food"})
+	assert.ErrorIs(t, err, domain.ErrAlreadyExists)
+
+	categories, err := b.Categories.GetAll(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, categories, 2)
+	assert.Equal(t, "Food", categories[0].Name)
+	assert.Equal(t, "Treats", categories[1].Name)
+	assert.Nil(t, categories[1].ParentID)
+}
+
+func testCategoryDelete(t *testing.T, b *Backend) {
+	ctx := context.Background()
+	userID := createUser(t, b, "delete-categories@example.com")
+	foodID := createCategory(t, b, userID, "Food", nil)
+	snacksID := createCategory(t, b, userID, "Snacks", &foodID)
+	expense := createExpense(t, b, userID, snacksID, "1.00", date(2024, time.September, 1), "")
+	budget := &domain.Budget{UserID: userID, CategoryID: &snacksID, Month: 9, Year: 2024, BudgetAmount: 1000}
+	require.NoError(t, b.Budgets.Create(ctx, budget))
+
+	// A category with subcategories or expenses is in use
+	assert.ErrorIs(t, b.Categories.Delete(ctx, userID, foodID), domain.ErrInUse)
+	assert.ErrorIs(t, b.Categories.Delete(ctx, userID, snacksID), domain.ErrInUse)
+
+	// Expenses in the trash don't count, and the category's budgets go with it
+	require.NoError(t, b.Expenses.Delete(ctx, userID, expense.ID))
+	require.NoError(t, b.Categories.Delete(ctx, userID, snacksID))
+	_, err := b.Categories.GetByID(ctx, userID, snacksID)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	_, err = b.Budgets.GetByID(ctx, userID, budget.ID)
+	assert.Error(t, err)
+	assert.ErrorIs(t, b.Categories.Delete(ctx, userID, snacksID), domain.ErrNotFound)
+
+	// The name is free again once the category is in the trash
+	createCategory(t, b, userID, "Snacks", nil)
+	require.NoError(t, b.Categories.Delete(ctx, userID, foodID))
+}
+
+func testCategorySetArchived(t *testing.T, b *Backend) {
+	ctx := context.Background()
+	userID := createUser(t, b, "archive@example.com")
+	foodID := createCategory(t, b, userID, "Food", nil)
+	snacksID := createCategory(t, b, userID, "Snacks", &foodID)
+	chipsID := createCategory(t, b, userID, "Chips", &snacksID)
+	travelID := createCategory(t, b, userID, "Travel", nil)
+
+	require.NoError(t, b.Categories.SetArchived(ctx, userID, foodID, true))
+	archived := func() map[int]bool {
+		categories, err := b.Categories.GetAll(ctx, userID)
+		require.NoError(t, err)
+		flags := make(map[int]bool)
+		for _, category := range categories {
+			flags[category.ID] = category.Archived
+		}
+		return flags
+	}
+	assert.Equal(t, map[int]bool{foodID: true, snacksID: true, chipsID: true, travelID: false}, archived())
+
+	require.NoError(t, b.Categories.SetArchived(ctx, userID, snacksID, false))
+	assert.Equal(t, map[int]bool{foodID: true, snacksID: false, chipsID: false, travelID: false}, archived())
+}
+
+func testCategoryMerge(t *testing.T, b *Backend) {
+	ctx := context.Background()
+	userID := createUser(t, b, "merge@example.com")
+	sourceID := createCategory(t, b, userID, "Eating out", nil)
+	targetID := createCategory(t, b, userID, "Food", nil)
+	childID := createCategory(t, b, userID, "Cafes", &sourceID)
+	expense := createExpense(t, b, userID, sourceID, "15.00", date(2024, time.October, 3), "Dinner")
+
+	moved := &domain.Budget{UserID: userID, CategoryID: &sourceID, Month: 10, Year: 2024, BudgetAmount: 5000}
+	clashing := &domain.Budget{UserID: userID, CategoryID: &sourceID, Month: 11, Year: 2024, BudgetAmount: 5000}
+	kept := &domain.Budget{UserID: userID, CategoryID: &targetID, Month: 11, Year: 2024, BudgetAmount: 7000}
+	for _, budget := range []*domain.Budget{moved, clashing, kept} {
+		require.NoError(t, b.Budgets.Create(ctx, budget))
+	}
+
+	require.NoError(t, b.Categories.Merge(ctx, userID, sourceID, targetID))
+
+	_, err := b.Categories.GetByID(ctx, userID, sourceID)
+	assert.Error(t, err)
+	got, err := b.Expenses.GetByID(ctx, userID, expense.ID)
+	require.NoError(t, err)
+	assert.Equal(t, targetID, got.CategoryID)
+	child, err := b.Categories.GetByID(ctx, userID, childID)
+	require.NoError(t, err)
+	assert.Equal(t, &targetID, child.ParentID)
+
+	budgets, err := b.Budgets.GetAll(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, budgets, 2)
+	november, err := b.Budgets.GetByMonthAndCategory(ctx, userID, 11, 2024, targetID)
+	require.NoError(t, err)
+	assert.Equal(t, kept.ID, november.ID)
+	october, err := b.Budgets.GetByMonthAndCategory(ctx, userID, 10, 2024, targetID)
+	require.NoError(t, err)
+	assert.Equal(t, moved.ID, october.ID)
+}
+
+func testTagRenameAndMerge(t *testing.T, b *Backend) {
+	ctx := context.Background()
+	userID := createUser(t, b, "tags@example.com")
+	categoryID := createCategory(t, b, userID, "Food", nil)
+	lunch := createExpense(t, b, userID, categoryID, "12.00", date(2024, time.May, 2), "Lunch", "goa", "work")
+	dinner := createExpense(t, b, userID, categoryID, "30.00", date(2024, time.May, 3), "Dinner", "trip")
+
+	tagID := func(name string) int {
+		t.Helper()
+		tags, err := b.Tags.GetAll(ctx, userID)
+		require.NoError(t, err)
+		for _, tag := range tags {
+			if tag.Name == name {
+				return tag.ID
+			}
+		}
+		t.Fatalf("no tag %q", name)
+		return 0
+	}
+	expenseTags := func(id int) []string {
+		t.Helper()
+		expense, err := b.Expenses.GetByID(ctx, userID, id)
+		require.NoError(t, err)
+		return expense.Tags
+	}
+	events := func(entity domain.AuditEntity, id int) []*domain.AuditEvent {
+		t.Helper()
+		events, err := b.Audit.GetAll(ctx, userID, &domain.AuditFilter{Entity: entity, EntityID: id, Limit: 10})
+		require.NoError(t, err)
+		return events
+	}
+
+	goaID, tripID := tagID("goa"), tagID("trip")
+	assert.ErrorIs(t, b.Tags.Rename(ctx, userID, goaID, "trip"), domain.ErrAlreadyExists)
+	require.NoError(t, b.Tags.Rename(ctx, userID, goaID, "goa-2024"))
+	assert.Equal(t, []string{"goa-2024", "work"}, expenseTags(lunch.ID))
+
+	renamed := events(domain.AuditEntityTag, goaID)
+	require.Len(t, renamed, 1)
+	assert.Equal(t, domain.AuditActionUpdate, renamed[0].Action)
+	assert.JSONEq(t, `"goa"`, string(renamed[0].Changes["name"].From))
+	assert.JSONEq(t, `"goa-2024"`, string(renamed[0].Changes["name"].To))
+	retagged := events(domain.AuditEntityExpense, lunch.ID)
+	require.Len(t, retagged, 2)
+	assert.JSONEq(t, `["goa","work"]`, string(retagged[0].Changes["tags"].From))
+	assert.JSONEq(t, `["goa-2024","work"]`, string(retagged[0].Changes["tags"].To))
+
+	require.NoError(t, b.Tags.Merge(ctx, userID, tripID, goaID))
+	assert.Equal(t, []string{"goa-2024"}, expenseTags(dinner.ID))
+	_, err := b.Tags.GetByID(ctx, userID, tripID)
+	assert.Error(t, err)
+
+	merged := events(domain.AuditEntityTag, tripID)
+	require.Len(t, merged, 1)
+	assert.Equal(t, domain.AuditActionMerge, merged[0].Action)
+	assert.JSONEq(t, fmt.Sprint(goaID), string(merged[0].Changes["merged_into"].To))
+	retagged = events(domain.AuditEntityExpense, dinner.ID)
+	require.Len(t, retagged, 2)
+	assert.JSONEq(t, `["trip"]`, string(retagged[0].Changes["tags"].From))
+	assert.JSONEq(t, `["goa-2024"]`, string(retagged[0].Changes["tags"].To))
+	assert.Len(t, events(domain.AuditEntityExpense, lunch.ID), 2, "an expense without the merged tag is left alone")
+}
+
+func testPaymentMethodLookup(t *testing.T, b *Backend) {
+	ctx := context.Background()
+	userID := createUser(t, b, "payment-methods@example.com")
+	method := &domain.PaymentMethod{UserID: userID, Name: "Travel card", Type: domain.PaymentMethodTypeCard, Active: true}
+	require.NoError(t, b.PaymentMethods.Create(ctx, method))
+
+	got, err := b.PaymentMethods.GetByName(ctx, userID, "Travel card")
+	require.NoError(t, err)
+	assert.Equal(t, method.ID, got.ID)
+	got, err = b.PaymentMethods.GetByID(ctx, userID, method.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Travel card", got.Name)
+
+	// Missing methods and other users' methods are not found
+	otherID := createUser(t, b, "other-payment-methods@example.com")
+	_, err = b.PaymentMethods.GetByName(ctx, userID, "Typo")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	_, err = b.PaymentMethods.GetByName(ctx, otherID, "Travel card")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	_, err = b.PaymentMethods.GetByID(ctx, userID, method.ID+100)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	_, err = b.PaymentMethods.GetByID(ctx, otherID, method.ID)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func testBudgetRoundTrip(t *testing.T, b *Backend) {
+	ctx := context.Background()
+	userID := createUser(t, b, "budgets@example.com")
+	foodID := createCategory(t, b, userID, "Food", nil)
+	travelID := createCategory(t, b, userID, "Travel", nil)
+
+	overall := &domain.Budget{UserID: userID, Month: 2, Year: 2024, BudgetAmount: 100000}
+	food := &domain.Budget{UserID: userID, CategoryID: &foodID, Month: 2, Year: 2024, BudgetAmount: 30000}
+	travel := &domain.Budget{UserID: userID, CategoryID: &travelID, Month: 2, Year: 2024, BudgetAmount: 20000}
+	earlier := &domain.Budget{UserID: userID, Month: 1, Year: 2024, BudgetAmount: 90000}
+	later := &domain.Budget{UserID: userID, CategoryID: &foodID, Month: 1, Year: 2025, BudgetAmount: 35000}
+	for _, budget := range []*domain.Budget{travel, food, earlier, later, overall} {
+		require.NoError(t, b.Budgets.Create(ctx, budget))
+	}
+
+	got, err := b.Budgets.GetByID(ctx, userID, food.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &foodID, got.CategoryID)
+	assert.Equal(t, 2, got.Month)
+	assert.Equal(t, 2024, got.Year)
+	assert.Equal(t, "300.00", got.BudgetAmount.String())
+
+	// Newest month first, the overall budget ahead of the category budgets
+	budgets, err := b.Budgets.GetAll(ctx, userID)
+	require.NoError(t, err)
+	ids := make([]int, 0, len(budgets))
+	for _, budget := range budgets {
+		ids = append(ids, budget.ID)
+	}
+	assert.Equal(t, later.ID, ids[0])
+	assert.Equal(t, overall.ID, ids[1])
+	assert.ElementsMatch(t, []int{food.ID, travel.ID}, ids[2:4])
+	assert.Equal(t, earlier.ID, ids[4])
+
+	month, err := b.Budgets.GetByMonth(ctx, userID, 2, 2024)
+	require.NoError(t, err)
+	assert.Equal(t, overall.ID, month.ID)
+	byCategory, err := b.Budgets.GetByMonthAndCategory(ctx, userID, 2, 2024, travelID)
+	require.NoError(t, err)
+	assert.Equal(t, travel.ID, byCategory.ID)
+	_, err = b.Budgets.GetByMonth(ctx, userID, 3, 2024)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	_, err = b.Budgets.GetByMonthAndCategory(ctx, userID, 3, 2024, travelID)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	categoryBudgets, err := b.Budgets.GetCategoryBudgetsByMonth(ctx, userID, 2, 2024)
+	require.NoError(t, err)
+	require.Len(t, categoryBudgets, 2)
+	assert.Equal(t, []int{foodID, travelID}, []int{*categoryBudgets[0].CategoryID, *categoryBudgets[1].CategoryID})
+
+	food.BudgetAmount = 45000
+	require.NoError(t, b.Budgets.Update(ctx, food))
+	got, err = b.Budgets.GetByID(ctx, userID, food.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "450.00", got.BudgetAmount.String())
+
+	require.NoError(t, b.Budgets.Delete(ctx, userID, food.ID))
+	_, err = b.Budgets.GetByID(ctx, userID, food.ID)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	assert.ErrorIs(t, b.Budgets.Delete(ctx, userID, food.ID), domain.ErrNotFound)
+
+	otherID := createUser(t, b, "other-budgets@example.com")
+	_, err = b.Budgets.GetByID(ctx, otherID, overall.ID)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func testBudgetUniqueness(t *testing.T, b *Backend) {
+	ctx := context.Background()
+	userID := createUser(t, b, "unique-budgets@example.com")
+	foodID := createCategory(t, b, userID, "Food", nil)
+
+	require.NoError(t, b.Budgets.Create(ctx, &domain.Budget{UserID: userID, Month: 3, Year: 2024, BudgetAmount: 100}))
+	err := b.Budgets.Create(ctx, &domain.Budget{UserID: userID, Month: 3, Year: 2024, BudgetAmount: 200})
+	assert.ErrorIs(t, err, domain.ErrAlreadyExists)
+
+	categoryBudget := &domain.Budget{UserID: userID, CategoryID: &foodID, Month: 3, Year: 2024, BudgetAmount: 100}
+	require.NoError(t, b.Budgets.Create(ctx, categoryBudget))
+	err = b.Budgets.Create(ctx, &domain.Budget{UserID: userID, CategoryID: &foodID, Month: 3, Year: 2024, BudgetAmount: 300})
+	assert.ErrorIs(t, err, domain.ErrAlreadyExists)
+
+	// A budget in the trash doesn't hold its month
+	require.NoError(t, b.Budgets.Delete(ctx, userID, categoryBudget.ID))
+	require.NoError(t, b.Budgets.Create(ctx, &domain.Budget{UserID: userID, CategoryID: &foodID, Month: 3, Year: 2024, BudgetAmount: 300}))
+}
+
+func testTxRollback(t *testing.T, b *Backend) {
+	ctx := context.Background()
+	userID := createUser(t, b, "tx@example.com")
+	categoryID := createCategory(t, b, userID, "Food", nil)
+
+	failure := errors.New("failure")
+	err := b.TxManager.WithinTx(ctx, func(tx domain.Tx) error {
+		category := &domain.Category{UserID: userID, Name: "Rolled back"}
+		if err := b.Categories.WithTx(tx).Create(ctx, category); err != nil {
+			return err
+		}
+		expense := &domain.Expense{UserID: userID, CategoryID: categoryID, Amount: 100, Currency: "INR", OriginalAmount: 100,
+			ExchangeRate: domain.RateOne, PaymentMode: domain.PaymentModeCash, ExpenseDate: date(2024, time.April, 1)}
+		if err := b.Expenses.WithTx(tx).Create(ctx, expense); err != nil {
+			return err
+		}
+		budget := &domain.Budget{UserID: userID, Month: 4, Year: 2024, BudgetAmount: 100}
+		if err := b.Budgets.WithTx(tx).Create(ctx, budget); err != nil {
+			return err
+		}
+
+		// The transaction sees its own changes
+		count, err := b.Expenses.WithTx(tx).Count(ctx, userID, &domain.ExpenseFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+		return failure
+	})
+	assert.ErrorIs(t, err, failure)
+
+	categories, err := b.Categories.GetAll(ctx, userID)
+	require.NoError(t, err)
+	assert.Len(t, categories, 1)
+	count, err := b.Expenses.Count(ctx, userID, &domain.ExpenseFilter{})
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	_, err = b.Budgets.GetByMonth(ctx, userID, 4, 2024)
+	assert.Error(t, err)
+
+	err = b.TxManager.WithinTx(ctx, func(tx domain.Tx) error {
+		return b.Categories.WithTx(tx).Create(ctx, &domain.Category{UserID: userID, Name: "Committed"})
+	})
+	require.NoError(t, err)
+	categories, err = b.Categories.GetAll(ctx, userID)
+	require.NoError(t, err)
+	assert.Len(t, categories, 2)
+}
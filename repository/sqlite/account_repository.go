@@ -0,0 +1,223 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"expense-tracker-api/domain"
+	"time"
+)
+
+type accountRepository struct {
+	tx *sql.Tx
+}
+
+// NewAccountRepository creates a new account repository
+func NewAccountRepository() domain.AccountRepository {
+	return &accountRepository{}
+}
+
+// WithTx returns the repository bound to a transaction from NewTxManager
+func (r *accountRepository) WithTx(tx domain.Tx) domain.AccountRepository {
+	return &accountRepository{tx: boundTx(tx)}
+}
+
+const accountColumns = `id, user_id, name, type, opening_balance, opening_date, created_at, updated_at`
+
+func (r *accountRepository) Create(ctx context.Context, account *domain.Account) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO accounts (user_id, name, type, opening_balance, opening_date, created_at, updated_at)
+			  VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7) RETURNING id`
+	now := time.Now()
+	err = tx.QueryRowContext(ctx, query, account.UserID, account.Name, account.Type, int64(account.OpeningBalance),
+		dateValue(account.OpeningDate), timestampValue(now), timestampValue(now)).Scan(&account.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.ErrAlreadyExists
+		}
+		return err
+	}
+	account.CreatedAt = now
+	account.UpdatedAt = now
+
+	if err := recordChange(ctx, tx, account.UserID, domain.AuditEntityAccount, account.ID, domain.AuditActionCreate, nil, account); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const accountByIDQuery = `SELECT ` + accountColumns + ` FROM accounts WHERE id = ?1 AND user_id = ?2`
+
+func (r *accountRepository) GetByID(ctx context.Context, userID, id int) (*domain.Account, error) {
+	account, err := scanAccount(conn(r.tx).QueryRowContext(ctx, accountByIDQuery, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	return account, err
+}
+
+// lockAccount reads the account within tx , which holds the database's write lock until it ends
+func lockAccount(ctx context.Context, tx executor, userID, id int) (*domain.Account, error) {
+	account, err := scanAccount(tx.QueryRowContext(ctx, accountByIDQuery+``, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	return account, err
+}
+
+func (r *accountRepository) GetAll(ctx context.Context, userID int) ([]*domain.Account, error) {
+	query := `SELECT ` + accountColumns + ` FROM accounts WHERE user_id = ?1 ORDER BY name`
+	rows, err := conn(r.tx).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []*domain.Account{}
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+func (r *accountRepository) Update(ctx context.Context, account *domain.Account) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := lockAccount(ctx, tx, account.UserID, account.ID)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE accounts SET name = ?1, type = ?2, opening_balance = ?3, opening_date = ?4, updated_at = ?5
+			  WHERE id = ?6`
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, query, account.Name, account.Type, int64(account.OpeningBalance),
+		dateValue(account.OpeningDate), timestampValue(now), account.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.ErrAlreadyExists
+		}
+		return err
+	}
+	account.UpdatedAt = now
+
+	after, err := lockAccount(ctx, tx, account.UserID, account.ID)
+	if err != nil {
+		return err
+	}
+	if err := recordChange(ctx, tx, account.UserID, domain.AuditEntityAccount, account.ID, domain.AuditActionUpdate, before, after); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete removes an account that no expense, income or transfer refers to.
+// Its reconciliations go with it.
+func (r *accountRepository) Delete(ctx context.Context, userID, id int) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := lockAccount(ctx, tx, userID, id)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM accounts WHERE id = ?1`, id); err != nil {
+		if isForeignKeyViolation(err) {
+			return domain.ErrInUse
+		}
+		return err
+	}
+	if err := recordChange(ctx, tx, userID, domain.AuditEntityAccount, id, domain.AuditActionDelete, before, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetBalance sums each kind of movement dated from the account's opening date through asOf
+func (r *accountRepository) GetBalance(ctx context.Context, userID, id int, asOf time.Time) (*domain.AccountBalance, error) {
+	balance := &domain.AccountBalance{AccountID: id, AsOf: asOf}
+	query := `SELECT a.opening_balance,
+			  COALESCE((SELECT SUM(i.amount) FROM incomes i
+				WHERE i.account_id = a.id AND i.income_date BETWEEN a.opening_date AND ?3), 0),
+			  COALESCE((SELECT SUM(e.amount) FROM expenses e
+				WHERE e.account_id = a.id AND e.deleted_at IS NULL AND e.expense_date BETWEEN a.opening_date AND ?3), 0),
+			  COALESCE((SELECT SUM(t.amount) FROM transfers t
+				WHERE t.to_account_id = a.id AND t.transfer_date BETWEEN a.opening_date AND ?3), 0),
+			  COALESCE((SELECT SUM(t.amount) FROM transfers t
+				WHERE t.from_account_id = a.id AND t.transfer_date BETWEEN a.opening_date AND ?3), 0)
+			  FROM accounts a WHERE a.id = ?1 AND a.user_id = ?2`
+	err := conn(r.tx).QueryRowContext(ctx, query, id, userID, dateValue(asOf)).Scan(money(&balance.OpeningBalance), money(&balance.Income),
+		money(&balance.Expenses), money(&balance.TransfersIn), money(&balance.TransfersOut))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return balance, nil
+}
+
+func (r *accountRepository) CreateReconciliation(ctx context.Context, reconciliation *domain.Reconciliation) error {
+	query := `INSERT INTO reconciliations (user_id, account_id, statement_date, statement_balance, computed_balance,
+			  discrepancy, created_at)
+			  VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7) RETURNING id`
+	now := time.Now()
+	err := conn(r.tx).QueryRowContext(ctx, query, reconciliation.UserID, reconciliation.AccountID, dateValue(reconciliation.StatementDate),
+		int64(reconciliation.StatementBalance), int64(reconciliation.ComputedBalance), int64(reconciliation.Discrepancy),
+		timestampValue(now)).Scan(&reconciliation.ID)
+	if err != nil {
+		return err
+	}
+	reconciliation.CreatedAt = now
+	return nil
+}
+
+// GetReconciliations lists an account's reconciliations, latest statement first
+func (r *accountRepository) GetReconciliations(ctx context.Context, userID, accountID int) ([]*domain.Reconciliation, error) {
+	query := `SELECT id, user_id, account_id, statement_date, statement_balance, computed_balance, discrepancy, created_at
+			  FROM reconciliations WHERE account_id = ?1 AND user_id = ?2
+			  ORDER BY statement_date DESC, id DESC`
+	rows, err := conn(r.tx).QueryContext(ctx, query, accountID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reconciliations := []*domain.Reconciliation{}
+	for rows.Next() {
+		reconciliation := &domain.Reconciliation{}
+		err := rows.Scan(&reconciliation.ID, &reconciliation.UserID, &reconciliation.AccountID, &reconciliation.StatementDate,
+			money(&reconciliation.StatementBalance), money(&reconciliation.ComputedBalance), money(&reconciliation.Discrepancy),
+			&reconciliation.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		reconciliations = append(reconciliations, reconciliation)
+	}
+	return reconciliations, rows.Err()
+}
+
+func scanAccount(row rowScanner) (*domain.Account, error) {
+	account := &domain.Account{}
+	err := row.Scan(&account.ID, &account.UserID, &account.Name, &account.Type, money(&account.OpeningBalance),
+		&account.OpeningDate, &account.CreatedAt, &account.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
@@ -0,0 +1,67 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"expense-tracker-api/domain"
+	"time"
+)
+
+type auditRepository struct{}
+
+// NewAuditRepository creates a new audit log repository
+func NewAuditRepository() domain.AuditRepository {
+	return &auditRepository{}
+}
+
+func (r *auditRepository) GetAll(ctx context.Context, userID int, filter *domain.AuditFilter) ([]*domain.AuditEvent, error) {
+	query := `SELECT id, user_id, actor_id, entity, entity_id, action, changes, created_at FROM audit_events
+			  WHERE user_id = ?1 AND (?2 = '' OR entity = ?2) AND (?3 = 0 OR entity_id = ?3)
+			  ORDER BY id DESC LIMIT ?4`
+	rows, err := DB.QueryContext(ctx, query, userID, filter.Entity, filter.EntityID, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*domain.AuditEvent{}
+	for rows.Next() {
+		event := &domain.AuditEvent{}
+		var changes string
+		err := rows.Scan(&event.ID, &event.UserID, &event.ActorID, &event.Entity, &event.EntityID, &event.Action,
+			&changes, &event.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(changes), &event.Changes); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// recordAudit appends the event in the transaction making the change, so the
+// event is kept exactly when the change is
+func recordAudit(ctx context.Context, tx executor, event *domain.AuditEvent) error {
+	changes, err := json.Marshal(event.Changes)
+	if err != nil {
+		return err
+	}
+	event.CreatedAt = time.Now()
+	query := `INSERT INTO audit_events (user_id, actor_id, entity, entity_id, action, changes, created_at)
+			  VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id`
+	return tx.QueryRowContext(ctx, query, event.UserID, event.ActorID, event.Entity, event.EntityID, event.Action,
+		string(changes), timestampValue(event.CreatedAt)).Scan(&event.ID)
+}
+
+// recordChange appends an event for a change the user made, comparing the record
+// before and after it. before is nil for a new record and after for a deleted one.
+func recordChange(ctx context.Context, tx executor, userID int, entity domain.AuditEntity, entityID int, action domain.AuditAction,
+	before, after interface{}) error {
+	event, err := domain.NewAuditEvent(userID, entity, entityID, action, before, after)
+	if err != nil {
+		return err
+	}
+	return recordAudit(ctx, tx, event)
+}
@@ -0,0 +1,198 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"expense-tracker-api/domain"
+	"time"
+)
+
+type budgetRepository struct {
+	tx *sql.Tx
+}
+
+// NewBudgetRepository creates a new budget repository
+func NewBudgetRepository() domain.BudgetRepository {
+	return &budgetRepository{}
+}
+
+// WithTx returns the repository bound to a transaction from NewTxManager
+func (r *budgetRepository) WithTx(tx domain.Tx) domain.BudgetRepository {
+	return &budgetRepository{tx: boundTx(tx)}
+}
+
+func (r *budgetRepository) Create(ctx context.Context, budget *domain.Budget) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO budgets (user_id, category_id, month, year, budget_amount, created_at, updated_at) 
+			  VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7) RETURNING id`
+	now := time.Now()
+	err = tx.QueryRowContext(ctx, query, budget.UserID, budget.CategoryID, budget.Month, budget.Year, int64(budget.BudgetAmount),
+		timestampValue(now), timestampValue(now)).Scan(&budget.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.ErrAlreadyExists
+		}
+		return err
+	}
+	budget.CreatedAt = now
+	budget.UpdatedAt = now
+
+	if err := recordChange(ctx, tx, budget.UserID, domain.AuditEntityBudget, budget.ID, domain.AuditActionCreate, nil, budget); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *budgetRepository) GetByID(ctx context.Context, userID, id int) (*domain.Budget, error) {
+	budget := &domain.Budget{}
+	query := `SELECT id, user_id, category_id, month, year, budget_amount, created_at, updated_at 
+			  FROM budgets WHERE id = ?1 AND user_id = ?2 AND deleted_at IS NULL`
+	err := conn(r.tx).QueryRowContext(ctx, query, id, userID).Scan(&budget.ID, &budget.UserID, &budget.CategoryID, &budget.Month, &budget.Year,
+		money(&budget.BudgetAmount), &budget.CreatedAt, &budget.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return budget, nil
+}
+
+// lockBudget reads the live budget within tx, which holds the database's write lock until it ends
+func lockBudget(ctx context.Context, tx executor, userID, id int) (*domain.Budget, error) {
+	budget := &domain.Budget{}
+	query := `SELECT id, user_id, category_id, month, year, budget_amount, created_at, updated_at 
+			  FROM budgets WHERE id = ?1 AND user_id = ?2 AND deleted_at IS NULL`
+	err := tx.QueryRowContext(ctx, query, id, userID).Scan(&budget.ID, &budget.UserID, &budget.CategoryID, &budget.Month, &budget.Year,
+		money(&budget.BudgetAmount), &budget.CreatedAt, &budget.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return budget, nil
+}
+
+func (r *budgetRepository) GetAll(ctx context.Context, userID int) ([]*domain.Budget, error) {
+	query := `SELECT id, user_id, category_id, month, year, budget_amount, created_at, updated_at 
+			  FROM budgets WHERE user_id = ?1 AND deleted_at IS NULL ORDER BY year DESC, month DESC, category_id NULLS FIRST`
+	rows, err := conn(r.tx).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanBudgets(rows)
+}
+
+func (r *budgetRepository) GetByMonth(ctx context.Context, userID, month, year int) (*domain.Budget, error) {
+	budget := &domain.Budget{}
+	query := `SELECT id, user_id, category_id, month, year, budget_amount, created_at, updated_at 
+			  FROM budgets WHERE user_id = ?1 AND month = ?2 AND year = ?3 AND category_id IS NULL
+			  AND deleted_at IS NULL`
+	err := conn(r.tx).QueryRowContext(ctx, query, userID, month, year).Scan(&budget.ID, &budget.UserID, &budget.CategoryID, &budget.Month, &budget.Year,
+		money(&budget.BudgetAmount), &budget.CreatedAt, &budget.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return budget, nil
+}
+
+func (r *budgetRepository) GetByMonthAndCategory(ctx context.Context, userID, month, year, categoryID int) (*domain.Budget, error) {
+	budget := &domain.Budget{}
+	query := `SELECT id, user_id, category_id, month, year, budget_amount, created_at, updated_at 
+			  FROM budgets WHERE user_id = ?1 AND month = ?2 AND year = ?3 AND category_id = ?4
+			  AND deleted_at IS NULL`
+	err := conn(r.tx).QueryRowContext(ctx, query, userID, month, year, categoryID).Scan(&budget.ID, &budget.UserID, &budget.CategoryID, &budget.Month, &budget.Year,
+		money(&budget.BudgetAmount), &budget.CreatedAt, &budget.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return budget, nil
+}
+
+func (r *budgetRepository) GetCategoryBudgetsByMonth(ctx context.Context, userID, month, year int) ([]*domain.Budget, error) {
+	query := `SELECT id, user_id, category_id, month, year, budget_amount, created_at, updated_at 
+			  FROM budgets WHERE user_id = ?1 AND month = ?2 AND year = ?3 AND category_id IS NOT NULL
+			  AND deleted_at IS NULL
+			  ORDER BY category_id`
+	rows, err := conn(r.tx).QueryContext(ctx, query, userID, month, year)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanBudgets(rows)
+}
+
+func (r *budgetRepository) Update(ctx context.Context, budget *domain.Budget) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := lockBudget(ctx, tx, budget.UserID, budget.ID)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE budgets SET budget_amount = ?1, updated_at = ?2 WHERE id = ?3`
+	budget.UpdatedAt = time.Now()
+	if _, err := tx.ExecContext(ctx, query, int64(budget.BudgetAmount), timestampValue(budget.UpdatedAt), budget.ID); err != nil {
+		return err
+	}
+
+	after := *before
+	after.BudgetAmount = budget.BudgetAmount
+	after.UpdatedAt = budget.UpdatedAt
+	if err := recordChange(ctx, tx, budget.UserID, domain.AuditEntityBudget, budget.ID, domain.AuditActionUpdate, before, &after); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete moves the budget to the trash; the purge job removes it for good
+func (r *budgetRepository) Delete(ctx context.Context, userID, id int) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := lockBudget(ctx, tx, userID, id)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE budgets SET deleted_at = ? WHERE id = ?`, timestampValue(time.Now()), id); err != nil {
+		return err
+	}
+	if err := recordChange(ctx, tx, userID, domain.AuditEntityBudget, id, domain.AuditActionDelete, before, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func scanBudgets(rows *sql.Rows) ([]*domain.Budget, error) {
+	var budgets []*domain.Budget
+	for rows.Next() {
+		budget := &domain.Budget{}
+		err := rows.Scan(&budget.ID, &budget.UserID, &budget.CategoryID, &budget.Month, &budget.Year,
+			money(&budget.BudgetAmount), &budget.CreatedAt, &budget.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, budget)
+	}
+	return budgets, rows.Err()
+}
@@ -0,0 +1,338 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"expense-tracker-api/domain"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+type categoryRepository struct {
+	tx *sql.Tx
+}
+
+// NewCategoryRepository creates a new category repository
+func NewCategoryRepository() domain.CategoryRepository {
+	return &categoryRepository{}
+}
+
+// WithTx returns the repository bound to a transaction from NewTxManager
+func (r *categoryRepository) WithTx(tx domain.Tx) domain.CategoryRepository {
+	return &categoryRepository{tx: boundTx(tx)}
+}
+
+const categoryColumns = `id, user_id, parent_id, name, archived, created_at`
+
+// categoryClosure is a recursive CTE named closure pairing each of the user's (?1)
+// live categories with itself and every category below it, so spending in a subcategory
+// can be rolled up into all of its parents
+const categoryClosure = `closure (ancestor_id, category_id) AS (
+		SELECT id, id FROM categories WHERE user_id = ?1 AND deleted_at IS NULL
+		UNION
+		SELECT closure.ancestor_id, c.id FROM closure JOIN categories c ON c.parent_id = closure.category_id
+		WHERE c.deleted_at IS NULL
+	)`
+
+// subcategoriesCTE is a recursive CTE named subcategories holding the ID of one of
+// the user's (?1) categories and of every category below it. It is formatted with
+// the placeholder number of the category ID.
+const subcategoriesCTE = `subcategories (id) AS (
+		SELECT id FROM categories WHERE id = ?%d AND user_id = ?1 AND deleted_at IS NULL
+		UNION
+		SELECT c.id FROM subcategories JOIN categories c ON c.parent_id = subcategories.id WHERE c.deleted_at IS NULL
+	)`
+
+func scanCategory(row rowScanner) (*domain.Category, error) {
+	category := &domain.Category{}
+	err := row.Scan(&category.ID, &category.UserID, &category.ParentID, &category.Name, &category.Archived, &category.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+func (r *categoryRepository) Create(ctx context.Context, category *domain.Category) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO categories (user_id, parent_id, name, created_at) 
+			  VALUES (?1, ?2, ?3, ?4) RETURNING id`
+	category.CreatedAt = time.Now()
+	err = tx.QueryRowContext(ctx, query, category.UserID, category.ParentID, category.Name, timestampValue(category.CreatedAt)).Scan(&category.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.ErrAlreadyExists
+		}
+		return err
+	}
+
+	if err := recordChange(ctx, tx, category.UserID, domain.AuditEntityCategory, category.ID, domain.AuditActionCreate, nil, category); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const categoryByIDQuery = `SELECT ` + categoryColumns + ` FROM categories WHERE id = ?1 AND user_id = ?2 AND deleted_at IS NULL`
+
+func (r *categoryRepository) GetByID(ctx context.Context, userID, id int) (*domain.Category, error) {
+	category, err := scanCategory(conn(r.tx).QueryRowContext(ctx, categoryByIDQuery, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	return category, err
+}
+
+// lockCategory reads the live category within tx, which holds the database's write lock until it ends
+func lockCategory(ctx context.Context, tx executor, userID, id int) (*domain.Category, error) {
+	category, err := scanCategory(tx.QueryRowContext(ctx, categoryByIDQuery, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	return category, err
+}
+
+func (r *categoryRepository) GetAll(ctx context.Context, userID int) ([]*domain.Category, error) {
+	query := `SELECT ` + categoryColumns + ` FROM categories WHERE user_id = ?1 AND deleted_at IS NULL ORDER BY name`
+	rows, err := conn(r.tx).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []*domain.Category
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, nil
+}
+
+func (r *categoryRepository) Update(ctx context.Context, category *domain.Category) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := lockCategory(ctx, tx, category.UserID, category.ID)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE categories SET name = ?1, parent_id = ?2 WHERE id = ?3`
+	if _, err := tx.ExecContext(ctx, query, category.Name, category.ParentID, category.ID); err != nil {
+		if isUniqueViolation(err) {
+			return domain.ErrAlreadyExists
+		}
+		return err
+	}
+
+	after := *before
+	after.Name = category.Name
+	after.ParentID = category.ParentID
+	if err := recordChange(ctx, tx, category.UserID, domain.AuditEntityCategory, category.ID, domain.AuditActionUpdate, before, &after); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetArchived archives or unarchives the category together with its subcategories,
+// logging a change for each one whose flag flips
+func (r *categoryRepository) SetArchived(ctx context.Context, userID, id int, archived bool) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `WITH RECURSIVE ` + fmt.Sprintf(subcategoriesCTE, 2) + `
+			  UPDATE categories SET archived = ?3
+			  WHERE user_id = ?1 AND id IN (SELECT id FROM subcategories) AND archived <> ?3
+			  RETURNING ` + categoryColumns
+	rows, err := tx.QueryContext(ctx, query, userID, id, archived)
+	if err != nil {
+		return err
+	}
+	var changed []*domain.Category
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		changed = append(changed, category)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, after := range changed {
+		before := *after
+		before.Archived = !archived
+		if err := recordChange(ctx, tx, userID, domain.AuditEntityCategory, after.ID, domain.AuditActionUpdate, &before, after); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Delete moves the category and its budgets to the trash together, so restoring it
+// brings them back. It refuses with ErrInUse while the category still has expenses,
+// recurring expenses or subcategories that aren't in the trash.
+func (r *categoryRepository) Delete(ctx context.Context, userID, id int) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The write lock holds back expenses being added to the category meanwhile
+	before, err := lockCategory(ctx, tx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	var inUse bool
+	query := `SELECT EXISTS (SELECT 1 FROM expenses WHERE category_id = ?1 AND deleted_at IS NULL)
+			  OR EXISTS (SELECT 1 FROM recurring_expenses WHERE category_id = ?1)
+			  OR EXISTS (SELECT 1 FROM categories WHERE parent_id = ?1 AND deleted_at IS NULL)`
+	if err := tx.QueryRowContext(ctx, query, id).Scan(&inUse); err != nil {
+		return err
+	}
+	if inUse {
+		return domain.ErrInUse
+	}
+
+	// The same deletion time ties the budgets to the category
+	deletedAt := timestampValue(time.Now())
+	if _, err := tx.ExecContext(ctx, `UPDATE categories SET deleted_at = ?1 WHERE id = ?2`, deletedAt, id); err != nil {
+		return err
+	}
+	if err := recordChange(ctx, tx, userID, domain.AuditEntityCategory, id, domain.AuditActionDelete, before, nil); err != nil {
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, `UPDATE budgets SET deleted_at = ?1 WHERE category_id = ?2 AND deleted_at IS NULL
+			  RETURNING id, user_id, category_id, month, year, budget_amount, created_at, updated_at`, deletedAt, id)
+	if err != nil {
+		return err
+	}
+	budgets, err := scanBudgets(rows)
+	rows.Close()
+	if err != nil {
+		return err
+	}
+	for _, budget := range budgets {
+		if err := recordChange(ctx, tx, userID, domain.AuditEntityBudget, budget.ID, domain.AuditActionDelete, budget, nil); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// categoryMoves move each kind of record from the source category (?2) to the
+// target (?3), returning the IDs moved so each move can be logged. A budget only
+// moves when the target has none for its month.
+var categoryMoves = []struct {
+	entity domain.AuditEntity
+	column string
+	query  string
+}{
+	{domain.AuditEntityExpense, "category_id",
+		`UPDATE expenses SET category_id = ?3 WHERE user_id = ?1 AND category_id = ?2 RETURNING id`},
+	{domain.AuditEntityRecurringExpense, "category_id",
+		`UPDATE recurring_expenses SET category_id = ?3 WHERE user_id = ?1 AND category_id = ?2 RETURNING id`},
+	{domain.AuditEntityIncome, "category_id",
+		`UPDATE incomes SET category_id = ?3 WHERE user_id = ?1 AND category_id = ?2 RETURNING id`},
+	{domain.AuditEntityCategory, "parent_id",
+		`UPDATE categories SET parent_id = ?3 WHERE user_id = ?1 AND parent_id = ?2 RETURNING id`},
+	{domain.AuditEntityBudget, "category_id",
+		`UPDATE budgets AS b SET category_id = ?3, updated_at = ` + nowTimestamp + `
+			WHERE b.user_id = ?1 AND b.category_id = ?2 AND b.deleted_at IS NULL
+			AND NOT EXISTS (SELECT 1 FROM budgets t WHERE t.user_id = ?1 AND t.category_id = ?3
+				AND t.month = b.month AND t.year = b.year AND t.deleted_at IS NULL)
+			RETURNING id`},
+}
+
+func (r *categoryRepository) Merge(ctx context.Context, userID, sourceID, targetID int) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	source, err := lockCategory(ctx, tx, userID, sourceID)
+	if err != nil {
+		return err
+	}
+
+	for _, move := range categoryMoves {
+		rows, err := tx.QueryContext(ctx, move.query, userID, sourceID, targetID)
+		if err != nil {
+			return err
+		}
+		var ids []int
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		before := map[string]int{move.column: sourceID}
+		after := map[string]int{move.column: targetID}
+		for _, id := range ids {
+			if err := recordChange(ctx, tx, userID, move.entity, id, domain.AuditActionUpdate, before, after); err != nil {
+				return err
+			}
+		}
+	}
+
+	// The budgets that didn't move go with the category
+	rows, err := tx.QueryContext(ctx, `DELETE FROM budgets WHERE user_id = ?1 AND category_id = ?2
+			  RETURNING id, user_id, category_id, month, year, budget_amount, created_at, updated_at`, userID, sourceID)
+	if err != nil {
+		return err
+	}
+	budgets, err := scanBudgets(rows)
+	rows.Close()
+	if err != nil {
+		return err
+	}
+	for _, budget := range budgets {
+		if err := recordChange(ctx, tx, userID, domain.AuditEntityBudget, budget.ID, domain.AuditActionDelete, budget, nil); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM categories WHERE user_id = ?1 AND id = ?2`, userID, sourceID); err != nil {
+		return err
+	}
+	event, err := domain.NewAuditEvent(userID, domain.AuditEntityCategory, sourceID, domain.AuditActionMerge, source, nil)
+	if err != nil {
+		return err
+	}
+	event.Changes["merged_into"] = domain.AuditChange{To: json.RawMessage(strconv.Itoa(targetID))}
+	if err := recordAudit(ctx, tx, event); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
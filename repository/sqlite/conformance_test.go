@@ -0,0 +1,31 @@
+package sqlite
+
+import (
+	"context"
+	"expense-tracker-api/repository/repositorytest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) *repositorytest.Backend {
+		require.NoError(t, InitDB(filepath.Join(t.TempDir(), "expense_tracker.db")))
+		t.Cleanup(func() { CloseDB() })
+		require.NoError(t, NewSchemaMigrator().Up(context.Background()))
+		return &repositorytest.Backend{
+			Users:          NewUserRepository(),
+			Categories:     NewCategoryRepository(),
+			Expenses:       NewExpenseRepository(),
+			Budgets:        NewBudgetRepository(),
+			Tags:           NewTagRepository(),
+			Audit:          NewAuditRepository(),
+			PaymentMethods: NewPaymentMethodRepository(),
+			Accounts:       NewAccountRepository(),
+			Incomes:        NewIncomeRepository(),
+			ExchangeRates:  NewExchangeRateRepository(),
+			TxManager:      NewTxManager(),
+		}
+	})
+}
@@ -0,0 +1,179 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"expense-tracker-api/domain"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// DB holds the database connection
+var DB *sql.DB
+
+// InitDB opens the database file at path, creating it if it doesn't exist
+func InitDB(path string) error {
+	// Foreign keys are off in SQLite unless asked for. Transactions take the write
+	// lock when they begin, so reads in them stay current until they end, the way
+	// the Postgres repositories lock the rows they read.
+	params := url.Values{}
+	params.Set("_foreign_keys", "on")
+	params.Set("_busy_timeout", "5000")
+	params.Set("_journal_mode", "WAL")
+	params.Set("_txlock", "immediate")
+
+	var err error
+	DB, err = sql.Open("sqlite3", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if err = DB.Ping(); err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+
+	return nil
+}
+
+// InitBaseCurrency records the base currency the first time the server starts.
+// Amounts are stored in the base currency, so starting with a different one later is an error.
+func InitBaseCurrency(currency string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var stored string
+	err = tx.QueryRow(`SELECT value FROM app_settings WHERE key = 'base_currency'`).Scan(&stored)
+	switch {
+	case err == sql.ErrNoRows:
+		if _, err := tx.Exec(`INSERT INTO app_settings (key, value) VALUES ('base_currency', ?)`, currency); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case stored != currency:
+		return fmt.Errorf("amounts are stored in %s and can't be switched to base currency %s", stored, currency)
+	}
+
+	return tx.Commit()
+}
+
+// isUniqueViolation reports whether err is an SQLite unique constraint violation
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) &&
+		(sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey)
+}
+
+// isForeignKeyViolation reports whether err is an SQLite foreign key violation
+func isForeignKeyViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintForeignKey
+}
+
+// constraintError turns a constraint violation no repository method anticipated
+// into its domain error, so the driver's errors never leave the package
+func constraintError(err error) error {
+	switch {
+	case isUniqueViolation(err):
+		return domain.ErrAlreadyExists
+	case isForeignKeyViolation(err):
+		return domain.ErrInvalidReference
+	}
+	return err
+}
+
+// CloseDB closes the database connection
+func CloseDB() error {
+	if DB != nil {
+		return DB.Close()
+	}
+	return nil
+}
+
+const (
+	dateLayout      = "2006-01-02"
+	timestampLayout = "2006-01-02 15:04:05.000000"
+)
+
+// nowTimestamp is the current time in timestampLayout, for statements that share
+// their placeholders with others and can't take it as a parameter
+const nowTimestamp = `strftime('%Y-%m-%d %H:%M:%f000', 'now')`
+
+// dateValue stores the calendar date of t, so dates compare as text
+func dateValue(t time.Time) string {
+	return t.Format(dateLayout)
+}
+
+// nullableDate stores the calendar date of t, or NULL for no date
+func nullableDate(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return dateValue(*t)
+}
+
+// timestampValue stores t in UTC to the microsecond, as Postgres keeps timestamps,
+// so timestamps compare as text
+func timestampValue(t time.Time) string {
+	return t.UTC().Format(timestampLayout)
+}
+
+// minorUnits reads an amount stored as a whole number of minor units
+type minorUnits domain.Money
+
+func (m *minorUnits) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case int64:
+		*m = minorUnits(v)
+		return nil
+	case nil:
+		*m = 0
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into an amount", src)
+}
+
+// money returns the scan destination for an amount column
+func money(m *domain.Money) sql.Scanner {
+	return (*minorUnits)(m)
+}
+
+// stringList reads a JSON array of strings, as built by json_group_array
+type stringList []string
+
+func (l *stringList) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	case nil:
+		*l = []string{}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into a list", src)
+	}
+	list := []string{}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// jsonList passes a list to a query as a JSON array, for json_each to read
+func jsonList(values []string) string {
+	if values == nil {
+		values = []string{}
+	}
+	data, _ := json.Marshal(values)
+	return string(data)
+}
@@ -0,0 +1,510 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"expense-tracker-api/domain"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type expenseRepository struct {
+	tx *sql.Tx
+}
+
+// NewExpenseRepository creates a new expense repository
+func NewExpenseRepository() domain.ExpenseRepository {
+	return &expenseRepository{}
+}
+
+// WithTx returns the repository bound to a transaction from NewTxManager
+func (r *expenseRepository) WithTx(tx domain.Tx) domain.ExpenseRepository {
+	return &expenseRepository{tx: boundTx(tx)}
+}
+
+// expenseColumns lists the expense columns in the order expenseFields scans them
+const expenseColumns = `id, user_id, category_id, amount, currency, original_amount, exchange_rate,
+			  description, payment_mode, account_id, expense_date, recurring_expense_id, created_at`
+
+// expenseFields returns the scan destinations for expenseColumns
+func expenseFields(expense *domain.Expense) []interface{} {
+	return []interface{}{&expense.ID, &expense.UserID, &expense.CategoryID, money(&expense.Amount), &expense.Currency,
+		money(&expense.OriginalAmount), &expense.ExchangeRate, &expense.Description, &expense.PaymentMode, &expense.AccountID,
+		&expense.ExpenseDate, &expense.RecurringExpenseID, &expense.CreatedAt}
+}
+
+// expenseTagsColumn selects the sorted tag names of the expense identified by idColumn as a JSON array
+func expenseTagsColumn(idColumn string) string {
+	return fmt.Sprintf(`(SELECT json_group_array(t.name ORDER BY t.name) FROM expense_tags et JOIN tags t ON t.id = et.tag_id
+			  WHERE et.expense_id = %s)`, idColumn)
+}
+
+// expenseValues returns the values of the columns an expense is inserted with
+func expenseValues(expense *domain.Expense, createdAt time.Time) []interface{} {
+	return []interface{}{expense.UserID, expense.CategoryID, int64(expense.Amount), expense.Currency,
+		int64(expense.OriginalAmount), expense.ExchangeRate, expense.Description, expense.PaymentMode, expense.AccountID,
+		dateValue(expense.ExpenseDate), expense.RecurringExpenseID, timestampValue(createdAt)}
+}
+
+const insertExpenseQuery = `INSERT INTO expenses (user_id, category_id, amount, currency, original_amount, exchange_rate,
+			  description, payment_mode, account_id, expense_date, recurring_expense_id, created_at)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`
+
+// Create inserts the expense and attaches its tags in a single transaction
+func (r *expenseRepository) Create(ctx context.Context, expense *domain.Expense) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, insertExpenseQuery, expenseValues(expense, time.Now())...).Scan(&expense.ID)
+	if err != nil {
+		// A recurring occurrence can only be created once per date
+		if isUniqueViolation(err) {
+			return domain.ErrAlreadyExists
+		}
+		return err
+	}
+
+	if err := setExpenseTags(ctx, tx, expense); err != nil {
+		return err
+	}
+	if err := recordExpenseCreated(ctx, tx, expense); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// recordExpenseCreated logs a new expense as it was saved. Occurrences of a recurring
+// expense are created by the scheduler rather than by the user.
+func recordExpenseCreated(ctx context.Context, tx executor, expense *domain.Expense) error {
+	after, err := lockExpense(ctx, tx, expense.UserID, expense.ID)
+	if err != nil {
+		return err
+	}
+	event, err := domain.NewAuditEvent(expense.UserID, domain.AuditEntityExpense, expense.ID, domain.AuditActionCreate, nil, after)
+	if err != nil {
+		return err
+	}
+	if expense.RecurringExpenseID != nil {
+		event.ActorID = nil
+	}
+	return recordAudit(ctx, tx, event)
+}
+
+// setExpenseTags replaces the tags of the expense, creating tags the user doesn't have yet
+func setExpenseTags(ctx context.Context, tx executor, expense *domain.Expense) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM expense_tags WHERE expense_id = ?`, expense.ID); err != nil {
+		return err
+	}
+	if len(expense.Tags) == 0 {
+		return nil
+	}
+
+	// WHERE TRUE tells SQLite the ON CONFLICT belongs to the INSERT, not to the SELECT
+	_, err := tx.ExecContext(ctx, `INSERT INTO tags (user_id, name, created_at) SELECT ?1, value, ?2 FROM json_each(?3) WHERE TRUE
+			  ON CONFLICT (user_id, name) DO NOTHING`, expense.UserID, timestampValue(time.Now()), jsonList(expense.Tags))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT OR IGNORE INTO expense_tags (expense_id, tag_id)
+			  SELECT ?1, id FROM tags WHERE user_id = ?2 AND name IN (SELECT value FROM json_each(?3))`,
+		expense.ID, expense.UserID, jsonList(expense.Tags))
+	return err
+}
+
+// CreateBatch inserts all expenses in a single transaction
+func (r *expenseRepository) CreateBatch(ctx context.Context, expenses []*domain.Expense) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, insertExpenseQuery)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for _, expense := range expenses {
+		if err := stmt.QueryRowContext(ctx, expenseValues(expense, now)...).Scan(&expense.ID); err != nil {
+			return err
+		}
+		expense.CreatedAt = now
+
+		if err := setExpenseTags(ctx, tx, expense); err != nil {
+			return err
+		}
+		if err := recordExpenseCreated(ctx, tx, expense); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// expenseByIDQuery selects one of the user's (?2) live expenses with its tags
+var expenseByIDQuery = `SELECT ` + expenseColumns + `, ` + expenseTagsColumn("expenses.id") + ` FROM expenses
+			  WHERE id = ?1 AND user_id = ?2 AND deleted_at IS NULL`
+
+func (r *expenseRepository) GetByID(ctx context.Context, userID, id int) (*domain.Expense, error) {
+	expense := &domain.Expense{}
+	err := conn(r.tx).QueryRowContext(ctx, expenseByIDQuery, id, userID).Scan(append(expenseFields(expense), (*stringList)(&expense.Tags))...)
+	if err != nil {
+		return nil, err
+	}
+	return expense, nil
+}
+
+// lockExpense reads the expense within tx, which holds the database's write lock until it ends
+func lockExpense(ctx context.Context, tx executor, userID, id int) (*domain.Expense, error) {
+	expense := &domain.Expense{}
+	err := tx.QueryRowContext(ctx, expenseByIDQuery, id, userID).Scan(append(expenseFields(expense), (*stringList)(&expense.Tags))...)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return expense, nil
+}
+
+// expenseSortColumns maps each sort field to its column
+var expenseSortColumns = map[domain.ExpenseSort]string{
+	domain.ExpenseSortDate:      "expense_date",
+	domain.ExpenseSortAmount:    "amount",
+	domain.ExpenseSortCreatedAt: "created_at",
+	domain.ExpenseSortRelevance: "rank",
+}
+
+// expenseCursorValue converts the cursor's sort key to the form its column is stored in
+func expenseCursorValue(cursor *domain.ExpenseCursor) (interface{}, error) {
+	switch cursor.Sort {
+	case domain.ExpenseSortAmount:
+		amount, err := domain.ParseMoney(cursor.Value)
+		return int64(amount), err
+	case domain.ExpenseSortCreatedAt:
+		createdAt, err := time.Parse("2006-01-02T15:04:05.999999", cursor.Value)
+		return timestampValue(createdAt), err
+	case domain.ExpenseSortRelevance:
+		return strconv.ParseFloat(cursor.Value, 64)
+	}
+	return cursor.Value, nil
+}
+
+// searchWords splits a search query into the lowercase words every match must contain
+func searchWords(query string) []string {
+	return strings.Fields(strings.ToLower(query))
+}
+
+// searchRank scores an expense by the words found in its description (1 each) and
+// category name (0.5 each). The weights add up exactly, so ranks work as cursors.
+func searchRank(words []string, argIndex int) string {
+	terms := make([]string, 0, len(words))
+	for i := range words {
+		terms = append(terms, fmt.Sprintf(`(instr(lower(description), ?%d) > 0)
+			  + 0.5 * (instr(lower((SELECT name FROM categories WHERE id = category_id)), ?%d) > 0)`, argIndex+i, argIndex+i))
+	}
+	return `(` + strings.Join(terms, ` + `) + `)`
+}
+
+// highlight wraps every occurrence of the words in <mark> tags, ignoring case
+func highlight(text string, words []string) string {
+	quoted := make([]string, 0, len(words))
+	for _, word := range words {
+		quoted = append(quoted, regexp.QuoteMeta(word))
+	}
+	pattern := regexp.MustCompile(`(?i)` + strings.Join(quoted, "|"))
+	return pattern.ReplaceAllString(text, `<mark>$0</mark>`)
+}
+
+// GetAll returns one page of matching expenses. The ID breaks ties in the sort
+// order so that keyset pagination with filter.After never skips or repeats a row.
+// With a search query each expense also carries its rank and a highlighted description.
+func (r *expenseRepository) GetAll(ctx context.Context, userID int, filter *domain.ExpenseFilter) ([]*domain.Expense, error) {
+	where, args := buildExpenseFilter(userID, filter, "")
+
+	columns := expenseColumns + `, ` + expenseTagsColumn("expenses.id") + ` AS tags`
+	words := searchWords(filter.Query)
+	searching := len(words) > 0
+	if searching {
+		columns += `, ` + searchRank(words, len(args)+1) + ` AS rank`
+		for _, word := range words {
+			args = append(args, word)
+		}
+	}
+
+	sort, ok := expenseSortColumns[filter.Sort]
+	if !ok || (filter.Sort == domain.ExpenseSortRelevance && !searching) {
+		sort = expenseSortColumns[domain.ExpenseSortDate]
+	}
+	direction, comparison := "ASC", ">"
+	if filter.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	// The page condition goes on an outer query so it can refer to the computed rank
+	query := `SELECT * FROM (SELECT ` + columns + ` FROM expenses WHERE ` + where + `) AS e`
+	if filter.After != nil {
+		value, err := expenseCursorValue(filter.After)
+		if err != nil {
+			return nil, domain.ErrInvalidInput
+		}
+		query += fmt.Sprintf(` WHERE (%s, id) %s (?%d, ?%d)`, sort, comparison, len(args)+1, len(args)+2)
+		args = append(args, value, filter.After.ID)
+	}
+	query += fmt.Sprintf(` ORDER BY %s %s, id %s`, sort, direction, direction)
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(` LIMIT ?%d`, len(args)+1)
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := conn(r.tx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	expenses := []*domain.Expense{}
+	for rows.Next() {
+		expense := &domain.Expense{}
+		dest := append(expenseFields(expense), (*stringList)(&expense.Tags))
+		if searching {
+			dest = append(dest, &expense.Rank)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		if searching {
+			expense.Highlight = highlight(expense.Description, words)
+		}
+		expenses = append(expenses, expense)
+	}
+	return expenses, rows.Err()
+}
+
+// Count returns the number of expenses matching the filter, ignoring paging
+func (r *expenseRepository) Count(ctx context.Context, userID int, filter *domain.ExpenseFilter) (int, error) {
+	where, args := buildExpenseFilter(userID, filter, "")
+	var count int
+	err := conn(r.tx).QueryRowContext(ctx, `SELECT COUNT(*) FROM expenses WHERE `+where, args...).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// StreamAll calls fn for each matching expense as it is read from the database
+// cursor, so large exports are never held in memory. It stops at the first error fn returns.
+func (r *expenseRepository) StreamAll(ctx context.Context, userID int, filter *domain.ExpenseFilter, fn func(row *domain.ExpenseExportRow) error) error {
+	where, args := buildExpenseFilter(userID, filter, "e.")
+	query := `SELECT e.id, e.user_id, e.category_id, e.amount, e.currency, e.original_amount, e.exchange_rate,
+			  e.description, e.payment_mode, e.account_id, e.expense_date, e.recurring_expense_id, e.created_at, ` +
+		expenseTagsColumn("e.id") + `, c.name
+			  FROM expenses e JOIN categories c ON c.id = e.category_id
+			  WHERE ` + where + ` ORDER BY e.expense_date DESC, e.created_at DESC`
+
+	rows, err := conn(r.tx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row := &domain.ExpenseExportRow{Expense: &domain.Expense{}}
+		err := rows.Scan(append(expenseFields(row.Expense), (*stringList)(&row.Tags), &row.CategoryName)...)
+		if err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// buildExpenseFilter turns the owner and filter into conditions on the expenses table.
+// prefix qualifies the column names when the query joins other tables.
+func buildExpenseFilter(userID int, filter *domain.ExpenseFilter, prefix string) (string, []interface{}) {
+	where := fmt.Sprintf(`%suser_id = ?1 AND %sdeleted_at IS NULL`, prefix, prefix)
+	args := []interface{}{userID}
+	argIndex := 2
+
+	if filter.CategoryID != nil {
+		if filter.IncludeSubcategories {
+			where += fmt.Sprintf(` AND %scategory_id IN (WITH RECURSIVE `+subcategoriesCTE+` SELECT id FROM subcategories)`,
+				prefix, argIndex)
+		} else {
+			where += fmt.Sprintf(` AND %scategory_id = ?%d`, prefix, argIndex)
+		}
+		args = append(args, *filter.CategoryID)
+		argIndex++
+	}
+
+	if filter.PaymentMode != nil {
+		where += fmt.Sprintf(` AND %spayment_mode = ?%d`, prefix, argIndex)
+		args = append(args, string(*filter.PaymentMode))
+		argIndex++
+	}
+
+	if filter.AccountID != nil {
+		where += fmt.Sprintf(` AND %saccount_id = ?%d`, prefix, argIndex)
+		args = append(args, *filter.AccountID)
+		argIndex++
+	}
+
+	if filter.StartDate != nil {
+		where += fmt.Sprintf(` AND %sexpense_date >= ?%d`, prefix, argIndex)
+		args = append(args, dateValue(*filter.StartDate))
+		argIndex++
+	}
+
+	if filter.EndDate != nil {
+		where += fmt.Sprintf(` AND %sexpense_date <= ?%d`, prefix, argIndex)
+		args = append(args, dateValue(*filter.EndDate))
+		argIndex++
+	}
+
+	// Every word has to appear in the description or the category name
+	for _, word := range searchWords(filter.Query) {
+		where += fmt.Sprintf(` AND (instr(lower(%sdescription), ?%d) > 0
+			  OR instr(lower((SELECT name FROM categories WHERE id = %scategory_id)), ?%d) > 0)`, prefix, argIndex, prefix, argIndex)
+		args = append(args, word)
+		argIndex++
+	}
+
+	if len(filter.Tags) > 0 {
+		idColumn := prefix + "id"
+		if prefix == "" {
+			idColumn = "expenses.id"
+		}
+		// Counting matched tags covers both modes: at least one for any, every one for all
+		minMatches := "1"
+		if filter.AllTags {
+			minMatches = fmt.Sprintf(`json_array_length(?%d)`, argIndex)
+		}
+		where += fmt.Sprintf(` AND (SELECT COUNT(*) FROM expense_tags et JOIN tags t ON t.id = et.tag_id
+			  WHERE et.expense_id = %s AND t.name IN (SELECT value FROM json_each(?%d))) >= %s`, idColumn, argIndex, minMatches)
+		args = append(args, jsonList(filter.Tags))
+		argIndex++
+	}
+
+	return where, args
+}
+
+// Update saves the expense and replaces its tags in a single transaction
+func (r *expenseRepository) Update(ctx context.Context, expense *domain.Expense) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Never attach tags to an expense the user doesn't own
+	before, err := lockExpense(ctx, tx, expense.UserID, expense.ID)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE expenses SET category_id = ?, amount = ?, currency = ?, original_amount = ?, exchange_rate = ?,
+			  description = ?, payment_mode = ?, account_id = ?, expense_date = ?
+			  WHERE id = ? AND user_id = ?`
+	_, err = tx.ExecContext(ctx, query, expense.CategoryID, int64(expense.Amount), expense.Currency, int64(expense.OriginalAmount),
+		expense.ExchangeRate, expense.Description, expense.PaymentMode, expense.AccountID, dateValue(expense.ExpenseDate),
+		expense.ID, expense.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := setExpenseTags(ctx, tx, expense); err != nil {
+		return err
+	}
+
+	after, err := lockExpense(ctx, tx, expense.UserID, expense.ID)
+	if err != nil {
+		return err
+	}
+	if err := recordChange(ctx, tx, expense.UserID, domain.AuditEntityExpense, expense.ID, domain.AuditActionUpdate, before, after); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete moves the expense to the trash; the purge job removes it for good
+func (r *expenseRepository) Delete(ctx context.Context, userID, id int) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := lockExpense(ctx, tx, userID, id)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE expenses SET deleted_at = ? WHERE id = ?`, timestampValue(time.Now()), id); err != nil {
+		return err
+	}
+	if err := recordChange(ctx, tx, userID, domain.AuditEntityExpense, id, domain.AuditActionDelete, before, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// monthRange returns the first day of the month and of the month after it
+func monthRange(month, year int) (string, string) {
+	first := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	return dateValue(first), dateValue(first.AddDate(0, 1, 0))
+}
+
+func (r *expenseRepository) GetTotalByMonth(ctx context.Context, userID, month, year int) (domain.Money, error) {
+	var total domain.Money
+	from, to := monthRange(month, year)
+	query := `SELECT COALESCE(SUM(amount), 0) FROM expenses
+			  WHERE user_id = ? AND deleted_at IS NULL AND expense_date >= ? AND expense_date < ?`
+	err := conn(r.tx).QueryRowContext(ctx, query, userID, from, to).Scan(money(&total))
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// GetTotalsByCategoryForMonth returns each category's spending including its subcategories
+func (r *expenseRepository) GetTotalsByCategoryForMonth(ctx context.Context, userID, month, year int) (map[int]domain.Money, error) {
+	from, to := monthRange(month, year)
+	query := `WITH RECURSIVE ` + categoryClosure + `,
+			  spent AS (
+				  SELECT category_id, SUM(amount) AS amount FROM expenses
+				  WHERE user_id = ?1 AND deleted_at IS NULL AND expense_date >= ?2 AND expense_date < ?3
+				  GROUP BY category_id
+			  )
+			  SELECT closure.ancestor_id, SUM(spent.amount)
+			  FROM closure JOIN spent ON spent.category_id = closure.category_id
+			  GROUP BY closure.ancestor_id`
+	rows, err := conn(r.tx).QueryContext(ctx, query, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := make(map[int]domain.Money)
+	for rows.Next() {
+		var categoryID int
+		var total domain.Money
+		if err := rows.Scan(&categoryID, money(&total)); err != nil {
+			return nil, err
+		}
+		totals[categoryID] = total
+	}
+	return totals, rows.Err()
+}
@@ -0,0 +1,166 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"expense-tracker-api/domain"
+	"time"
+)
+
+type incomeRepository struct {
+	tx *sql.Tx
+}
+
+// NewIncomeRepository creates a new income repository
+func NewIncomeRepository() domain.IncomeRepository {
+	return &incomeRepository{}
+}
+
+// WithTx returns the repository bound to a transaction from NewTxManager
+func (r *incomeRepository) WithTx(tx domain.Tx) domain.IncomeRepository {
+	return &incomeRepository{tx: boundTx(tx)}
+}
+
+const incomeColumns = `id, user_id, category_id, source, amount, description, payment_mode, account_id, income_date,
+			  created_at, updated_at`
+
+func (r *incomeRepository) Create(ctx context.Context, income *domain.Income) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO incomes (user_id, category_id, source, amount, description, payment_mode, account_id, income_date,
+			  created_at, updated_at)
+			  VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10) RETURNING id`
+	now := time.Now()
+	err = tx.QueryRowContext(ctx, query, income.UserID, income.CategoryID, income.Source, int64(income.Amount), income.Description,
+		income.PaymentMode, income.AccountID, dateValue(income.IncomeDate), timestampValue(now), timestampValue(now)).Scan(&income.ID)
+	if err != nil {
+		return err
+	}
+	income.CreatedAt = now
+	income.UpdatedAt = now
+
+	if err := recordChange(ctx, tx, income.UserID, domain.AuditEntityIncome, income.ID, domain.AuditActionCreate, nil, income); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const incomeByIDQuery = `SELECT ` + incomeColumns + ` FROM incomes WHERE id = ?1 AND user_id = ?2`
+
+func (r *incomeRepository) GetByID(ctx context.Context, userID, id int) (*domain.Income, error) {
+	income, err := scanIncome(conn(r.tx).QueryRowContext(ctx, incomeByIDQuery, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	return income, err
+}
+
+// lockIncome reads the income within tx , which holds the database's write lock until it ends
+func lockIncome(ctx context.Context, tx executor, userID, id int) (*domain.Income, error) {
+	income, err := scanIncome(tx.QueryRowContext(ctx, incomeByIDQuery+``, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	return income, err
+}
+
+// GetAll returns the user's incomes in the filter's date range, newest first
+func (r *incomeRepository) GetAll(ctx context.Context, userID int, filter *domain.IncomeFilter) ([]*domain.Income, error) {
+	query := `SELECT ` + incomeColumns + ` FROM incomes
+			  WHERE user_id = ?1 AND (?2 IS NULL OR income_date >= ?2) AND (?3 IS NULL OR income_date <= ?3)
+			  ORDER BY income_date DESC, id DESC`
+	rows, err := conn(r.tx).QueryContext(ctx, query, userID, nullableDate(filter.StartDate), nullableDate(filter.EndDate))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	incomes := []*domain.Income{}
+	for rows.Next() {
+		income, err := scanIncome(rows)
+		if err != nil {
+			return nil, err
+		}
+		incomes = append(incomes, income)
+	}
+	return incomes, rows.Err()
+}
+
+func (r *incomeRepository) Update(ctx context.Context, income *domain.Income) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := lockIncome(ctx, tx, income.UserID, income.ID)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE incomes SET category_id = ?1, source = ?2, amount = ?3, description = ?4, payment_mode = ?5,
+			  account_id = ?6, income_date = ?7, updated_at = ?8 WHERE id = ?9`
+	income.UpdatedAt = time.Now()
+	_, err = tx.ExecContext(ctx, query, income.CategoryID, income.Source, int64(income.Amount), income.Description, income.PaymentMode,
+		income.AccountID, dateValue(income.IncomeDate), timestampValue(income.UpdatedAt), income.ID)
+	if err != nil {
+		return err
+	}
+
+	after, err := lockIncome(ctx, tx, income.UserID, income.ID)
+	if err != nil {
+		return err
+	}
+	if err := recordChange(ctx, tx, income.UserID, domain.AuditEntityIncome, income.ID, domain.AuditActionUpdate, before, after); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *incomeRepository) Delete(ctx context.Context, userID, id int) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := lockIncome(ctx, tx, userID, id)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM incomes WHERE id = ?1`, id); err != nil {
+		return err
+	}
+	if err := recordChange(ctx, tx, userID, domain.AuditEntityIncome, id, domain.AuditActionDelete, before, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *incomeRepository) GetTotalByMonth(ctx context.Context, userID, month, year int) (domain.Money, error) {
+	var total domain.Money
+	from, to := monthRange(month, year)
+	query := `SELECT COALESCE(SUM(amount), 0) FROM incomes
+			  WHERE user_id = ? AND income_date >= ? AND income_date < ?`
+	err := conn(r.tx).QueryRowContext(ctx, query, userID, from, to).Scan(money(&total))
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func scanIncome(row rowScanner) (*domain.Income, error) {
+	income := &domain.Income{}
+	var description sql.NullString
+	err := row.Scan(&income.ID, &income.UserID, &income.CategoryID, &income.Source, money(&income.Amount), &description,
+		&income.PaymentMode, &income.AccountID, &income.IncomeDate, &income.CreatedAt, &income.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	income.Description = description.String
+	return income, nil
+}
@@ -0,0 +1,20 @@
+package sqlite
+
+import (
+	"embed"
+	"expense-tracker-api/repository"
+	"io/fs"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// NewSchemaMigrator creates the migrator for the SQLite schema. A database file is
+// only ever used by one server, so it takes no lock beyond SQLite's own.
+func NewSchemaMigrator() *repository.Migrator {
+	migrations, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		panic(err)
+	}
+	return repository.NewMigrator(DB, migrations, nil)
+}
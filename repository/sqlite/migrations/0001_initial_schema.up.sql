@@ -0,0 +1,221 @@
+-- The schema of the Postgres database, in SQLite terms. Amounts are whole minor units
+-- (paise, cents), dates are YYYY-MM-DD text and timestamps are UTC text, so comparing
+-- and summing them stays exact.
+CREATE TABLE users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    email TEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE categories (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    parent_id INTEGER REFERENCES categories(id),
+    name TEXT NOT NULL,
+    archived BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX idx_categories_parent ON categories(parent_id);
+
+-- A name in the trash can be used again; restoring it then fails with a conflict
+CREATE UNIQUE INDEX idx_categories_user_name_live ON categories(user_id, name) WHERE deleted_at IS NULL;
+
+CREATE TABLE payment_methods (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    type TEXT NOT NULL CHECK (type IN ('card', 'bank', 'wallet', 'cash', 'upi')),
+    last4 TEXT,
+    vpa TEXT,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    UNIQUE (user_id, name)
+);
+
+-- Accounts hold money in the base currency; the opening balance applies from opening_date
+CREATE TABLE accounts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    type TEXT NOT NULL CHECK (type IN ('bank', 'card', 'cash', 'wallet')),
+    opening_balance INTEGER NOT NULL DEFAULT 0,
+    opening_date DATE NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    UNIQUE (user_id, name)
+);
+
+-- Payment modes name one of the user's payment methods. Renames cascade, and methods
+-- in use can't be deleted.
+CREATE TABLE recurring_expenses (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category_id INTEGER NOT NULL REFERENCES categories(id),
+    amount INTEGER NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    payment_mode TEXT NOT NULL,
+    frequency TEXT NOT NULL CHECK (frequency IN ('daily', 'weekly', 'monthly', 'yearly')),
+    day_of_month INTEGER CHECK (day_of_month BETWEEN 1 AND 31),
+    start_date DATE NOT NULL,
+    end_date DATE,
+    next_run_date DATE NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id, payment_mode) REFERENCES payment_methods(user_id, name) ON UPDATE CASCADE
+);
+
+CREATE INDEX idx_recurring_expenses_next_run ON recurring_expenses(next_run_date) WHERE active;
+
+-- Expenses keep the amount as entered and the rate used to convert it to the base currency
+CREATE TABLE expenses (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category_id INTEGER NOT NULL REFERENCES categories(id),
+    amount INTEGER NOT NULL,
+    currency TEXT NOT NULL,
+    original_amount INTEGER NOT NULL,
+    exchange_rate TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    payment_mode TEXT NOT NULL,
+    account_id INTEGER REFERENCES accounts(id),
+    expense_date DATE NOT NULL,
+    recurring_expense_id INTEGER REFERENCES recurring_expenses(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL,
+    deleted_at TIMESTAMP,
+    FOREIGN KEY (user_id, payment_mode) REFERENCES payment_methods(user_id, name) ON UPDATE CASCADE
+);
+
+-- Serves the default newest-first page of a user's expenses
+CREATE INDEX idx_expenses_user_date ON expenses(user_id, expense_date DESC, id DESC);
+CREATE INDEX idx_expenses_category ON expenses(category_id);
+CREATE INDEX idx_expenses_account ON expenses(account_id);
+CREATE INDEX idx_expenses_deleted_at ON expenses(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Each recurring occurrence is created at most once, even when catching up after downtime
+CREATE UNIQUE INDEX idx_expenses_recurring_occurrence ON expenses(recurring_expense_id, expense_date)
+    WHERE recurring_expense_id IS NOT NULL;
+
+CREATE TABLE tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE (user_id, name)
+);
+
+CREATE TABLE expense_tags (
+    expense_id INTEGER NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (expense_id, tag_id)
+);
+
+CREATE INDEX idx_expense_tags_tag ON expense_tags(tag_id);
+
+-- Rates are shared by all users: units of the base currency per unit of the foreign currency
+CREATE TABLE exchange_rates (
+    currency TEXT NOT NULL,
+    rate_date DATE NOT NULL,
+    rate TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (currency, rate_date)
+);
+
+CREATE TABLE app_settings (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL
+);
+
+-- One overall budget (category_id NULL) and one budget per category for each user and
+-- month that isn't in the trash
+CREATE TABLE budgets (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category_id INTEGER REFERENCES categories(id) ON DELETE CASCADE,
+    month INTEGER NOT NULL CHECK (month BETWEEN 1 AND 12),
+    year INTEGER NOT NULL,
+    budget_amount INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    deleted_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_budgets_user_month_year_category_live
+    ON budgets(user_id, month, year, COALESCE(category_id, 0)) WHERE deleted_at IS NULL;
+CREATE INDEX idx_budgets_category ON budgets(category_id);
+
+-- Incomes are in the base currency. The payment method they were received into is optional.
+CREATE TABLE incomes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category_id INTEGER REFERENCES categories(id) ON DELETE SET NULL,
+    source TEXT NOT NULL,
+    amount INTEGER NOT NULL CHECK (amount > 0),
+    description TEXT,
+    payment_mode TEXT,
+    account_id INTEGER REFERENCES accounts(id),
+    income_date DATE NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id, payment_mode) REFERENCES payment_methods(user_id, name) ON UPDATE CASCADE
+);
+
+CREATE INDEX idx_incomes_user_date ON incomes(user_id, income_date);
+CREATE INDEX idx_incomes_account ON incomes(account_id);
+
+CREATE TABLE transfers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    from_account_id INTEGER NOT NULL REFERENCES accounts(id),
+    to_account_id INTEGER NOT NULL REFERENCES accounts(id),
+    amount INTEGER NOT NULL CHECK (amount > 0),
+    description TEXT,
+    transfer_date DATE NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    CHECK (from_account_id <> to_account_id)
+);
+
+CREATE INDEX idx_transfers_from_account ON transfers(from_account_id);
+CREATE INDEX idx_transfers_to_account ON transfers(to_account_id);
+
+CREATE TABLE reconciliations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    statement_date DATE NOT NULL,
+    statement_balance INTEGER NOT NULL,
+    computed_balance INTEGER NOT NULL,
+    discrepancy INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_reconciliations_account ON reconciliations(account_id, statement_date);
+
+-- Every change to a user's records is logged in the transaction that makes it. The log
+-- outlives the records it describes and is append-only: rows can't be changed or removed.
+CREATE TABLE audit_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    actor_id INTEGER,
+    entity TEXT NOT NULL,
+    entity_id INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    changes TEXT NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_audit_events_user_entity ON audit_events(user_id, entity, entity_id, id);
+
+CREATE TRIGGER audit_events_no_update BEFORE UPDATE ON audit_events
+BEGIN
+    SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
+
+CREATE TRIGGER audit_events_no_delete BEFORE DELETE ON audit_events
+BEGIN
+    SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
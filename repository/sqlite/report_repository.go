@@ -0,0 +1,174 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"expense-tracker-api/domain"
+	"time"
+)
+
+type reportRepository struct{}
+
+// NewReportRepository creates a new report repository
+func NewReportRepository() domain.ReportRepository {
+	return &reportRepository{}
+}
+
+func (r *reportRepository) GetSummary(ctx context.Context, userID int, from, to time.Time) (*domain.SpendingSummary, error) {
+	summary := &domain.SpendingSummary{}
+	query := `SELECT COALESCE(SUM(amount), 0), COUNT(*) FROM expenses
+			  WHERE user_id = ?1 AND deleted_at IS NULL AND expense_date >= ?2 AND expense_date < ?3`
+	err := DB.QueryRowContext(ctx, query, userID, dateValue(from), dateValue(to)).Scan(money(&summary.Total), &summary.Count)
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+func (r *reportRepository) GetLargestExpense(ctx context.Context, userID int, from, to time.Time) (*domain.Expense, error) {
+	expense := &domain.Expense{}
+	query := `SELECT ` + expenseColumns + `
+			  FROM expenses WHERE user_id = ?1 AND deleted_at IS NULL AND expense_date >= ?2 AND expense_date < ?3
+			  ORDER BY amount DESC, expense_date, id LIMIT 1`
+	err := DB.QueryRowContext(ctx, query, userID, dateValue(from), dateValue(to)).Scan(expenseFields(expense)...)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return expense, nil
+}
+
+// GetCategoryBreakdown returns spending per category, largest rolled-up amount first.
+// Every category with spending of its own or below it is listed, so parents appear
+// even when all their expenses are in subcategories.
+func (r *reportRepository) GetCategoryBreakdown(ctx context.Context, userID int, from, to time.Time) ([]*domain.CategoryBreakdown, error) {
+	query := `WITH RECURSIVE ` + categoryClosure + `,
+			  spent AS (
+				  SELECT category_id, SUM(amount) AS amount, COUNT(*) AS count FROM expenses
+				  WHERE user_id = ?1 AND deleted_at IS NULL AND expense_date >= ?2 AND expense_date < ?3
+				  GROUP BY category_id
+			  )
+			  SELECT c.id, c.parent_id, c.name, COALESCE(own.amount, 0),
+			  ROUND(COALESCE(own.amount, 0) * 100.0 / NULLIF((SELECT SUM(amount) FROM spent), 0), 2),
+			  COALESCE(own.count, 0), SUM(spent.amount), SUM(spent.count)
+			  FROM categories c
+			  JOIN closure ON closure.ancestor_id = c.id
+			  JOIN spent ON spent.category_id = closure.category_id
+			  LEFT JOIN spent own ON own.category_id = c.id
+			  GROUP BY c.id, c.parent_id, c.name, own.amount, own.count
+			  ORDER BY SUM(spent.amount) DESC, c.name`
+	rows, err := DB.QueryContext(ctx, query, userID, dateValue(from), dateValue(to))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	breakdown := []*domain.CategoryBreakdown{}
+	for rows.Next() {
+		item := &domain.CategoryBreakdown{}
+		var share sql.NullFloat64
+		if err := rows.Scan(&item.CategoryID, &item.ParentID, &item.CategoryName, money(&item.Amount), &share, &item.Count,
+			money(&item.RolledUpAmount), &item.RolledUpCount); err != nil {
+			return nil, err
+		}
+		item.Share = share.Float64
+		breakdown = append(breakdown, item)
+	}
+	return breakdown, rows.Err()
+}
+
+// GetPaymentModeBreakdown returns spending per payment mode, largest first
+func (r *reportRepository) GetPaymentModeBreakdown(ctx context.Context, userID int, from, to time.Time) ([]*domain.PaymentModeBreakdown, error) {
+	query := `SELECT payment_mode, SUM(amount),
+			  ROUND(SUM(amount) * 100.0 / NULLIF(SUM(SUM(amount)) OVER (), 0), 2), COUNT(*)
+			  FROM expenses
+			  WHERE user_id = ?1 AND deleted_at IS NULL AND expense_date >= ?2 AND expense_date < ?3
+			  GROUP BY payment_mode
+			  ORDER BY SUM(amount) DESC, payment_mode`
+	rows, err := DB.QueryContext(ctx, query, userID, dateValue(from), dateValue(to))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	breakdown := []*domain.PaymentModeBreakdown{}
+	for rows.Next() {
+		item := &domain.PaymentModeBreakdown{}
+		var share sql.NullFloat64
+		if err := rows.Scan(&item.PaymentMode, money(&item.Amount), &share, &item.Count); err != nil {
+			return nil, err
+		}
+		item.Share = share.Float64
+		breakdown = append(breakdown, item)
+	}
+	return breakdown, rows.Err()
+}
+
+// GetDailyTotals returns one entry per day in the range, including days without spending
+func (r *reportRepository) GetDailyTotals(ctx context.Context, userID int, from, to time.Time) ([]*domain.DailyTotal, error) {
+	query := `WITH RECURSIVE days (day) AS (
+				  SELECT ?2 WHERE ?2 < ?3
+				  UNION ALL
+				  SELECT date(day, '+1 day') FROM days WHERE date(day, '+1 day') < ?3
+			  )
+			  SELECT d.day, COALESCE(SUM(e.amount), 0), COUNT(e.id)
+			  FROM days d
+			  LEFT JOIN expenses e ON e.expense_date = d.day AND e.user_id = ?1 AND e.deleted_at IS NULL
+			  GROUP BY d.day
+			  ORDER BY d.day`
+	rows, err := DB.QueryContext(ctx, query, userID, dateValue(from), dateValue(to))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := []*domain.DailyTotal{}
+	for rows.Next() {
+		total := &domain.DailyTotal{}
+		var day string
+		if err := rows.Scan(&day, money(&total.Amount), &total.Count); err != nil {
+			return nil, err
+		}
+		if total.Date, err = time.Parse(dateLayout, day); err != nil {
+			return nil, err
+		}
+		totals = append(totals, total)
+	}
+	return totals, rows.Err()
+}
+
+// GetMonthlyCashflow returns one entry per calendar month the range touches. Only
+// income and expenses inside the range are counted, so partial months are partial.
+func (r *reportRepository) GetMonthlyCashflow(ctx context.Context, userID int, from, to time.Time) ([]*domain.CashflowMonth, error) {
+	query := `WITH RECURSIVE months (month) AS (
+				  SELECT date(?2, 'start of month') WHERE ?2 < ?3
+				  UNION ALL
+				  SELECT date(month, '+1 month') FROM months WHERE date(month, '+1 month') < ?3
+			  )
+			  SELECT strftime('%Y-%m', m.month),
+			  COALESCE((SELECT SUM(i.amount) FROM incomes i
+				WHERE i.user_id = ?1 AND i.income_date >= max(m.month, ?2)
+				AND i.income_date < min(date(m.month, '+1 month'), ?3)), 0),
+			  COALESCE((SELECT SUM(e.amount) FROM expenses e
+				WHERE e.user_id = ?1 AND e.deleted_at IS NULL AND e.expense_date >= max(m.month, ?2)
+				AND e.expense_date < min(date(m.month, '+1 month'), ?3)), 0)
+			  FROM months m
+			  ORDER BY m.month`
+	rows, err := DB.QueryContext(ctx, query, userID, dateValue(from), dateValue(to))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	months := []*domain.CashflowMonth{}
+	for rows.Next() {
+		month := &domain.CashflowMonth{}
+		if err := rows.Scan(&month.Month, money(&month.Income), money(&month.Expenses)); err != nil {
+			return nil, err
+		}
+		months = append(months, month)
+	}
+	return months, rows.Err()
+}
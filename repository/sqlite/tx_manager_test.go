@@ -0,0 +1,26 @@
+package sqlite
+
+import (
+	"context"
+	"expense-tracker-api/domain"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithinTxTranslatesConstraintViolations(t *testing.T) {
+	require.NoError(t, InitDB(filepath.Join(t.TempDir(), "expense_tracker.db")))
+	t.Cleanup(func() { CloseDB() })
+	require.NoError(t, NewSchemaMigrator().Up(context.Background()))
+	ctx := context.Background()
+
+	err := NewTxManager().WithinTx(ctx, func(tx domain.Tx) error {
+		_, err := boundTx(tx).ExecContext(ctx, `INSERT INTO categories (user_id, name, created_at) VALUES (?1, ?2, ?3)`,
+			999, "Food", timestampValue(time.Now()))
+		return err
+	})
+	assert.ErrorIs(t, err, domain.ErrInvalidReference)
+}
@@ -0,0 +1,65 @@
+package sqlite
+
+import (
+	"context"
+	"expense-tracker-api/domain"
+	"time"
+)
+
+type userRepository struct{}
+
+// NewUserRepository creates a new user repository
+func NewUserRepository() domain.UserRepository {
+	return &userRepository{}
+}
+
+// Create inserts a user with the default payment methods
+func (r *userRepository) Create(ctx context.Context, user *domain.User) error {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO users (email, password_hash, created_at) VALUES (?, ?, ?) RETURNING id`
+	now := time.Now()
+	err = tx.QueryRowContext(ctx, query, user.Email, user.PasswordHash, timestampValue(now)).Scan(&user.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.ErrEmailAlreadyExists
+		}
+		return err
+	}
+	user.CreatedAt = now
+
+	for _, method := range domain.DefaultPaymentMethods() {
+		_, err := tx.ExecContext(ctx, `INSERT INTO payment_methods (user_id, name, type, active, created_at, updated_at)
+			  VALUES (?1, ?2, ?3, ?4, ?5, ?5)`,
+			user.ID, method.Name, method.Type, method.Active, timestampValue(now))
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id int) (*domain.User, error) {
+	user := &domain.User{}
+	query := `SELECT id, email, password_hash, created_at FROM users WHERE id = ?`
+	err := DB.QueryRowContext(ctx, query, id).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	user := &domain.User{}
+	query := `SELECT id, email, password_hash, created_at FROM users WHERE email = ?`
+	err := DB.QueryRowContext(ctx, query, email).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
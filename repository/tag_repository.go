@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"expense-tracker-api/domain"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+type tagRepository struct {
+	tx *sql.Tx
+}
+
+// NewTagRepository creates a new tag repository
+func NewTagRepository() domain.TagRepository {
+	return &tagRepository{}
+}
+
+// WithTx returns the repository bound to a transaction from NewTxManager
+func (r *tagRepository) WithTx(tx domain.Tx) domain.TagRepository {
+	return &tagRepository{tx: boundTx(tx)}
+}
+
+// GetAll returns the user's tags with the number of expenses carrying each one
+func (r *tagRepository) GetAll(ctx context.Context, userID int) ([]*domain.Tag, error) {
+	query := `SELECT t.id, t.user_id, t.name, t.created_at, COUNT(e.id)
+			  FROM tags t LEFT JOIN expense_tags et ON et.tag_id = t.id
+			  LEFT JOIN expenses e ON e.id = et.expense_id AND e.deleted_at IS NULL
+			  WHERE t.user_id = $1
+			  GROUP BY t.id
+			  ORDER BY t.name`
+	rows, err := conn(r.tx).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []*domain.Tag{}
+	for rows.Next() {
+		tag := &domain.Tag{}
+		if err := rows.Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.CreatedAt, &tag.UsageCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+const tagByIDQuery = `SELECT t.id, t.user_id, t.name, t.created_at,
+			  (SELECT COUNT(*) FROM expense_tags et JOIN expenses e ON e.id = et.expense_id
+				WHERE et.tag_id = t.id AND e.deleted_at IS NULL)
+			  FROM tags t WHERE t.id = $1 AND t.user_id = $2`
+
+func (r *tagRepository) GetByID(ctx context.Context, userID, id int) (*domain.Tag, error) {
+	return scanTag(conn(r.tx).QueryRowContext(ctx, tagByIDQuery+lockClause(r.tx), id, userID))
+}
+
+// lockTag reads the tag within tx and locks it until tx ends
+func lockTag(ctx context.Context, tx executor, userID, id int) (*domain.Tag, error) {
+	tag, err := scanTag(tx.QueryRowContext(ctx, tagByIDQuery+` FOR UPDATE`, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	return tag, err
+}
+
+func scanTag(row rowScanner) (*domain.Tag, error) {
+	tag := &domain.Tag{}
+	if err := row.Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.CreatedAt, &tag.UsageCount); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// taggedExpense is an expense carrying a tag, with the tags it carried before a change
+type taggedExpense struct {
+	id   int
+	tags []string
+}
+
+// taggedExpenses returns the expenses carrying the tag, in the trash or not, with their tags
+func taggedExpenses(ctx context.Context, tx executor, tagID int) ([]taggedExpense, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT link.expense_id, `+expenseTagsColumn("link.expense_id")+`
+			  FROM expense_tags link WHERE link.tag_id = $1 ORDER BY link.expense_id`, tagID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var expenses []taggedExpense
+	for rows.Next() {
+		var expense taggedExpense
+		if err := rows.Scan(&expense.id, pq.Array(&expense.tags)); err != nil {
+			return nil, err
+		}
+		expenses = append(expenses, expense)
+	}
+	return expenses, rows.Err()
+}
+
+// recordRetagged logs the change a tag rename or merge made to the tags of each expense
+func recordRetagged(ctx context.Context, tx executor, userID int, expenses []taggedExpense) error {
+	for _, expense := range expenses {
+		var tags []string
+		err := tx.QueryRowContext(ctx, `SELECT `+expenseTagsColumn("$1::integer"), expense.id).Scan(pq.Array(&tags))
+		if err != nil {
+			return err
+		}
+		before := map[string][]string{"tags": expense.tags}
+		after := map[string][]string{"tags": tags}
+		if err := recordChange(ctx, tx, userID, domain.AuditEntityExpense, expense.id, domain.AuditActionUpdate, before, after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rename renames the tag, which renames it on every expense carrying it
+func (r *tagRepository) Rename(ctx context.Context, userID, id int, name string) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := lockTag(ctx, tx, userID, id)
+	if err != nil {
+		return err
+	}
+	expenses, err := taggedExpenses(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE tags SET name = $1 WHERE id = $2`, name, id); err != nil {
+		if isUniqueViolation(err) {
+			return domain.ErrAlreadyExists
+		}
+		return err
+	}
+
+	after := *before
+	after.Name = name
+	if err := recordChange(ctx, tx, userID, domain.AuditEntityTag, id, domain.AuditActionUpdate, before, &after); err != nil {
+		return err
+	}
+	if err := recordRetagged(ctx, tx, userID, expenses); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Merge moves every expense from the source tag to the target tag and deletes the source.
+// Expenses that already carry both tags keep a single link to the target.
+func (r *tagRepository) Merge(ctx context.Context, userID, sourceID, targetID int) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	source, err := lockTag(ctx, tx, userID, sourceID)
+	if err != nil {
+		return err
+	}
+	if _, err := lockTag(ctx, tx, userID, targetID); err != nil {
+		return err
+	}
+	expenses, err := taggedExpenses(ctx, tx, sourceID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO expense_tags (expense_id, tag_id)
+			  SELECT expense_id, $2::integer FROM expense_tags WHERE tag_id = $1
+			  ON CONFLICT DO NOTHING`, sourceID, targetID)
+	if err != nil {
+		return err
+	}
+
+	// Deleting the source tag cascades to its remaining links
+	if _, err := tx.ExecContext(ctx, `DELETE FROM tags WHERE id = $1`, sourceID); err != nil {
+		return err
+	}
+
+	if err := recordRetagged(ctx, tx, userID, expenses); err != nil {
+		return err
+	}
+	event, err := domain.NewAuditEvent(userID, domain.AuditEntityTag, sourceID, domain.AuditActionMerge, source, nil)
+	if err != nil {
+		return err
+	}
+	event.Changes["merged_into"] = domain.AuditChange{To: json.RawMessage(strconv.Itoa(targetID))}
+	if err := recordAudit(ctx, tx, event); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"expense-tracker-api/domain"
+	"time"
+)
+
+type transferRepository struct {
+	tx *sql.Tx
+}
+
+// NewTransferRepository creates a new transfer repository
+func NewTransferRepository() domain.TransferRepository {
+	return &transferRepository{}
+}
+
+// WithTx returns the repository bound to a transaction from NewTxManager
+func (r *transferRepository) WithTx(tx domain.Tx) domain.TransferRepository {
+	return &transferRepository{tx: boundTx(tx)}
+}
+
+const transferColumns = `id, user_id, from_account_id, to_account_id, amount, description, transfer_date, created_at`
+
+func (r *transferRepository) Create(ctx context.Context, transfer *domain.Transfer) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO transfers (user_id, from_account_id, to_account_id, amount, description, transfer_date, created_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+	now := time.Now()
+	err = tx.QueryRowContext(ctx, query, transfer.UserID, transfer.FromAccountID, transfer.ToAccountID, transfer.Amount,
+		transfer.Description, transfer.TransferDate, now).Scan(&transfer.ID)
+	if err != nil {
+		return err
+	}
+	transfer.CreatedAt = now
+
+	if err := recordChange(ctx, tx, transfer.UserID, domain.AuditEntityTransfer, transfer.ID, domain.AuditActionCreate, nil, transfer); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const transferByIDQuery = `SELECT ` + transferColumns + ` FROM transfers WHERE id = $1 AND user_id = $2`
+
+func (r *transferRepository) GetByID(ctx context.Context, userID, id int) (*domain.Transfer, error) {
+	transfer, err := scanTransfer(conn(r.tx).QueryRowContext(ctx, transferByIDQuery+lockClause(r.tx), id, userID))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	return transfer, err
+}
+
+func (r *transferRepository) GetAll(ctx context.Context, userID int, accountID *int) ([]*domain.Transfer, error) {
+	query := `SELECT ` + transferColumns + ` FROM transfers
+			  WHERE user_id = $1 AND ($2::int IS NULL OR from_account_id = $2 OR to_account_id = $2)
+			  ORDER BY transfer_date DESC, id DESC`
+	rows, err := conn(r.tx).QueryContext(ctx, query, userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transfers := []*domain.Transfer{}
+	for rows.Next() {
+		transfer, err := scanTransfer(rows)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, transfer)
+	}
+	return transfers, rows.Err()
+}
+
+func (r *transferRepository) Delete(ctx context.Context, userID, id int) error {
+	tx, err := beginTx(ctx, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := scanTransfer(tx.QueryRowContext(ctx, transferByIDQuery+` FOR UPDATE`, id, userID))
+	if err == sql.ErrNoRows {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM transfers WHERE id = $1`, id); err != nil {
+		return err
+	}
+	if err := recordChange(ctx, tx, userID, domain.AuditEntityTransfer, id, domain.AuditActionDelete, before, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func scanTransfer(row rowScanner) (*domain.Transfer, error) {
+	transfer := &domain.Transfer{}
+	var description sql.NullString
+	err := row.Scan(&transfer.ID, &transfer.UserID, &transfer.FromAccountID, &transfer.ToAccountID, &transfer.Amount,
+		&description, &transfer.TransferDate, &transfer.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	transfer.Description = description.String
+	return transfer, nil
+}
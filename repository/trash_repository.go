@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"expense-tracker-api/domain"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type trashRepository struct{}
+
+// NewTrashRepository creates a new trash repository
+func NewTrashRepository() domain.TrashRepository {
+	return &trashRepository{}
+}
+
+func (r *trashRepository) GetAll(ctx context.Context, userID int) (*domain.Trash, error) {
+	trash := &domain.Trash{Expenses: []*domain.Expense{}, Categories: []*domain.Category{}, Budgets: []*domain.Budget{}}
+
+	rows, err := DB.QueryContext(ctx, `SELECT `+expenseColumns+`, deleted_at, `+expenseTagsColumn("expenses.id")+`
+			  FROM expenses WHERE user_id = $1 AND deleted_at IS NOT NULL
+			  ORDER BY deleted_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		expense := &domain.Expense{}
+		if err := rows.Scan(append(expenseFields(expense), &expense.DeletedAt, pq.Array(&expense.Tags))...); err != nil {
+			return nil, err
+		}
+		trash.Expenses = append(trash.Expenses, expense)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = DB.QueryContext(ctx, `SELECT id, user_id, parent_id, name, archived, created_at, deleted_at
+			  FROM categories WHERE user_id = $1 AND deleted_at IS NOT NULL
+			  ORDER BY deleted_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		category := &domain.Category{}
+		if err := rows.Scan(&category.ID, &category.UserID, &category.ParentID, &category.Name, &category.Archived,
+			&category.CreatedAt, &category.DeletedAt); err != nil {
+			return nil, err
+		}
+		trash.Categories = append(trash.Categories, category)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = DB.QueryContext(ctx, `SELECT id, user_id, category_id, month, year, budget_amount, created_at, updated_at, deleted_at
+			  FROM budgets WHERE user_id = $1 AND deleted_at IS NOT NULL
+			  ORDER BY deleted_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		budget := &domain.Budget{}
+		if err := rows.Scan(&budget.ID, &budget.UserID, &budget.CategoryID, &budget.Month, &budget.Year,
+			&budget.BudgetAmount, &budget.CreatedAt, &budget.UpdatedAt, &budget.DeletedAt); err != nil {
+			return nil, err
+		}
+		trash.Budgets = append(trash.Budgets, budget)
+	}
+	return trash, rows.Err()
+}
+
+// trashRestoreQueries look up an item in the trash, reporting whether the category
+// it belongs under is in the trash as well, and then restore it
+var trashRestoreQueries = map[domain.TrashItemType]struct {
+	lookup  string
+	restore string
+}{
+	domain.TrashItemExpense: {
+		lookup: `SELECT c.deleted_at IS NOT NULL FROM expenses e JOIN categories c ON c.id = e.category_id
+				 WHERE e.id = $1 AND e.user_id = $2 AND e.deleted_at IS NOT NULL FOR UPDATE OF e`,
+		restore: `UPDATE expenses SET deleted_at = NULL WHERE id = $1`,
+	},
+	domain.TrashItemCategory: {
+		lookup: `SELECT COALESCE(p.deleted_at IS NOT NULL, FALSE) FROM categories c LEFT JOIN categories p ON p.id = c.parent_id
+				 WHERE c.id = $1 AND c.user_id = $2 AND c.deleted_at IS NOT NULL FOR UPDATE OF c`,
+		restore: `UPDATE categories SET deleted_at = NULL WHERE id = $1`,
+	},
+	domain.TrashItemBudget: {
+		lookup: `SELECT COALESCE(c.deleted_at IS NOT NULL, FALSE) FROM budgets b LEFT JOIN categories c ON c.id = b.category_id
+				 WHERE b.id = $1 AND b.user_id = $2 AND b.deleted_at IS NOT NULL FOR UPDATE OF b`,
+		restore: `UPDATE budgets SET deleted_at = NULL WHERE id = $1`,
+	},
+}
+
+func (r *trashRepository) Restore(ctx context.Context, userID int, itemType domain.TrashItemType, id int) error {
+	queries, ok := trashRestoreQueries[itemType]
+	if !ok {
+		return domain.ErrInvalidInput
+	}
+
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var categoryDeleted bool
+	err = tx.QueryRowContext(ctx, queries.lookup, id, userID).Scan(&categoryDeleted)
+	if err == sql.ErrNoRows {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if categoryDeleted {
+		return domain.ErrInvalidCategory
+	}
+
+	if itemType == domain.TrashItemCategory {
+		if err := restoreCategoryBudgets(ctx, tx, userID, id); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, queries.restore, id); err != nil {
+		if isUniqueViolation(err) {
+			return domain.ErrAlreadyExists
+		}
+		return err
+	}
+
+	var restored interface{}
+	var entity domain.AuditEntity
+	switch itemType {
+	case domain.TrashItemExpense:
+		restored, err = lockExpense(ctx, tx, userID, id)
+		entity = domain.AuditEntityExpense
+	case domain.TrashItemCategory:
+		restored, err = lockCategory(ctx, tx, userID, id)
+		entity = domain.AuditEntityCategory
+	case domain.TrashItemBudget:
+		restored, err = lockBudget(ctx, tx, userID, id)
+		entity = domain.AuditEntityBudget
+	}
+	if err != nil {
+		return err
+	}
+	if err := recordChange(ctx, tx, userID, entity, id, domain.AuditActionRestore, nil, restored); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// restoreCategoryBudgets brings back the budgets deleted together with the category.
+// They were deleted in the same transaction, so they share its timestamp.
+func restoreCategoryBudgets(ctx context.Context, tx executor, userID, categoryID int) error {
+	rows, err := tx.QueryContext(ctx, `UPDATE budgets SET deleted_at = NULL
+			  WHERE category_id = $1 AND deleted_at = (SELECT deleted_at FROM categories WHERE id = $1)
+			  RETURNING id, user_id, category_id, month, year, budget_amount, created_at, updated_at`, categoryID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.ErrAlreadyExists
+		}
+		return err
+	}
+	budgets, err := scanBudgets(rows)
+	rows.Close()
+	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.ErrAlreadyExists
+		}
+		return err
+	}
+
+	for _, budget := range budgets {
+		if err := recordChange(ctx, tx, userID, domain.AuditEntityBudget, budget.ID, domain.AuditActionRestore, nil, budget); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Purge removes expenses and budgets first so the categories they used can go in the
+// same pass. A category still referenced by anything, such as a newer expense in the
+// trash, is kept until a later purge.
+func (r *trashRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Each purged record is logged with no actor, in the same statement that removes it
+	queries := []string{
+		`WITH purged AS (DELETE FROM expenses WHERE deleted_at < $1 RETURNING id, user_id)
+			INSERT INTO audit_events (user_id, entity, entity_id, action, created_at)
+			SELECT user_id, 'expense', id, 'purge', NOW() FROM purged`,
+		`WITH purged AS (DELETE FROM budgets WHERE deleted_at < $1 RETURNING id, user_id)
+			INSERT INTO audit_events (user_id, entity, entity_id, action, created_at)
+			SELECT user_id, 'budget', id, 'purge', NOW() FROM purged`,
+		`WITH purged AS (DELETE FROM categories c WHERE c.deleted_at < $1
+				AND NOT EXISTS (SELECT 1 FROM expenses e WHERE e.category_id = c.id)
+				AND NOT EXISTS (SELECT 1 FROM recurring_expenses re WHERE re.category_id = c.id)
+				AND NOT EXISTS (SELECT 1 FROM categories sub WHERE sub.parent_id = c.id)
+				RETURNING id, user_id)
+			INSERT INTO audit_events (user_id, entity, entity_id, action, created_at)
+			SELECT user_id, 'category', id, 'purge', NOW() FROM purged`,
+	}
+
+	var purged int64
+	for _, query := range queries {
+		result, err := tx.ExecContext(ctx, query, before)
+		if err != nil {
+			return 0, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		purged += affected
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"expense-tracker-api/domain"
+)
+
+type txManager struct{}
+
+// NewTxManager creates a transaction manager for the Postgres repositories
+func NewTxManager() domain.TxManager {
+	return &txManager{}
+}
+
+// WithinTx hands fn a *sql.Tx, which the repositories' WithTx methods bind to
+func (m *txManager) WithinTx(ctx context.Context, fn func(tx domain.Tx) error) error {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// executor runs statements on the shared pool or within a transaction
+type executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// conn returns the transaction a repository is bound to, or the shared pool when it isn't bound to one
+func conn(tx *sql.Tx) executor {
+	if tx != nil {
+		return tx
+	}
+	return DB
+}
+
+// lockClause locks the rows a query reads until the transaction ends when the
+// repository is bound to one, so a read-modify-write can't lose a concurrent update
+func lockClause(tx *sql.Tx) string {
+	if tx != nil {
+		return ` FOR UPDATE`
+	}
+	return ``
+}
+
+// unitTx is the transaction a repository method runs its statements in. For a
+// repository bound to a transaction it is that transaction, and committing or
+// rolling it back is left to whoever began it.
+type unitTx struct {
+	*sql.Tx
+	owned bool
+}
+
+// beginTx begins a transaction of the method's own, or joins the bound one
+func beginTx(ctx context.Context, bound *sql.Tx) (*unitTx, error) {
+	if bound != nil {
+		return &unitTx{Tx: bound}, nil
+	}
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &unitTx{Tx: tx, owned: true}, nil
+}
+
+func (t *unitTx) Commit() error {
+	if !t.owned {
+		return nil
+	}
+	return t.Tx.Commit()
+}
+
+func (t *unitTx) Rollback() error {
+	if !t.owned {
+		return nil
+	}
+	return t.Tx.Rollback()
+}
+
+// boundTx returns the *sql.Tx a repository's WithTx is given, which must come from this package's TxManager
+func boundTx(tx domain.Tx) *sql.Tx {
+	return tx.(*sql.Tx)
+}
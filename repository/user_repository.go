@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"expense-tracker-api/domain"
+	"time"
+)
+
+type userRepository struct{}
+
+// NewUserRepository creates a new user repository
+func NewUserRepository() domain.UserRepository {
+	return &userRepository{}
+}
+
+// Create inserts a user with the default payment methods
+func (r *userRepository) Create(ctx context.Context, user *domain.User) error {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO users (email, password_hash, created_at) VALUES ($1, $2, $3) RETURNING id`
+	now := time.Now()
+	err = tx.QueryRowContext(ctx, query, user.Email, user.PasswordHash, now).Scan(&user.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.ErrEmailAlreadyExists
+		}
+		return err
+	}
+	user.CreatedAt = now
+
+	for _, method := range domain.DefaultPaymentMethods() {
+		_, err := tx.ExecContext(ctx, `INSERT INTO payment_methods (user_id, name, type, active, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $5) ON CONFLICT (user_id, name) DO NOTHING`,
+			user.ID, method.Name, method.Type, method.Active, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id int) (*domain.User, error) {
+	user := &domain.User{}
+	query := `SELECT id, email, password_hash, created_at FROM users WHERE id = $1`
+	err := DB.QueryRowContext(ctx, query, id).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	user := &domain.User{}
+	query := `SELECT id, email, password_hash, created_at FROM users WHERE email = $1`
+	err := DB.QueryRowContext(ctx, query, email).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ownedTables lists the tables whose rows belong to a user. Payment methods
+// come before the expense tables whose (user_id, payment_mode) keys refer to them.
+var ownedTables = []string{"categories", "payment_methods", "recurring_expenses", "expenses", "budgets"}
+
+// AdoptLegacyData gives the user with the email every row left without an owner by
+// databases created before user accounts existed, and returns how many rows moved.
+// It is an operator's decision, run with the adopt command, never a side effect of
+// registering. Unowned payment methods the user already has are dropped in favour of theirs.
+func AdoptLegacyData(ctx context.Context, email string) (int64, error) {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var userID int
+	err = tx.QueryRowContext(ctx, `SELECT id FROM users WHERE email = $1`, email).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, domain.ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM payment_methods legacy WHERE legacy.user_id IS NULL
+		  AND EXISTS (SELECT 1 FROM payment_methods own WHERE own.user_id = $1 AND own.name = legacy.name)`, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	var adopted int64
+	for _, table := range ownedTables {
+		result, err := tx.ExecContext(ctx, `UPDATE `+table+` SET user_id = $1 WHERE user_id IS NULL`, userID)
+		if err != nil {
+			return 0, err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		adopted += n
+	}
+
+	return adopted, tx.Commit()
+}
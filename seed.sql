@@ -1,18 +1,78 @@
+-- Seed demo user (password: password123)
+INSERT INTO users (email, password_hash, created_at) VALUES
+('demo@example.com', '$2a$10$5vx0ISMgU.nZFC8PiGZ/d.G5Zs6nXWPotfp9TV51ZR/dch.VznwHa', NOW())
+ON CONFLICT (email) DO NOTHING;
+
+-- Seed Payment Methods
+INSERT INTO payment_methods (user_id, name, type, active, created_at, updated_at)
+SELECT u.id, m.name, m.type, TRUE, NOW(), NOW()
+FROM (VALUES
+    ('UPI', 'upi'),
+    ('Cash', 'cash'),
+    ('Bank Transfer', 'bank')
+) AS m(name, type)
+JOIN users u ON u.email = 'demo@example.com'
+ON CONFLICT (user_id, name) DO NOTHING;
+
+-- Seed Accounts, opened at the start of Feb 2026
+INSERT INTO accounts (user_id, name, type, opening_balance, opening_date, created_at, updated_at)
+SELECT u.id, a.name, a.type, a.opening_balance, DATE '2026-02-01', NOW(), NOW()
+FROM (VALUES
+    ('HDFC Bank', 'bank', 25000.00),
+    ('Cash', 'cash', 500.00)
+) AS a(name, type, opening_balance)
+JOIN users u ON u.email = 'demo@example.com'
+ON CONFLICT (user_id, name) DO NOTHING;
+
 -- Seed Categories
-INSERT INTO categories (name, created_at) VALUES 
-('Food', NOW()),
-('Transport', NOW()),
-('Rent', NOW()),
-('Entertainment', NOW())
+INSERT INTO categories (user_id, name, created_at) VALUES 
+((SELECT id FROM users WHERE email = 'demo@example.com'), 'Food', NOW()),
+((SELECT id FROM users WHERE email = 'demo@example.com'), 'Transport', NOW()),
+((SELECT id FROM users WHERE email = 'demo@example.com'), 'Rent', NOW()),
+((SELECT id FROM users WHERE email = 'demo@example.com'), 'Entertainment', NOW())
+ON CONFLICT DO NOTHING;
+
+-- Seed a subcategory; its spending counts towards the Food budget
+INSERT INTO categories (user_id, parent_id, name, created_at)
+SELECT u.id, c.id, 'Groceries', NOW()
+FROM users u JOIN categories c ON c.user_id = u.id AND c.name = 'Food'
+WHERE u.email = 'demo@example.com'
 ON CONFLICT DO NOTHING;
 
 -- Seed Budget for Feb 2026
-INSERT INTO budgets (month, year, budget_amount, created_at, updated_at) VALUES 
-(2, 2026, 12000.00, NOW(), NOW())
-ON CONFLICT (month, year) DO UPDATE SET budget_amount = 12000.00, updated_at = NOW();
+INSERT INTO budgets (user_id, month, year, budget_amount, created_at, updated_at) VALUES 
+((SELECT id FROM users WHERE email = 'demo@example.com'), 2, 2026, 12000.00, NOW(), NOW())
+ON CONFLICT (user_id, month, year, COALESCE(category_id, 0)) WHERE deleted_at IS NULL DO UPDATE SET budget_amount = 12000.00, updated_at = NOW();
+
+-- Seed Food category budget for Feb 2026
+INSERT INTO budgets (user_id, category_id, month, year, budget_amount, created_at, updated_at) VALUES 
+((SELECT id FROM users WHERE email = 'demo@example.com'),
+ (SELECT c.id FROM categories c JOIN users u ON u.id = c.user_id WHERE u.email = 'demo@example.com' AND c.name = 'Food'), 2, 2026, 6000.00, NOW(), NOW())
+ON CONFLICT (user_id, month, year, COALESCE(category_id, 0)) WHERE deleted_at IS NULL DO UPDATE SET budget_amount = 6000.00, updated_at = NOW();
 
 -- Seed some initial expenses for Feb 2026 (Total: 8000, within 12000 budget)
-INSERT INTO expenses (category_id, amount, description, payment_mode, expense_date, created_at) VALUES 
-((SELECT id FROM categories WHERE name = 'Rent' LIMIT 1), 5000.00, 'Monthly Rent', 'Bank Transfer', '2026-02-01', NOW()),
-((SELECT id FROM categories WHERE name = 'Food' LIMIT 1), 2000.00, 'Grocery shopping', 'UPI', '2026-02-05', NOW()),
-((SELECT id FROM categories WHERE name = 'Transport' LIMIT 1), 1000.00, 'Fuel', 'Cash', '2026-02-10', NOW());
+INSERT INTO expenses (user_id, category_id, amount, currency, original_amount, exchange_rate, description, payment_mode, account_id, expense_date, created_at)
+SELECT u.id, c.id, e.amount, COALESCE((SELECT value FROM app_settings WHERE key = 'base_currency'), 'INR'), e.amount, 1,
+       e.description, e.payment_mode, a.id, e.expense_date, NOW()
+FROM (VALUES
+    ('Rent', 5000.00, 'Monthly Rent', 'Bank Transfer', 'HDFC Bank', DATE '2026-02-01'),
+    ('Groceries', 2000.00, 'Grocery shopping', 'UPI', 'HDFC Bank', DATE '2026-02-05'),
+    ('Transport', 1000.00, 'Fuel', 'Cash', 'Cash', DATE '2026-02-10')
+) AS e(category, amount, description, payment_mode, account, expense_date)
+JOIN users u ON u.email = 'demo@example.com'
+JOIN categories c ON c.user_id = u.id AND c.name = e.category
+JOIN accounts a ON a.user_id = u.id AND a.name = e.account;
+
+-- Seed an ATM withdrawal from the bank into cash
+INSERT INTO transfers (user_id, from_account_id, to_account_id, amount, description, transfer_date, created_at)
+SELECT u.id, b.id, c.id, 2000.00, 'ATM withdrawal', DATE '2026-02-09', NOW()
+FROM users u
+JOIN accounts b ON b.user_id = u.id AND b.name = 'HDFC Bank'
+JOIN accounts c ON c.user_id = u.id AND c.name = 'Cash'
+WHERE u.email = 'demo@example.com';
+
+-- Seed salary for Feb 2026, paid into the bank account
+INSERT INTO incomes (user_id, source, amount, description, payment_mode, account_id, income_date, created_at, updated_at)
+SELECT u.id, 'Salary', 60000.00, 'February salary', 'Bank Transfer', a.id, DATE '2026-02-01', NOW(), NOW()
+FROM users u JOIN accounts a ON a.user_id = u.id AND a.name = 'HDFC Bank'
+WHERE u.email = 'demo@example.com';
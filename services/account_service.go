@@ -0,0 +1,267 @@
+package services
+
+import (
+	"context"
+	"expense-tracker-api/domain"
+	"strings"
+	"time"
+)
+
+// maxAccountNameLength matches the accounts.name column
+const maxAccountNameLength = 50
+
+type AccountService struct {
+	txManager    domain.TxManager
+	accountRepo  domain.AccountRepository
+	transferRepo domain.TransferRepository
+}
+
+// NewAccountService creates a new account service
+func NewAccountService(txManager domain.TxManager, accountRepo domain.AccountRepository, transferRepo domain.TransferRepository) *AccountService {
+	return &AccountService{
+		txManager:    txManager,
+		accountRepo:  accountRepo,
+		transferRepo: transferRepo,
+	}
+}
+
+// withTx returns a copy of the service whose repositories are bound to tx
+func (s *AccountService) withTx(tx domain.Tx) *AccountService {
+	return &AccountService{
+		txManager:    s.txManager,
+		accountRepo:  s.accountRepo.WithTx(tx),
+		transferRepo: s.transferRepo.WithTx(tx),
+	}
+}
+
+// CreateAccount creates a new account, opened today unless an opening date is given
+func (s *AccountService) CreateAccount(ctx context.Context, account *domain.Account) (*domain.Account, error) {
+	if account.OpeningDate.IsZero() {
+		account.OpeningDate = time.Now()
+	}
+
+	if err := validateAccount(account); err != nil {
+		return nil, err
+	}
+
+	err := s.accountRepo.Create(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// GetAccounts retrieves all accounts
+func (s *AccountService) GetAccounts(ctx context.Context, userID int) ([]*domain.Account, error) {
+	return s.accountRepo.GetAll(ctx, userID)
+}
+
+// GetAccountByID retrieves an account by ID
+func (s *AccountService) GetAccountByID(ctx context.Context, userID, id int) (*domain.Account, error) {
+	account, err := s.accountRepo.GetByID(ctx, userID, id)
+	if err != nil {
+		return nil, domain.ErrNotFound
+	}
+	return account, nil
+}
+
+// UpdateAccount replaces an account. A missing opening date keeps the recorded one.
+func (s *AccountService) UpdateAccount(ctx context.Context, account *domain.Account) (*domain.Account, error) {
+	return withinTx(ctx, s.txManager, func(tx domain.Tx) (*domain.Account, error) {
+		return s.withTx(tx).updateAccount(ctx, account)
+	})
+}
+
+// updateAccount replaces an account with the service's repositories
+func (s *AccountService) updateAccount(ctx context.Context, account *domain.Account) (*domain.Account, error) {
+	existing, err := s.accountRepo.GetByID(ctx, account.UserID, account.ID)
+	if err != nil {
+		return nil, domain.ErrNotFound
+	}
+
+	if account.OpeningDate.IsZero() {
+		account.OpeningDate = existing.OpeningDate
+	}
+
+	if err := validateAccount(account); err != nil {
+		return nil, err
+	}
+	account.CreatedAt = existing.CreatedAt
+
+	err = s.accountRepo.Update(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// DeleteAccount deletes an account that has no expenses, incomes or transfers
+func (s *AccountService) DeleteAccount(ctx context.Context, userID, id int) error {
+	return s.txManager.WithinTx(ctx, func(tx domain.Tx) error {
+		accountRepo := s.accountRepo.WithTx(tx)
+
+		_, err := accountRepo.GetByID(ctx, userID, id)
+		if err != nil {
+			return domain.ErrNotFound
+		}
+
+		return accountRepo.Delete(ctx, userID, id)
+	})
+}
+
+// GetBalance computes the balance at the end of asOf, today when asOf is zero.
+// There is no balance before the account's opening date.
+func (s *AccountService) GetBalance(ctx context.Context, userID, id int, asOf time.Time) (*domain.AccountBalance, error) {
+	account, err := s.accountRepo.GetByID(ctx, userID, id)
+	if err != nil {
+		return nil, domain.ErrNotFound
+	}
+
+	if asOf.IsZero() {
+		asOf = time.Now()
+	}
+	asOf = domain.TruncateToDate(asOf)
+	if asOf.Before(account.OpeningDate) {
+		return nil, domain.ErrInvalidInput
+	}
+
+	balance, err := s.accountRepo.GetBalance(ctx, userID, id, asOf)
+	if err != nil {
+		return nil, err
+	}
+	balance.Balance = balance.OpeningBalance + balance.Income - balance.Expenses + balance.TransfersIn - balance.TransfersOut
+	return balance, nil
+}
+
+// Reconcile records a statement balance for the account and how far it is from
+// the balance computed for the statement date. The account is locked while the
+// balance is computed, so the reconciliation matches the movements it was computed from.
+func (s *AccountService) Reconcile(ctx context.Context, userID, accountID int, statementDate time.Time, statementBalance domain.Money) (*domain.Reconciliation, error) {
+	if statementDate.IsZero() {
+		return nil, domain.ErrInvalidInput
+	}
+
+	return withinTx(ctx, s.txManager, func(tx domain.Tx) (*domain.Reconciliation, error) {
+		return s.withTx(tx).reconcile(ctx, userID, accountID, statementDate, statementBalance)
+	})
+}
+
+// reconcile records the reconciliation with the service's repositories
+func (s *AccountService) reconcile(ctx context.Context, userID, accountID int, statementDate time.Time, statementBalance domain.Money) (*domain.Reconciliation, error) {
+	balance, err := s.GetBalance(ctx, userID, accountID, statementDate)
+	if err != nil {
+		return nil, err
+	}
+
+	reconciliation := &domain.Reconciliation{
+		UserID:           userID,
+		AccountID:        accountID,
+		StatementDate:    balance.AsOf,
+		StatementBalance: statementBalance,
+		ComputedBalance:  balance.Balance,
+		Discrepancy:      statementBalance - balance.Balance,
+	}
+
+	err = s.accountRepo.CreateReconciliation(ctx, reconciliation)
+	if err != nil {
+		return nil, err
+	}
+
+	return reconciliation, nil
+}
+
+// GetReconciliations retrieves an account's reconciliations, latest statement first
+func (s *AccountService) GetReconciliations(ctx context.Context, userID, accountID int) ([]*domain.Reconciliation, error) {
+	if _, err := s.accountRepo.GetByID(ctx, userID, accountID); err != nil {
+		return nil, domain.ErrNotFound
+	}
+	return s.accountRepo.GetReconciliations(ctx, userID, accountID)
+}
+
+// CreateTransfer moves money between two of the user's accounts, dated today unless a date is given
+func (s *AccountService) CreateTransfer(ctx context.Context, transfer *domain.Transfer) (*domain.Transfer, error) {
+	if transfer.Amount <= 0 || transfer.FromAccountID == transfer.ToAccountID {
+		return nil, domain.ErrInvalidInput
+	}
+
+	return withinTx(ctx, s.txManager, func(tx domain.Tx) (*domain.Transfer, error) {
+		return s.withTx(tx).createTransfer(ctx, transfer)
+	})
+}
+
+// createTransfer creates a transfer with the service's repositories
+func (s *AccountService) createTransfer(ctx context.Context, transfer *domain.Transfer) (*domain.Transfer, error) {
+	for _, accountID := range []int{transfer.FromAccountID, transfer.ToAccountID} {
+		if err := checkAccount(ctx, s.accountRepo, transfer.UserID, &accountID); err != nil {
+			return nil, err
+		}
+	}
+
+	if transfer.TransferDate.IsZero() {
+		transfer.TransferDate = time.Now()
+	}
+	transfer.TransferDate = domain.TruncateToDate(transfer.TransferDate)
+
+	err := s.transferRepo.Create(ctx, transfer)
+	if err != nil {
+		return nil, err
+	}
+
+	return transfer, nil
+}
+
+// GetTransfers retrieves transfers newest first, optionally only those into or out of an account
+func (s *AccountService) GetTransfers(ctx context.Context, userID int, accountID *int) ([]*domain.Transfer, error) {
+	return s.transferRepo.GetAll(ctx, userID, accountID)
+}
+
+// GetTransferByID retrieves a transfer by ID
+func (s *AccountService) GetTransferByID(ctx context.Context, userID, id int) (*domain.Transfer, error) {
+	transfer, err := s.transferRepo.GetByID(ctx, userID, id)
+	if err != nil {
+		return nil, domain.ErrNotFound
+	}
+	return transfer, nil
+}
+
+// DeleteTransfer deletes a transfer
+func (s *AccountService) DeleteTransfer(ctx context.Context, userID, id int) error {
+	return s.txManager.WithinTx(ctx, func(tx domain.Tx) error {
+		transferRepo := s.transferRepo.WithTx(tx)
+
+		_, err := transferRepo.GetByID(ctx, userID, id)
+		if err != nil {
+			return domain.ErrNotFound
+		}
+
+		return transferRepo.Delete(ctx, userID, id)
+	})
+}
+
+// validateAccount checks an account and normalizes its name and opening date
+func validateAccount(account *domain.Account) error {
+	account.Name = strings.TrimSpace(account.Name)
+	if account.Name == "" || len(account.Name) > maxAccountNameLength {
+		return domain.ErrInvalidInput
+	}
+
+	if !account.Type.IsValid() {
+		return domain.ErrInvalidInput
+	}
+
+	account.OpeningDate = domain.TruncateToDate(account.OpeningDate)
+	return nil
+}
+
+// checkAccount verifies that an optional account ID names one of the user's accounts
+func checkAccount(ctx context.Context, accountRepo domain.AccountRepository, userID int, accountID *int) error {
+	if accountID == nil {
+		return nil
+	}
+	if _, err := accountRepo.GetByID(ctx, userID, *accountID); err != nil {
+		return domain.ErrInvalidAccount
+	}
+	return nil
+}
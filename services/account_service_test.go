@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"expense-tracker-api/domain"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAccountRepository is a mock implementation of AccountRepository
+type MockAccountRepository struct {
+	mock.Mock
+}
+
+// WithTx returns the mock itself, so expectations hold inside and outside a transaction
+func (m *MockAccountRepository) WithTx(tx domain.Tx) domain.AccountRepository {
+	return m
+}
+
+func (m *MockAccountRepository) Create(ctx context.Context, account *domain.Account) error {
+	args := m.Called(account)
+	return args.Error(0)
+}
+
+func (m *MockAccountRepository) GetByID(ctx context.Context, userID, id int) (*domain.Account, error) {
+	args := m.Called(userID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Account), args.Error(1)
+}
+
+func (m *MockAccountRepository) GetAll(ctx context.Context, userID int) ([]*domain.Account, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Account), args.Error(1)
+}
+
+func (m *MockAccountRepository) Update(ctx context.Context, account *domain.Account) error {
+	args := m.Called(account)
+	return args.Error(0)
+}
+
+func (m *MockAccountRepository) Delete(ctx context.Context, userID, id int) error {
+	args := m.Called(userID, id)
+	return args.Error(0)
+}
+
+func (m *MockAccountRepository) GetBalance(ctx context.Context, userID, id int, asOf time.Time) (*domain.AccountBalance, error) {
+	args := m.Called(userID, id, asOf)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AccountBalance), args.Error(1)
+}
+
+func (m *MockAccountRepository) CreateReconciliation(ctx context.Context, reconciliation *domain.Reconciliation) error {
+	args := m.Called(reconciliation)
+	return args.Error(0)
+}
+
+func (m *MockAccountRepository) GetReconciliations(ctx context.Context, userID, accountID int) ([]*domain.Reconciliation, error) {
+	args := m.Called(userID, accountID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Reconciliation), args.Error(1)
+}
+
+// MockTransferRepository is a mock implementation of TransferRepository
+type MockTransferRepository struct {
+	mock.Mock
+}
+
+// WithTx returns the mock itself, so expectations hold inside and outside a transaction
+func (m *MockTransferRepository) WithTx(tx domain.Tx) domain.TransferRepository {
+	return m
+}
+
+func (m *MockTransferRepository) Create(ctx context.Context, transfer *domain.Transfer) error {
+	args := m.Called(transfer)
+	return args.Error(0)
+}
+
+func (m *MockTransferRepository) GetByID(ctx context.Context, userID, id int) (*domain.Transfer, error) {
+	args := m.Called(userID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Transfer), args.Error(1)
+}
+
+func (m *MockTransferRepository) GetAll(ctx context.Context, userID int, accountID *int) ([]*domain.Transfer, error) {
+	args := m.Called(userID, accountID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Transfer), args.Error(1)
+}
+
+func (m *MockTransferRepository) Delete(ctx context.Context, userID, id int) error {
+	args := m.Called(userID, id)
+	return args.Error(0)
+}
+
+func TestAccountService_GetBalance(t *testing.T) {
+	bank := &domain.Account{ID: 1, UserID: testUserID, Name: "HDFC Savings", Type: domain.AccountTypeBank,
+		OpeningBalance: amount("10000.00"), OpeningDate: date(2024, 1, 1)}
+
+	t.Run("Opening balance moved by incomes, expenses and transfers", func(t *testing.T) {
+		mockAccountRepo := new(MockAccountRepository)
+		accountService := NewAccountService(fakeTxManager{}, mockAccountRepo, new(MockTransferRepository))
+
+		mockAccountRepo.On("GetByID", testUserID, 1).Return(bank, nil)
+		mockAccountRepo.On("GetBalance", testUserID, 1, date(2024, 1, 31)).Return(&domain.AccountBalance{
+			AccountID:      1,
+			AsOf:           date(2024, 1, 31),
+			OpeningBalance: amount("10000.00"),
+			Income:         amount("60000.00"),
+			Expenses:       amount("25000.50"),
+			TransfersIn:    amount("0.00"),
+			TransfersOut:   amount("2000.00"),
+		}, nil)
+
+		balance, err := accountService.GetBalance(context.Background(), testUserID, 1, date(2024, 1, 31))
+		assert.NoError(t, err)
+		assert.Equal(t, amount("42999.50"), balance.Balance)
+		mockAccountRepo.AssertExpectations(t)
+	})
+
+	t.Run("Before the opening date", func(t *testing.T) {
+		mockAccountRepo := new(MockAccountRepository)
+		accountService := NewAccountService(fakeTxManager{}, mockAccountRepo, new(MockTransferRepository))
+
+		mockAccountRepo.On("GetByID", testUserID, 1).Return(bank, nil)
+
+		_, err := accountService.GetBalance(context.Background(), testUserID, 1, date(2023, 12, 31))
+		assert.Equal(t, domain.ErrInvalidInput, err)
+		mockAccountRepo.AssertNotCalled(t, "GetBalance", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestAccountService_Reconcile(t *testing.T) {
+	mockAccountRepo := new(MockAccountRepository)
+	accountService := NewAccountService(fakeTxManager{}, mockAccountRepo, new(MockTransferRepository))
+
+	cash := &domain.Account{ID: 2, UserID: testUserID, Name: "Cash", Type: domain.AccountTypeCash, OpeningDate: date(2024, 1, 1)}
+	mockAccountRepo.On("GetByID", testUserID, 2).Return(cash, nil)
+	mockAccountRepo.On("GetBalance", testUserID, 2, date(2024, 2, 1)).Return(&domain.AccountBalance{
+		AccountID:   2,
+		AsOf:        date(2024, 2, 1),
+		TransfersIn: amount("2000.00"),
+		Expenses:    amount("1450.00"),
+	}, nil)
+	mockAccountRepo.On("CreateReconciliation", mock.AnythingOfType("*domain.Reconciliation")).Return(nil)
+
+	// 50 in the wallet that was never recorded as spent
+	reconciliation, err := accountService.Reconcile(context.Background(), testUserID, 2, date(2024, 2, 1), amount("500.00"))
+	assert.NoError(t, err)
+	assert.Equal(t, amount("550.00"), reconciliation.ComputedBalance)
+	assert.Equal(t, amount("-50.00"), reconciliation.Discrepancy)
+	mockAccountRepo.AssertExpectations(t)
+}
+
+func TestAccountService_CreateTransfer(t *testing.T) {
+	t.Run("ATM withdrawal from bank to cash", func(t *testing.T) {
+		mockAccountRepo := new(MockAccountRepository)
+		mockTransferRepo := new(MockTransferRepository)
+		accountService := NewAccountService(fakeTxManager{}, mockAccountRepo, mockTransferRepo)
+
+		mockAccountRepo.On("GetByID", testUserID, 1).Return(&domain.Account{ID: 1}, nil)
+		mockAccountRepo.On("GetByID", testUserID, 2).Return(&domain.Account{ID: 2}, nil)
+		mockTransferRepo.On("Create", mock.AnythingOfType("*domain.Transfer")).Return(nil)
+
+		transfer, err := accountService.CreateTransfer(context.Background(), &domain.Transfer{UserID: testUserID, FromAccountID: 1, ToAccountID: 2,
+			Amount: amount("2000.00"), Description: "ATM withdrawal"})
+		assert.NoError(t, err)
+		assert.Equal(t, domain.TruncateToDate(time.Now()), transfer.TransferDate)
+		mockTransferRepo.AssertExpectations(t)
+	})
+
+	t.Run("Invalid transfers", func(t *testing.T) {
+		mockAccountRepo := new(MockAccountRepository)
+		mockTransferRepo := new(MockTransferRepository)
+		accountService := NewAccountService(fakeTxManager{}, mockAccountRepo, mockTransferRepo)
+
+		mockAccountRepo.On("GetByID", testUserID, 1).Return(&domain.Account{ID: 1}, nil)
+		mockAccountRepo.On("GetByID", testUserID, 99).Return(nil, domain.ErrNotFound)
+
+		tests := []struct {
+			transfer *domain.Transfer
+			err      error
+		}{
+			{&domain.Transfer{FromAccountID: 1, ToAccountID: 1, Amount: amount("10.00")}, domain.ErrInvalidInput},
+			{&domain.Transfer{FromAccountID: 1, ToAccountID: 2, Amount: amount("0.00")}, domain.ErrInvalidInput},
+			{&domain.Transfer{FromAccountID: 1, ToAccountID: 99, Amount: amount("10.00")}, domain.ErrInvalidAccount},
+		}
+
+		for _, tt := range tests {
+			tt.transfer.UserID = testUserID
+			_, err := accountService.CreateTransfer(context.Background(), tt.transfer)
+			assert.Equal(t, tt.err, err)
+		}
+		mockTransferRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+}
+
+func TestExpenseService_CreateExpense_Account(t *testing.T) {
+	mockAccountRepo := new(MockAccountRepository)
+	mockCategoryRepo := new(MockCategoryRepositoryForExpense)
+	mockExpenseRepo := new(MockExpenseRepository)
+	expenseService := NewExpenseService(fakeTxManager{}, mockExpenseRepo, mockCategoryRepo, new(MockBudgetRepositoryForExpense),
+		newDefaultPaymentMethodRepository(), mockAccountRepo, newTestExchangeRateService())
+
+	// Account 7 belongs to another user
+	accountID := 7
+	mockAccountRepo.On("GetByID", testUserID, accountID).Return(nil, domain.ErrNotFound)
+
+	_, err := expenseService.CreateExpense(context.Background(), &domain.Expense{
+		UserID:      testUserID,
+		CategoryID:  1,
+		Amount:      amount("250.00"),
+		PaymentMode: domain.PaymentModeUPI,
+		AccountID:   &accountID,
+	})
+	assert.Equal(t, domain.ErrInvalidAccount, err)
+	mockExpenseRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"expense-tracker-api/domain"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 500
+)
+
+type AuditService struct {
+	auditRepo domain.AuditRepository
+}
+
+// NewAuditService creates a new audit log service
+func NewAuditService(auditRepo domain.AuditRepository) *AuditService {
+	return &AuditService{auditRepo: auditRepo}
+}
+
+// GetEvents retrieves the user's audit events, newest first. An entity ID
+// only makes sense together with the entity it belongs to.
+func (s *AuditService) GetEvents(ctx context.Context, userID int, filter domain.AuditFilter) ([]*domain.AuditEvent, error) {
+	if filter.Entity != "" && !filter.Entity.IsValid() {
+		return nil, domain.ErrInvalidInput
+	}
+	if filter.EntityID < 0 || (filter.EntityID != 0 && filter.Entity == "") || filter.Limit < 0 {
+		return nil, domain.ErrInvalidInput
+	}
+
+	if filter.Limit == 0 {
+		filter.Limit = defaultAuditPageSize
+	}
+	if filter.Limit > maxAuditPageSize {
+		filter.Limit = maxAuditPageSize
+	}
+	return s.auditRepo.GetAll(ctx, userID, &filter)
+}
+
+// GetHistory retrieves every change to one record, newest first. The history
+// outlives the record, so a deleted record still has one.
+func (s *AuditService) GetHistory(ctx context.Context, userID int, entity domain.AuditEntity, id int) ([]*domain.AuditEvent, error) {
+	return s.GetEvents(ctx, userID, domain.AuditFilter{Entity: entity, EntityID: id, Limit: maxAuditPageSize})
+}
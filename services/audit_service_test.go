@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"expense-tracker-api/domain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAuditRepository is a mock implementation of AuditRepository
+type MockAuditRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditRepository) GetAll(ctx context.Context, userID int, filter *domain.AuditFilter) ([]*domain.AuditEvent, error) {
+	args := m.Called(userID, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.AuditEvent), args.Error(1)
+}
+
+func TestAuditService_GetEvents(t *testing.T) {
+	t.Run("Default page size", func(t *testing.T) {
+		mockRepo := new(MockAuditRepository)
+		auditService := NewAuditService(mockRepo)
+
+		mockRepo.On("GetAll", testUserID, &domain.AuditFilter{Entity: domain.AuditEntityExpense, EntityID: 5, Limit: 50}).
+			Return([]*domain.AuditEvent{}, nil)
+
+		_, err := auditService.GetEvents(context.Background(), testUserID, domain.AuditFilter{Entity: domain.AuditEntityExpense, EntityID: 5})
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Invalid filters", func(t *testing.T) {
+		mockRepo := new(MockAuditRepository)
+		auditService := NewAuditService(mockRepo)
+
+		tests := []domain.AuditFilter{
+			{Entity: "user"},
+			{EntityID: 5},
+			{Entity: domain.AuditEntityBudget, EntityID: -1},
+			{Limit: -1},
+		}
+		for _, filter := range tests {
+			_, err := auditService.GetEvents(context.Background(), testUserID, filter)
+			assert.Equal(t, domain.ErrInvalidInput, err, filter)
+		}
+		mockRepo.AssertNotCalled(t, "GetAll", mock.Anything, mock.Anything)
+	})
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	t.Run("Only changed fields are kept", func(t *testing.T) {
+		categoryID := 3
+		before := &domain.Budget{ID: 1, CategoryID: &categoryID, Month: 3, Year: 2024, BudgetAmount: amount("5000.00")}
+		after := *before
+		after.BudgetAmount = amount("6500.00")
+		after.CategoryID = nil
+
+		changes, err := domain.DiffSnapshots(before, &after)
+		assert.NoError(t, err)
+		assert.Len(t, changes, 2)
+		assert.JSONEq(t, `5000.00`, string(changes["budget_amount"].From))
+		assert.JSONEq(t, `6500.00`, string(changes["budget_amount"].To))
+		// category_id is left out of the JSON when nil, so clearing it shows as null
+		assert.JSONEq(t, `3`, string(changes["category_id"].From))
+		assert.JSONEq(t, `null`, string(changes["category_id"].To))
+	})
+
+	t.Run("A new record has no previous values", func(t *testing.T) {
+		event, err := domain.NewAuditEvent(testUserID, domain.AuditEntityExpense, 7, domain.AuditActionCreate, nil,
+			&domain.Expense{ID: 7, Amount: amount("250.00"), Tags: []string{"lunch"}})
+		assert.NoError(t, err)
+		assert.Equal(t, testUserID, *event.ActorID)
+		assert.Nil(t, event.Changes["amount"].From)
+
+		data, err := json.Marshal(event.Changes["tags"])
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"to": ["lunch"]}`, string(data))
+	})
+}
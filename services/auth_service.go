@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"expense-tracker-api/domain"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	accessTokenTTL    = 15 * time.Minute
+	refreshTokenTTL   = 7 * 24 * time.Hour
+	minPasswordLength = 8
+	// maxPasswordLength is the most bcrypt hashes, in bytes
+	maxPasswordLength = 72
+
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+)
+
+// tokenClaims are the claims carried by access and refresh tokens.
+// The user ID is stored in the standard subject claim.
+type tokenClaims struct {
+	TokenType string `json:"token_type"`
+	jwt.RegisteredClaims
+}
+
+type AuthService struct {
+	userRepo domain.UserRepository
+	secret   []byte
+}
+
+// NewAuthService creates a new auth service that signs tokens with secret
+func NewAuthService(userRepo domain.UserRepository, secret []byte) *AuthService {
+	return &AuthService{
+		userRepo: userRepo,
+		secret:   secret,
+	}
+}
+
+// Register creates a new user with a bcrypt-hashed password
+func (s *AuthService) Register(ctx context.Context, email, password string) (*domain.User, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if _, err := mail.ParseAddress(email); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return nil, domain.ErrInvalidInput
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &domain.User{
+		Email:        email,
+		PasswordHash: string(hash),
+	}
+
+	err = s.userRepo.Create(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// Login checks the credentials and issues a new token pair
+func (s *AuthService) Login(ctx context.Context, email, password string) (*domain.TokenPair, error) {
+	user, err := s.userRepo.GetByEmail(ctx, strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	return s.issueTokens(user.ID)
+}
+
+// Refresh exchanges a valid refresh token for a new token pair
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*domain.TokenPair, error) {
+	userID, err := s.parseToken(refreshToken, tokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	// The account may have been removed since the token was issued
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, domain.ErrUnauthorized
+	}
+
+	return s.issueTokens(userID)
+}
+
+// Authenticate validates an access token and returns the user ID it was issued to
+func (s *AuthService) Authenticate(accessToken string) (int, error) {
+	return s.parseToken(accessToken, tokenTypeAccess)
+}
+
+// issueTokens signs a new access and refresh token for the user
+func (s *AuthService) issueTokens(userID int) (*domain.TokenPair, error) {
+	now := time.Now()
+	accessExpiresAt := now.Add(accessTokenTTL)
+
+	accessToken, err := s.signToken(userID, tokenTypeAccess, now, accessExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := s.signToken(userID, tokenTypeRefresh, now, now.Add(refreshTokenTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresAt:    accessExpiresAt,
+	}, nil
+}
+
+func (s *AuthService) signToken(userID int, tokenType string, issuedAt, expiresAt time.Time) (string, error) {
+	claims := tokenClaims{
+		TokenType: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(userID),
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+}
+
+// parseToken verifies the signature, expiry and type of a token and returns its user ID
+func (s *AuthService) parseToken(tokenString, tokenType string) (int, error) {
+	claims := &tokenClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return s.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || claims.TokenType != tokenType {
+		return 0, domain.ErrUnauthorized
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return 0, domain.ErrUnauthorized
+	}
+	return userID, nil
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"expense-tracker-api/domain"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MockUserRepository is a mock implementation of UserRepository
+type MockUserRepository struct {
+	mock.Mock
+}
+
+func (m *MockUserRepository) Create(ctx context.Context, user *domain.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) GetByID(ctx context.Context, id int) (*domain.User, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	args := m.Called(email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func newTestUser(t *testing.T, password string) *domain.User {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	assert.NoError(t, err)
+	return &domain.User{ID: testUserID, Email: "jane@example.com", PasswordHash: string(hash)}
+}
+
+func TestAuthService_Register(t *testing.T) {
+	t.Run("Successful registration", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		authService := NewAuthService(mockUserRepo, []byte("secret"))
+
+		mockUserRepo.On("Create", mock.AnythingOfType("*domain.User")).Return(nil)
+
+		user, err := authService.Register(context.Background(), " Jane@Example.com ", "correct horse")
+		assert.NoError(t, err)
+		assert.Equal(t, "jane@example.com", user.Email)
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("correct horse")))
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("Duplicate email", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		authService := NewAuthService(mockUserRepo, []byte("secret"))
+
+		mockUserRepo.On("Create", mock.AnythingOfType("*domain.User")).Return(domain.ErrEmailAlreadyExists)
+
+		user, err := authService.Register(context.Background(), "jane@example.com", "correct horse")
+		assert.Nil(t, user)
+		assert.Equal(t, domain.ErrEmailAlreadyExists, err)
+	})
+
+	t.Run("Short password", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		authService := NewAuthService(mockUserRepo, []byte("secret"))
+
+		user, err := authService.Register(context.Background(), "jane@example.com", "short")
+		assert.Nil(t, user)
+		assert.Equal(t, domain.ErrInvalidInput, err)
+		mockUserRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+
+	t.Run("Password longer than bcrypt takes", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		authService := NewAuthService(mockUserRepo, []byte("secret"))
+
+		user, err := authService.Register(context.Background(), "jane@example.com", strings.Repeat("a", maxPasswordLength+1))
+		assert.Nil(t, user)
+		assert.Equal(t, domain.ErrInvalidInput, err)
+		mockUserRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+}
+
+func TestAuthService_Login(t *testing.T) {
+	t.Run("Tokens identify the user", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		authService := NewAuthService(mockUserRepo, []byte("secret"))
+
+		mockUserRepo.On("GetByEmail", "jane@example.com").Return(newTestUser(t, "correct horse"), nil)
+
+		tokens, err := authService.Login(context.Background(), "jane@example.com", "correct horse")
+		assert.NoError(t, err)
+		assert.Equal(t, "Bearer", tokens.TokenType)
+
+		userID, err := authService.Authenticate(tokens.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, testUserID, userID)
+	})
+
+	t.Run("Wrong password", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		authService := NewAuthService(mockUserRepo, []byte("secret"))
+
+		mockUserRepo.On("GetByEmail", "jane@example.com").Return(newTestUser(t, "correct horse"), nil)
+
+		tokens, err := authService.Login(context.Background(), "jane@example.com", "battery staple")
+		assert.Nil(t, tokens)
+		assert.Equal(t, domain.ErrInvalidCredentials, err)
+	})
+}
+
+func TestAuthService_Tokens(t *testing.T) {
+	t.Run("Refresh token cannot be used as access token", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		authService := NewAuthService(mockUserRepo, []byte("secret"))
+
+		mockUserRepo.On("GetByEmail", "jane@example.com").Return(newTestUser(t, "correct horse"), nil)
+		tokens, err := authService.Login(context.Background(), "jane@example.com", "correct horse")
+		assert.NoError(t, err)
+
+		_, err = authService.Authenticate(tokens.RefreshToken)
+		assert.Equal(t, domain.ErrUnauthorized, err)
+
+		_, err = authService.Refresh(context.Background(), tokens.AccessToken)
+		assert.Equal(t, domain.ErrUnauthorized, err)
+	})
+
+	t.Run("Refresh issues a new pair", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		authService := NewAuthService(mockUserRepo, []byte("secret"))
+
+		user := newTestUser(t, "correct horse")
+		mockUserRepo.On("GetByEmail", "jane@example.com").Return(user, nil)
+		mockUserRepo.On("GetByID", testUserID).Return(user, nil)
+		tokens, err := authService.Login(context.Background(), "jane@example.com", "correct horse")
+		assert.NoError(t, err)
+
+		refreshed, err := authService.Refresh(context.Background(), tokens.RefreshToken)
+		assert.NoError(t, err)
+		userID, err := authService.Authenticate(refreshed.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, testUserID, userID)
+	})
+
+	t.Run("Token signed with another secret", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		authService := NewAuthService(mockUserRepo, []byte("secret"))
+		otherService := NewAuthService(mockUserRepo, []byte("other"))
+
+		mockUserRepo.On("GetByEmail", "jane@example.com").Return(newTestUser(t, "correct horse"), nil)
+		tokens, err := otherService.Login(context.Background(), "jane@example.com", "correct horse")
+		assert.NoError(t, err)
+
+		_, err = authService.Authenticate(tokens.AccessToken)
+		assert.Equal(t, domain.ErrUnauthorized, err)
+	})
+}
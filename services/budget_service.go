@@ -1,24 +1,44 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"expense-tracker-api/domain"
 )
 
 type BudgetService struct {
-	budgetRepo  domain.BudgetRepository
-	expenseRepo domain.ExpenseRepository
+	txManager    domain.TxManager
+	budgetRepo   domain.BudgetRepository
+	expenseRepo  domain.ExpenseRepository
+	categoryRepo domain.CategoryRepository
+	incomeRepo   domain.IncomeRepository
 }
 
 // NewBudgetService creates a new budget service
-func NewBudgetService(budgetRepo domain.BudgetRepository, expenseRepo domain.ExpenseRepository) *BudgetService {
+func NewBudgetService(txManager domain.TxManager, budgetRepo domain.BudgetRepository, expenseRepo domain.ExpenseRepository, categoryRepo domain.CategoryRepository,
+	incomeRepo domain.IncomeRepository) *BudgetService {
 	return &BudgetService{
-		budgetRepo:  budgetRepo,
-		expenseRepo: expenseRepo,
+		txManager:    txManager,
+		budgetRepo:   budgetRepo,
+		expenseRepo:  expenseRepo,
+		categoryRepo: categoryRepo,
+		incomeRepo:   incomeRepo,
 	}
 }
 
-// CreateOrUpdateBudget creates or updates a monthly budget
-func (s *BudgetService) CreateOrUpdateBudget(month, year int, budgetAmount float64) (*domain.Budget, error) {
+// withTx returns a copy of the service whose repositories are bound to tx
+func (s *BudgetService) withTx(tx domain.Tx) *BudgetService {
+	bound := *s
+	bound.budgetRepo = s.budgetRepo.WithTx(tx)
+	bound.expenseRepo = s.expenseRepo.WithTx(tx)
+	bound.categoryRepo = s.categoryRepo.WithTx(tx)
+	bound.incomeRepo = s.incomeRepo.WithTx(tx)
+	return &bound
+}
+
+// CreateOrUpdateBudget creates or updates a monthly budget.
+// A nil categoryID sets the overall budget for the month.
+func (s *BudgetService) CreateOrUpdateBudget(ctx context.Context, userID, month, year int, categoryID *int, budgetAmount domain.Money) (*domain.Budget, error) {
 	if month < 1 || month > 12 {
 		return nil, domain.ErrInvalidInput
 	}
@@ -27,12 +47,40 @@ func (s *BudgetService) CreateOrUpdateBudget(month, year int, budgetAmount float
 		return nil, domain.ErrInvalidInput
 	}
 
+	save := func(tx domain.Tx) (*domain.Budget, error) {
+		return s.withTx(tx).saveBudget(ctx, userID, month, year, categoryID, budgetAmount)
+	}
+	budget, err := withinTx(ctx, s.txManager, save)
+	if errors.Is(err, domain.ErrAlreadyExists) {
+		// A concurrent request created the budget after this one looked for it,
+		// so looking again finds that budget to update
+		budget, err = withinTx(ctx, s.txManager, save)
+	}
+	return budget, err
+}
+
+// saveBudget updates the month's budget if there is one and otherwise creates it
+func (s *BudgetService) saveBudget(ctx context.Context, userID, month, year int, categoryID *int, budgetAmount domain.Money) (*domain.Budget, error) {
 	// Check if budget already exists
-	existingBudget, err := s.budgetRepo.GetByMonth(month, year)
-	if err == nil && existingBudget != nil {
+	var existingBudget *domain.Budget
+	var err error
+	if categoryID != nil {
+		if _, err := s.categoryRepo.GetByID(ctx, userID, *categoryID); errors.Is(err, domain.ErrNotFound) {
+			return nil, domain.ErrInvalidCategory
+		} else if err != nil {
+			return nil, err
+		}
+		existingBudget, err = s.budgetRepo.GetByMonthAndCategory(ctx, userID, month, year, *categoryID)
+	} else {
+		existingBudget, err = s.budgetRepo.GetByMonth(ctx, userID, month, year)
+	}
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return nil, err
+	}
+	if existingBudget != nil {
 		// Update existing budget
 		existingBudget.BudgetAmount = budgetAmount
-		err = s.budgetRepo.Update(existingBudget)
+		err = s.budgetRepo.Update(ctx, existingBudget)
 		if err != nil {
 			return nil, err
 		}
@@ -41,12 +89,14 @@ func (s *BudgetService) CreateOrUpdateBudget(month, year int, budgetAmount float
 
 	// Create new budget
 	budget := &domain.Budget{
+		UserID:       userID,
+		CategoryID:   categoryID,
 		Month:        month,
 		Year:         year,
 		BudgetAmount: budgetAmount,
 	}
 
-	err = s.budgetRepo.Create(budget)
+	err = s.budgetRepo.Create(ctx, budget)
 	if err != nil {
 		return nil, err
 	}
@@ -55,23 +105,82 @@ func (s *BudgetService) CreateOrUpdateBudget(month, year int, budgetAmount float
 }
 
 // GetBudgets retrieves all budgets
-func (s *BudgetService) GetBudgets() ([]*domain.Budget, error) {
-	return s.budgetRepo.GetAll()
+func (s *BudgetService) GetBudgets(ctx context.Context, userID int) ([]*domain.Budget, error) {
+	return s.budgetRepo.GetAll(ctx, userID)
 }
 
-// GetBudgetByMonth retrieves a budget for a specific month with status
-func (s *BudgetService) GetBudgetByMonth(month, year int) (*domain.BudgetStatus, error) {
-	budget, err := s.budgetRepo.GetByMonth(month, year)
+// GetBudgetByMonth retrieves the budgets for a specific month with status.
+// The overall figure is listed next to each category budget set for the month.
+// With includeIncome, spending is also given as a share of the month's income.
+func (s *BudgetService) GetBudgetByMonth(ctx context.Context, userID, month, year int, includeIncome bool) (*domain.BudgetStatus, error) {
+	// The overall budget is optional when category budgets are set
+	budget, err := s.budgetRepo.GetByMonth(ctx, userID, month, year)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return nil, err
+	}
+
+	categoryBudgets, err := s.budgetRepo.GetCategoryBudgetsByMonth(ctx, userID, month, year)
 	if err != nil {
+		return nil, err
+	}
+
+	if budget == nil && len(categoryBudgets) == 0 {
 		return nil, domain.ErrNotFound
 	}
 
 	// Calculate spent amount for the month
-	spentAmount, err := s.expenseRepo.GetTotalByMonth(month, year)
+	spentAmount, err := s.expenseRepo.GetTotalByMonth(ctx, userID, month, year)
 	if err != nil {
 		return nil, err
 	}
 
+	budgetStatus := newBudgetStatus(budget, spentAmount)
+
+	if len(categoryBudgets) > 0 {
+		categoryTotals, err := s.expenseRepo.GetTotalsByCategoryForMonth(ctx, userID, month, year)
+		if err != nil {
+			return nil, err
+		}
+		for _, categoryBudget := range categoryBudgets {
+			budgetStatus.Categories = append(budgetStatus.Categories,
+				newBudgetStatus(categoryBudget, categoryTotals[*categoryBudget.CategoryID]))
+		}
+	}
+
+	if includeIncome {
+		income, err := s.incomeRepo.GetTotalByMonth(ctx, userID, month, year)
+		if err != nil {
+			return nil, err
+		}
+		setIncomeShare(budgetStatus, income)
+		for _, categoryStatus := range budgetStatus.Categories {
+			setIncomeShare(categoryStatus, income)
+		}
+	}
+
+	return budgetStatus, nil
+}
+
+// setIncomeShare records the month's income on a status and the spending as a
+// percentage of it, leaving the percentage out when there was no income
+func setIncomeShare(status *domain.BudgetStatus, income domain.Money) {
+	status.Income = &income
+	if income > 0 {
+		percent := status.SpentAmount.Percent(income)
+		status.SpentPercentOfIncome = &percent
+	}
+}
+
+// newBudgetStatus compares spending against a budget, which may be nil
+// when only category budgets are set for the month
+func newBudgetStatus(budget *domain.Budget, spentAmount domain.Money) *domain.BudgetStatus {
+	if budget == nil {
+		return &domain.BudgetStatus{
+			SpentAmount: spentAmount,
+			Status:      "no_budget",
+		}
+	}
+
 	remaining := budget.BudgetAmount - spentAmount
 	status := "within_budget"
 	if spentAmount > budget.BudgetAmount {
@@ -83,16 +192,20 @@ func (s *BudgetService) GetBudgetByMonth(month, year int) (*domain.BudgetStatus,
 		SpentAmount: spentAmount,
 		Remaining:   remaining,
 		Status:      status,
-	}, nil
+	}
 }
 
 // DeleteBudget deletes a budget
-func (s *BudgetService) DeleteBudget(budgetID int) error {
-	// Verify budget exists
-	_, err := s.budgetRepo.GetByID(budgetID)
-	if err != nil {
-		return domain.ErrNotFound
-	}
+func (s *BudgetService) DeleteBudget(ctx context.Context, userID, budgetID int) error {
+	return s.txManager.WithinTx(ctx, func(tx domain.Tx) error {
+		budgetRepo := s.budgetRepo.WithTx(tx)
+
+		// Verify budget exists
+		_, err := budgetRepo.GetByID(ctx, userID, budgetID)
+		if err != nil {
+			return domain.ErrNotFound
+		}
 
-	return s.budgetRepo.Delete(budgetID)
+		return budgetRepo.Delete(ctx, userID, budgetID)
+	})
 }
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"expense-tracker-api/domain"
 	"testing"
 
@@ -13,42 +15,63 @@ type MockBudgetRepository struct {
 	mock.Mock
 }
 
-func (m *MockBudgetRepository) Create(budget *domain.Budget) error {
+// WithTx returns the mock itself, so expectations hold inside and outside a transaction
+func (m *MockBudgetRepository) WithTx(tx domain.Tx) domain.BudgetRepository {
+	return m
+}
+
+func (m *MockBudgetRepository) Create(ctx context.Context, budget *domain.Budget) error {
 	args := m.Called(budget)
 	return args.Error(0)
 }
 
-func (m *MockBudgetRepository) GetByID(id int) (*domain.Budget, error) {
-	args := m.Called(id)
+func (m *MockBudgetRepository) GetByID(ctx context.Context, userID, id int) (*domain.Budget, error) {
+	args := m.Called(userID, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*domain.Budget), args.Error(1)
 }
 
-func (m *MockBudgetRepository) GetAll() ([]*domain.Budget, error) {
-	args := m.Called()
+func (m *MockBudgetRepository) GetAll(ctx context.Context, userID int) ([]*domain.Budget, error) {
+	args := m.Called(userID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).([]*domain.Budget), args.Error(1)
 }
 
-func (m *MockBudgetRepository) GetByMonth(month, year int) (*domain.Budget, error) {
-	args := m.Called(month, year)
+func (m *MockBudgetRepository) GetByMonth(ctx context.Context, userID, month, year int) (*domain.Budget, error) {
+	args := m.Called(userID, month, year)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*domain.Budget), args.Error(1)
 }
 
-func (m *MockBudgetRepository) Update(budget *domain.Budget) error {
+func (m *MockBudgetRepository) GetByMonthAndCategory(ctx context.Context, userID, month, year, categoryID int) (*domain.Budget, error) {
+	args := m.Called(userID, month, year, categoryID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Budget), args.Error(1)
+}
+
+func (m *MockBudgetRepository) GetCategoryBudgetsByMonth(ctx context.Context, userID, month, year int) ([]*domain.Budget, error) {
+	args := m.Called(userID, month, year)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Budget), args.Error(1)
+}
+
+func (m *MockBudgetRepository) Update(ctx context.Context, budget *domain.Budget) error {
 	args := m.Called(budget)
 	return args.Error(0)
 }
 
-func (m *MockBudgetRepository) Delete(id int) error {
-	args := m.Called(id)
+func (m *MockBudgetRepository) Delete(ctx context.Context, userID, id int) error {
+	args := m.Called(userID, id)
 	return args.Error(0)
 }
 
@@ -57,78 +80,171 @@ type MockExpenseRepositoryForBudget struct {
 	mock.Mock
 }
 
-func (m *MockExpenseRepositoryForBudget) Create(expense *domain.Expense) error {
+// WithTx returns the mock itself, so expectations hold inside and outside a transaction
+func (m *MockExpenseRepositoryForBudget) WithTx(tx domain.Tx) domain.ExpenseRepository {
+	return m
+}
+
+func (m *MockExpenseRepositoryForBudget) Create(ctx context.Context, expense *domain.Expense) error {
 	args := m.Called(expense)
 	return args.Error(0)
 }
 
-func (m *MockExpenseRepositoryForBudget) GetByID(id int) (*domain.Expense, error) {
-	args := m.Called(id)
+func (m *MockExpenseRepositoryForBudget) CreateBatch(ctx context.Context, expenses []*domain.Expense) error {
+	args := m.Called(expenses)
+	return args.Error(0)
+}
+
+func (m *MockExpenseRepositoryForBudget) GetByID(ctx context.Context, userID, id int) (*domain.Expense, error) {
+	args := m.Called(userID, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*domain.Expense), args.Error(1)
 }
 
-func (m *MockExpenseRepositoryForBudget) GetAll(filter *domain.ExpenseFilter) ([]*domain.Expense, error) {
-	args := m.Called(filter)
+func (m *MockExpenseRepositoryForBudget) GetAll(ctx context.Context, userID int, filter *domain.ExpenseFilter) ([]*domain.Expense, error) {
+	args := m.Called(userID, filter)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).([]*domain.Expense), args.Error(1)
 }
 
-func (m *MockExpenseRepositoryForBudget) Update(expense *domain.Expense) error {
+func (m *MockExpenseRepositoryForBudget) Count(ctx context.Context, userID int, filter *domain.ExpenseFilter) (int, error) {
+	args := m.Called(userID, filter)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockExpenseRepositoryForBudget) StreamAll(ctx context.Context, userID int, filter *domain.ExpenseFilter, fn func(row *domain.ExpenseExportRow) error) error {
+	args := m.Called(userID, filter, fn)
+	return args.Error(0)
+}
+
+func (m *MockExpenseRepositoryForBudget) Update(ctx context.Context, expense *domain.Expense) error {
 	args := m.Called(expense)
 	return args.Error(0)
 }
 
-func (m *MockExpenseRepositoryForBudget) Delete(id int) error {
-	args := m.Called(id)
+func (m *MockExpenseRepositoryForBudget) Delete(ctx context.Context, userID, id int) error {
+	args := m.Called(userID, id)
 	return args.Error(0)
 }
 
-func (m *MockExpenseRepositoryForBudget) GetTotalByMonth(month, year int) (float64, error) {
-	args := m.Called(month, year)
-	return args.Get(0).(float64), args.Error(1)
+func (m *MockExpenseRepositoryForBudget) GetTotalByMonth(ctx context.Context, userID, month, year int) (domain.Money, error) {
+	args := m.Called(userID, month, year)
+	return args.Get(0).(domain.Money), args.Error(1)
+}
+
+func (m *MockExpenseRepositoryForBudget) GetTotalsByCategoryForMonth(ctx context.Context, userID, month, year int) (map[int]domain.Money, error) {
+	args := m.Called(userID, month, year)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[int]domain.Money), args.Error(1)
 }
 
 func TestBudgetService_CreateOrUpdateBudget(t *testing.T) {
 	t.Run("Successful creation", func(t *testing.T) {
 		mockBudgetRepo := new(MockBudgetRepository)
 		mockExpenseRepo := new(MockExpenseRepositoryForBudget)
-		budgetService := NewBudgetService(mockBudgetRepo, mockExpenseRepo)
+		mockCategoryRepo := new(MockCategoryRepository)
+		budgetService := NewBudgetService(fakeTxManager{}, mockBudgetRepo, mockExpenseRepo, mockCategoryRepo, new(MockIncomeRepository))
 
-		mockBudgetRepo.On("GetByMonth", 1, 2024).Return(nil, domain.ErrNotFound)
+		mockBudgetRepo.On("GetByMonth", testUserID, 1, 2024).Return(nil, domain.ErrNotFound)
 		mockBudgetRepo.On("Create", mock.AnythingOfType("*domain.Budget")).Return(nil).Run(func(args mock.Arguments) {
 			budget := args.Get(0).(*domain.Budget)
 			budget.ID = 1
 		})
 
-		budget, err := budgetService.CreateOrUpdateBudget(1, 2024, 5000.0)
+		budget, err := budgetService.CreateOrUpdateBudget(context.Background(), testUserID, 1, 2024, nil, amount("5000.00"))
 		assert.NoError(t, err)
 		assert.NotNil(t, budget)
-		assert.Equal(t, 5000.0, budget.BudgetAmount)
+		assert.Equal(t, amount("5000.00"), budget.BudgetAmount)
 		mockBudgetRepo.AssertExpectations(t)
 	})
 
 	t.Run("Invalid month", func(t *testing.T) {
 		mockBudgetRepo := new(MockBudgetRepository)
 		mockExpenseRepo := new(MockExpenseRepositoryForBudget)
-		budgetService := NewBudgetService(mockBudgetRepo, mockExpenseRepo)
+		mockCategoryRepo := new(MockCategoryRepository)
+		budgetService := NewBudgetService(fakeTxManager{}, mockBudgetRepo, mockExpenseRepo, mockCategoryRepo, new(MockIncomeRepository))
 
-		budget, err := budgetService.CreateOrUpdateBudget(13, 2024, 5000.0)
+		budget, err := budgetService.CreateOrUpdateBudget(context.Background(), testUserID, 13, 2024, nil, amount("5000.00"))
 		assert.Error(t, err)
 		assert.Nil(t, budget)
 		assert.Equal(t, domain.ErrInvalidInput, err)
 	})
 
+	t.Run("Successful category budget creation", func(t *testing.T) {
+		mockBudgetRepo := new(MockBudgetRepository)
+		mockExpenseRepo := new(MockExpenseRepositoryForBudget)
+		mockCategoryRepo := new(MockCategoryRepository)
+		budgetService := NewBudgetService(fakeTxManager{}, mockBudgetRepo, mockExpenseRepo, mockCategoryRepo, new(MockIncomeRepository))
+
+		categoryID := 2
+		mockCategoryRepo.On("GetByID", testUserID, 2).Return(&domain.Category{ID: 2, Name: "Food"}, nil)
+		mockBudgetRepo.On("GetByMonthAndCategory", testUserID, 1, 2024, 2).Return(nil, domain.ErrNotFound)
+		mockBudgetRepo.On("Create", mock.AnythingOfType("*domain.Budget")).Return(nil)
+
+		budget, err := budgetService.CreateOrUpdateBudget(context.Background(), testUserID, 1, 2024, &categoryID, amount("2000.00"))
+		assert.NoError(t, err)
+		assert.NotNil(t, budget)
+		assert.Equal(t, 2, *budget.CategoryID)
+		assert.Equal(t, amount("2000.00"), budget.BudgetAmount)
+		mockBudgetRepo.AssertExpectations(t)
+		mockCategoryRepo.AssertExpectations(t)
+	})
+
+	t.Run("Category budget with unknown category", func(t *testing.T) {
+		mockBudgetRepo := new(MockBudgetRepository)
+		mockExpenseRepo := new(MockExpenseRepositoryForBudget)
+		mockCategoryRepo := new(MockCategoryRepository)
+		budgetService := NewBudgetService(fakeTxManager{}, mockBudgetRepo, mockExpenseRepo, mockCategoryRepo, new(MockIncomeRepository))
+
+		categoryID := 99
+		mockCategoryRepo.On("GetByID", testUserID, 99).Return(nil, domain.ErrNotFound)
+
+		budget, err := budgetService.CreateOrUpdateBudget(context.Background(), testUserID, 1, 2024, &categoryID, amount("2000.00"))
+		assert.Error(t, err)
+		assert.Nil(t, budget)
+		assert.Equal(t, domain.ErrInvalidCategory, err)
+		mockCategoryRepo.AssertExpectations(t)
+	})
+
+	t.Run("Category lookup fails", func(t *testing.T) {
+		mockBudgetRepo := new(MockBudgetRepository)
+		mockCategoryRepo := new(MockCategoryRepository)
+		budgetService := NewBudgetService(fakeTxManager{}, mockBudgetRepo, new(MockExpenseRepositoryForBudget), mockCategoryRepo, new(MockIncomeRepository))
+
+		categoryID := 2
+		lookupErr := errors.New("connection reset")
+		mockCategoryRepo.On("GetByID", testUserID, 2).Return(nil, lookupErr)
+
+		_, err := budgetService.CreateOrUpdateBudget(context.Background(), testUserID, 1, 2024, &categoryID, amount("2000.00"))
+		assert.ErrorIs(t, err, lookupErr)
+		mockBudgetRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+
+	t.Run("Budget lookup fails", func(t *testing.T) {
+		mockBudgetRepo := new(MockBudgetRepository)
+		budgetService := NewBudgetService(fakeTxManager{}, mockBudgetRepo, new(MockExpenseRepositoryForBudget), new(MockCategoryRepository), new(MockIncomeRepository))
+
+		lookupErr := errors.New("connection reset")
+		mockBudgetRepo.On("GetByMonth", testUserID, 1, 2024).Return(nil, lookupErr)
+
+		_, err := budgetService.CreateOrUpdateBudget(context.Background(), testUserID, 1, 2024, nil, amount("5000.00"))
+		assert.ErrorIs(t, err, lookupErr)
+		mockBudgetRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+
 	t.Run("Negative budget amount", func(t *testing.T) {
 		mockBudgetRepo := new(MockBudgetRepository)
 		mockExpenseRepo := new(MockExpenseRepositoryForBudget)
-		budgetService := NewBudgetService(mockBudgetRepo, mockExpenseRepo)
+		mockCategoryRepo := new(MockCategoryRepository)
+		budgetService := NewBudgetService(fakeTxManager{}, mockBudgetRepo, mockExpenseRepo, mockCategoryRepo, new(MockIncomeRepository))
 
-		budget, err := budgetService.CreateOrUpdateBudget(1, 2024, -100.0)
+		budget, err := budgetService.CreateOrUpdateBudget(context.Background(), testUserID, 1, 2024, nil, amount("-100.00"))
 		assert.Error(t, err)
 		assert.Nil(t, budget)
 		assert.Equal(t, domain.ErrInvalidInput, err)
@@ -139,22 +255,24 @@ func TestBudgetService_GetBudgetByMonth(t *testing.T) {
 	t.Run("Budget within limit", func(t *testing.T) {
 		mockBudgetRepo := new(MockBudgetRepository)
 		mockExpenseRepo := new(MockExpenseRepositoryForBudget)
-		budgetService := NewBudgetService(mockBudgetRepo, mockExpenseRepo)
+		mockCategoryRepo := new(MockCategoryRepository)
+		budgetService := NewBudgetService(fakeTxManager{}, mockBudgetRepo, mockExpenseRepo, mockCategoryRepo, new(MockIncomeRepository))
 
 		budget := &domain.Budget{
 			ID:           1,
 			Month:        1,
 			Year:         2024,
-			BudgetAmount: 5000.0,
+			BudgetAmount: amount("5000.00"),
 		}
-		mockBudgetRepo.On("GetByMonth", 1, 2024).Return(budget, nil)
-		mockExpenseRepo.On("GetTotalByMonth", 1, 2024).Return(3000.0, nil)
+		mockBudgetRepo.On("GetByMonth", testUserID, 1, 2024).Return(budget, nil)
+		mockBudgetRepo.On("GetCategoryBudgetsByMonth", testUserID, 1, 2024).Return([]*domain.Budget{}, nil)
+		mockExpenseRepo.On("GetTotalByMonth", testUserID, 1, 2024).Return(amount("3000.00"), nil)
 
-		budgetStatus, err := budgetService.GetBudgetByMonth(1, 2024)
+		budgetStatus, err := budgetService.GetBudgetByMonth(context.Background(), testUserID, 1, 2024, false)
 		assert.NoError(t, err)
 		assert.NotNil(t, budgetStatus)
 		assert.Equal(t, "within_budget", budgetStatus.Status)
-		assert.Equal(t, 2000.0, budgetStatus.Remaining)
+		assert.Equal(t, amount("2000.00"), budgetStatus.Remaining)
 		mockBudgetRepo.AssertExpectations(t)
 		mockExpenseRepo.AssertExpectations(t)
 	})
@@ -162,23 +280,136 @@ func TestBudgetService_GetBudgetByMonth(t *testing.T) {
 	t.Run("Budget exceeded", func(t *testing.T) {
 		mockBudgetRepo := new(MockBudgetRepository)
 		mockExpenseRepo := new(MockExpenseRepositoryForBudget)
-		budgetService := NewBudgetService(mockBudgetRepo, mockExpenseRepo)
+		mockCategoryRepo := new(MockCategoryRepository)
+		budgetService := NewBudgetService(fakeTxManager{}, mockBudgetRepo, mockExpenseRepo, mockCategoryRepo, new(MockIncomeRepository))
 
 		budget := &domain.Budget{
 			ID:           1,
 			Month:        1,
 			Year:         2024,
-			BudgetAmount: 5000.0,
+			BudgetAmount: amount("5000.00"),
 		}
-		mockBudgetRepo.On("GetByMonth", 1, 2024).Return(budget, nil)
-		mockExpenseRepo.On("GetTotalByMonth", 1, 2024).Return(6000.0, nil)
+		mockBudgetRepo.On("GetByMonth", testUserID, 1, 2024).Return(budget, nil)
+		mockBudgetRepo.On("GetCategoryBudgetsByMonth", testUserID, 1, 2024).Return([]*domain.Budget{}, nil)
+		mockExpenseRepo.On("GetTotalByMonth", testUserID, 1, 2024).Return(amount("6000.00"), nil)
 
-		budgetStatus, err := budgetService.GetBudgetByMonth(1, 2024)
+		budgetStatus, err := budgetService.GetBudgetByMonth(context.Background(), testUserID, 1, 2024, false)
 		assert.NoError(t, err)
 		assert.NotNil(t, budgetStatus)
 		assert.Equal(t, "exceeded", budgetStatus.Status)
-		assert.Equal(t, -1000.0, budgetStatus.Remaining)
+		assert.Equal(t, amount("-1000.00"), budgetStatus.Remaining)
 		mockBudgetRepo.AssertExpectations(t)
 		mockExpenseRepo.AssertExpectations(t)
 	})
+
+	t.Run("Spending exactly at the budget is within it", func(t *testing.T) {
+		mockBudgetRepo := new(MockBudgetRepository)
+		mockExpenseRepo := new(MockExpenseRepositoryForBudget)
+		mockCategoryRepo := new(MockCategoryRepository)
+		budgetService := NewBudgetService(fakeTxManager{}, mockBudgetRepo, mockExpenseRepo, mockCategoryRepo, new(MockIncomeRepository))
+
+		// 0.1 + 0.2 is not 0.3 in float64; in minor units it is
+		budget := &domain.Budget{ID: 1, Month: 1, Year: 2024, BudgetAmount: amount("0.30")}
+		mockBudgetRepo.On("GetByMonth", testUserID, 1, 2024).Return(budget, nil)
+		mockBudgetRepo.On("GetCategoryBudgetsByMonth", testUserID, 1, 2024).Return([]*domain.Budget{}, nil)
+		mockExpenseRepo.On("GetTotalByMonth", testUserID, 1, 2024).Return(amount("0.10")+amount("0.20"), nil)
+
+		budgetStatus, err := budgetService.GetBudgetByMonth(context.Background(), testUserID, 1, 2024, false)
+		assert.NoError(t, err)
+		assert.Equal(t, "within_budget", budgetStatus.Status)
+		assert.Equal(t, domain.Money(0), budgetStatus.Remaining)
+	})
+	t.Run("Category budgets listed next to overall budget", func(t *testing.T) {
+		mockBudgetRepo := new(MockBudgetRepository)
+		mockExpenseRepo := new(MockExpenseRepositoryForBudget)
+		mockCategoryRepo := new(MockCategoryRepository)
+		budgetService := NewBudgetService(fakeTxManager{}, mockBudgetRepo, mockExpenseRepo, mockCategoryRepo, new(MockIncomeRepository))
+
+		foodID, rentID := 1, 2
+		budget := &domain.Budget{ID: 1, Month: 1, Year: 2024, BudgetAmount: amount("12000.00")}
+		categoryBudgets := []*domain.Budget{
+			{ID: 2, CategoryID: &foodID, Month: 1, Year: 2024, BudgetAmount: amount("6000.00")},
+			{ID: 3, CategoryID: &rentID, Month: 1, Year: 2024, BudgetAmount: amount("5000.00")},
+		}
+		mockBudgetRepo.On("GetByMonth", testUserID, 1, 2024).Return(budget, nil)
+		mockBudgetRepo.On("GetCategoryBudgetsByMonth", testUserID, 1, 2024).Return(categoryBudgets, nil)
+		mockExpenseRepo.On("GetTotalByMonth", testUserID, 1, 2024).Return(amount("11500.00"), nil)
+		mockExpenseRepo.On("GetTotalsByCategoryForMonth", testUserID, 1, 2024).Return(map[int]domain.Money{1: amount("6500.00"), 2: amount("5000.00")}, nil)
+
+		budgetStatus, err := budgetService.GetBudgetByMonth(context.Background(), testUserID, 1, 2024, false)
+		assert.NoError(t, err)
+		assert.Equal(t, "within_budget", budgetStatus.Status)
+		assert.Len(t, budgetStatus.Categories, 2)
+		assert.Equal(t, "exceeded", budgetStatus.Categories[0].Status)
+		assert.Equal(t, amount("-500.00"), budgetStatus.Categories[0].Remaining)
+		assert.Equal(t, "within_budget", budgetStatus.Categories[1].Status)
+		assert.Equal(t, amount("0.00"), budgetStatus.Categories[1].Remaining)
+		mockBudgetRepo.AssertExpectations(t)
+		mockExpenseRepo.AssertExpectations(t)
+	})
+
+	t.Run("Spending as a share of the month's income", func(t *testing.T) {
+		mockBudgetRepo := new(MockBudgetRepository)
+		mockExpenseRepo := new(MockExpenseRepositoryForBudget)
+		mockIncomeRepo := new(MockIncomeRepository)
+		budgetService := NewBudgetService(fakeTxManager{}, mockBudgetRepo, mockExpenseRepo, new(MockCategoryRepository), mockIncomeRepo)
+
+		budget := &domain.Budget{ID: 1, Month: 1, Year: 2024, BudgetAmount: amount("30000.00")}
+		mockBudgetRepo.On("GetByMonth", testUserID, 1, 2024).Return(budget, nil)
+		mockBudgetRepo.On("GetCategoryBudgetsByMonth", testUserID, 1, 2024).Return([]*domain.Budget{}, nil)
+		mockExpenseRepo.On("GetTotalByMonth", testUserID, 1, 2024).Return(amount("20000.00"), nil)
+		mockIncomeRepo.On("GetTotalByMonth", testUserID, 1, 2024).Return(amount("60000.00"), nil)
+
+		budgetStatus, err := budgetService.GetBudgetByMonth(context.Background(), testUserID, 1, 2024, true)
+		assert.NoError(t, err)
+		assert.Equal(t, amount("60000.00"), *budgetStatus.Income)
+		assert.Equal(t, 33.33, *budgetStatus.SpentPercentOfIncome)
+		mockIncomeRepo.AssertExpectations(t)
+	})
+
+	t.Run("Month without income has no percentage", func(t *testing.T) {
+		mockBudgetRepo := new(MockBudgetRepository)
+		mockExpenseRepo := new(MockExpenseRepositoryForBudget)
+		mockIncomeRepo := new(MockIncomeRepository)
+		budgetService := NewBudgetService(fakeTxManager{}, mockBudgetRepo, mockExpenseRepo, new(MockCategoryRepository), mockIncomeRepo)
+
+		budget := &domain.Budget{ID: 1, Month: 1, Year: 2024, BudgetAmount: amount("30000.00")}
+		mockBudgetRepo.On("GetByMonth", testUserID, 1, 2024).Return(budget, nil)
+		mockBudgetRepo.On("GetCategoryBudgetsByMonth", testUserID, 1, 2024).Return([]*domain.Budget{}, nil)
+		mockExpenseRepo.On("GetTotalByMonth", testUserID, 1, 2024).Return(amount("20000.00"), nil)
+		mockIncomeRepo.On("GetTotalByMonth", testUserID, 1, 2024).Return(domain.Money(0), nil)
+
+		budgetStatus, err := budgetService.GetBudgetByMonth(context.Background(), testUserID, 1, 2024, true)
+		assert.NoError(t, err)
+		assert.Equal(t, domain.Money(0), *budgetStatus.Income)
+		assert.Nil(t, budgetStatus.SpentPercentOfIncome)
+	})
+
+	t.Run("No budgets for month", func(t *testing.T) {
+		mockBudgetRepo := new(MockBudgetRepository)
+		mockExpenseRepo := new(MockExpenseRepositoryForBudget)
+		mockCategoryRepo := new(MockCategoryRepository)
+		budgetService := NewBudgetService(fakeTxManager{}, mockBudgetRepo, mockExpenseRepo, mockCategoryRepo, new(MockIncomeRepository))
+
+		mockBudgetRepo.On("GetByMonth", testUserID, 1, 2024).Return(nil, domain.ErrNotFound)
+		mockBudgetRepo.On("GetCategoryBudgetsByMonth", testUserID, 1, 2024).Return([]*domain.Budget{}, nil)
+
+		budgetStatus, err := budgetService.GetBudgetByMonth(context.Background(), testUserID, 1, 2024, false)
+		assert.Error(t, err)
+		assert.Nil(t, budgetStatus)
+		assert.Equal(t, domain.ErrNotFound, err)
+		mockBudgetRepo.AssertExpectations(t)
+	})
+
+	t.Run("Overall budget lookup fails", func(t *testing.T) {
+		mockBudgetRepo := new(MockBudgetRepository)
+		budgetService := NewBudgetService(fakeTxManager{}, mockBudgetRepo, new(MockExpenseRepositoryForBudget), new(MockCategoryRepository), new(MockIncomeRepository))
+
+		lookupErr := errors.New("connection reset")
+		mockBudgetRepo.On("GetByMonth", testUserID, 1, 2024).Return(nil, lookupErr)
+
+		budgetStatus, err := budgetService.GetBudgetByMonth(context.Background(), testUserID, 1, 2024, false)
+		assert.ErrorIs(t, err, lookupErr)
+		assert.Nil(t, budgetStatus)
+	})
 }
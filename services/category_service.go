@@ -1,29 +1,53 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"expense-tracker-api/domain"
+	"fmt"
 )
 
 type CategoryService struct {
+	txManager    domain.TxManager
 	categoryRepo domain.CategoryRepository
 }
 
 // NewCategoryService creates a new category service
-func NewCategoryService(categoryRepo domain.CategoryRepository) *CategoryService {
-	return &CategoryService{categoryRepo: categoryRepo}
+func NewCategoryService(txManager domain.TxManager, categoryRepo domain.CategoryRepository) *CategoryService {
+	return &CategoryService{txManager: txManager, categoryRepo: categoryRepo}
 }
 
-// CreateCategory creates a new category
-func (s *CategoryService) CreateCategory(name string) (*domain.Category, error) {
+// withTx returns a copy of the service whose repository is bound to tx
+func (s *CategoryService) withTx(tx domain.Tx) *CategoryService {
+	return &CategoryService{txManager: s.txManager, categoryRepo: s.categoryRepo.WithTx(tx)}
+}
+
+// CreateCategory creates a new category, nested under parentID when given
+func (s *CategoryService) CreateCategory(ctx context.Context, userID int, name string, parentID *int) (*domain.Category, error) {
 	if name == "" {
 		return nil, domain.ErrInvalidInput
 	}
 
+	return withinTx(ctx, s.txManager, func(tx domain.Tx) (*domain.Category, error) {
+		return s.withTx(tx).createCategory(ctx, userID, name, parentID)
+	})
+}
+
+// createCategory creates a category with the service's repository
+func (s *CategoryService) createCategory(ctx context.Context, userID int, name string, parentID *int) (*domain.Category, error) {
 	category := &domain.Category{
-		Name: name,
+		UserID: userID,
+		Name:   name,
 	}
 
-	err := s.categoryRepo.Create(category)
+	if parentID != nil {
+		if err := checkCategory(ctx, s.categoryRepo, userID, *parentID); err != nil {
+			return nil, err
+		}
+		category.ParentID = parentID
+	}
+
+	err := s.categoryRepo.Create(ctx, category)
 	if err != nil {
 		return nil, err
 	}
@@ -31,30 +55,75 @@ func (s *CategoryService) CreateCategory(name string) (*domain.Category, error)
 	return category, nil
 }
 
-// GetCategories retrieves all categories
-func (s *CategoryService) GetCategories() ([]*domain.Category, error) {
-	return s.categoryRepo.GetAll()
+// GetCategories retrieves all categories, leaving out archived ones unless includeArchived is set
+func (s *CategoryService) GetCategories(ctx context.Context, userID int, includeArchived bool) ([]*domain.Category, error) {
+	categories, err := s.categoryRepo.GetAll(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if includeArchived {
+		return categories, nil
+	}
+
+	active := []*domain.Category{}
+	for _, category := range categories {
+		if !category.Archived {
+			active = append(active, category)
+		}
+	}
+	return active, nil
 }
 
 // GetCategoryByID retrieves a category by ID
-func (s *CategoryService) GetCategoryByID(id int) (*domain.Category, error) {
-	return s.categoryRepo.GetByID(id)
+func (s *CategoryService) GetCategoryByID(ctx context.Context, userID, id int) (*domain.Category, error) {
+	return s.categoryRepo.GetByID(ctx, userID, id)
 }
 
-// UpdateCategory updates a category
-func (s *CategoryService) UpdateCategory(categoryID int, name string) (*domain.Category, error) {
-	if name == "" {
+// GetCategoryTree retrieves the categories nested under their parents. Archiving a
+// category archives its subcategories, so leaving out archived ones drops whole branches.
+func (s *CategoryService) GetCategoryTree(ctx context.Context, userID int, includeArchived bool) ([]*domain.CategoryNode, error) {
+	categories, err := s.GetCategories(ctx, userID, includeArchived)
+	if err != nil {
+		return nil, err
+	}
+	return domain.BuildCategoryTree(categories), nil
+}
+
+// UpdateCategory renames a category and, when parentID is given, moves it under
+// that parent. A parentID of 0 makes it a top-level category. An empty name keeps
+// the current one only when the category is being moved.
+func (s *CategoryService) UpdateCategory(ctx context.Context, userID, categoryID int, name string, parentID *int) (*domain.Category, error) {
+	if name == "" && parentID == nil {
 		return nil, domain.ErrInvalidInput
 	}
 
+	return withinTx(ctx, s.txManager, func(tx domain.Tx) (*domain.Category, error) {
+		return s.withTx(tx).updateCategory(ctx, userID, categoryID, name, parentID)
+	})
+}
+
+// updateCategory updates a category with the service's repository
+func (s *CategoryService) updateCategory(ctx context.Context, userID, categoryID int, name string, parentID *int) (*domain.Category, error) {
 	// Verify category exists
-	category, err := s.categoryRepo.GetByID(categoryID)
+	category, err := s.categoryRepo.GetByID(ctx, userID, categoryID)
 	if err != nil {
 		return nil, domain.ErrNotFound
 	}
 
-	category.Name = name
-	err = s.categoryRepo.Update(category)
+	if parentID != nil {
+		if err := s.checkParent(ctx, userID, categoryID, *parentID); err != nil {
+			return nil, err
+		}
+		category.ParentID = parentID
+		if *parentID == 0 {
+			category.ParentID = nil
+		}
+	}
+
+	if name != "" {
+		category.Name = name
+	}
+	err = s.categoryRepo.Update(ctx, category)
 	if err != nil {
 		return nil, err
 	}
@@ -62,13 +131,144 @@ func (s *CategoryService) UpdateCategory(categoryID int, name string) (*domain.C
 	return category, nil
 }
 
-// DeleteCategory deletes a category
-func (s *CategoryService) DeleteCategory(categoryID int) error {
-	// Verify category exists
-	_, err := s.categoryRepo.GetByID(categoryID)
+// checkParent verifies that categoryID can move under parentID without creating a
+// cycle, which would happen if the new parent is the category or one of its subcategories
+func (s *CategoryService) checkParent(ctx context.Context, userID, categoryID, parentID int) error {
+	if parentID == 0 {
+		return nil
+	}
+	if parentID == categoryID {
+		return domain.ErrCategoryCycle
+	}
+
+	categories, err := s.categoryRepo.GetAll(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, category := range categories {
+		if category.ID == parentID {
+			found = !category.Archived
+			break
+		}
+	}
+	if !found {
+		return domain.ErrInvalidCategory
+	}
+
+	for _, ancestorID := range domain.AncestorIDs(categories, parentID) {
+		if ancestorID == categoryID {
+			return domain.ErrCategoryCycle
+		}
+	}
+	return nil
+}
+
+// SetCategoryArchived archives or restores a category with all of its subcategories.
+// Archived categories keep their expenses, budgets and reports but can't be picked for
+// new expenses. A subcategory can't be restored while its parent is archived.
+func (s *CategoryService) SetCategoryArchived(ctx context.Context, userID, categoryID int, archived bool) (*domain.Category, error) {
+	return withinTx(ctx, s.txManager, func(tx domain.Tx) (*domain.Category, error) {
+		return s.withTx(tx).setCategoryArchived(ctx, userID, categoryID, archived)
+	})
+}
+
+// setCategoryArchived archives or restores a category with the service's repository
+func (s *CategoryService) setCategoryArchived(ctx context.Context, userID, categoryID int, archived bool) (*domain.Category, error) {
+	category, err := s.categoryRepo.GetByID(ctx, userID, categoryID)
+	if err != nil {
+		return nil, domain.ErrNotFound
+	}
+
+	if !archived && category.ParentID != nil {
+		parent, err := s.categoryRepo.GetByID(ctx, userID, *category.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent.Archived {
+			return nil, fmt.Errorf("%w: parent category %q is archived", domain.ErrInvalidCategory, parent.Name)
+		}
+	}
+
+	if err := s.categoryRepo.SetArchived(ctx, userID, categoryID, archived); err != nil {
+		return nil, err
+	}
+	category.Archived = archived
+	return category, nil
+}
+
+// DeleteCategory deletes a category. Without reassignTo it is refused with ErrInUse
+// while expenses, recurring expenses or subcategories still use the category; with
+// it they are first moved to that category, as in MergeCategory.
+func (s *CategoryService) DeleteCategory(ctx context.Context, userID, categoryID int, reassignTo *int) error {
+	return s.txManager.WithinTx(ctx, func(tx domain.Tx) error {
+		bound := s.withTx(tx)
+		if reassignTo != nil {
+			_, err := bound.mergeCategory(ctx, userID, categoryID, *reassignTo)
+			return err
+		}
+
+		// Verify category exists
+		_, err := bound.categoryRepo.GetByID(ctx, userID, categoryID)
+		if err != nil {
+			return domain.ErrNotFound
+		}
+
+		return bound.categoryRepo.Delete(ctx, userID, categoryID)
+	})
+}
+
+// MergeCategory moves everything in the source category into the target and deletes the
+// source in one step. Source budgets are kept for months the target has no budget.
+// The target can't be archived or sit below the source.
+func (s *CategoryService) MergeCategory(ctx context.Context, userID, sourceID, targetID int) (*domain.Category, error) {
+	return withinTx(ctx, s.txManager, func(tx domain.Tx) (*domain.Category, error) {
+		return s.withTx(tx).mergeCategory(ctx, userID, sourceID, targetID)
+	})
+}
+
+// mergeCategory merges the categories with the service's repository
+func (s *CategoryService) mergeCategory(ctx context.Context, userID, sourceID, targetID int) (*domain.Category, error) {
+	if sourceID == targetID {
+		return nil, fmt.Errorf("%w: a category cannot be merged into itself", domain.ErrInvalidInput)
+	}
+
+	if _, err := s.categoryRepo.GetByID(ctx, userID, sourceID); err != nil {
+		return nil, domain.ErrNotFound
+	}
+	if err := checkCategory(ctx, s.categoryRepo, userID, targetID); err != nil {
+		return nil, err
+	}
+
+	categories, err := s.categoryRepo.GetAll(ctx, userID)
 	if err != nil {
-		return domain.ErrNotFound
+		return nil, err
 	}
+	for _, ancestorID := range domain.AncestorIDs(categories, targetID) {
+		if ancestorID == sourceID {
+			return nil, fmt.Errorf("%w: cannot merge a category into one of its subcategories", domain.ErrInvalidCategory)
+		}
+	}
+
+	if err := s.categoryRepo.Merge(ctx, userID, sourceID, targetID); err != nil {
+		return nil, err
+	}
+	return s.categoryRepo.GetByID(ctx, userID, targetID)
+}
 
-	return s.categoryRepo.Delete(categoryID)
+// checkCategory verifies that categoryID names one of the user's categories that
+// can be picked for new records, that is one that isn't archived
+func checkCategory(ctx context.Context, categoryRepo domain.CategoryRepository, userID, categoryID int) error {
+	category, err := categoryRepo.GetByID(ctx, userID, categoryID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return domain.ErrInvalidCategory
+	}
+	if err != nil {
+		return err
+	}
+	if category.Archived {
+		return fmt.Errorf("%w: category %q is archived", domain.ErrInvalidCategory, category.Name)
+	}
+	return nil
 }
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"expense-tracker-api/domain"
 	"testing"
 
@@ -8,53 +10,71 @@ import (
 	"github.com/stretchr/testify/mock"
 )
 
+// testUserID owns every fixture in the service tests
+const testUserID = 42
+
 // MockCategoryRepository is a mock implementation of CategoryRepository
 type MockCategoryRepository struct {
 	mock.Mock
 }
 
-func (m *MockCategoryRepository) Create(category *domain.Category) error {
+// WithTx returns the mock itself, so expectations hold inside and outside a transaction
+func (m *MockCategoryRepository) WithTx(tx domain.Tx) domain.CategoryRepository {
+	return m
+}
+
+func (m *MockCategoryRepository) Create(ctx context.Context, category *domain.Category) error {
 	args := m.Called(category)
 	return args.Error(0)
 }
 
-func (m *MockCategoryRepository) GetByID(id int) (*domain.Category, error) {
-	args := m.Called(id)
+func (m *MockCategoryRepository) GetByID(ctx context.Context, userID, id int) (*domain.Category, error) {
+	args := m.Called(userID, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*domain.Category), args.Error(1)
 }
 
-func (m *MockCategoryRepository) GetAll() ([]*domain.Category, error) {
-	args := m.Called()
+func (m *MockCategoryRepository) GetAll(ctx context.Context, userID int) ([]*domain.Category, error) {
+	args := m.Called(userID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).([]*domain.Category), args.Error(1)
 }
 
-func (m *MockCategoryRepository) Update(category *domain.Category) error {
+func (m *MockCategoryRepository) Update(ctx context.Context, category *domain.Category) error {
 	args := m.Called(category)
 	return args.Error(0)
 }
 
-func (m *MockCategoryRepository) Delete(id int) error {
-	args := m.Called(id)
+func (m *MockCategoryRepository) SetArchived(ctx context.Context, userID, id int, archived bool) error {
+	args := m.Called(userID, id, archived)
+	return args.Error(0)
+}
+
+func (m *MockCategoryRepository) Delete(ctx context.Context, userID, id int) error {
+	args := m.Called(userID, id)
+	return args.Error(0)
+}
+
+func (m *MockCategoryRepository) Merge(ctx context.Context, userID, sourceID, targetID int) error {
+	args := m.Called(userID, sourceID, targetID)
 	return args.Error(0)
 }
 
 func TestCategoryService_CreateCategory(t *testing.T) {
 	t.Run("Successful creation", func(t *testing.T) {
 		mockRepo := new(MockCategoryRepository)
-		categoryService := NewCategoryService(mockRepo)
+		categoryService := NewCategoryService(fakeTxManager{}, mockRepo)
 
 		mockRepo.On("Create", mock.AnythingOfType("*domain.Category")).Return(nil).Run(func(args mock.Arguments) {
 			category := args.Get(0).(*domain.Category)
 			category.ID = 1
 		})
 
-		category, err := categoryService.CreateCategory("Food")
+		category, err := categoryService.CreateCategory(context.Background(), testUserID, "Food", nil)
 		assert.NoError(t, err)
 		assert.NotNil(t, category)
 		assert.Equal(t, "Food", category.Name)
@@ -63,25 +83,37 @@ func TestCategoryService_CreateCategory(t *testing.T) {
 
 	t.Run("Empty name", func(t *testing.T) {
 		mockRepo := new(MockCategoryRepository)
-		categoryService := NewCategoryService(mockRepo)
+		categoryService := NewCategoryService(fakeTxManager{}, mockRepo)
 
-		category, err := categoryService.CreateCategory("")
+		category, err := categoryService.CreateCategory(context.Background(), testUserID, "", nil)
 		assert.Error(t, err)
 		assert.Nil(t, category)
 		assert.Equal(t, domain.ErrInvalidInput, err)
 	})
+
+	t.Run("Subcategory of an unknown parent", func(t *testing.T) {
+		mockRepo := new(MockCategoryRepository)
+		categoryService := NewCategoryService(fakeTxManager{}, mockRepo)
+
+		mockRepo.On("GetByID", testUserID, 99).Return(nil, domain.ErrNotFound)
+
+		parentID := 99
+		_, err := categoryService.CreateCategory(context.Background(), testUserID, "Groceries", &parentID)
+		assert.Equal(t, domain.ErrInvalidCategory, err)
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
 }
 
 func TestCategoryService_UpdateCategory(t *testing.T) {
 	t.Run("Successful update", func(t *testing.T) {
 		mockRepo := new(MockCategoryRepository)
-		categoryService := NewCategoryService(mockRepo)
+		categoryService := NewCategoryService(fakeTxManager{}, mockRepo)
 
 		existingCategory := &domain.Category{ID: 1, Name: "Old Name"}
-		mockRepo.On("GetByID", 1).Return(existingCategory, nil)
+		mockRepo.On("GetByID", testUserID, 1).Return(existingCategory, nil)
 		mockRepo.On("Update", mock.AnythingOfType("*domain.Category")).Return(nil)
 
-		category, err := categoryService.UpdateCategory(1, "New Name")
+		category, err := categoryService.UpdateCategory(context.Background(), testUserID, 1, "New Name", nil)
 		assert.NoError(t, err)
 		assert.NotNil(t, category)
 		assert.Equal(t, "New Name", category.Name)
@@ -90,14 +122,226 @@ func TestCategoryService_UpdateCategory(t *testing.T) {
 
 	t.Run("Category not found", func(t *testing.T) {
 		mockRepo := new(MockCategoryRepository)
-		categoryService := NewCategoryService(mockRepo)
+		categoryService := NewCategoryService(fakeTxManager{}, mockRepo)
 
-		mockRepo.On("GetByID", 1).Return(nil, domain.ErrNotFound)
+		mockRepo.On("GetByID", testUserID, 1).Return(nil, domain.ErrNotFound)
 
-		category, err := categoryService.UpdateCategory(1, "New Name")
+		category, err := categoryService.UpdateCategory(context.Background(), testUserID, 1, "New Name", nil)
 		assert.Error(t, err)
 		assert.Nil(t, category)
 		assert.Equal(t, domain.ErrNotFound, err)
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+func TestCategoryService_UpdateCategory_Parent(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+	// Food (1) > Groceries (2) > Vegetables (3), and Travel (4)
+	categories := func() []*domain.Category {
+		return []*domain.Category{
+			{ID: 1, Name: "Food"},
+			{ID: 2, Name: "Groceries", ParentID: intPtr(1)},
+			{ID: 3, Name: "Vegetables", ParentID: intPtr(2)},
+			{ID: 4, Name: "Travel"},
+		}
+	}
+
+	t.Run("Move under another category", func(t *testing.T) {
+		mockRepo := new(MockCategoryRepository)
+		categoryService := NewCategoryService(fakeTxManager{}, mockRepo)
+
+		all := categories()
+		mockRepo.On("GetByID", testUserID, 4).Return(all[3], nil)
+		mockRepo.On("GetAll", testUserID).Return(all, nil)
+		mockRepo.On("Update", mock.AnythingOfType("*domain.Category")).Return(nil)
+
+		category, err := categoryService.UpdateCategory(context.Background(), testUserID, 4, "", intPtr(2))
+		assert.NoError(t, err)
+		assert.Equal(t, "Travel", category.Name)
+		assert.Equal(t, 2, *category.ParentID)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Move to the top level", func(t *testing.T) {
+		mockRepo := new(MockCategoryRepository)
+		categoryService := NewCategoryService(fakeTxManager{}, mockRepo)
+
+		mockRepo.On("GetByID", testUserID, 2).Return(categories()[1], nil)
+		mockRepo.On("Update", mock.AnythingOfType("*domain.Category")).Return(nil)
+
+		category, err := categoryService.UpdateCategory(context.Background(), testUserID, 2, "", intPtr(0))
+		assert.NoError(t, err)
+		assert.Nil(t, category.ParentID)
+	})
+
+	t.Run("Cycles are refused", func(t *testing.T) {
+		for _, parentID := range []int{1, 2, 3} {
+			mockRepo := new(MockCategoryRepository)
+			categoryService := NewCategoryService(fakeTxManager{}, mockRepo)
+
+			all := categories()
+			mockRepo.On("GetByID", testUserID, 1).Return(all[0], nil)
+			mockRepo.On("GetAll", testUserID).Return(all, nil).Maybe()
+
+			_, err := categoryService.UpdateCategory(context.Background(), testUserID, 1, "", intPtr(parentID))
+			assert.Equal(t, domain.ErrCategoryCycle, err, parentID)
+			mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+		}
+	})
+
+	t.Run("Unknown parent", func(t *testing.T) {
+		mockRepo := new(MockCategoryRepository)
+		categoryService := NewCategoryService(fakeTxManager{}, mockRepo)
+
+		all := categories()
+		mockRepo.On("GetByID", testUserID, 4).Return(all[3], nil)
+		mockRepo.On("GetAll", testUserID).Return(all, nil)
+
+		_, err := categoryService.UpdateCategory(context.Background(), testUserID, 4, "", intPtr(99))
+		assert.Equal(t, domain.ErrInvalidCategory, err)
+	})
+}
+
+func TestCategoryService_GetCategoryTree(t *testing.T) {
+	mockRepo := new(MockCategoryRepository)
+	categoryService := NewCategoryService(fakeTxManager{}, mockRepo)
+
+	foodID := 1
+	mockRepo.On("GetAll", testUserID).Return([]*domain.Category{
+		{ID: 1, Name: "Food"},
+		{ID: 2, Name: "Groceries", ParentID: &foodID},
+		{ID: 3, Name: "Restaurants", ParentID: &foodID},
+		{ID: 4, Name: "Travel"},
+		{ID: 5, Name: "Hobbies", Archived: true},
+	}, nil)
+
+	tree, err := categoryService.GetCategoryTree(context.Background(), testUserID, false)
+	assert.NoError(t, err)
+	assert.Len(t, tree, 2)
+	assert.Equal(t, "Food", tree[0].Name)
+	assert.Len(t, tree[0].Children, 2)
+	assert.Equal(t, "Groceries", tree[0].Children[0].Name)
+	assert.Empty(t, tree[1].Children)
+}
+
+func TestCategoryService_DeleteCategory(t *testing.T) {
+	t.Run("Category still in use", func(t *testing.T) {
+		mockRepo := new(MockCategoryRepository)
+		categoryService := NewCategoryService(fakeTxManager{}, mockRepo)
+
+		mockRepo.On("GetByID", testUserID, 1).Return(&domain.Category{ID: 1, Name: "Food"}, nil)
+		mockRepo.On("Delete", testUserID, 1).Return(domain.ErrInUse)
+
+		err := categoryService.DeleteCategory(context.Background(), testUserID, 1, nil)
+		assert.Equal(t, domain.ErrInUse, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Expenses are reassigned before deleting", func(t *testing.T) {
+		mockRepo := new(MockCategoryRepository)
+		categoryService := NewCategoryService(fakeTxManager{}, mockRepo)
+
+		mockRepo.On("GetByID", testUserID, 1).Return(&domain.Category{ID: 1, Name: "Food"}, nil)
+		mockRepo.On("GetByID", testUserID, 2).Return(&domain.Category{ID: 2, Name: "Groceries"}, nil)
+		mockRepo.On("GetAll", testUserID).Return([]*domain.Category{{ID: 1}, {ID: 2}}, nil)
+		mockRepo.On("Merge", testUserID, 1, 2).Return(nil)
+
+		reassignTo := 2
+		err := categoryService.DeleteCategory(context.Background(), testUserID, 1, &reassignTo)
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+}
+
+func TestCategoryService_MergeCategory(t *testing.T) {
+	foodID := 1
+	t.Run("Into an archived category", func(t *testing.T) {
+		mockRepo := new(MockCategoryRepository)
+		categoryService := NewCategoryService(fakeTxManager{}, mockRepo)
+
+		mockRepo.On("GetByID", testUserID, 1).Return(&domain.Category{ID: 1, Name: "Food"}, nil)
+		mockRepo.On("GetByID", testUserID, 3).Return(&domain.Category{ID: 3, Name: "Dining", Archived: true}, nil)
+
+		_, err := categoryService.MergeCategory(context.Background(), testUserID, 1, 3)
+		assert.ErrorIs(t, err, domain.ErrInvalidCategory)
+		mockRepo.AssertNotCalled(t, "Merge", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Into one of its subcategories", func(t *testing.T) {
+		mockRepo := new(MockCategoryRepository)
+		categoryService := NewCategoryService(fakeTxManager{}, mockRepo)
+
+		mockRepo.On("GetByID", testUserID, 1).Return(&domain.Category{ID: 1, Name: "Food"}, nil)
+		mockRepo.On("GetByID", testUserID, 2).Return(&domain.Category{ID: 2, Name: "Groceries", ParentID: &foodID}, nil)
+		mockRepo.On("GetAll", testUserID).Return([]*domain.Category{{ID: 1}, {ID: 2, ParentID: &foodID}}, nil)
+
+		_, err := categoryService.MergeCategory(context.Background(), testUserID, 1, 2)
+		assert.ErrorIs(t, err, domain.ErrInvalidCategory)
+		mockRepo.AssertNotCalled(t, "Merge", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Into itself", func(t *testing.T) {
+		categoryService := NewCategoryService(fakeTxManager{}, new(MockCategoryRepository))
+
+		_, err := categoryService.MergeCategory(context.Background(), testUserID, 1, 1)
+		assert.ErrorIs(t, err, domain.ErrInvalidInput)
+	})
+}
+
+func TestCategoryService_SetCategoryArchived(t *testing.T) {
+	foodID := 1
+	t.Run("Archive", func(t *testing.T) {
+		mockRepo := new(MockCategoryRepository)
+		categoryService := NewCategoryService(fakeTxManager{}, mockRepo)
+
+		mockRepo.On("GetByID", testUserID, 1).Return(&domain.Category{ID: 1, Name: "Food"}, nil)
+		mockRepo.On("SetArchived", testUserID, 1, true).Return(nil)
+
+		category, err := categoryService.SetCategoryArchived(context.Background(), testUserID, 1, true)
+		assert.NoError(t, err)
+		assert.True(t, category.Archived)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Unarchive below an archived parent", func(t *testing.T) {
+		mockRepo := new(MockCategoryRepository)
+		categoryService := NewCategoryService(fakeTxManager{}, mockRepo)
+
+		mockRepo.On("GetByID", testUserID, 2).Return(&domain.Category{ID: 2, Name: "Groceries", ParentID: &foodID, Archived: true}, nil)
+		mockRepo.On("GetByID", testUserID, 1).Return(&domain.Category{ID: 1, Name: "Food", Archived: true}, nil)
+
+		_, err := categoryService.SetCategoryArchived(context.Background(), testUserID, 2, false)
+		assert.ErrorIs(t, err, domain.ErrInvalidCategory)
+		mockRepo.AssertNotCalled(t, "SetArchived", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestCheckCategory(t *testing.T) {
+	lookupErr := errors.New("connection reset")
+	tests := []struct {
+		name     string
+		category *domain.Category
+		err      error
+		want     error
+	}{
+		{"usable", &domain.Category{ID: 1, Name: "Food"}, nil, nil},
+		{"archived", &domain.Category{ID: 1, Name: "Food", Archived: true}, nil, domain.ErrInvalidCategory},
+		{"missing", nil, domain.ErrNotFound, domain.ErrInvalidCategory},
+		{"lookup fails", nil, lookupErr, lookupErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockCategoryRepository)
+			mockRepo.On("GetByID", testUserID, 1).Return(tt.category, tt.err)
+
+			err := checkCategory(context.Background(), mockRepo, testUserID, 1)
+			if tt.want == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"expense-tracker-api/domain"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type ExchangeRateService struct {
+	rateRepo     domain.ExchangeRateRepository
+	baseCurrency string
+}
+
+// NewExchangeRateService creates a new exchange rate service converting into baseCurrency
+func NewExchangeRateService(rateRepo domain.ExchangeRateRepository, baseCurrency string) *ExchangeRateService {
+	return &ExchangeRateService{rateRepo: rateRepo, baseCurrency: baseCurrency}
+}
+
+// BaseCurrency returns the currency every total is kept in
+func (s *ExchangeRateService) BaseCurrency() string {
+	return s.baseCurrency
+}
+
+// Convert turns an amount in currency into the base currency using the latest rate
+// on or before date. It returns the converted amount and the rate it used.
+func (s *ExchangeRateService) Convert(ctx context.Context, amount domain.Money, currency string, date time.Time) (domain.Money, domain.Rate, error) {
+	if currency == s.baseCurrency {
+		return amount, domain.RateOne, nil
+	}
+
+	rate, err := s.rateRepo.GetOnOrBefore(ctx, currency, date)
+	if err == domain.ErrNotFound {
+		return 0, 0, domain.ErrNoExchangeRate
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	return amount.Convert(rate.Rate), rate.Rate, nil
+}
+
+// GetRates lists the loaded rates, optionally for a single currency
+func (s *ExchangeRateService) GetRates(ctx context.Context, currency string) ([]*domain.ExchangeRate, error) {
+	if currency != "" {
+		var err error
+		if currency, err = domain.NormalizeCurrency(currency); err != nil {
+			return nil, err
+		}
+	}
+	return s.rateRepo.GetAll(ctx, currency)
+}
+
+// SaveRates validates the rates and saves them together, replacing existing
+// rates for the same currency and date. Expenses already converted keep their rate.
+func (s *ExchangeRateService) SaveRates(ctx context.Context, rates []*domain.ExchangeRate) error {
+	if len(rates) == 0 {
+		return domain.ErrInvalidInput
+	}
+
+	for i, rate := range rates {
+		if err := s.validateRate(rate); err != nil {
+			return fmt.Errorf("%w: rate %d", err, i+1)
+		}
+	}
+
+	return s.rateRepo.Upsert(ctx, rates)
+}
+
+func (s *ExchangeRateService) validateRate(rate *domain.ExchangeRate) error {
+	currency, err := domain.NormalizeCurrency(rate.Currency)
+	if err != nil {
+		return err
+	}
+	if currency == s.baseCurrency {
+		return fmt.Errorf("%w: %s is the base currency", domain.ErrInvalidInput, currency)
+	}
+	if rate.Date.IsZero() {
+		return fmt.Errorf("%w: missing date", domain.ErrInvalidInput)
+	}
+	if rate.Rate <= 0 {
+		return domain.ErrInvalidRate
+	}
+
+	rate.Currency = currency
+	rate.Date = domain.TruncateToDate(rate.Date)
+	return nil
+}
+
+// ImportCSV loads rates from a CSV file with a currency,date,rate header. The file is
+// saved all or nothing: the first bad row fails the import and nothing is written.
+func (s *ExchangeRateService) ImportCSV(ctx context.Context, r io.Reader) (int, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return 0, fmt.Errorf("%w: cannot read CSV header", domain.ErrInvalidInput)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"currency", "date", "rate"} {
+		if _, ok := columns[name]; !ok {
+			return 0, fmt.Errorf("%w: missing column %q", domain.ErrInvalidInput, name)
+		}
+	}
+
+	var rates []*domain.ExchangeRate
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("%w: %v", domain.ErrInvalidInput, err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		date, err := time.Parse("2006-01-02", record[columns["date"]])
+		if err != nil {
+			return 0, fmt.Errorf("%w: row %d: invalid date %q", domain.ErrInvalidInput, line, record[columns["date"]])
+		}
+		rate, err := domain.ParseRate(record[columns["rate"]])
+		if err != nil {
+			return 0, fmt.Errorf("%w: row %d", err, line)
+		}
+		exchangeRate := &domain.ExchangeRate{Currency: record[columns["currency"]], Date: date, Rate: rate}
+		if err := s.validateRate(exchangeRate); err != nil {
+			return 0, fmt.Errorf("%w: row %d", err, line)
+		}
+		rates = append(rates, exchangeRate)
+	}
+
+	if len(rates) == 0 {
+		return 0, fmt.Errorf("%w: no rates in file", domain.ErrInvalidInput)
+	}
+	if err := s.rateRepo.Upsert(ctx, rates); err != nil {
+		return 0, err
+	}
+	return len(rates), nil
+}
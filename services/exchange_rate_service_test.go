@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"expense-tracker-api/domain"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockExchangeRateRepository is a mock implementation of ExchangeRateRepository
+type MockExchangeRateRepository struct {
+	mock.Mock
+}
+
+func (m *MockExchangeRateRepository) Upsert(ctx context.Context, rates []*domain.ExchangeRate) error {
+	args := m.Called(rates)
+	return args.Error(0)
+}
+
+func (m *MockExchangeRateRepository) GetOnOrBefore(ctx context.Context, currency string, date time.Time) (*domain.ExchangeRate, error) {
+	args := m.Called(currency, date)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ExchangeRate), args.Error(1)
+}
+
+func (m *MockExchangeRateRepository) GetAll(ctx context.Context, currency string) ([]*domain.ExchangeRate, error) {
+	args := m.Called(currency)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.ExchangeRate), args.Error(1)
+}
+
+// newTestExchangeRateService returns a service with INR as the base currency and no rates loaded
+func newTestExchangeRateService() *ExchangeRateService {
+	return NewExchangeRateService(new(MockExchangeRateRepository), "INR")
+}
+
+func rate(s string) domain.Rate {
+	r, err := domain.ParseRate(s)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func TestExpenseService_CreateExpense_Currency(t *testing.T) {
+	t.Run("Foreign amount is converted with the rate for the expense date", func(t *testing.T) {
+		mockExpenseRepo := new(MockExpenseRepository)
+		mockCategoryRepo := new(MockCategoryRepositoryForExpense)
+		mockBudgetRepo := new(MockBudgetRepositoryForExpense)
+		mockRateRepo := new(MockExchangeRateRepository)
+		expenseService := NewExpenseService(fakeTxManager{}, mockExpenseRepo, mockCategoryRepo, mockBudgetRepo,
+			newDefaultPaymentMethodRepository(), new(MockAccountRepository), NewExchangeRateService(mockRateRepo, "INR"))
+
+		expenseDate := date(2024, 3, 9)
+		mockCategoryRepo.On("GetByID", testUserID, 1).Return(&domain.Category{ID: 1}, nil)
+		mockRateRepo.On("GetOnOrBefore", "USD", expenseDate).
+			Return(&domain.ExchangeRate{Currency: "USD", Date: date(2024, 3, 8), Rate: rate("82.995")}, nil)
+		mockExpenseRepo.On("Create", mock.AnythingOfType("*domain.Expense")).Return(nil)
+		mockBudgetRepo.On("GetByMonth", testUserID, 3, 2024).Return(nil, domain.ErrNotFound)
+		mockBudgetRepo.On("GetByMonthAndCategory", testUserID, 3, 2024, 1).Return(nil, domain.ErrNotFound)
+
+		expense, err := expenseService.CreateExpense(context.Background(), &domain.Expense{
+			UserID:      testUserID,
+			CategoryID:  1,
+			Amount:      amount("12.99"),
+			Currency:    "usd",
+			PaymentMode: domain.PaymentModeCash,
+			ExpenseDate: expenseDate,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "USD", expense.Currency)
+		assert.Equal(t, amount("12.99"), expense.OriginalAmount)
+		assert.Equal(t, rate("82.995"), expense.ExchangeRate)
+		// 12.99 * 82.995 = 1078.10505
+		assert.Equal(t, amount("1078.11"), expense.Amount)
+		mockRateRepo.AssertExpectations(t)
+	})
+
+	t.Run("Base currency by default", func(t *testing.T) {
+		mockExpenseRepo := new(MockExpenseRepository)
+		mockCategoryRepo := new(MockCategoryRepositoryForExpense)
+		mockBudgetRepo := new(MockBudgetRepositoryForExpense)
+		expenseService := NewExpenseService(fakeTxManager{}, mockExpenseRepo, mockCategoryRepo, mockBudgetRepo, newDefaultPaymentMethodRepository(), new(MockAccountRepository), newTestExchangeRateService())
+
+		mockCategoryRepo.On("GetByID", testUserID, 1).Return(&domain.Category{ID: 1}, nil)
+		mockExpenseRepo.On("Create", mock.AnythingOfType("*domain.Expense")).Return(nil)
+		mockBudgetRepo.On("GetByMonth", testUserID, mock.Anything, mock.Anything).Return(nil, domain.ErrNotFound)
+		mockBudgetRepo.On("GetByMonthAndCategory", testUserID, mock.Anything, mock.Anything, 1).Return(nil, domain.ErrNotFound)
+
+		expense, err := expenseService.CreateExpense(context.Background(), &domain.Expense{
+			UserID:      testUserID,
+			CategoryID:  1,
+			Amount:      amount("250.00"),
+			PaymentMode: domain.PaymentModeUPI,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "INR", expense.Currency)
+		assert.Equal(t, amount("250.00"), expense.Amount)
+		assert.Equal(t, domain.RateOne, expense.ExchangeRate)
+	})
+
+	t.Run("No rate loaded for the currency", func(t *testing.T) {
+		mockCategoryRepo := new(MockCategoryRepositoryForExpense)
+		mockRateRepo := new(MockExchangeRateRepository)
+		expenseService := NewExpenseService(fakeTxManager{}, new(MockExpenseRepository), mockCategoryRepo, new(MockBudgetRepositoryForExpense),
+			newDefaultPaymentMethodRepository(), new(MockAccountRepository), NewExchangeRateService(mockRateRepo, "INR"))
+
+		mockCategoryRepo.On("GetByID", testUserID, 1).Return(&domain.Category{ID: 1}, nil)
+		mockRateRepo.On("GetOnOrBefore", "EUR", mock.Anything).Return(nil, domain.ErrNotFound)
+
+		_, err := expenseService.CreateExpense(context.Background(), &domain.Expense{
+			UserID:      testUserID,
+			CategoryID:  1,
+			Amount:      amount("40.00"),
+			Currency:    "EUR",
+			PaymentMode: domain.PaymentModeUPI,
+		})
+		assert.Equal(t, domain.ErrNoExchangeRate, err)
+	})
+
+	t.Run("Converted amount too large to store", func(t *testing.T) {
+		mockExpenseRepo := new(MockExpenseRepository)
+		mockCategoryRepo := new(MockCategoryRepositoryForExpense)
+		mockRateRepo := new(MockExchangeRateRepository)
+		expenseService := NewExpenseService(fakeTxManager{}, mockExpenseRepo, mockCategoryRepo, new(MockBudgetRepositoryForExpense),
+			newDefaultPaymentMethodRepository(), new(MockAccountRepository), NewExchangeRateService(mockRateRepo, "INR"))
+
+		mockCategoryRepo.On("GetByID", testUserID, 1).Return(&domain.Category{ID: 1}, nil)
+		mockRateRepo.On("GetOnOrBefore", "USD", mock.Anything).
+			Return(&domain.ExchangeRate{Currency: "USD", Date: date(2024, 3, 8), Rate: rate("83")}, nil)
+
+		_, err := expenseService.CreateExpense(context.Background(), &domain.Expense{
+			UserID:      testUserID,
+			CategoryID:  1,
+			Amount:      amount("2000000.00"),
+			Currency:    "USD",
+			PaymentMode: domain.PaymentModeUPI,
+		})
+		assert.Equal(t, domain.ErrAmountTooLarge, err)
+		mockExpenseRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+}
+
+func TestExpenseService_UpdateExpense_KeepsRecordedRate(t *testing.T) {
+	mockExpenseRepo := new(MockExpenseRepository)
+	mockRateRepo := new(MockExchangeRateRepository)
+	mockBudgetRepo := new(MockBudgetRepositoryForExpense)
+	mockCategoryRepo := new(MockCategoryRepositoryForExpense)
+	expenseService := NewExpenseService(fakeTxManager{}, mockExpenseRepo, mockCategoryRepo, mockBudgetRepo,
+		newDefaultPaymentMethodRepository(), new(MockAccountRepository), NewExchangeRateService(mockRateRepo, "INR"))
+
+	existing := &domain.Expense{ID: 5, UserID: testUserID, CategoryID: 1, Amount: amount("830.00"), Currency: "USD",
+		OriginalAmount: amount("10.00"), ExchangeRate: rate("83"), PaymentMode: domain.PaymentModeUPI, ExpenseDate: date(2024, 3, 9)}
+	mockExpenseRepo.On("GetByID", testUserID, 5).Return(existing, nil)
+	mockExpenseRepo.On("Update", existing).Return(nil)
+	mockCategoryRepo.On("GetByID", testUserID, 1).Return(&domain.Category{ID: 1}, nil)
+	mockBudgetRepo.On("GetByMonth", testUserID, 3, 2024).Return(nil, domain.ErrNotFound)
+	mockBudgetRepo.On("GetByMonthAndCategory", testUserID, 3, 2024, 1).Return(nil, domain.ErrNotFound)
+
+	updated, err := expenseService.UpdateExpense(context.Background(), &domain.Expense{ID: 5, UserID: testUserID, Description: "Museum tickets"})
+	assert.NoError(t, err)
+	assert.Equal(t, amount("830.00"), updated.Amount)
+	assert.Equal(t, rate("83"), updated.ExchangeRate)
+	mockRateRepo.AssertNotCalled(t, "GetOnOrBefore", mock.Anything, mock.Anything)
+}
+
+func TestExchangeRateService_ImportCSV(t *testing.T) {
+	t.Run("Rates are normalized and saved together", func(t *testing.T) {
+		mockRateRepo := new(MockExchangeRateRepository)
+		rateService := NewExchangeRateService(mockRateRepo, "INR")
+
+		mockRateRepo.On("Upsert", mock.AnythingOfType("[]*domain.ExchangeRate")).Return(nil).Run(func(args mock.Arguments) {
+			rates := args.Get(0).([]*domain.ExchangeRate)
+			assert.Equal(t, "USD", rates[0].Currency)
+			assert.Equal(t, rate("83.1"), rates[0].Rate)
+		})
+
+		imported, err := rateService.ImportCSV(context.Background(), strings.NewReader("currency,date,rate\nusd,2024-03-08,83.10\nEUR,2024-03-08,90.5\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, imported)
+		mockRateRepo.AssertExpectations(t)
+	})
+
+	t.Run("A bad row fails the whole file", func(t *testing.T) {
+		mockRateRepo := new(MockExchangeRateRepository)
+		rateService := NewExchangeRateService(mockRateRepo, "INR")
+
+		_, err := rateService.ImportCSV(context.Background(), strings.NewReader("currency,date,rate\nUSD,2024-03-08,83.10\nINR,2024-03-08,1\n"))
+		assert.ErrorIs(t, err, domain.ErrInvalidInput)
+		assert.Contains(t, err.Error(), "row 3")
+		mockRateRepo.AssertNotCalled(t, "Upsert", mock.Anything)
+	})
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"expense-tracker-api/domain"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ImportCSV loads expenses from a CSV file with a header row. Every row goes
+// through the same validation as CreateExpense. The whole file is read and its rows
+// parsed and validated first; only then does a transaction resolve their categories
+// and insert the valid ones together, so a slow upload never holds the database.
+// With dryRun set the report is built without writing anything.
+func (s *ExpenseService) ImportCSV(ctx context.Context, userID int, r io.Reader, mapping domain.ImportMapping, dryRun bool) (*domain.ImportReport, error) {
+	report, rows, err := s.parseImportCSV(ctx, userID, r, mapping)
+	if err != nil {
+		return nil, err
+	}
+	report.DryRun = dryRun
+
+	if len(rows) > 0 {
+		_, err = withinTx(ctx, s.txManager, func(tx domain.Tx) (struct{}, error) {
+			return struct{}{}, s.withTx(tx).importRows(ctx, userID, rows, dryRun)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, result := range report.Rows {
+		switch result.Status {
+		case domain.ImportRowCreated:
+			report.Created++
+		case domain.ImportRowSkipped:
+			report.Skipped++
+		case domain.ImportRowFailed:
+			report.Failed++
+		}
+	}
+
+	return report, nil
+}
+
+// importRow is a row that passed every check but its category's, which the
+// transaction looks up
+type importRow struct {
+	result   *domain.ImportRowResult
+	expense  *domain.Expense
+	category string
+}
+
+// parseImportCSV reads the whole file and reports every row, returning the rows
+// still waiting for their category
+func (s *ExpenseService) parseImportCSV(ctx context.Context, userID int, r io.Reader, mapping domain.ImportMapping) (*domain.ImportReport, []*importRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: cannot read CSV header", domain.ErrInvalidInput)
+	}
+
+	columns, err := resolveImportColumns(header, mapping)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	report := &domain.ImportReport{Rows: []*domain.ImportRowResult{}}
+	var rows []*importRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		result := &domain.ImportRowResult{}
+		report.Rows = append(report.Rows, result)
+		if err != nil {
+			if parseErr, ok := err.(*csv.ParseError); ok {
+				result.Row = parseErr.Line
+			}
+			result.Status, result.Reason = domain.ImportRowFailed, err.Error()
+			continue
+		}
+		result.Row, _ = reader.FieldPos(0)
+
+		if isBlankRecord(record) {
+			result.Status, result.Reason = domain.ImportRowSkipped, "empty row"
+			continue
+		}
+
+		expense, category, err := parseImportRecord(record, columns, mapping.DateFormat)
+		if err == nil {
+			expense.UserID = userID
+			err = s.prepareNewExpense(ctx, expense)
+		}
+		if err != nil {
+			result.Status, result.Reason = domain.ImportRowFailed, err.Error()
+			continue
+		}
+		rows = append(rows, &importRow{result: result, expense: expense, category: category})
+	}
+
+	return report, rows, nil
+}
+
+// importRows gives the rows their categories and inserts those that have one,
+// with the service's repositories
+func (s *ExpenseService) importRows(ctx context.Context, userID int, rows []*importRow, dryRun bool) error {
+	categories, err := s.categoryRepo.GetAll(ctx, userID)
+	if err != nil {
+		return err
+	}
+	categoryIDs := make(map[string]int, len(categories))
+	for _, category := range categories {
+		if !category.Archived {
+			categoryIDs[strings.ToLower(category.Name)] = category.ID
+		}
+	}
+
+	var expenses []*domain.Expense
+	for _, row := range rows {
+		categoryID, ok := categoryIDs[strings.ToLower(row.category)]
+		if !ok {
+			err := fmt.Errorf("%w: unknown category %q", domain.ErrInvalidCategory, row.category)
+			row.result.Status, row.result.Reason = domain.ImportRowFailed, err.Error()
+			continue
+		}
+		row.expense.CategoryID = categoryID
+
+		row.result.Status, row.result.Expense = domain.ImportRowCreated, row.expense
+		expenses = append(expenses, row.expense)
+	}
+
+	if !dryRun && len(expenses) > 0 {
+		return s.expenseRepo.CreateBatch(ctx, expenses)
+	}
+	return nil
+}
+
+// importColumns holds the index of each mapped column, or -1 for an optional column that is absent
+type importColumns struct {
+	date, amount, currency, description, category, paymentMode int
+}
+
+// resolveImportColumns finds the mapped columns in the header, ignoring case
+func resolveImportColumns(header []string, mapping domain.ImportMapping) (*importColumns, error) {
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	find := func(name string, required bool) (int, error) {
+		if i, ok := index[strings.ToLower(strings.TrimSpace(name))]; ok {
+			return i, nil
+		}
+		if required {
+			return -1, fmt.Errorf("%w: missing column %q", domain.ErrInvalidInput, name)
+		}
+		return -1, nil
+	}
+
+	columns := &importColumns{}
+	var err error
+	if columns.date, err = find(mapping.Date, false); err != nil {
+		return nil, err
+	}
+	if columns.amount, err = find(mapping.Amount, true); err != nil {
+		return nil, err
+	}
+	if columns.currency, err = find(mapping.Currency, false); err != nil {
+		return nil, err
+	}
+	if columns.description, err = find(mapping.Description, false); err != nil {
+		return nil, err
+	}
+	if columns.category, err = find(mapping.Category, true); err != nil {
+		return nil, err
+	}
+	if columns.paymentMode, err = find(mapping.PaymentMode, true); err != nil {
+		return nil, err
+	}
+	return columns, nil
+}
+
+// parseImportRecord converts one CSV record into an expense and the name of its category
+func parseImportRecord(record []string, columns *importColumns, dateFormat string) (*domain.Expense, string, error) {
+	field := func(i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	expense := &domain.Expense{
+		Currency:    field(columns.currency),
+		Description: field(columns.description),
+		PaymentMode: domain.PaymentMode(field(columns.paymentMode)),
+	}
+
+	amountStr := strings.ReplaceAll(field(columns.amount), ",", "")
+	amount, err := domain.ParseMoney(amountStr)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: invalid amount %q", domain.ErrInvalidInput, field(columns.amount))
+	}
+	expense.Amount = amount
+
+	if dateStr := field(columns.date); dateStr != "" {
+		expenseDate, err := time.Parse(dateFormat, dateStr)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: invalid date %q", domain.ErrInvalidInput, dateStr)
+		}
+		// A date such as 0001-01-01 would otherwise pass for a missing one
+		if err := checkExpenseDate(expenseDate); err != nil {
+			return nil, "", err
+		}
+		expense.ExpenseDate = expenseDate
+	}
+
+	return expense, field(columns.category), nil
+}
+
+// isBlankRecord reports whether every field in the record is empty
+func isBlankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"expense-tracker-api/domain"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const importCSV = `date,amount,description,category,payment_mode
+2024-03-01,"1,250.50",Groceries,food,UPI
+2024-03-02,abc,Bad amount,Food,UPI
+,,,,
+2024-03-03,300,Movie,Unknown,Cash
+2024-03-04,120,Cab,Transport,Card
+2024-03-05,80,Tea,Food,Cash
+`
+
+func newTestImportService() (*ExpenseService, *MockExpenseRepository, *MockCategoryRepositoryForExpense) {
+	mockExpenseRepo := new(MockExpenseRepository)
+	mockCategoryRepo := new(MockCategoryRepositoryForExpense)
+	mockBudgetRepo := new(MockBudgetRepositoryForExpense)
+	expenseService := NewExpenseService(fakeTxManager{}, mockExpenseRepo, mockCategoryRepo, mockBudgetRepo, newDefaultPaymentMethodRepository(), new(MockAccountRepository), newTestExchangeRateService())
+
+	categories := []*domain.Category{{ID: 1, Name: "Food"}, {ID: 2, Name: "Transport"}}
+	mockCategoryRepo.On("GetAll", testUserID).Return(categories, nil)
+	mockCategoryRepo.On("GetByID", testUserID, 1).Return(categories[0], nil)
+	mockCategoryRepo.On("GetByID", testUserID, 2).Return(categories[1], nil)
+	return expenseService, mockExpenseRepo, mockCategoryRepo
+}
+
+func TestExpenseService_ImportCSV(t *testing.T) {
+	t.Run("Reports each row and inserts valid rows together", func(t *testing.T) {
+		expenseService, mockExpenseRepo, _ := newTestImportService()
+
+		mockExpenseRepo.On("CreateBatch", mock.AnythingOfType("[]*domain.Expense")).Return(nil).Run(func(args mock.Arguments) {
+			expenses := args.Get(0).([]*domain.Expense)
+			assert.Len(t, expenses, 2)
+		})
+
+		report, err := expenseService.ImportCSV(context.Background(), testUserID, strings.NewReader(importCSV), domain.DefaultImportMapping(), false)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, report.Created)
+		assert.Equal(t, 1, report.Skipped)
+		assert.Equal(t, 3, report.Failed)
+
+		assert.Equal(t, 2, report.Rows[0].Row)
+		assert.Equal(t, domain.ImportRowCreated, report.Rows[0].Status)
+		assert.Equal(t, amount("1250.50"), report.Rows[0].Expense.Amount)
+		assert.Equal(t, domain.ImportRowFailed, report.Rows[1].Status)
+		assert.Contains(t, report.Rows[1].Reason, "invalid amount")
+		assert.Equal(t, domain.ImportRowSkipped, report.Rows[2].Status)
+		assert.Contains(t, report.Rows[3].Reason, "unknown category")
+		assert.Equal(t, domain.ErrInvalidPaymentMode.Error(), report.Rows[4].Reason)
+		assert.Equal(t, 7, report.Rows[5].Row)
+		mockExpenseRepo.AssertExpectations(t)
+	})
+
+	t.Run("Dry run writes nothing", func(t *testing.T) {
+		expenseService, mockExpenseRepo, _ := newTestImportService()
+
+		report, err := expenseService.ImportCSV(context.Background(), testUserID, strings.NewReader(importCSV), domain.DefaultImportMapping(), true)
+		assert.NoError(t, err)
+		assert.True(t, report.DryRun)
+		assert.Equal(t, 2, report.Created)
+		mockExpenseRepo.AssertNotCalled(t, "CreateBatch", mock.Anything)
+	})
+
+	t.Run("Custom column mapping", func(t *testing.T) {
+		expenseService, mockExpenseRepo, _ := newTestImportService()
+
+		mockExpenseRepo.On("CreateBatch", mock.AnythingOfType("[]*domain.Expense")).Return(nil)
+
+		mapping := domain.DefaultImportMapping()
+		mapping.Date, mapping.Amount, mapping.Description = "Txn Date", "Debit", "Narration"
+		mapping.Category, mapping.PaymentMode, mapping.DateFormat = "Head", "Mode", "02/01/2006"
+		csv := "Txn Date,Narration,Debit,Head,Mode\n15/03/2024,Fuel,900,Transport,Cash\n"
+
+		report, err := expenseService.ImportCSV(context.Background(), testUserID, strings.NewReader(csv), mapping, false)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.Created)
+		assert.Equal(t, 2, report.Rows[0].Expense.CategoryID)
+		assert.Equal(t, 3, int(report.Rows[0].Expense.ExpenseDate.Month()))
+	})
+
+	t.Run("Amounts with more than two decimal places fail", func(t *testing.T) {
+		expenseService, mockExpenseRepo, _ := newTestImportService()
+
+		csv := "date,amount,description,category,payment_mode\n2024-03-01,10.005,Tea,Food,Cash\n"
+		report, err := expenseService.ImportCSV(context.Background(), testUserID, strings.NewReader(csv), domain.DefaultImportMapping(), false)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.Failed)
+		assert.Contains(t, report.Rows[0].Reason, "invalid amount")
+		mockExpenseRepo.AssertNotCalled(t, "CreateBatch", mock.Anything)
+	})
+
+	t.Run("Rows a request would be refused for fail", func(t *testing.T) {
+		expenseService, mockExpenseRepo, _ := newTestImportService()
+
+		csv := "date,amount,description,category,payment_mode\n" +
+			"2024-03-01,-50,Refund,Food,Cash\n" +
+			"2024-03-02,0,Nothing,Food,Cash\n" +
+			"0001-01-01,10,Tea,Food,Cash\n" +
+			"2024-03-04,10," + strings.Repeat("a", domain.MaxDescriptionLength+1) + ",Food,Cash\n"
+		report, err := expenseService.ImportCSV(context.Background(), testUserID, strings.NewReader(csv), domain.DefaultImportMapping(), false)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, report.Failed)
+		assert.Equal(t, 2, report.Rows[0].Row)
+		assert.Contains(t, report.Rows[0].Reason, "amount must be more than zero")
+		assert.Contains(t, report.Rows[1].Reason, "amount must be more than zero")
+		assert.Equal(t, 4, report.Rows[2].Row)
+		assert.Contains(t, report.Rows[2].Reason, "date must be between")
+		assert.Contains(t, report.Rows[3].Reason, "description must be at most")
+		mockExpenseRepo.AssertNotCalled(t, "CreateBatch", mock.Anything)
+	})
+
+	t.Run("The file is read before the transaction opens", func(t *testing.T) {
+		mockExpenseRepo := new(MockExpenseRepository)
+		mockCategoryRepo := new(MockCategoryRepositoryForExpense)
+		file := strings.NewReader(importCSV)
+		expenseService := NewExpenseService(readFirstTxManager{t, file}, mockExpenseRepo, mockCategoryRepo, new(MockBudgetRepositoryForExpense),
+			newDefaultPaymentMethodRepository(), new(MockAccountRepository), newTestExchangeRateService())
+
+		mockCategoryRepo.On("GetAll", testUserID).Return([]*domain.Category{{ID: 1, Name: "Food"}, {ID: 2, Name: "Transport"}}, nil)
+		mockExpenseRepo.On("CreateBatch", mock.AnythingOfType("[]*domain.Expense")).Return(nil)
+
+		report, err := expenseService.ImportCSV(context.Background(), testUserID, file, domain.DefaultImportMapping(), false)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, report.Created)
+		mockExpenseRepo.AssertExpectations(t)
+	})
+
+	t.Run("Missing required column", func(t *testing.T) {
+		expenseService, _, _ := newTestImportService()
+
+		report, err := expenseService.ImportCSV(context.Background(), testUserID, strings.NewReader("date,amount\n2024-03-01,10\n"), domain.DefaultImportMapping(), false)
+		assert.Error(t, err)
+		assert.Nil(t, report)
+		assert.ErrorIs(t, err, domain.ErrInvalidInput)
+	})
+}
+
+// readFirstTxManager fails the test when a transaction opens before the whole file has been read
+type readFirstTxManager struct {
+	t    *testing.T
+	file *strings.Reader
+}
+
+func (m readFirstTxManager) WithinTx(ctx context.Context, fn func(tx domain.Tx) error) error {
+	assert.Zero(m.t, m.file.Len(), "transaction opened with the file still being read")
+	return fn(nil)
+}
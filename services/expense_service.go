@@ -1,111 +1,384 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"expense-tracker-api/domain"
+	"fmt"
+	"strings"
 	"time"
 )
 
+const (
+	defaultExpensePageSize = 50
+	maxExpensePageSize     = 200
+)
+
 type ExpenseService struct {
-	expenseRepo  domain.ExpenseRepository
-	categoryRepo domain.CategoryRepository
-	budgetRepo   domain.BudgetRepository
+	txManager         domain.TxManager
+	expenseRepo       domain.ExpenseRepository
+	categoryRepo      domain.CategoryRepository
+	budgetRepo        domain.BudgetRepository
+	paymentMethodRepo domain.PaymentMethodRepository
+	accountRepo       domain.AccountRepository
+	exchangeRates     *ExchangeRateService
 }
 
 // NewExpenseService creates a new expense service
-func NewExpenseService(expenseRepo domain.ExpenseRepository, categoryRepo domain.CategoryRepository, budgetRepo domain.BudgetRepository) *ExpenseService {
+func NewExpenseService(txManager domain.TxManager, expenseRepo domain.ExpenseRepository, categoryRepo domain.CategoryRepository,
+	budgetRepo domain.BudgetRepository, paymentMethodRepo domain.PaymentMethodRepository,
+	accountRepo domain.AccountRepository, exchangeRates *ExchangeRateService) *ExpenseService {
 	return &ExpenseService{
-		expenseRepo:  expenseRepo,
-		categoryRepo: categoryRepo,
-		budgetRepo:   budgetRepo,
+		txManager:         txManager,
+		expenseRepo:       expenseRepo,
+		categoryRepo:      categoryRepo,
+		budgetRepo:        budgetRepo,
+		paymentMethodRepo: paymentMethodRepo,
+		accountRepo:       accountRepo,
+		exchangeRates:     exchangeRates,
 	}
 }
 
-// CreateExpense creates a new expense
-func (s *ExpenseService) CreateExpense(expense *domain.Expense) (*domain.Expense, error) {
-	// Validate payment mode
-	if !expense.PaymentMode.IsValid() {
-		return nil, domain.ErrInvalidPaymentMode
+// withTx returns a copy of the service whose repositories are bound to tx
+func (s *ExpenseService) withTx(tx domain.Tx) *ExpenseService {
+	bound := *s
+	bound.expenseRepo = s.expenseRepo.WithTx(tx)
+	bound.categoryRepo = s.categoryRepo.WithTx(tx)
+	bound.budgetRepo = s.budgetRepo.WithTx(tx)
+	bound.paymentMethodRepo = s.paymentMethodRepo.WithTx(tx)
+	bound.accountRepo = s.accountRepo.WithTx(tx)
+	return &bound
+}
+
+// CreateExpense creates a new expense. The budget check sees the expense, and
+// only the expense, in the same transaction that creates it.
+func (s *ExpenseService) CreateExpense(ctx context.Context, expense *domain.Expense) (*domain.Expense, error) {
+	return withinTx(ctx, s.txManager, func(tx domain.Tx) (*domain.Expense, error) {
+		return s.withTx(tx).createExpense(ctx, expense)
+	})
+}
+
+// createExpense creates an expense with the service's repositories
+func (s *ExpenseService) createExpense(ctx context.Context, expense *domain.Expense) (*domain.Expense, error) {
+	if err := s.validateNewExpense(ctx, expense); err != nil {
+		return nil, err
 	}
 
-	// Verify category exists
-	_, err := s.categoryRepo.GetByID(expense.CategoryID)
+	err := s.expenseRepo.Create(ctx, expense)
 	if err != nil {
-		return nil, domain.ErrInvalidCategory
+		return nil, err
+	}
+
+	// Check budget status
+	s.checkBudget(ctx, expense)
+
+	return expense, nil
+}
+
+// validateNewExpense checks an expense before it is created, defaults its date to today
+// and converts its amount, given in its currency, into the base currency
+func (s *ExpenseService) validateNewExpense(ctx context.Context, expense *domain.Expense) error {
+	if err := s.prepareNewExpense(ctx, expense); err != nil {
+		return err
+	}
+	return checkCategory(ctx, s.categoryRepo, expense.UserID, expense.CategoryID)
+}
+
+// prepareNewExpense does all of validateNewExpense but check the category
+func (s *ExpenseService) prepareNewExpense(ctx context.Context, expense *domain.Expense) error {
+	if err := checkExpenseFields(expense); err != nil {
+		return err
+	}
+
+	if err := s.validatePaymentMode(ctx, expense.UserID, expense.PaymentMode); err != nil {
+		return err
+	}
+
+	tags, err := domain.NormalizeTagNames(expense.Tags)
+	if err != nil {
+		return err
+	}
+	expense.Tags = tags
+
+	if err := checkAccount(ctx, s.accountRepo, expense.UserID, expense.AccountID); err != nil {
+		return err
 	}
 
 	if expense.ExpenseDate.IsZero() {
 		expense.ExpenseDate = time.Now()
 	}
 
-	err = s.expenseRepo.Create(expense)
+	expense.OriginalAmount = expense.Amount
+	return s.convertAmount(ctx, expense)
+}
+
+// checkExpenseFields checks what every new expense must satisfy, whether it comes
+// from a request or an import row
+func checkExpenseFields(expense *domain.Expense) error {
+	if expense.Amount <= 0 {
+		return fmt.Errorf("%w: amount must be more than zero", domain.ErrInvalidInput)
+	}
+	if len([]rune(expense.Description)) > domain.MaxDescriptionLength {
+		return fmt.Errorf("%w: description must be at most %d characters", domain.ErrInvalidInput, domain.MaxDescriptionLength)
+	}
+	if !expense.ExpenseDate.IsZero() {
+		return checkExpenseDate(expense.ExpenseDate)
+	}
+	return nil
+}
+
+// checkExpenseDate checks that a date is within domain.EarliestDate and domain.LatestDate
+func checkExpenseDate(date time.Time) error {
+	if date.Before(domain.EarliestDate) || date.After(domain.LatestDate) {
+		return fmt.Errorf("%w: date must be between %s and %s", domain.ErrInvalidInput,
+			domain.EarliestDate.Format("2006-01-02"), domain.LatestDate.Format("2006-01-02"))
+	}
+	return nil
+}
+
+// convertAmount sets the base currency amount from the original amount, using the
+// rate for the expense date. An expense without a currency is in the base currency.
+func (s *ExpenseService) convertAmount(ctx context.Context, expense *domain.Expense) error {
+	if expense.Currency == "" {
+		expense.Currency = s.exchangeRates.BaseCurrency()
+	}
+	currency, err := domain.NormalizeCurrency(expense.Currency)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	expense.Currency = currency
 
-	// Check budget status
-	s.checkBudget(expense)
+	amount, rate, err := s.exchangeRates.Convert(ctx, expense.OriginalAmount, expense.Currency, expense.ExpenseDate)
+	if err != nil {
+		return err
+	}
+	// Both amounts are stored, and either may outgrow the columns
+	if expense.OriginalAmount > domain.MaxAmount || amount > domain.MaxAmount {
+		return domain.ErrAmountTooLarge
+	}
+	expense.Amount, expense.ExchangeRate = amount, rate
+	return nil
+}
 
-	return expense, nil
+// validatePaymentMode checks that the user has an active payment method with the
+// given name. Errors looking it up are passed on as they are.
+func (s *ExpenseService) validatePaymentMode(ctx context.Context, userID int, paymentMode domain.PaymentMode) error {
+	method, err := s.paymentMethodRepo.GetByName(ctx, userID, string(paymentMode))
+	if errors.Is(err, domain.ErrNotFound) {
+		return domain.ErrInvalidPaymentMode
+	}
+	if err != nil {
+		return err
+	}
+	if !method.Active {
+		return domain.ErrInvalidPaymentMode
+	}
+	return nil
+}
+
+// HasPaymentMethod reports whether the user has a payment method with the given name.
+// Inactive methods count, as their expenses can still be looked up.
+func (s *ExpenseService) HasPaymentMethod(ctx context.Context, userID int, paymentMode domain.PaymentMode) (bool, error) {
+	_, err := s.paymentMethodRepo.GetByName(ctx, userID, string(paymentMode))
+	if errors.Is(err, domain.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
 }
 
-// checkBudget checks if the monthly budget is exceeded
-func (s *ExpenseService) checkBudget(expense *domain.Expense) {
+// checkBudget checks if the monthly budget or the expense's category budget is exceeded
+func (s *ExpenseService) checkBudget(ctx context.Context, expense *domain.Expense) {
 	month := int(expense.ExpenseDate.Month())
 	year := expense.ExpenseDate.Year()
 
-	budget, err := s.budgetRepo.GetByMonth(month, year)
+	monthExceeded := false
+	budget, err := s.budgetRepo.GetByMonth(ctx, expense.UserID, month, year)
 	if err == nil && budget != nil {
-		spentAmount, err := s.expenseRepo.GetTotalByMonth(month, year)
+		spentAmount, err := s.expenseRepo.GetTotalByMonth(ctx, expense.UserID, month, year)
 		if err == nil && spentAmount > budget.BudgetAmount {
-			expense.Warning = "Warning: Monthly budget exceeded!"
+			monthExceeded = true
+		}
+	}
+
+	categoryExceeded := s.categoryBudgetExceeded(ctx, expense, month, year)
+
+	switch {
+	case monthExceeded && categoryExceeded:
+		expense.Warning = "Warning: Monthly budget and category budget exceeded!"
+	case monthExceeded:
+		expense.Warning = "Warning: Monthly budget exceeded!"
+	case categoryExceeded:
+		expense.Warning = "Warning: Category budget exceeded!"
+	}
+}
+
+// categoryBudgetExceeded reports whether the month's spending in the expense's category,
+// or in any category above it, is over that category's budget. Subcategory spending
+// counts towards every parent's budget.
+func (s *ExpenseService) categoryBudgetExceeded(ctx context.Context, expense *domain.Expense, month, year int) bool {
+	var categoryTotals map[int]domain.Money
+	seen := map[int]bool{}
+	for categoryID := &expense.CategoryID; categoryID != nil && !seen[*categoryID]; {
+		seen[*categoryID] = true
+
+		categoryBudget, err := s.budgetRepo.GetByMonthAndCategory(ctx, expense.UserID, month, year, *categoryID)
+		if err == nil && categoryBudget != nil {
+			if categoryTotals == nil {
+				if categoryTotals, err = s.expenseRepo.GetTotalsByCategoryForMonth(ctx, expense.UserID, month, year); err != nil {
+					return false
+				}
+			}
+			if categoryTotals[*categoryID] > categoryBudget.BudgetAmount {
+				return true
+			}
+		}
+
+		category, err := s.categoryRepo.GetByID(ctx, expense.UserID, *categoryID)
+		if err != nil {
+			return false
 		}
+		categoryID = category.ParentID
 	}
+	return false
 }
 
-// GetExpenses retrieves expenses with optional filters
-func (s *ExpenseService) GetExpenses(filter *domain.ExpenseFilter) ([]*domain.Expense, error) {
+// GetExpenses retrieves one page of expenses with optional filters.
+// Without a sort the newest expenses come first, or the best matches when searching.
+func (s *ExpenseService) GetExpenses(ctx context.Context, userID int, filter *domain.ExpenseFilter) (*domain.ExpensePage, error) {
+	if filter == nil {
+		filter = &domain.ExpenseFilter{Descending: true}
+	}
+
+	query := *filter
+	query.Query = strings.TrimSpace(query.Query)
+	if query.Sort == "" {
+		query.Sort = domain.ExpenseSortDate
+		if query.Query != "" {
+			query.Sort = domain.ExpenseSortRelevance
+		}
+	}
+	if !query.Sort.IsValid() || query.Limit < 0 {
+		return nil, domain.ErrInvalidInput
+	}
+	if query.Sort == domain.ExpenseSortRelevance && query.Query == "" {
+		return nil, domain.ErrInvalidInput
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultExpensePageSize
+	}
+	if query.Limit > maxExpensePageSize {
+		query.Limit = maxExpensePageSize
+	}
+
+	// A cursor only makes sense for the ordering it was issued for
+	if query.After != nil && (query.After.Sort != query.Sort || query.After.Descending != query.Descending) {
+		return nil, domain.ErrInvalidInput
+	}
+
+	totalCount, err := s.expenseRepo.Count(ctx, userID, &query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch one extra row to find out whether another page follows
+	limit := query.Limit
+	query.Limit++
+	expenses, err := s.expenseRepo.GetAll(ctx, userID, &query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.ExpensePage{Items: expenses, TotalCount: totalCount}
+	if len(expenses) > limit {
+		page.Items = expenses[:limit]
+		next := domain.NewExpenseCursor(query.Sort, query.Descending, page.Items[limit-1]).Encode()
+		page.NextCursor = &next
+	}
+	return page, nil
+}
+
+// ExportExpenses streams the expenses matching the filter to fn, one row at a time
+func (s *ExpenseService) ExportExpenses(ctx context.Context, userID int, filter *domain.ExpenseFilter, fn func(row *domain.ExpenseExportRow) error) error {
 	if filter == nil {
 		filter = &domain.ExpenseFilter{}
 	}
-	return s.expenseRepo.GetAll(filter)
+	return s.expenseRepo.StreamAll(ctx, userID, filter, fn)
 }
 
 // GetExpenseByID retrieves an expense by ID
-func (s *ExpenseService) GetExpenseByID(expenseID int) (*domain.Expense, error) {
-	expense, err := s.expenseRepo.GetByID(expenseID)
+func (s *ExpenseService) GetExpenseByID(ctx context.Context, userID, expenseID int) (*domain.Expense, error) {
+	expense, err := s.expenseRepo.GetByID(ctx, userID, expenseID)
 	if err != nil {
 		return nil, domain.ErrNotFound
 	}
 	return expense, nil
 }
 
-// UpdateExpense updates an expense
-func (s *ExpenseService) UpdateExpense(expense *domain.Expense) (*domain.Expense, error) {
+// UpdateExpense updates an expense. The expense stays locked from reading it to
+// writing it back, so concurrent edits to different fields are all kept.
+func (s *ExpenseService) UpdateExpense(ctx context.Context, expense *domain.Expense) (*domain.Expense, error) {
+	return withinTx(ctx, s.txManager, func(tx domain.Tx) (*domain.Expense, error) {
+		return s.withTx(tx).updateExpense(ctx, expense)
+	})
+}
+
+// updateExpense updates an expense with the service's repositories
+func (s *ExpenseService) updateExpense(ctx context.Context, expense *domain.Expense) (*domain.Expense, error) {
 	// Verify expense exists
-	existingExpense, err := s.expenseRepo.GetByID(expense.ID)
+	existingExpense, err := s.expenseRepo.GetByID(ctx, expense.UserID, expense.ID)
 	if err != nil {
 		return nil, domain.ErrNotFound
 	}
 
-	// Validate payment mode if provided
-	if expense.PaymentMode != "" && !expense.PaymentMode.IsValid() {
-		return nil, domain.ErrInvalidPaymentMode
+	// Validate payment mode if it changes; an expense keeps a method that was deactivated later
+	if expense.PaymentMode != "" && expense.PaymentMode != existingExpense.PaymentMode {
+		if err := s.validatePaymentMode(ctx, expense.UserID, expense.PaymentMode); err != nil {
+			return nil, err
+		}
 	}
 
-	// Verify category exists if category is being updated
-	if expense.CategoryID != 0 {
-		_, err := s.categoryRepo.GetByID(expense.CategoryID)
+	// Account 0 detaches the expense from its account
+	if expense.AccountID != nil {
+		if *expense.AccountID == 0 {
+			existingExpense.AccountID = nil
+		} else {
+			if err := checkAccount(ctx, s.accountRepo, expense.UserID, expense.AccountID); err != nil {
+				return nil, err
+			}
+			existingExpense.AccountID = expense.AccountID
+		}
+	}
+
+	// Nil tags leave the existing tags alone; an empty list clears them
+	if expense.Tags != nil {
+		tags, err := domain.NormalizeTagNames(expense.Tags)
 		if err != nil {
-			return nil, domain.ErrInvalidCategory
+			return nil, err
+		}
+		existingExpense.Tags = tags
+	}
+
+	// Verify the category if it is being changed. An expense can stay in a category archived since.
+	if expense.CategoryID != 0 && expense.CategoryID != existingExpense.CategoryID {
+		if err := checkCategory(ctx, s.categoryRepo, expense.UserID, expense.CategoryID); err != nil {
+			return nil, err
 		}
 	}
 
+	// A new amount is in the expense's (possibly new) currency. The recorded rate
+	// only changes when the amount, currency or date is edited.
+	convert := expense.Amount != 0 || expense.Currency != "" ||
+		(!expense.ExpenseDate.IsZero() && !expense.ExpenseDate.Equal(existingExpense.ExpenseDate))
+
 	// Update fields
 	if expense.CategoryID != 0 {
 		existingExpense.CategoryID = expense.CategoryID
 	}
 	if expense.Amount != 0 {
-		existingExpense.Amount = expense.Amount
+		existingExpense.OriginalAmount = expense.Amount
+	}
+	if expense.Currency != "" {
+		existingExpense.Currency = expense.Currency
 	}
 	if expense.Description != "" {
 		existingExpense.Description = expense.Description
@@ -117,24 +390,34 @@ func (s *ExpenseService) UpdateExpense(expense *domain.Expense) (*domain.Expense
 		existingExpense.ExpenseDate = expense.ExpenseDate
 	}
 
-	err = s.expenseRepo.Update(existingExpense)
+	if convert {
+		if err := s.convertAmount(ctx, existingExpense); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.expenseRepo.Update(ctx, existingExpense)
 	if err != nil {
 		return nil, err
 	}
 
 	// Check budget status for updated expense
-	s.checkBudget(existingExpense)
+	s.checkBudget(ctx, existingExpense)
 
 	return existingExpense, nil
 }
 
 // DeleteExpense deletes an expense
-func (s *ExpenseService) DeleteExpense(expenseID int) error {
-	// Verify expense exists
-	_, err := s.expenseRepo.GetByID(expenseID)
-	if err != nil {
-		return domain.ErrNotFound
-	}
+func (s *ExpenseService) DeleteExpense(ctx context.Context, userID, expenseID int) error {
+	return s.txManager.WithinTx(ctx, func(tx domain.Tx) error {
+		expenseRepo := s.expenseRepo.WithTx(tx)
+
+		// Verify expense exists
+		_, err := expenseRepo.GetByID(ctx, userID, expenseID)
+		if err != nil {
+			return domain.ErrNotFound
+		}
 
-	return s.expenseRepo.Delete(expenseID)
+		return expenseRepo.Delete(ctx, userID, expenseID)
+	})
 }
@@ -1,52 +1,93 @@
 package services
 
 import (
+	"context"
 	"expense-tracker-api/domain"
+	"expense-tracker-api/repository/memory"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
+// amount parses a test amount such as "1500.00"
+func amount(s string) domain.Money {
+	m, err := domain.ParseMoney(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
 // MockExpenseRepository is a mock implementation of ExpenseRepository
 type MockExpenseRepository struct {
 	mock.Mock
 }
 
-func (m *MockExpenseRepository) Create(expense *domain.Expense) error {
+// WithTx returns the mock itself, so expectations hold inside and outside a transaction
+func (m *MockExpenseRepository) WithTx(tx domain.Tx) domain.ExpenseRepository {
+	return m
+}
+
+func (m *MockExpenseRepository) Create(ctx context.Context, expense *domain.Expense) error {
 	args := m.Called(expense)
 	return args.Error(0)
 }
 
-func (m *MockExpenseRepository) GetByID(id int) (*domain.Expense, error) {
-	args := m.Called(id)
+func (m *MockExpenseRepository) CreateBatch(ctx context.Context, expenses []*domain.Expense) error {
+	args := m.Called(expenses)
+	return args.Error(0)
+}
+
+func (m *MockExpenseRepository) GetByID(ctx context.Context, userID, id int) (*domain.Expense, error) {
+	args := m.Called(userID, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*domain.Expense), args.Error(1)
 }
 
-func (m *MockExpenseRepository) GetAll(filter *domain.ExpenseFilter) ([]*domain.Expense, error) {
-	args := m.Called(filter)
+func (m *MockExpenseRepository) GetAll(ctx context.Context, userID int, filter *domain.ExpenseFilter) ([]*domain.Expense, error) {
+	args := m.Called(userID, filter)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).([]*domain.Expense), args.Error(1)
 }
 
-func (m *MockExpenseRepository) Update(expense *domain.Expense) error {
+func (m *MockExpenseRepository) Count(ctx context.Context, userID int, filter *domain.ExpenseFilter) (int, error) {
+	args := m.Called(userID, filter)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockExpenseRepository) StreamAll(ctx context.Context, userID int, filter *domain.ExpenseFilter, fn func(row *domain.ExpenseExportRow) error) error {
+	args := m.Called(userID, filter, fn)
+	return args.Error(0)
+}
+
+func (m *MockExpenseRepository) Update(ctx context.Context, expense *domain.Expense) error {
 	args := m.Called(expense)
 	return args.Error(0)
 }
 
-func (m *MockExpenseRepository) Delete(id int) error {
-	args := m.Called(id)
+func (m *MockExpenseRepository) Delete(ctx context.Context, userID, id int) error {
+	args := m.Called(userID, id)
 	return args.Error(0)
 }
 
-func (m *MockExpenseRepository) GetTotalByMonth(month, year int) (float64, error) {
-	args := m.Called(month, year)
-	return args.Get(0).(float64), args.Error(1)
+func (m *MockExpenseRepository) GetTotalByMonth(ctx context.Context, userID, month, year int) (domain.Money, error) {
+	args := m.Called(userID, month, year)
+	return args.Get(0).(domain.Money), args.Error(1)
+}
+
+func (m *MockExpenseRepository) GetTotalsByCategoryForMonth(ctx context.Context, userID, month, year int) (map[int]domain.Money, error) {
+	args := m.Called(userID, month, year)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[int]domain.Money), args.Error(1)
 }
 
 // MockBudgetRepository for expense service tests
@@ -54,42 +95,63 @@ type MockBudgetRepositoryForExpense struct {
 	mock.Mock
 }
 
-func (m *MockBudgetRepositoryForExpense) Create(budget *domain.Budget) error {
+// WithTx returns the mock itself, so expectations hold inside and outside a transaction
+func (m *MockBudgetRepositoryForExpense) WithTx(tx domain.Tx) domain.BudgetRepository {
+	return m
+}
+
+func (m *MockBudgetRepositoryForExpense) Create(ctx context.Context, budget *domain.Budget) error {
 	args := m.Called(budget)
 	return args.Error(0)
 }
 
-func (m *MockBudgetRepositoryForExpense) GetByID(id int) (*domain.Budget, error) {
-	args := m.Called(id)
+func (m *MockBudgetRepositoryForExpense) GetByID(ctx context.Context, userID, id int) (*domain.Budget, error) {
+	args := m.Called(userID, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*domain.Budget), args.Error(1)
 }
 
-func (m *MockBudgetRepositoryForExpense) GetAll() ([]*domain.Budget, error) {
-	args := m.Called()
+func (m *MockBudgetRepositoryForExpense) GetAll(ctx context.Context, userID int) ([]*domain.Budget, error) {
+	args := m.Called(userID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).([]*domain.Budget), args.Error(1)
 }
 
-func (m *MockBudgetRepositoryForExpense) GetByMonth(month, year int) (*domain.Budget, error) {
-	args := m.Called(month, year)
+func (m *MockBudgetRepositoryForExpense) GetByMonth(ctx context.Context, userID, month, year int) (*domain.Budget, error) {
+	args := m.Called(userID, month, year)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*domain.Budget), args.Error(1)
 }
 
-func (m *MockBudgetRepositoryForExpense) Update(budget *domain.Budget) error {
+func (m *MockBudgetRepositoryForExpense) GetByMonthAndCategory(ctx context.Context, userID, month, year, categoryID int) (*domain.Budget, error) {
+	args := m.Called(userID, month, year, categoryID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Budget), args.Error(1)
+}
+
+func (m *MockBudgetRepositoryForExpense) GetCategoryBudgetsByMonth(ctx context.Context, userID, month, year int) ([]*domain.Budget, error) {
+	args := m.Called(userID, month, year)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Budget), args.Error(1)
+}
+
+func (m *MockBudgetRepositoryForExpense) Update(ctx context.Context, budget *domain.Budget) error {
 	args := m.Called(budget)
 	return args.Error(0)
 }
 
-func (m *MockBudgetRepositoryForExpense) Delete(id int) error {
-	args := m.Called(id)
+func (m *MockBudgetRepositoryForExpense) Delete(ctx context.Context, userID, id int) error {
+	args := m.Called(userID, id)
 	return args.Error(0)
 }
 
@@ -98,101 +160,432 @@ type MockCategoryRepositoryForExpense struct {
 	mock.Mock
 }
 
-func (m *MockCategoryRepositoryForExpense) Create(category *domain.Category) error {
+// WithTx returns the mock itself, so expectations hold inside and outside a transaction
+func (m *MockCategoryRepositoryForExpense) WithTx(tx domain.Tx) domain.CategoryRepository {
+	return m
+}
+
+func (m *MockCategoryRepositoryForExpense) Create(ctx context.Context, category *domain.Category) error {
 	args := m.Called(category)
 	return args.Error(0)
 }
 
-func (m *MockCategoryRepositoryForExpense) GetByID(id int) (*domain.Category, error) {
-	args := m.Called(id)
+func (m *MockCategoryRepositoryForExpense) GetByID(ctx context.Context, userID, id int) (*domain.Category, error) {
+	args := m.Called(userID, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*domain.Category), args.Error(1)
 }
 
-func (m *MockCategoryRepositoryForExpense) GetAll() ([]*domain.Category, error) {
-	args := m.Called()
+func (m *MockCategoryRepositoryForExpense) GetAll(ctx context.Context, userID int) ([]*domain.Category, error) {
+	args := m.Called(userID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).([]*domain.Category), args.Error(1)
 }
 
-func (m *MockCategoryRepositoryForExpense) Update(category *domain.Category) error {
+func (m *MockCategoryRepositoryForExpense) Update(ctx context.Context, category *domain.Category) error {
 	args := m.Called(category)
 	return args.Error(0)
 }
 
-func (m *MockCategoryRepositoryForExpense) Delete(id int) error {
-	args := m.Called(id)
+func (m *MockCategoryRepositoryForExpense) SetArchived(ctx context.Context, userID, id int, archived bool) error {
+	args := m.Called(userID, id, archived)
+	return args.Error(0)
+}
+
+func (m *MockCategoryRepositoryForExpense) Delete(ctx context.Context, userID, id int) error {
+	args := m.Called(userID, id)
+	return args.Error(0)
+}
+
+func (m *MockCategoryRepositoryForExpense) Merge(ctx context.Context, userID, sourceID, targetID int) error {
+	args := m.Called(userID, sourceID, targetID)
 	return args.Error(0)
 }
 
+// fakeTxManager runs the work without a transaction, for services whose repositories are mocks
+type fakeTxManager struct{}
+
+func (fakeTxManager) WithinTx(ctx context.Context, fn func(tx domain.Tx) error) error {
+	return fn(nil)
+}
+
 func TestExpenseService_CreateExpense(t *testing.T) {
 	t.Run("Successful creation", func(t *testing.T) {
 		mockExpenseRepo := new(MockExpenseRepository)
 		mockCategoryRepo := new(MockCategoryRepositoryForExpense)
 		mockBudgetRepo := new(MockBudgetRepositoryForExpense)
-		expenseService := NewExpenseService(mockExpenseRepo, mockCategoryRepo, mockBudgetRepo)
+		expenseService := NewExpenseService(fakeTxManager{}, mockExpenseRepo, mockCategoryRepo, mockBudgetRepo, newDefaultPaymentMethodRepository(), new(MockAccountRepository), newTestExchangeRateService())
 
 		category := &domain.Category{ID: 1}
-		mockCategoryRepo.On("GetByID", 1).Return(category, nil)
+		mockCategoryRepo.On("GetByID", testUserID, 1).Return(category, nil)
 		mockExpenseRepo.On("Create", mock.AnythingOfType("*domain.Expense")).Return(nil).Run(func(args mock.Arguments) {
 			expense := args.Get(0).(*domain.Expense)
 			expense.ID = 1
 		})
+		mockBudgetRepo.On("GetByMonth", testUserID, mock.Anything, mock.Anything).Return(nil, domain.ErrNotFound)
+		mockBudgetRepo.On("GetByMonthAndCategory", testUserID, mock.Anything, mock.Anything, 1).Return(nil, domain.ErrNotFound)
 
 		expense := &domain.Expense{
+			UserID:      testUserID,
 			CategoryID:  1,
-			Amount:      100.0,
+			Amount:      amount("100.00"),
 			Description: "Test expense",
 			PaymentMode: domain.PaymentModeUPI,
 		}
 
-		createdExpense, err := expenseService.CreateExpense(expense)
+		createdExpense, err := expenseService.CreateExpense(context.Background(), expense)
 		assert.NoError(t, err)
 		assert.NotNil(t, createdExpense)
+		assert.Empty(t, createdExpense.Warning)
 		mockExpenseRepo.AssertExpectations(t)
 		mockCategoryRepo.AssertExpectations(t)
 	})
 
+	t.Run("Expenses a request validator would refuse are refused", func(t *testing.T) {
+		mockExpenseRepo := new(MockExpenseRepository)
+		expenseService := NewExpenseService(fakeTxManager{}, mockExpenseRepo, new(MockCategoryRepositoryForExpense), new(MockBudgetRepositoryForExpense),
+			newDefaultPaymentMethodRepository(), new(MockAccountRepository), newTestExchangeRateService())
+
+		for _, expense := range []*domain.Expense{
+			{Amount: amount("-50")},
+			{Amount: 0},
+			{Amount: amount("10"), ExpenseDate: time.Date(2200, time.January, 1, 0, 0, 0, 0, time.UTC)},
+			{Amount: amount("10"), Description: strings.Repeat("a", domain.MaxDescriptionLength+1)},
+		} {
+			expense.UserID, expense.CategoryID, expense.PaymentMode = testUserID, 1, domain.PaymentModeCash
+			_, err := expenseService.CreateExpense(context.Background(), expense)
+			assert.ErrorIs(t, err, domain.ErrInvalidInput)
+		}
+		mockExpenseRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+
+	t.Run("Tags are normalized and deduplicated", func(t *testing.T) {
+		mockExpenseRepo := new(MockExpenseRepository)
+		mockCategoryRepo := new(MockCategoryRepositoryForExpense)
+		mockBudgetRepo := new(MockBudgetRepositoryForExpense)
+		expenseService := NewExpenseService(fakeTxManager{}, mockExpenseRepo, mockCategoryRepo, mockBudgetRepo, newDefaultPaymentMethodRepository(), new(MockAccountRepository), newTestExchangeRateService())
+
+		mockCategoryRepo.On("GetByID", testUserID, 1).Return(&domain.Category{ID: 1}, nil)
+		mockExpenseRepo.On("Create", mock.AnythingOfType("*domain.Expense")).Return(nil)
+		mockBudgetRepo.On("GetByMonth", testUserID, mock.Anything, mock.Anything).Return(nil, domain.ErrNotFound)
+		mockBudgetRepo.On("GetByMonthAndCategory", testUserID, mock.Anything, mock.Anything, 1).Return(nil, domain.ErrNotFound)
+
+		createdExpense, err := expenseService.CreateExpense(context.Background(), &domain.Expense{
+			UserID:      testUserID,
+			CategoryID:  1,
+			Amount:      amount("2400.00"),
+			PaymentMode: domain.PaymentModeUPI,
+			Tags:        []string{"Trip-Goa", " reimbursable", "trip-goa"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"trip-goa", "reimbursable"}, createdExpense.Tags)
+	})
+
+	t.Run("Blank tag", func(t *testing.T) {
+		expenseService := NewExpenseService(fakeTxManager{}, new(MockExpenseRepository), new(MockCategoryRepositoryForExpense), new(MockBudgetRepositoryForExpense), newDefaultPaymentMethodRepository(), new(MockAccountRepository), newTestExchangeRateService())
+
+		createdExpense, err := expenseService.CreateExpense(context.Background(), &domain.Expense{
+			UserID:      testUserID,
+			CategoryID:  1,
+			Amount:      amount("100.00"),
+			PaymentMode: domain.PaymentModeUPI,
+			Tags:        []string{"gift", " "},
+		})
+		assert.Nil(t, createdExpense)
+		assert.Equal(t, domain.ErrInvalidInput, err)
+	})
+
+	t.Run("Category budget exceeded", func(t *testing.T) {
+		mockExpenseRepo := new(MockExpenseRepository)
+		mockCategoryRepo := new(MockCategoryRepositoryForExpense)
+		mockBudgetRepo := new(MockBudgetRepositoryForExpense)
+		expenseService := NewExpenseService(fakeTxManager{}, mockExpenseRepo, mockCategoryRepo, mockBudgetRepo, newDefaultPaymentMethodRepository(), new(MockAccountRepository), newTestExchangeRateService())
+
+		categoryID := 1
+		mockCategoryRepo.On("GetByID", testUserID, 1).Return(&domain.Category{ID: 1}, nil)
+		mockExpenseRepo.On("Create", mock.AnythingOfType("*domain.Expense")).Return(nil)
+		mockBudgetRepo.On("GetByMonth", testUserID, 3, 2024).Return(&domain.Budget{Month: 3, Year: 2024, BudgetAmount: amount("12000.00")}, nil)
+		mockExpenseRepo.On("GetTotalByMonth", testUserID, 3, 2024).Return(amount("7000.00"), nil)
+		mockBudgetRepo.On("GetByMonthAndCategory", testUserID, 3, 2024, 1).Return(&domain.Budget{CategoryID: &categoryID, Month: 3, Year: 2024, BudgetAmount: amount("6000.00")}, nil)
+		mockExpenseRepo.On("GetTotalsByCategoryForMonth", testUserID, 3, 2024).Return(map[int]domain.Money{1: amount("6500.00")}, nil)
+
+		expense := &domain.Expense{
+			UserID:      testUserID,
+			CategoryID:  1,
+			Amount:      amount("1500.00"),
+			PaymentMode: domain.PaymentModeUPI,
+			ExpenseDate: time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC),
+		}
+
+		createdExpense, err := expenseService.CreateExpense(context.Background(), expense)
+		assert.NoError(t, err)
+		assert.Equal(t, "Warning: Category budget exceeded!", createdExpense.Warning)
+		mockExpenseRepo.AssertExpectations(t)
+		mockBudgetRepo.AssertExpectations(t)
+	})
+
+	t.Run("Subcategory spending exceeds the parent's budget", func(t *testing.T) {
+		mockExpenseRepo := new(MockExpenseRepository)
+		mockCategoryRepo := new(MockCategoryRepositoryForExpense)
+		mockBudgetRepo := new(MockBudgetRepositoryForExpense)
+		expenseService := NewExpenseService(fakeTxManager{}, mockExpenseRepo, mockCategoryRepo, mockBudgetRepo, newDefaultPaymentMethodRepository(), new(MockAccountRepository), newTestExchangeRateService())
+
+		// Groceries (2) is under Food (1), which has the budget
+		foodID := 1
+		mockCategoryRepo.On("GetByID", testUserID, 2).Return(&domain.Category{ID: 2, ParentID: &foodID}, nil)
+		mockCategoryRepo.On("GetByID", testUserID, 1).Return(&domain.Category{ID: 1}, nil)
+		mockExpenseRepo.On("Create", mock.AnythingOfType("*domain.Expense")).Return(nil)
+		mockBudgetRepo.On("GetByMonth", testUserID, 3, 2024).Return(nil, domain.ErrNotFound)
+		mockBudgetRepo.On("GetByMonthAndCategory", testUserID, 3, 2024, 2).Return(nil, domain.ErrNotFound)
+		mockBudgetRepo.On("GetByMonthAndCategory", testUserID, 3, 2024, 1).Return(&domain.Budget{CategoryID: &foodID, Month: 3, Year: 2024, BudgetAmount: amount("6000.00")}, nil)
+		mockExpenseRepo.On("GetTotalsByCategoryForMonth", testUserID, 3, 2024).
+			Return(map[int]domain.Money{1: amount("6500.00"), 2: amount("6500.00")}, nil)
+
+		createdExpense, err := expenseService.CreateExpense(context.Background(), &domain.Expense{
+			UserID:      testUserID,
+			CategoryID:  2,
+			Amount:      amount("1500.00"),
+			PaymentMode: domain.PaymentModeUPI,
+			ExpenseDate: time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "Warning: Category budget exceeded!", createdExpense.Warning)
+		mockBudgetRepo.AssertExpectations(t)
+	})
+
+	t.Run("Archived category", func(t *testing.T) {
+		mockExpenseRepo := new(MockExpenseRepository)
+		mockCategoryRepo := new(MockCategoryRepositoryForExpense)
+		expenseService := NewExpenseService(fakeTxManager{}, mockExpenseRepo, mockCategoryRepo, new(MockBudgetRepositoryForExpense), newDefaultPaymentMethodRepository(), new(MockAccountRepository), newTestExchangeRateService())
+
+		mockCategoryRepo.On("GetByID", testUserID, 1).Return(&domain.Category{ID: 1, Name: "Hobbies", Archived: true}, nil)
+
+		_, err := expenseService.CreateExpense(context.Background(), &domain.Expense{
+			UserID:      testUserID,
+			CategoryID:  1,
+			Amount:      amount("100.00"),
+			PaymentMode: domain.PaymentModeUPI,
+		})
+		assert.ErrorIs(t, err, domain.ErrInvalidCategory)
+		mockExpenseRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+
 	t.Run("Invalid payment mode", func(t *testing.T) {
 		mockExpenseRepo := new(MockExpenseRepository)
 		mockCategoryRepo := new(MockCategoryRepositoryForExpense)
 		mockBudgetRepo := new(MockBudgetRepositoryForExpense)
-		expenseService := NewExpenseService(mockExpenseRepo, mockCategoryRepo, mockBudgetRepo)
+		expenseService := NewExpenseService(fakeTxManager{}, mockExpenseRepo, mockCategoryRepo, mockBudgetRepo, newDefaultPaymentMethodRepository(), new(MockAccountRepository), newTestExchangeRateService())
 
 		expense := &domain.Expense{
+			UserID:      testUserID,
 			CategoryID:  1,
-			Amount:      100.0,
+			Amount:      amount("100.00"),
 			PaymentMode: domain.PaymentMode("Invalid"),
 		}
 
-		createdExpense, err := expenseService.CreateExpense(expense)
+		createdExpense, err := expenseService.CreateExpense(context.Background(), expense)
 		assert.Error(t, err)
 		assert.Nil(t, createdExpense)
 		assert.Equal(t, domain.ErrInvalidPaymentMode, err)
 	})
 
+	t.Run("Inactive payment method", func(t *testing.T) {
+		mockPaymentMethodRepo := new(MockPaymentMethodRepository)
+		expenseService := NewExpenseService(fakeTxManager{}, new(MockExpenseRepository), new(MockCategoryRepositoryForExpense), new(MockBudgetRepositoryForExpense), mockPaymentMethodRepo, new(MockAccountRepository), newTestExchangeRateService())
+
+		mockPaymentMethodRepo.On("GetByName", testUserID, "Old Card").Return(&domain.PaymentMethod{Name: "Old Card", Type: domain.PaymentMethodTypeCard}, nil)
+
+		expense := &domain.Expense{
+			UserID:      testUserID,
+			CategoryID:  1,
+			Amount:      amount("100.00"),
+			PaymentMode: domain.PaymentMode("Old Card"),
+		}
+
+		createdExpense, err := expenseService.CreateExpense(context.Background(), expense)
+		assert.Nil(t, createdExpense)
+		assert.Equal(t, domain.ErrInvalidPaymentMode, err)
+		mockPaymentMethodRepo.AssertExpectations(t)
+	})
+
 	t.Run("Invalid category", func(t *testing.T) {
 		mockExpenseRepo := new(MockExpenseRepository)
 		mockCategoryRepo := new(MockCategoryRepositoryForExpense)
 		mockBudgetRepo := new(MockBudgetRepositoryForExpense)
-		expenseService := NewExpenseService(mockExpenseRepo, mockCategoryRepo, mockBudgetRepo)
+		expenseService := NewExpenseService(fakeTxManager{}, mockExpenseRepo, mockCategoryRepo, mockBudgetRepo, newDefaultPaymentMethodRepository(), new(MockAccountRepository), newTestExchangeRateService())
 
-		mockCategoryRepo.On("GetByID", 1).Return(nil, domain.ErrNotFound)
+		mockCategoryRepo.On("GetByID", testUserID, 1).Return(nil, domain.ErrNotFound)
 
 		expense := &domain.Expense{
+			UserID:      testUserID,
 			CategoryID:  1,
-			Amount:      100.0,
+			Amount:      amount("100.00"),
 			PaymentMode: domain.PaymentModeUPI,
 		}
 
-		createdExpense, err := expenseService.CreateExpense(expense)
+		createdExpense, err := expenseService.CreateExpense(context.Background(), expense)
 		assert.Error(t, err)
 		assert.Nil(t, createdExpense)
 		assert.Equal(t, domain.ErrInvalidCategory, err)
 		mockCategoryRepo.AssertExpectations(t)
 	})
 }
+
+func TestExpenseService_GetExpenses(t *testing.T) {
+	t.Run("Returns a cursor when more rows follow", func(t *testing.T) {
+		mockExpenseRepo := new(MockExpenseRepository)
+		expenseService := NewExpenseService(fakeTxManager{}, mockExpenseRepo, new(MockCategoryRepositoryForExpense), new(MockBudgetRepositoryForExpense), newDefaultPaymentMethodRepository(), new(MockAccountRepository), newTestExchangeRateService())
+
+		expenses := []*domain.Expense{
+			{ID: 9, Amount: amount("500.00")},
+			{ID: 4, Amount: amount("300.00")},
+			{ID: 7, Amount: amount("300.00")},
+		}
+		mockExpenseRepo.On("Count", testUserID, mock.AnythingOfType("*domain.ExpenseFilter")).Return(5, nil)
+		mockExpenseRepo.On("GetAll", testUserID, mock.MatchedBy(func(filter *domain.ExpenseFilter) bool {
+			return filter.Limit == 3 && filter.Sort == domain.ExpenseSortAmount
+		})).Return(expenses, nil)
+
+		page, err := expenseService.GetExpenses(context.Background(), testUserID, &domain.ExpenseFilter{Sort: domain.ExpenseSortAmount, Descending: true, Limit: 2})
+		assert.NoError(t, err)
+		assert.Len(t, page.Items, 2)
+		assert.Equal(t, 5, page.TotalCount)
+		assert.NotNil(t, page.NextCursor)
+
+		cursor, err := domain.ParseExpenseCursor(*page.NextCursor)
+		assert.NoError(t, err)
+		assert.Equal(t, &domain.ExpenseCursor{Sort: domain.ExpenseSortAmount, Descending: true, Value: "300.00", ID: 4}, cursor)
+		mockExpenseRepo.AssertExpectations(t)
+	})
+
+	t.Run("Last page has no cursor and limit is capped", func(t *testing.T) {
+		mockExpenseRepo := new(MockExpenseRepository)
+		expenseService := NewExpenseService(fakeTxManager{}, mockExpenseRepo, new(MockCategoryRepositoryForExpense), new(MockBudgetRepositoryForExpense), newDefaultPaymentMethodRepository(), new(MockAccountRepository), newTestExchangeRateService())
+
+		mockExpenseRepo.On("Count", testUserID, mock.AnythingOfType("*domain.ExpenseFilter")).Return(1, nil)
+		mockExpenseRepo.On("GetAll", testUserID, mock.MatchedBy(func(filter *domain.ExpenseFilter) bool {
+			return filter.Limit == maxExpensePageSize+1
+		})).Return([]*domain.Expense{{ID: 1}}, nil)
+
+		page, err := expenseService.GetExpenses(context.Background(), testUserID, &domain.ExpenseFilter{Descending: true, Limit: 10000})
+		assert.NoError(t, err)
+		assert.Len(t, page.Items, 1)
+		assert.Nil(t, page.NextCursor)
+		mockExpenseRepo.AssertExpectations(t)
+	})
+
+	t.Run("Cursor from a different ordering", func(t *testing.T) {
+		mockExpenseRepo := new(MockExpenseRepository)
+		expenseService := NewExpenseService(fakeTxManager{}, mockExpenseRepo, new(MockCategoryRepositoryForExpense), new(MockBudgetRepositoryForExpense), newDefaultPaymentMethodRepository(), new(MockAccountRepository), newTestExchangeRateService())
+
+		after := &domain.ExpenseCursor{Sort: domain.ExpenseSortDate, Descending: true, Value: "2024-03-01", ID: 3}
+		page, err := expenseService.GetExpenses(context.Background(), testUserID, &domain.ExpenseFilter{Sort: domain.ExpenseSortAmount, Descending: true, After: after})
+		assert.Nil(t, page)
+		assert.Equal(t, domain.ErrInvalidInput, err)
+	})
+}
+
+func TestExpenseService_SearchExpenses(t *testing.T) {
+	t.Run("Search defaults to relevance order", func(t *testing.T) {
+		mockExpenseRepo := new(MockExpenseRepository)
+		expenseService := NewExpenseService(fakeTxManager{}, mockExpenseRepo, new(MockCategoryRepositoryForExpense), new(MockBudgetRepositoryForExpense), newDefaultPaymentMethodRepository(), new(MockAccountRepository), newTestExchangeRateService())
+
+		results := []*domain.Expense{
+			{ID: 12, Description: "Swiggy order", Rank: 0.6079271, Highlight: "<mark>Swiggy</mark> order"},
+			{ID: 3, Description: "Swiggy dinner", Rank: 0.2, Highlight: "<mark>Swiggy</mark> dinner"},
+		}
+		mockExpenseRepo.On("Count", testUserID, mock.AnythingOfType("*domain.ExpenseFilter")).Return(3, nil)
+		mockExpenseRepo.On("GetAll", testUserID, mock.MatchedBy(func(filter *domain.ExpenseFilter) bool {
+			return filter.Query == "swiggy" && filter.Sort == domain.ExpenseSortRelevance
+		})).Return(results, nil)
+
+		page, err := expenseService.GetExpenses(context.Background(), testUserID, &domain.ExpenseFilter{Query: " swiggy ", Descending: true, Limit: 1})
+		assert.NoError(t, err)
+		assert.Len(t, page.Items, 1)
+
+		cursor, err := domain.ParseExpenseCursor(*page.NextCursor)
+		assert.NoError(t, err)
+		assert.Equal(t, domain.ExpenseSortRelevance, cursor.Sort)
+		assert.Equal(t, "0.6079271", cursor.Value)
+		mockExpenseRepo.AssertExpectations(t)
+	})
+
+	t.Run("Relevance without a query", func(t *testing.T) {
+		mockExpenseRepo := new(MockExpenseRepository)
+		expenseService := NewExpenseService(fakeTxManager{}, mockExpenseRepo, new(MockCategoryRepositoryForExpense), new(MockBudgetRepositoryForExpense), newDefaultPaymentMethodRepository(), new(MockAccountRepository), newTestExchangeRateService())
+
+		page, err := expenseService.GetExpenses(context.Background(), testUserID, &domain.ExpenseFilter{Sort: domain.ExpenseSortRelevance})
+		assert.Nil(t, page)
+		assert.Equal(t, domain.ErrInvalidInput, err)
+	})
+}
+
+func TestExpenseService_MemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	expenseService := NewExpenseService(memory.NewTxManager(store), memory.NewExpenseRepository(store),
+		memory.NewCategoryRepository(store), memory.NewBudgetRepository(store), memory.NewPaymentMethodRepository(store),
+		memory.NewAccountRepository(store), NewExchangeRateService(memory.NewExchangeRateRepository(store), "INR"))
+
+	user := &domain.User{Email: "memory@example.com", PasswordHash: "hash"}
+	assert.NoError(t, memory.NewUserRepository(store).Create(ctx, user))
+	food := &domain.Category{UserID: user.ID, Name: "Food"}
+	assert.NoError(t, memory.NewCategoryRepository(store).Create(ctx, food))
+	groceries := &domain.Category{UserID: user.ID, Name: "Groceries", ParentID: &food.ID}
+	assert.NoError(t, memory.NewCategoryRepository(store).Create(ctx, groceries))
+	assert.NoError(t, memory.NewBudgetRepository(store).Create(ctx, &domain.Budget{UserID: user.ID, CategoryID: &food.ID,
+		Month: 3, Year: 2024, BudgetAmount: amount("500.00")}))
+
+	create := func(categoryID int, value, description string, day int) *domain.Expense {
+		expense, err := expenseService.CreateExpense(ctx, &domain.Expense{UserID: user.ID, CategoryID: categoryID, Amount: amount(value),
+			Description: description, PaymentMode: domain.PaymentModeUPI, ExpenseDate: date(2024, 3, day)})
+		assert.NoError(t, err)
+		return expense
+	}
+
+	t.Run("Subcategory spending counts towards the parent's budget", func(t *testing.T) {
+		assert.Empty(t, create(groceries.ID, "300.00", "Vegetables", 2).Warning)
+		assert.Equal(t, "Warning: Category budget exceeded!", create(groceries.ID, "300.00", "Fruit", 3).Warning)
+	})
+
+	t.Run("Pages through filtered expenses without gaps", func(t *testing.T) {
+		create(food.ID, "120.00", "Dinner", 4)
+		create(food.ID, "80.00", "Lunch", 5)
+
+		filter := &domain.ExpenseFilter{CategoryID: &food.ID, IncludeSubcategories: true, Sort: domain.ExpenseSortAmount, Descending: true, Limit: 3}
+		page, err := expenseService.GetExpenses(ctx, user.ID, filter)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, page.TotalCount)
+		assert.Len(t, page.Items, 3)
+		if assert.NotNil(t, page.NextCursor) {
+			filter.After, err = domain.ParseExpenseCursor(*page.NextCursor)
+			assert.NoError(t, err)
+			page, err = expenseService.GetExpenses(ctx, user.ID, filter)
+			assert.NoError(t, err)
+			assert.Len(t, page.Items, 1)
+			assert.Equal(t, "Lunch", page.Items[0].Description)
+			assert.Nil(t, page.NextCursor)
+		}
+	})
+}
+
+func TestExpenseService_HasPaymentMethod(t *testing.T) {
+	mockPaymentMethodRepo := new(MockPaymentMethodRepository)
+	expenseService := NewExpenseService(fakeTxManager{}, new(MockExpenseRepository), new(MockCategoryRepositoryForExpense), new(MockBudgetRepositoryForExpense),
+		mockPaymentMethodRepo, new(MockAccountRepository), newTestExchangeRateService())
+
+	mockPaymentMethodRepo.On("GetByName", testUserID, "Old Card").Return(&domain.PaymentMethod{Name: "Old Card", Active: false}, nil)
+	mockPaymentMethodRepo.On("GetByName", testUserID, "Amex").Return(nil, domain.ErrNotFound)
+
+	known, err := expenseService.HasPaymentMethod(context.Background(), testUserID, "Old Card")
+	assert.NoError(t, err)
+	assert.True(t, known, "an inactive method still names its expenses")
+
+	known, err = expenseService.HasPaymentMethod(context.Background(), testUserID, "Amex")
+	assert.NoError(t, err)
+	assert.False(t, known)
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"expense-tracker-api/domain"
+	"strings"
+	"time"
+)
+
+// maxIncomeSourceLength matches the incomes.source column
+const maxIncomeSourceLength = 255
+
+type IncomeService struct {
+	txManager         domain.TxManager
+	incomeRepo        domain.IncomeRepository
+	categoryRepo      domain.CategoryRepository
+	paymentMethodRepo domain.PaymentMethodRepository
+	accountRepo       domain.AccountRepository
+}
+
+// NewIncomeService creates a new income service
+func NewIncomeService(txManager domain.TxManager, incomeRepo domain.IncomeRepository, categoryRepo domain.CategoryRepository,
+	paymentMethodRepo domain.PaymentMethodRepository, accountRepo domain.AccountRepository) *IncomeService {
+	return &IncomeService{
+		txManager:         txManager,
+		incomeRepo:        incomeRepo,
+		categoryRepo:      categoryRepo,
+		paymentMethodRepo: paymentMethodRepo,
+		accountRepo:       accountRepo,
+	}
+}
+
+// withTx returns a copy of the service whose repositories are bound to tx
+func (s *IncomeService) withTx(tx domain.Tx) *IncomeService {
+	bound := *s
+	bound.incomeRepo = s.incomeRepo.WithTx(tx)
+	bound.categoryRepo = s.categoryRepo.WithTx(tx)
+	bound.paymentMethodRepo = s.paymentMethodRepo.WithTx(tx)
+	bound.accountRepo = s.accountRepo.WithTx(tx)
+	return &bound
+}
+
+// CreateIncome records money received, dated today unless a date is given
+func (s *IncomeService) CreateIncome(ctx context.Context, income *domain.Income) (*domain.Income, error) {
+	return withinTx(ctx, s.txManager, func(tx domain.Tx) (*domain.Income, error) {
+		return s.withTx(tx).createIncome(ctx, income)
+	})
+}
+
+// createIncome records an income with the service's repositories
+func (s *IncomeService) createIncome(ctx context.Context, income *domain.Income) (*domain.Income, error) {
+	if income.IncomeDate.IsZero() {
+		income.IncomeDate = time.Now()
+	}
+
+	if err := s.validate(ctx, income, income.PaymentMode != nil); err != nil {
+		return nil, err
+	}
+
+	err := s.incomeRepo.Create(ctx, income)
+	if err != nil {
+		return nil, err
+	}
+
+	return income, nil
+}
+
+// GetIncomes retrieves the incomes in the filter's date range, newest first
+func (s *IncomeService) GetIncomes(ctx context.Context, userID int, filter *domain.IncomeFilter) ([]*domain.Income, error) {
+	if filter.StartDate != nil && filter.EndDate != nil && filter.EndDate.Before(*filter.StartDate) {
+		return nil, domain.ErrInvalidInput
+	}
+	return s.incomeRepo.GetAll(ctx, userID, filter)
+}
+
+// GetIncomeByID retrieves an income by ID
+func (s *IncomeService) GetIncomeByID(ctx context.Context, userID, id int) (*domain.Income, error) {
+	income, err := s.incomeRepo.GetByID(ctx, userID, id)
+	if err != nil {
+		return nil, domain.ErrNotFound
+	}
+	return income, nil
+}
+
+// UpdateIncome replaces an income. A missing date keeps the recorded one.
+func (s *IncomeService) UpdateIncome(ctx context.Context, income *domain.Income) (*domain.Income, error) {
+	return withinTx(ctx, s.txManager, func(tx domain.Tx) (*domain.Income, error) {
+		return s.withTx(tx).updateIncome(ctx, income)
+	})
+}
+
+// updateIncome replaces an income with the service's repositories
+func (s *IncomeService) updateIncome(ctx context.Context, income *domain.Income) (*domain.Income, error) {
+	existing, err := s.incomeRepo.GetByID(ctx, income.UserID, income.ID)
+	if err != nil {
+		return nil, domain.ErrNotFound
+	}
+
+	if income.IncomeDate.IsZero() {
+		income.IncomeDate = existing.IncomeDate
+	}
+
+	// A payment method deactivated since the income was recorded can stay on it
+	checkPaymentMode := income.PaymentMode != nil &&
+		(existing.PaymentMode == nil || *existing.PaymentMode != *income.PaymentMode)
+	if err := s.validate(ctx, income, checkPaymentMode); err != nil {
+		return nil, err
+	}
+	income.CreatedAt = existing.CreatedAt
+
+	err = s.incomeRepo.Update(ctx, income)
+	if err != nil {
+		return nil, err
+	}
+
+	return income, nil
+}
+
+// DeleteIncome deletes an income
+func (s *IncomeService) DeleteIncome(ctx context.Context, userID, id int) error {
+	return s.txManager.WithinTx(ctx, func(tx domain.Tx) error {
+		incomeRepo := s.incomeRepo.WithTx(tx)
+
+		_, err := incomeRepo.GetByID(ctx, userID, id)
+		if err != nil {
+			return domain.ErrNotFound
+		}
+
+		return incomeRepo.Delete(ctx, userID, id)
+	})
+}
+
+// validate checks an income and normalizes its source and date. The payment
+// method, when checked, must be one of the user's active methods, and the
+// account, when given, one of the user's accounts.
+func (s *IncomeService) validate(ctx context.Context, income *domain.Income, checkPaymentMode bool) error {
+	income.Source = strings.TrimSpace(income.Source)
+	if income.Source == "" || len(income.Source) > maxIncomeSourceLength {
+		return domain.ErrInvalidInput
+	}
+
+	if income.Amount <= 0 {
+		return domain.ErrInvalidInput
+	}
+
+	if checkPaymentMode {
+		method, err := s.paymentMethodRepo.GetByName(ctx, income.UserID, string(*income.PaymentMode))
+		if err != nil || !method.Active {
+			return domain.ErrInvalidPaymentMode
+		}
+	}
+
+	if income.CategoryID != nil {
+		if _, err := s.categoryRepo.GetByID(ctx, income.UserID, *income.CategoryID); err != nil {
+			return domain.ErrInvalidCategory
+		}
+	}
+
+	if err := checkAccount(ctx, s.accountRepo, income.UserID, income.AccountID); err != nil {
+		return err
+	}
+
+	income.IncomeDate = domain.TruncateToDate(income.IncomeDate)
+	return nil
+}
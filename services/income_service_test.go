@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"expense-tracker-api/domain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockIncomeRepository is a mock implementation of IncomeRepository
+type MockIncomeRepository struct {
+	mock.Mock
+}
+
+// WithTx returns the mock itself, so expectations hold inside and outside a transaction
+func (m *MockIncomeRepository) WithTx(tx domain.Tx) domain.IncomeRepository {
+	return m
+}
+
+func (m *MockIncomeRepository) Create(ctx context.Context, income *domain.Income) error {
+	args := m.Called(income)
+	return args.Error(0)
+}
+
+func (m *MockIncomeRepository) GetByID(ctx context.Context, userID, id int) (*domain.Income, error) {
+	args := m.Called(userID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Income), args.Error(1)
+}
+
+func (m *MockIncomeRepository) GetAll(ctx context.Context, userID int, filter *domain.IncomeFilter) ([]*domain.Income, error) {
+	args := m.Called(userID, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Income), args.Error(1)
+}
+
+func (m *MockIncomeRepository) Update(ctx context.Context, income *domain.Income) error {
+	args := m.Called(income)
+	return args.Error(0)
+}
+
+func (m *MockIncomeRepository) Delete(ctx context.Context, userID, id int) error {
+	args := m.Called(userID, id)
+	return args.Error(0)
+}
+
+func (m *MockIncomeRepository) GetTotalByMonth(ctx context.Context, userID, month, year int) (domain.Money, error) {
+	args := m.Called(userID, month, year)
+	return args.Get(0).(domain.Money), args.Error(1)
+}
+
+func TestIncomeService_CreateIncome(t *testing.T) {
+	t.Run("Salary received into a payment method", func(t *testing.T) {
+		mockIncomeRepo := new(MockIncomeRepository)
+		mockCategoryRepo := new(MockCategoryRepository)
+		incomeService := NewIncomeService(fakeTxManager{}, mockIncomeRepo, mockCategoryRepo, newDefaultPaymentMethodRepository(), new(MockAccountRepository))
+
+		categoryID := 7
+		paymentMode := domain.PaymentModeUPI
+		mockCategoryRepo.On("GetByID", testUserID, categoryID).Return(&domain.Category{ID: categoryID}, nil)
+		mockIncomeRepo.On("Create", mock.AnythingOfType("*domain.Income")).Return(nil)
+
+		income, err := incomeService.CreateIncome(context.Background(), &domain.Income{
+			UserID:      testUserID,
+			Source:      "  Salary ",
+			Amount:      amount("60000.00"),
+			PaymentMode: &paymentMode,
+			CategoryID:  &categoryID,
+			IncomeDate:  date(2024, 1, 31),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "Salary", income.Source)
+		mockIncomeRepo.AssertExpectations(t)
+	})
+
+	t.Run("Invalid income", func(t *testing.T) {
+		mockIncomeRepo := new(MockIncomeRepository)
+		incomeService := NewIncomeService(fakeTxManager{}, mockIncomeRepo, new(MockCategoryRepository), newDefaultPaymentMethodRepository(), new(MockAccountRepository))
+
+		unknownMode := domain.PaymentMode("Crypto Wallet")
+		tests := []struct {
+			income *domain.Income
+			err    error
+		}{
+			{&domain.Income{Source: " ", Amount: amount("100.00")}, domain.ErrInvalidInput},
+			{&domain.Income{Source: "Refund", Amount: amount("0.00")}, domain.ErrInvalidInput},
+			{&domain.Income{Source: "Refund", Amount: amount("100.00"), PaymentMode: &unknownMode}, domain.ErrInvalidPaymentMode},
+		}
+
+		for _, tt := range tests {
+			tt.income.UserID = testUserID
+			_, err := incomeService.CreateIncome(context.Background(), tt.income)
+			assert.Equal(t, tt.err, err)
+		}
+		mockIncomeRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+}
+
+func TestIncomeService_UpdateIncome(t *testing.T) {
+	mockIncomeRepo := new(MockIncomeRepository)
+	paymentMethodRepo := new(MockPaymentMethodRepository)
+	incomeService := NewIncomeService(fakeTxManager{}, mockIncomeRepo, new(MockCategoryRepository), paymentMethodRepo, new(MockAccountRepository))
+
+	// The income was received into a payment method that has since been deactivated
+	paymentMode := domain.PaymentMode("Old Bank")
+	existing := &domain.Income{ID: 3, UserID: testUserID, Source: "Salary", Amount: amount("60000.00"),
+		PaymentMode: &paymentMode, IncomeDate: date(2024, 1, 31)}
+	mockIncomeRepo.On("GetByID", testUserID, 3).Return(existing, nil)
+	mockIncomeRepo.On("Update", mock.AnythingOfType("*domain.Income")).Return(nil)
+
+	updated, err := incomeService.UpdateIncome(context.Background(), &domain.Income{ID: 3, UserID: testUserID, Source: "Salary",
+		Amount: amount("62000.00"), PaymentMode: &paymentMode})
+	assert.NoError(t, err)
+	assert.Equal(t, date(2024, 1, 31), updated.IncomeDate)
+	paymentMethodRepo.AssertNotCalled(t, "GetByName", mock.Anything, mock.Anything)
+}
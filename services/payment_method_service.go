@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"expense-tracker-api/domain"
+	"strings"
+)
+
+// maxPaymentMethodNameLength matches the payment_mode columns that refer to the name
+const maxPaymentMethodNameLength = 50
+
+type PaymentMethodService struct {
+	txManager         domain.TxManager
+	paymentMethodRepo domain.PaymentMethodRepository
+}
+
+// NewPaymentMethodService creates a new payment method service
+func NewPaymentMethodService(txManager domain.TxManager, paymentMethodRepo domain.PaymentMethodRepository) *PaymentMethodService {
+	return &PaymentMethodService{txManager: txManager, paymentMethodRepo: paymentMethodRepo}
+}
+
+// CreatePaymentMethod creates a new payment method
+func (s *PaymentMethodService) CreatePaymentMethod(ctx context.Context, method *domain.PaymentMethod) (*domain.PaymentMethod, error) {
+	if err := validatePaymentMethod(method); err != nil {
+		return nil, err
+	}
+
+	err := s.paymentMethodRepo.Create(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+
+	return method, nil
+}
+
+// GetPaymentMethods retrieves all payment methods, including inactive ones
+func (s *PaymentMethodService) GetPaymentMethods(ctx context.Context, userID int) ([]*domain.PaymentMethod, error) {
+	return s.paymentMethodRepo.GetAll(ctx, userID)
+}
+
+// GetPaymentMethodByID retrieves a payment method by ID
+func (s *PaymentMethodService) GetPaymentMethodByID(ctx context.Context, userID, id int) (*domain.PaymentMethod, error) {
+	method, err := s.paymentMethodRepo.GetByID(ctx, userID, id)
+	if err != nil {
+		return nil, domain.ErrNotFound
+	}
+	return method, nil
+}
+
+// UpdatePaymentMethod replaces a payment method's details. Renaming it also
+// renames the payment mode on every expense and template that uses it.
+func (s *PaymentMethodService) UpdatePaymentMethod(ctx context.Context, method *domain.PaymentMethod) (*domain.PaymentMethod, error) {
+	return withinTx(ctx, s.txManager, func(tx domain.Tx) (*domain.PaymentMethod, error) {
+		paymentMethodRepo := s.paymentMethodRepo.WithTx(tx)
+
+		// Verify payment method exists
+		existing, err := paymentMethodRepo.GetByID(ctx, method.UserID, method.ID)
+		if err != nil {
+			return nil, domain.ErrNotFound
+		}
+
+		if err := validatePaymentMethod(method); err != nil {
+			return nil, err
+		}
+
+		method.CreatedAt = existing.CreatedAt
+		err = paymentMethodRepo.Update(ctx, method)
+		if err != nil {
+			return nil, err
+		}
+
+		return method, nil
+	})
+}
+
+// DeletePaymentMethod deletes a payment method. A method that expenses still
+// refer to can't be deleted (ErrInUse); deactivate it instead.
+func (s *PaymentMethodService) DeletePaymentMethod(ctx context.Context, userID, id int) error {
+	return s.txManager.WithinTx(ctx, func(tx domain.Tx) error {
+		paymentMethodRepo := s.paymentMethodRepo.WithTx(tx)
+
+		// Verify payment method exists
+		_, err := paymentMethodRepo.GetByID(ctx, userID, id)
+		if err != nil {
+			return domain.ErrNotFound
+		}
+
+		return paymentMethodRepo.Delete(ctx, userID, id)
+	})
+}
+
+// validatePaymentMethod trims the name and checks the type-specific details
+func validatePaymentMethod(method *domain.PaymentMethod) error {
+	method.Name = strings.TrimSpace(method.Name)
+	if method.Name == "" || len(method.Name) > maxPaymentMethodNameLength {
+		return domain.ErrInvalidInput
+	}
+	if !method.Type.IsValid() {
+		return domain.ErrInvalidInput
+	}
+
+	if method.Last4 != nil {
+		if method.Type != domain.PaymentMethodTypeCard && method.Type != domain.PaymentMethodTypeBank {
+			return domain.ErrInvalidInput
+		}
+		if !isDigits(*method.Last4, 4) {
+			return domain.ErrInvalidInput
+		}
+	}
+
+	if method.VPA != nil {
+		vpa := strings.TrimSpace(*method.VPA)
+		if method.Type != domain.PaymentMethodTypeUPI || !strings.Contains(vpa, "@") {
+			return domain.ErrInvalidInput
+		}
+		method.VPA = &vpa
+	}
+
+	return nil
+}
+
+// isDigits reports whether s is exactly n ASCII digits
+func isDigits(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
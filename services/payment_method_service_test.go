@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"expense-tracker-api/domain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockPaymentMethodRepository is a mock implementation of PaymentMethodRepository
+type MockPaymentMethodRepository struct {
+	mock.Mock
+}
+
+// WithTx returns the mock itself, so expectations hold inside and outside a transaction
+func (m *MockPaymentMethodRepository) WithTx(tx domain.Tx) domain.PaymentMethodRepository {
+	return m
+}
+
+func (m *MockPaymentMethodRepository) Create(ctx context.Context, method *domain.PaymentMethod) error {
+	args := m.Called(method)
+	return args.Error(0)
+}
+
+func (m *MockPaymentMethodRepository) GetByID(ctx context.Context, userID, id int) (*domain.PaymentMethod, error) {
+	args := m.Called(userID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.PaymentMethod), args.Error(1)
+}
+
+func (m *MockPaymentMethodRepository) GetByName(ctx context.Context, userID int, name string) (*domain.PaymentMethod, error) {
+	args := m.Called(userID, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.PaymentMethod), args.Error(1)
+}
+
+func (m *MockPaymentMethodRepository) GetAll(ctx context.Context, userID int) ([]*domain.PaymentMethod, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.PaymentMethod), args.Error(1)
+}
+
+func (m *MockPaymentMethodRepository) Update(ctx context.Context, method *domain.PaymentMethod) error {
+	args := m.Called(method)
+	return args.Error(0)
+}
+
+func (m *MockPaymentMethodRepository) Delete(ctx context.Context, userID, id int) error {
+	args := m.Called(userID, id)
+	return args.Error(0)
+}
+
+// newDefaultPaymentMethodRepository returns a mock that knows the test user's
+// default payment methods, UPI and Cash, and nothing else
+func newDefaultPaymentMethodRepository() *MockPaymentMethodRepository {
+	mockRepo := new(MockPaymentMethodRepository)
+	for _, method := range domain.DefaultPaymentMethods() {
+		mockRepo.On("GetByName", testUserID, method.Name).Return(method, nil).Maybe()
+	}
+	mockRepo.On("GetByName", mock.Anything, mock.Anything).Return(nil, domain.ErrNotFound).Maybe()
+	return mockRepo
+}
+
+func TestPaymentMethodService_CreatePaymentMethod(t *testing.T) {
+	t.Run("Card with last four digits", func(t *testing.T) {
+		mockRepo := new(MockPaymentMethodRepository)
+		paymentMethodService := NewPaymentMethodService(fakeTxManager{}, mockRepo)
+
+		mockRepo.On("Create", mock.AnythingOfType("*domain.PaymentMethod")).Return(nil)
+
+		last4 := "4242"
+		method, err := paymentMethodService.CreatePaymentMethod(context.Background(), &domain.PaymentMethod{
+			UserID: testUserID,
+			Name:   "  HDFC Credit Card ",
+			Type:   domain.PaymentMethodTypeCard,
+			Last4:  &last4,
+			Active: true,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "HDFC Credit Card", method.Name)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Invalid details", func(t *testing.T) {
+		mockRepo := new(MockPaymentMethodRepository)
+		paymentMethodService := NewPaymentMethodService(fakeTxManager{}, mockRepo)
+
+		shortLast4 := "42"
+		cardLast4 := "4242"
+		badVPA := "sakshi"
+		tests := []*domain.PaymentMethod{
+			{Name: " ", Type: domain.PaymentMethodTypeCash},
+			{Name: "Paytm", Type: "crypto"},
+			{Name: "Card", Type: domain.PaymentMethodTypeCard, Last4: &shortLast4},
+			{Name: "Wallet", Type: domain.PaymentMethodTypeWallet, Last4: &cardLast4},
+			{Name: "GPay", Type: domain.PaymentMethodTypeUPI, VPA: &badVPA},
+		}
+
+		for _, method := range tests {
+			method.UserID = testUserID
+			_, err := paymentMethodService.CreatePaymentMethod(context.Background(), method)
+			assert.Equal(t, domain.ErrInvalidInput, err, method.Name)
+		}
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+}
+
+func TestPaymentMethodService_DeletePaymentMethod(t *testing.T) {
+	t.Run("Method still used by expenses", func(t *testing.T) {
+		mockRepo := new(MockPaymentMethodRepository)
+		paymentMethodService := NewPaymentMethodService(fakeTxManager{}, mockRepo)
+
+		mockRepo.On("GetByID", testUserID, 1).Return(&domain.PaymentMethod{ID: 1, Name: "UPI"}, nil)
+		mockRepo.On("Delete", testUserID, 1).Return(domain.ErrInUse)
+
+		err := paymentMethodService.DeletePaymentMethod(context.Background(), testUserID, 1)
+		assert.Equal(t, domain.ErrInUse, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Method not found", func(t *testing.T) {
+		mockRepo := new(MockPaymentMethodRepository)
+		paymentMethodService := NewPaymentMethodService(fakeTxManager{}, mockRepo)
+
+		mockRepo.On("GetByID", testUserID, 99).Return(nil, domain.ErrNotFound)
+
+		err := paymentMethodService.DeletePaymentMethod(context.Background(), testUserID, 99)
+		assert.Equal(t, domain.ErrNotFound, err)
+		mockRepo.AssertNotCalled(t, "Delete", testUserID, 99)
+	})
+}
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"expense-tracker-api/domain"
+	"fmt"
+	"time"
+)
+
+// maxPreviewCount caps how many upcoming occurrences a preview returns
+const maxPreviewCount = 100
+
+type RecurringExpenseService struct {
+	txManager      domain.TxManager
+	recurringRepo  domain.RecurringExpenseRepository
+	categoryRepo   domain.CategoryRepository
+	expenseService *ExpenseService
+}
+
+// NewRecurringExpenseService creates a new recurring expense service
+func NewRecurringExpenseService(txManager domain.TxManager, recurringRepo domain.RecurringExpenseRepository, categoryRepo domain.CategoryRepository, expenseService *ExpenseService) *RecurringExpenseService {
+	return &RecurringExpenseService{
+		txManager:      txManager,
+		recurringRepo:  recurringRepo,
+		categoryRepo:   categoryRepo,
+		expenseService: expenseService,
+	}
+}
+
+// withTx returns a copy of the service whose repositories, and those of its
+// expense service, are bound to tx
+func (s *RecurringExpenseService) withTx(tx domain.Tx) *RecurringExpenseService {
+	return &RecurringExpenseService{
+		txManager:      s.txManager,
+		recurringRepo:  s.recurringRepo.WithTx(tx),
+		categoryRepo:   s.categoryRepo.WithTx(tx),
+		expenseService: s.expenseService.withTx(tx),
+	}
+}
+
+// CreateRecurringExpense creates a new recurring expense template
+func (s *RecurringExpenseService) CreateRecurringExpense(ctx context.Context, recurring *domain.RecurringExpense) (*domain.RecurringExpense, error) {
+	return withinTx(ctx, s.txManager, func(tx domain.Tx) (*domain.RecurringExpense, error) {
+		return s.withTx(tx).createRecurringExpense(ctx, recurring)
+	})
+}
+
+// createRecurringExpense creates a template with the service's repositories
+func (s *RecurringExpenseService) createRecurringExpense(ctx context.Context, recurring *domain.RecurringExpense) (*domain.RecurringExpense, error) {
+	if recurring.StartDate.IsZero() {
+		recurring.StartDate = time.Now()
+	}
+	recurring.StartDate = domain.TruncateToDate(recurring.StartDate)
+	recurring.Active = true
+
+	if err := s.validate(ctx, recurring); err != nil {
+		return nil, err
+	}
+
+	// Start dates in the past are caught up by the scheduler
+	recurring.NextRunDate = recurring.OccurrenceOnOrAfter(recurring.StartDate)
+
+	err := s.recurringRepo.Create(ctx, recurring)
+	if err != nil {
+		return nil, err
+	}
+
+	return recurring, nil
+}
+
+// GetRecurringExpenses retrieves all recurring expense templates
+func (s *RecurringExpenseService) GetRecurringExpenses(ctx context.Context, userID int) ([]*domain.RecurringExpense, error) {
+	return s.recurringRepo.GetAll(ctx, userID)
+}
+
+// GetRecurringExpenseByID retrieves a recurring expense template by ID
+func (s *RecurringExpenseService) GetRecurringExpenseByID(ctx context.Context, userID, id int) (*domain.RecurringExpense, error) {
+	recurring, err := s.recurringRepo.GetByID(ctx, userID, id)
+	if err != nil {
+		return nil, domain.ErrNotFound
+	}
+	return recurring, nil
+}
+
+// UpdateRecurringExpense replaces a recurring expense template. Changing the
+// schedule only moves future occurrences; dates already created are not repeated.
+func (s *RecurringExpenseService) UpdateRecurringExpense(ctx context.Context, recurring *domain.RecurringExpense) (*domain.RecurringExpense, error) {
+	return withinTx(ctx, s.txManager, func(tx domain.Tx) (*domain.RecurringExpense, error) {
+		return s.withTx(tx).updateRecurringExpense(ctx, recurring)
+	})
+}
+
+// updateRecurringExpense replaces a template with the service's repositories
+func (s *RecurringExpenseService) updateRecurringExpense(ctx context.Context, recurring *domain.RecurringExpense) (*domain.RecurringExpense, error) {
+	existing, err := s.recurringRepo.GetByID(ctx, recurring.UserID, recurring.ID)
+	if err != nil {
+		return nil, domain.ErrNotFound
+	}
+
+	if recurring.StartDate.IsZero() {
+		recurring.StartDate = existing.StartDate
+	}
+	recurring.StartDate = domain.TruncateToDate(recurring.StartDate)
+
+	if err := s.validate(ctx, recurring); err != nil {
+		return nil, err
+	}
+
+	from := existing.NextRunDate
+	if recurring.StartDate.After(from) {
+		from = recurring.StartDate
+	}
+	recurring.NextRunDate = recurring.OccurrenceOnOrAfter(from)
+	recurring.CreatedAt = existing.CreatedAt
+
+	err = s.recurringRepo.Update(ctx, recurring)
+	if err != nil {
+		return nil, err
+	}
+
+	return recurring, nil
+}
+
+// DeleteRecurringExpense deletes a recurring expense template.
+// Expenses it already created are kept.
+func (s *RecurringExpenseService) DeleteRecurringExpense(ctx context.Context, userID, id int) error {
+	return s.txManager.WithinTx(ctx, func(tx domain.Tx) error {
+		recurringRepo := s.recurringRepo.WithTx(tx)
+
+		_, err := recurringRepo.GetByID(ctx, userID, id)
+		if err != nil {
+			return domain.ErrNotFound
+		}
+
+		return recurringRepo.Delete(ctx, userID, id)
+	})
+}
+
+// PreviewOccurrences lists the next count dates the template will create expenses for
+func (s *RecurringExpenseService) PreviewOccurrences(ctx context.Context, userID, id, count int) ([]time.Time, error) {
+	if count < 1 || count > maxPreviewCount {
+		return nil, domain.ErrInvalidInput
+	}
+
+	recurring, err := s.recurringRepo.GetByID(ctx, userID, id)
+	if err != nil {
+		return nil, domain.ErrNotFound
+	}
+
+	occurrences := []time.Time{}
+	date := recurring.NextRunDate
+	for len(occurrences) < count && recurring.IsDue(date) {
+		occurrences = append(occurrences, date)
+		date = recurring.NextOccurrence(date)
+	}
+	return occurrences, nil
+}
+
+// ProcessDue creates the expenses for every occurrence due on or before today,
+// catching up on runs missed while the server was down. Occurrences that were
+// already created are skipped, so an interrupted run can safely be repeated.
+// It returns the expenses created and the first error hit, if any.
+func (s *RecurringExpenseService) ProcessDue(ctx context.Context, today time.Time) ([]*domain.Expense, error) {
+	today = domain.TruncateToDate(today)
+	due, err := s.recurringRepo.GetDue(ctx, today)
+	if err != nil {
+		return nil, err
+	}
+
+	var created []*domain.Expense
+	var firstErr error
+	for _, recurring := range due {
+		expenses, err := s.catchUp(ctx, recurring, today)
+		created = append(created, expenses...)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return created, firstErr
+}
+
+// catchUp creates the pending occurrences of one template up to today. Each
+// expense is created in the same transaction that moves the template on to its
+// next run, so a failure leaves neither behind.
+func (s *RecurringExpenseService) catchUp(ctx context.Context, recurring *domain.RecurringExpense, today time.Time) ([]*domain.Expense, error) {
+	var created []*domain.Expense
+	for !recurring.NextRunDate.After(today) && recurring.IsDue(recurring.NextRunDate) {
+		recurringID := recurring.ID
+		nextRunDate := recurring.NextOccurrence(recurring.NextRunDate)
+		expense, err := withinTx(ctx, s.txManager, func(tx domain.Tx) (*domain.Expense, error) {
+			bound := s.withTx(tx)
+			expense, err := bound.expenseService.createExpense(ctx, &domain.Expense{
+				UserID:             recurring.UserID,
+				CategoryID:         recurring.CategoryID,
+				Amount:             recurring.Amount,
+				Description:        recurring.Description,
+				PaymentMode:        recurring.PaymentMode,
+				ExpenseDate:        recurring.NextRunDate,
+				RecurringExpenseID: &recurringID,
+			})
+			if err != nil {
+				return nil, err
+			}
+			return expense, bound.recurringRepo.UpdateNextRunDate(ctx, recurring.ID, nextRunDate)
+		})
+		if errors.Is(err, domain.ErrAlreadyExists) {
+			// Another scheduler run created the occurrence first
+			err = s.recurringRepo.UpdateNextRunDate(ctx, recurring.ID, nextRunDate)
+		}
+		if errors.Is(err, domain.ErrInvalidCategory) || errors.Is(err, domain.ErrInvalidPaymentMode) {
+			// The category or payment method was archived or deleted, so every retry
+			// would fail the same way. The template is paused until its owner fixes it.
+			if pauseErr := s.pause(ctx, recurring); pauseErr != nil {
+				return created, pauseErr
+			}
+			return created, fmt.Errorf("recurring expense %d paused: %w", recurring.ID, err)
+		}
+		if err != nil {
+			// Leave the occurrence pending so the next run retries it
+			return created, err
+		}
+		if expense != nil {
+			created = append(created, expense)
+		}
+		recurring.NextRunDate = nextRunDate
+	}
+	return created, nil
+}
+
+// pause deactivates a template the scheduler can no longer create expenses for
+func (s *RecurringExpenseService) pause(ctx context.Context, recurring *domain.RecurringExpense) error {
+	return s.txManager.WithinTx(ctx, func(tx domain.Tx) error {
+		recurringRepo := s.recurringRepo.WithTx(tx)
+
+		current, err := recurringRepo.GetByID(ctx, recurring.UserID, recurring.ID)
+		if err != nil {
+			return err
+		}
+		current.Active = false
+		return recurringRepo.Update(ctx, current)
+	})
+}
+
+// validate checks the fields shared by create and update
+func (s *RecurringExpenseService) validate(ctx context.Context, recurring *domain.RecurringExpense) error {
+	if recurring.Amount <= 0 {
+		return domain.ErrInvalidInput
+	}
+
+	if err := s.expenseService.validatePaymentMode(ctx, recurring.UserID, recurring.PaymentMode); err != nil {
+		return err
+	}
+
+	if !recurring.Frequency.IsValid() {
+		return domain.ErrInvalidSchedule
+	}
+
+	if recurring.DayOfMonth != nil {
+		if recurring.Frequency != domain.FrequencyMonthly || *recurring.DayOfMonth < 1 || *recurring.DayOfMonth > 31 {
+			return domain.ErrInvalidSchedule
+		}
+	}
+
+	if recurring.EndDate != nil {
+		endDate := domain.TruncateToDate(*recurring.EndDate)
+		if endDate.Before(recurring.StartDate) {
+			return domain.ErrInvalidSchedule
+		}
+		recurring.EndDate = &endDate
+	}
+
+	if err := checkCategory(ctx, s.categoryRepo, recurring.UserID, recurring.CategoryID); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,334 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"expense-tracker-api/domain"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockRecurringExpenseRepository is a mock implementation of RecurringExpenseRepository
+type MockRecurringExpenseRepository struct {
+	mock.Mock
+}
+
+// WithTx returns the mock itself, so expectations hold inside and outside a transaction
+func (m *MockRecurringExpenseRepository) WithTx(tx domain.Tx) domain.RecurringExpenseRepository {
+	return m
+}
+
+func (m *MockRecurringExpenseRepository) Create(ctx context.Context, recurring *domain.RecurringExpense) error {
+	args := m.Called(recurring)
+	return args.Error(0)
+}
+
+func (m *MockRecurringExpenseRepository) GetByID(ctx context.Context, userID, id int) (*domain.RecurringExpense, error) {
+	args := m.Called(userID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.RecurringExpense), args.Error(1)
+}
+
+func (m *MockRecurringExpenseRepository) GetAll(ctx context.Context, userID int) ([]*domain.RecurringExpense, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.RecurringExpense), args.Error(1)
+}
+
+func (m *MockRecurringExpenseRepository) GetDue(ctx context.Context, asOf time.Time) ([]*domain.RecurringExpense, error) {
+	args := m.Called(asOf)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.RecurringExpense), args.Error(1)
+}
+
+func (m *MockRecurringExpenseRepository) Update(ctx context.Context, recurring *domain.RecurringExpense) error {
+	args := m.Called(recurring)
+	return args.Error(0)
+}
+
+func (m *MockRecurringExpenseRepository) UpdateNextRunDate(ctx context.Context, id int, nextRunDate time.Time) error {
+	args := m.Called(id, nextRunDate)
+	return args.Error(0)
+}
+
+func (m *MockRecurringExpenseRepository) Delete(ctx context.Context, userID, id int) error {
+	args := m.Called(userID, id)
+	return args.Error(0)
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func newTestRecurringExpenseService() (*RecurringExpenseService, *MockRecurringExpenseRepository, *MockExpenseRepository, *MockCategoryRepositoryForExpense, *MockBudgetRepositoryForExpense) {
+	mockRecurringRepo := new(MockRecurringExpenseRepository)
+	mockExpenseRepo := new(MockExpenseRepository)
+	mockCategoryRepo := new(MockCategoryRepositoryForExpense)
+	mockBudgetRepo := new(MockBudgetRepositoryForExpense)
+	expenseService := NewExpenseService(fakeTxManager{}, mockExpenseRepo, mockCategoryRepo, mockBudgetRepo, newDefaultPaymentMethodRepository(), new(MockAccountRepository), newTestExchangeRateService())
+	recurringService := NewRecurringExpenseService(fakeTxManager{}, mockRecurringRepo, mockCategoryRepo, expenseService)
+	return recurringService, mockRecurringRepo, mockExpenseRepo, mockCategoryRepo, mockBudgetRepo
+}
+
+func TestRecurringExpenseService_CreateRecurringExpense(t *testing.T) {
+	t.Run("Next run date follows day-of-month rule", func(t *testing.T) {
+		recurringService, mockRecurringRepo, _, mockCategoryRepo, _ := newTestRecurringExpenseService()
+
+		mockCategoryRepo.On("GetByID", testUserID, 1).Return(&domain.Category{ID: 1}, nil)
+		mockRecurringRepo.On("Create", mock.AnythingOfType("*domain.RecurringExpense")).Return(nil)
+
+		dayOfMonth := 5
+		recurring, err := recurringService.CreateRecurringExpense(context.Background(), &domain.RecurringExpense{
+			UserID:      testUserID,
+			CategoryID:  1,
+			Amount:      amount("15000.00"),
+			Description: "Rent",
+			PaymentMode: domain.PaymentModeUPI,
+			Frequency:   domain.FrequencyMonthly,
+			DayOfMonth:  &dayOfMonth,
+			StartDate:   date(2024, 1, 10),
+		})
+		assert.NoError(t, err)
+		assert.True(t, recurring.Active)
+		assert.Equal(t, date(2024, 2, 5), recurring.NextRunDate)
+		mockRecurringRepo.AssertExpectations(t)
+	})
+
+	t.Run("Day of month on non-monthly schedule", func(t *testing.T) {
+		recurringService, _, _, _, _ := newTestRecurringExpenseService()
+
+		dayOfMonth := 5
+		recurring, err := recurringService.CreateRecurringExpense(context.Background(), &domain.RecurringExpense{
+			UserID:      testUserID,
+			CategoryID:  1,
+			Amount:      amount("100.00"),
+			PaymentMode: domain.PaymentModeUPI,
+			Frequency:   domain.FrequencyWeekly,
+			DayOfMonth:  &dayOfMonth,
+		})
+		assert.Error(t, err)
+		assert.Nil(t, recurring)
+		assert.Equal(t, domain.ErrInvalidSchedule, err)
+	})
+
+	t.Run("Invalid frequency", func(t *testing.T) {
+		recurringService, _, _, _, _ := newTestRecurringExpenseService()
+
+		recurring, err := recurringService.CreateRecurringExpense(context.Background(), &domain.RecurringExpense{
+			UserID:      testUserID,
+			CategoryID:  1,
+			Amount:      amount("100.00"),
+			PaymentMode: domain.PaymentModeUPI,
+			Frequency:   domain.Frequency("hourly"),
+		})
+		assert.Error(t, err)
+		assert.Nil(t, recurring)
+		assert.Equal(t, domain.ErrInvalidSchedule, err)
+	})
+}
+
+func TestRecurringExpenseService_PreviewOccurrences(t *testing.T) {
+	t.Run("Monthly on the 31st clamps to month end", func(t *testing.T) {
+		recurringService, mockRecurringRepo, _, _, _ := newTestRecurringExpenseService()
+
+		recurring := &domain.RecurringExpense{
+			UserID:      testUserID,
+			ID:          1,
+			Frequency:   domain.FrequencyMonthly,
+			StartDate:   date(2024, 1, 31),
+			NextRunDate: date(2024, 1, 31),
+			Active:      true,
+		}
+		mockRecurringRepo.On("GetByID", testUserID, 1).Return(recurring, nil)
+
+		occurrences, err := recurringService.PreviewOccurrences(context.Background(), testUserID, 1, 4)
+		assert.NoError(t, err)
+		assert.Equal(t, []time.Time{date(2024, 1, 31), date(2024, 2, 29), date(2024, 3, 31), date(2024, 4, 30)}, occurrences)
+	})
+
+	t.Run("Stops at end date", func(t *testing.T) {
+		recurringService, mockRecurringRepo, _, _, _ := newTestRecurringExpenseService()
+
+		endDate := date(2024, 1, 20)
+		recurring := &domain.RecurringExpense{
+			UserID:      testUserID,
+			ID:          1,
+			Frequency:   domain.FrequencyWeekly,
+			StartDate:   date(2024, 1, 1),
+			EndDate:     &endDate,
+			NextRunDate: date(2024, 1, 8),
+			Active:      true,
+		}
+		mockRecurringRepo.On("GetByID", testUserID, 1).Return(recurring, nil)
+
+		occurrences, err := recurringService.PreviewOccurrences(context.Background(), testUserID, 1, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []time.Time{date(2024, 1, 8), date(2024, 1, 15)}, occurrences)
+	})
+
+	t.Run("Count out of range", func(t *testing.T) {
+		recurringService, _, _, _, _ := newTestRecurringExpenseService()
+
+		occurrences, err := recurringService.PreviewOccurrences(context.Background(), testUserID, 1, 0)
+		assert.Error(t, err)
+		assert.Nil(t, occurrences)
+		assert.Equal(t, domain.ErrInvalidInput, err)
+	})
+}
+
+func TestRecurringExpenseService_ProcessDue(t *testing.T) {
+	t.Run("Catches up on missed occurrences", func(t *testing.T) {
+		recurringService, mockRecurringRepo, mockExpenseRepo, mockCategoryRepo, mockBudgetRepo := newTestRecurringExpenseService()
+
+		recurring := &domain.RecurringExpense{
+			UserID:      testUserID,
+			ID:          7,
+			CategoryID:  1,
+			Amount:      amount("499.00"),
+			Description: "Streaming subscription",
+			PaymentMode: domain.PaymentModeUPI,
+			Frequency:   domain.FrequencyMonthly,
+			StartDate:   date(2024, 1, 15),
+			NextRunDate: date(2024, 1, 15),
+			Active:      true,
+		}
+		mockRecurringRepo.On("GetDue", date(2024, 3, 20)).Return([]*domain.RecurringExpense{recurring}, nil)
+		mockCategoryRepo.On("GetByID", testUserID, 1).Return(&domain.Category{ID: 1}, nil)
+		mockExpenseRepo.On("Create", mock.AnythingOfType("*domain.Expense")).Return(nil)
+		mockBudgetRepo.On("GetByMonth", testUserID, mock.Anything, mock.Anything).Return(nil, domain.ErrNotFound)
+		mockBudgetRepo.On("GetByMonthAndCategory", testUserID, mock.Anything, mock.Anything, 1).Return(nil, domain.ErrNotFound)
+		mockRecurringRepo.On("UpdateNextRunDate", 7, date(2024, 2, 15)).Return(nil)
+		mockRecurringRepo.On("UpdateNextRunDate", 7, date(2024, 3, 15)).Return(nil)
+		mockRecurringRepo.On("UpdateNextRunDate", 7, date(2024, 4, 15)).Return(nil)
+
+		created, err := recurringService.ProcessDue(context.Background(), time.Date(2024, 3, 20, 9, 30, 0, 0, time.UTC))
+		assert.NoError(t, err)
+		assert.Len(t, created, 3)
+		assert.Equal(t, date(2024, 1, 15), created[0].ExpenseDate)
+		assert.Equal(t, 7, *created[2].RecurringExpenseID)
+		assert.Equal(t, date(2024, 4, 15), recurring.NextRunDate)
+		mockRecurringRepo.AssertExpectations(t)
+	})
+
+	t.Run("Skips occurrences already created", func(t *testing.T) {
+		recurringService, mockRecurringRepo, mockExpenseRepo, mockCategoryRepo, _ := newTestRecurringExpenseService()
+
+		recurring := &domain.RecurringExpense{
+			UserID:      testUserID,
+			ID:          7,
+			CategoryID:  1,
+			Amount:      amount("499.00"),
+			PaymentMode: domain.PaymentModeUPI,
+			Frequency:   domain.FrequencyDaily,
+			StartDate:   date(2024, 3, 20),
+			NextRunDate: date(2024, 3, 20),
+			Active:      true,
+		}
+		mockRecurringRepo.On("GetDue", date(2024, 3, 20)).Return([]*domain.RecurringExpense{recurring}, nil)
+		mockCategoryRepo.On("GetByID", testUserID, 1).Return(&domain.Category{ID: 1}, nil)
+		mockExpenseRepo.On("Create", mock.AnythingOfType("*domain.Expense")).Return(domain.ErrAlreadyExists)
+		mockRecurringRepo.On("UpdateNextRunDate", 7, date(2024, 3, 21)).Return(nil)
+
+		created, err := recurringService.ProcessDue(context.Background(), date(2024, 3, 20))
+		assert.NoError(t, err)
+		assert.Empty(t, created)
+		mockRecurringRepo.AssertExpectations(t)
+	})
+
+	t.Run("Pauses a template whose category was archived", func(t *testing.T) {
+		recurringService, mockRecurringRepo, mockExpenseRepo, mockCategoryRepo, _ := newTestRecurringExpenseService()
+
+		recurring := &domain.RecurringExpense{
+			UserID:      testUserID,
+			ID:          7,
+			CategoryID:  1,
+			Amount:      amount("499.00"),
+			PaymentMode: domain.PaymentModeUPI,
+			Frequency:   domain.FrequencyDaily,
+			StartDate:   date(2024, 3, 20),
+			NextRunDate: date(2024, 3, 20),
+			Active:      true,
+		}
+		stored := *recurring
+		mockRecurringRepo.On("GetDue", date(2024, 3, 20)).Return([]*domain.RecurringExpense{recurring}, nil)
+		mockCategoryRepo.On("GetByID", testUserID, 1).Return(&domain.Category{ID: 1, Name: "Subscriptions", Archived: true}, nil)
+		mockRecurringRepo.On("GetByID", testUserID, 7).Return(&stored, nil)
+		mockRecurringRepo.On("Update", mock.MatchedBy(func(r *domain.RecurringExpense) bool { return r.ID == 7 && !r.Active })).Return(nil)
+
+		created, err := recurringService.ProcessDue(context.Background(), date(2024, 3, 20))
+		assert.ErrorIs(t, err, domain.ErrInvalidCategory)
+		assert.Empty(t, created)
+		mockRecurringRepo.AssertExpectations(t)
+		mockRecurringRepo.AssertNotCalled(t, "UpdateNextRunDate", mock.Anything, mock.Anything)
+		mockExpenseRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+
+	t.Run("Pauses a template whose category was deleted", func(t *testing.T) {
+		recurringService, mockRecurringRepo, mockExpenseRepo, mockCategoryRepo, _ := newTestRecurringExpenseService()
+
+		recurring := &domain.RecurringExpense{
+			UserID:      testUserID,
+			ID:          7,
+			CategoryID:  1,
+			Amount:      amount("499.00"),
+			PaymentMode: domain.PaymentModeUPI,
+			Frequency:   domain.FrequencyDaily,
+			StartDate:   date(2024, 3, 20),
+			NextRunDate: date(2024, 3, 20),
+			Active:      true,
+		}
+		stored := *recurring
+		mockRecurringRepo.On("GetDue", date(2024, 3, 20)).Return([]*domain.RecurringExpense{recurring}, nil)
+		mockCategoryRepo.On("GetByID", testUserID, 1).Return(nil, domain.ErrNotFound)
+		mockRecurringRepo.On("GetByID", testUserID, 7).Return(&stored, nil)
+		mockRecurringRepo.On("Update", mock.MatchedBy(func(r *domain.RecurringExpense) bool { return r.ID == 7 && !r.Active })).Return(nil)
+
+		created, err := recurringService.ProcessDue(context.Background(), date(2024, 3, 20))
+		assert.ErrorIs(t, err, domain.ErrInvalidCategory)
+		assert.Empty(t, created)
+		mockRecurringRepo.AssertExpectations(t)
+		mockExpenseRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+
+	t.Run("Keeps a template active when its payment method lookup fails", func(t *testing.T) {
+		mockRecurringRepo := new(MockRecurringExpenseRepository)
+		mockExpenseRepo := new(MockExpenseRepository)
+		mockCategoryRepo := new(MockCategoryRepositoryForExpense)
+		mockPaymentMethodRepo := new(MockPaymentMethodRepository)
+		expenseService := NewExpenseService(fakeTxManager{}, mockExpenseRepo, mockCategoryRepo, new(MockBudgetRepositoryForExpense),
+			mockPaymentMethodRepo, new(MockAccountRepository), newTestExchangeRateService())
+		recurringService := NewRecurringExpenseService(fakeTxManager{}, mockRecurringRepo, mockCategoryRepo, expenseService)
+
+		recurring := &domain.RecurringExpense{
+			UserID:      testUserID,
+			ID:          7,
+			CategoryID:  1,
+			Amount:      amount("499.00"),
+			PaymentMode: domain.PaymentModeUPI,
+			Frequency:   domain.FrequencyDaily,
+			StartDate:   date(2024, 3, 20),
+			NextRunDate: date(2024, 3, 20),
+			Active:      true,
+		}
+		lookupErr := errors.New("connection reset")
+		mockRecurringRepo.On("GetDue", date(2024, 3, 20)).Return([]*domain.RecurringExpense{recurring}, nil)
+		mockPaymentMethodRepo.On("GetByName", testUserID, string(domain.PaymentModeUPI)).Return(nil, lookupErr)
+
+		created, err := recurringService.ProcessDue(context.Background(), date(2024, 3, 20))
+		assert.ErrorIs(t, err, lookupErr)
+		assert.NotErrorIs(t, err, domain.ErrInvalidPaymentMode)
+		assert.Empty(t, created)
+		mockRecurringRepo.AssertNotCalled(t, "Update", mock.Anything)
+		mockRecurringRepo.AssertNotCalled(t, "UpdateNextRunDate", mock.Anything, mock.Anything)
+		mockExpenseRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+}
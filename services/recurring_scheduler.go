@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// StartScheduler processes due recurring expenses immediately and then on every
+// interval until ctx is cancelled. It blocks, so run it in its own goroutine.
+func (s *RecurringExpenseService) StartScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.runScheduled(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runScheduled runs one scheduler pass and logs the outcome
+func (s *RecurringExpenseService) runScheduled(ctx context.Context) {
+	created, err := s.ProcessDue(ctx, time.Now())
+	for _, expense := range created {
+		if expense.Warning != "" {
+			log.Printf("Recurring expense %d created expense %d: %s", *expense.RecurringExpenseID, expense.ID, expense.Warning)
+		}
+	}
+	if len(created) > 0 {
+		log.Printf("Recurring scheduler created %d expense(s)", len(created))
+	}
+	if err != nil {
+		log.Printf("Recurring scheduler failed: %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"expense-tracker-api/domain"
+	"time"
+)
+
+// defaultCashflowMonths is how many months a cash flow report covers when no start is given,
+// and maxCashflowMonths caps the months a single report may span
+const (
+	defaultCashflowMonths = 12
+	maxCashflowMonths     = 120
+)
+
+type ReportService struct {
+	reportRepo domain.ReportRepository
+}
+
+// NewReportService creates a new report service
+func NewReportService(reportRepo domain.ReportRepository) *ReportService {
+	return &ReportService{reportRepo: reportRepo}
+}
+
+// GetMonthlyReport builds the spending report for a calendar month.
+// The average per day is taken over every day of the month.
+func (s *ReportService) GetMonthlyReport(ctx context.Context, userID, year, month int) (*domain.MonthlyReport, error) {
+	if month < 1 || month > 12 || year < 1 || year > 9999 {
+		return nil, domain.ErrInvalidInput
+	}
+
+	from := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	summary, err := s.reportRepo.GetSummary(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &domain.MonthlyReport{
+		Month: month,
+		Year:  year,
+		Total: summary.Total,
+		Count: summary.Count,
+	}
+	days := to.AddDate(0, 0, -1).Day()
+	report.AveragePerDay = summary.Total.Div(days)
+
+	// An empty month has no largest expense
+	report.LargestExpense, err = s.reportRepo.GetLargestExpense(ctx, userID, from, to)
+	if err != nil && err != domain.ErrNotFound {
+		return nil, err
+	}
+
+	report.ByCategory, err = s.reportRepo.GetCategoryBreakdown(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report.ByPaymentMode, err = s.reportRepo.GetPaymentModeBreakdown(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report.Daily, err = s.reportRepo.GetDailyTotals(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// GetCashflow builds the income, expenses and net savings for each month from from to to,
+// both inclusive. A zero to means today and a zero from means the start of the month
+// eleven months before to, giving a year of months.
+func (s *ReportService) GetCashflow(ctx context.Context, userID int, from, to time.Time) (*domain.CashflowReport, error) {
+	if to.IsZero() {
+		to = time.Now()
+	}
+	to = domain.TruncateToDate(to)
+	if from.IsZero() {
+		from = time.Date(to.Year(), to.Month()-(defaultCashflowMonths-1), 1, 0, 0, 0, 0, time.UTC)
+	}
+	from = domain.TruncateToDate(from)
+
+	if to.Before(from) {
+		return nil, domain.ErrInvalidInput
+	}
+	if months := (to.Year()-from.Year())*12 + int(to.Month()-from.Month()) + 1; months > maxCashflowMonths {
+		return nil, domain.ErrInvalidInput
+	}
+
+	months, err := s.reportRepo.GetMonthlyCashflow(ctx, userID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	report := &domain.CashflowReport{From: from, To: to, Months: months}
+	for _, month := range months {
+		month.Net = month.Income - month.Expenses
+		month.SavingsRate = savingsRate(month.Net, month.Income)
+		report.Income += month.Income
+		report.Expenses += month.Expenses
+	}
+	report.Net = report.Income - report.Expenses
+	report.SavingsRate = savingsRate(report.Net, report.Income)
+
+	return report, nil
+}
+
+// savingsRate is the share of income kept, as a percentage. It is nil without income.
+func savingsRate(net, income domain.Money) *float64 {
+	if income <= 0 {
+		return nil
+	}
+	rate := net.Percent(income)
+	return &rate
+}
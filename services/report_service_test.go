@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"expense-tracker-api/domain"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockReportRepository is a mock implementation of ReportRepository. Like a query
+// run by the database driver, each method fails once its context is done.
+type MockReportRepository struct {
+	mock.Mock
+}
+
+func (m *MockReportRepository) GetSummary(ctx context.Context, userID int, from, to time.Time) (*domain.SpendingSummary, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(userID, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.SpendingSummary), args.Error(1)
+}
+
+func (m *MockReportRepository) GetLargestExpense(ctx context.Context, userID int, from, to time.Time) (*domain.Expense, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(userID, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Expense), args.Error(1)
+}
+
+func (m *MockReportRepository) GetCategoryBreakdown(ctx context.Context, userID int, from, to time.Time) ([]*domain.CategoryBreakdown, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(userID, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.CategoryBreakdown), args.Error(1)
+}
+
+func (m *MockReportRepository) GetPaymentModeBreakdown(ctx context.Context, userID int, from, to time.Time) ([]*domain.PaymentModeBreakdown, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(userID, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.PaymentModeBreakdown), args.Error(1)
+}
+
+func (m *MockReportRepository) GetDailyTotals(ctx context.Context, userID int, from, to time.Time) ([]*domain.DailyTotal, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(userID, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.DailyTotal), args.Error(1)
+}
+
+func (m *MockReportRepository) GetMonthlyCashflow(ctx context.Context, userID int, from, to time.Time) ([]*domain.CashflowMonth, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(userID, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.CashflowMonth), args.Error(1)
+}
+
+func TestReportService_GetMonthlyReport(t *testing.T) {
+	from, to := date(2024, 2, 1), date(2024, 3, 1)
+
+	t.Run("Combines aggregates for the month", func(t *testing.T) {
+		mockReportRepo := new(MockReportRepository)
+		reportService := NewReportService(mockReportRepo)
+
+		largest := &domain.Expense{ID: 3, Amount: amount("5000.00")}
+		mockReportRepo.On("GetSummary", testUserID, from, to).Return(&domain.SpendingSummary{Total: amount("8000.00"), Count: 3}, nil)
+		mockReportRepo.On("GetLargestExpense", testUserID, from, to).Return(largest, nil)
+		mockReportRepo.On("GetCategoryBreakdown", testUserID, from, to).Return([]*domain.CategoryBreakdown{
+			{CategoryID: 3, CategoryName: "Rent", Amount: amount("5000.00"), Share: 62.5, Count: 1},
+		}, nil)
+		mockReportRepo.On("GetPaymentModeBreakdown", testUserID, from, to).Return([]*domain.PaymentModeBreakdown{
+			{PaymentMode: domain.PaymentModeUPI, Amount: amount("8000.00"), Share: 100, Count: 3},
+		}, nil)
+		mockReportRepo.On("GetDailyTotals", testUserID, from, to).Return([]*domain.DailyTotal{}, nil)
+
+		report, err := reportService.GetMonthlyReport(context.Background(), testUserID, 2024, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, amount("8000.00"), report.Total)
+		assert.Equal(t, 3, report.Count)
+		assert.Equal(t, amount("275.86"), report.AveragePerDay)
+		assert.Equal(t, largest, report.LargestExpense)
+		assert.Len(t, report.ByCategory, 1)
+		mockReportRepo.AssertExpectations(t)
+	})
+
+	t.Run("Empty month has no largest expense", func(t *testing.T) {
+		mockReportRepo := new(MockReportRepository)
+		reportService := NewReportService(mockReportRepo)
+
+		mockReportRepo.On("GetSummary", testUserID, from, to).Return(&domain.SpendingSummary{}, nil)
+		mockReportRepo.On("GetLargestExpense", testUserID, from, to).Return(nil, domain.ErrNotFound)
+		mockReportRepo.On("GetCategoryBreakdown", testUserID, from, to).Return([]*domain.CategoryBreakdown{}, nil)
+		mockReportRepo.On("GetPaymentModeBreakdown", testUserID, from, to).Return([]*domain.PaymentModeBreakdown{}, nil)
+		mockReportRepo.On("GetDailyTotals", testUserID, from, to).Return([]*domain.DailyTotal{}, nil)
+
+		report, err := reportService.GetMonthlyReport(context.Background(), testUserID, 2024, 2)
+		assert.NoError(t, err)
+		assert.Nil(t, report.LargestExpense)
+		assert.Equal(t, amount("0.00"), report.AveragePerDay)
+	})
+
+	t.Run("Stops when the caller goes away", func(t *testing.T) {
+		mockReportRepo := new(MockReportRepository)
+		reportService := NewReportService(mockReportRepo)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		mockReportRepo.On("GetSummary", testUserID, from, to).Return(&domain.SpendingSummary{}, nil).Run(func(mock.Arguments) {
+			cancel()
+		})
+
+		report, err := reportService.GetMonthlyReport(ctx, testUserID, 2024, 2)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Nil(t, report)
+		mockReportRepo.AssertNotCalled(t, "GetLargestExpense", mock.Anything, mock.Anything, mock.Anything)
+		mockReportRepo.AssertNotCalled(t, "GetCategoryBreakdown", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Invalid month", func(t *testing.T) {
+		mockReportRepo := new(MockReportRepository)
+		reportService := NewReportService(mockReportRepo)
+
+		report, err := reportService.GetMonthlyReport(context.Background(), testUserID, 2024, 13)
+		assert.Nil(t, report)
+		assert.Equal(t, domain.ErrInvalidInput, err)
+	})
+}
+
+func TestReportService_GetCashflow(t *testing.T) {
+	t.Run("Net and savings rate per month and overall", func(t *testing.T) {
+		mockReportRepo := new(MockReportRepository)
+		reportService := NewReportService(mockReportRepo)
+
+		mockReportRepo.On("GetMonthlyCashflow", testUserID, date(2024, 1, 1), date(2024, 3, 1)).Return([]*domain.CashflowMonth{
+			{Month: "2024-01", Income: amount("50000.00"), Expenses: amount("35000.00")},
+			{Month: "2024-02", Income: amount("0.00"), Expenses: amount("1200.00")},
+		}, nil)
+
+		report, err := reportService.GetCashflow(context.Background(), testUserID, date(2024, 1, 1), date(2024, 2, 29))
+		assert.NoError(t, err)
+		assert.Equal(t, amount("15000.00"), report.Months[0].Net)
+		assert.Equal(t, 30.0, *report.Months[0].SavingsRate)
+		assert.Equal(t, amount("-1200.00"), report.Months[1].Net)
+		assert.Nil(t, report.Months[1].SavingsRate)
+		assert.Equal(t, amount("36200.00"), report.Expenses)
+		assert.Equal(t, amount("13800.00"), report.Net)
+		assert.Equal(t, 27.6, *report.SavingsRate)
+		mockReportRepo.AssertExpectations(t)
+	})
+
+	t.Run("End before start", func(t *testing.T) {
+		mockReportRepo := new(MockReportRepository)
+		reportService := NewReportService(mockReportRepo)
+
+		report, err := reportService.GetCashflow(context.Background(), testUserID, date(2024, 3, 1), date(2024, 2, 1))
+		assert.Nil(t, report)
+		assert.Equal(t, domain.ErrInvalidInput, err)
+		mockReportRepo.AssertNotCalled(t, "GetMonthlyCashflow", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
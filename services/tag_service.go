@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"expense-tracker-api/domain"
+)
+
+type TagService struct {
+	txManager domain.TxManager
+	tagRepo   domain.TagRepository
+}
+
+// NewTagService creates a new tag service
+func NewTagService(txManager domain.TxManager, tagRepo domain.TagRepository) *TagService {
+	return &TagService{txManager: txManager, tagRepo: tagRepo}
+}
+
+// GetTags retrieves all tags with their usage counts
+func (s *TagService) GetTags(ctx context.Context, userID int) ([]*domain.Tag, error) {
+	return s.tagRepo.GetAll(ctx, userID)
+}
+
+// RenameTag renames a tag. Renaming onto an existing tag fails with
+// ErrAlreadyExists; use MergeTags to combine the two.
+func (s *TagService) RenameTag(ctx context.Context, userID, tagID int, name string) (*domain.Tag, error) {
+	name, err := domain.NormalizeTagName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return withinTx(ctx, s.txManager, func(tx domain.Tx) (*domain.Tag, error) {
+		tagRepo := s.tagRepo.WithTx(tx)
+
+		// Verify tag exists
+		tag, err := tagRepo.GetByID(ctx, userID, tagID)
+		if err != nil {
+			return nil, domain.ErrNotFound
+		}
+
+		if err := tagRepo.Rename(ctx, userID, tagID, name); err != nil {
+			return nil, err
+		}
+
+		tag.Name = name
+		return tag, nil
+	})
+}
+
+// MergeTags moves every expense tagged with the source tag onto the target tag
+// and deletes the source. It returns the target with its new usage count.
+func (s *TagService) MergeTags(ctx context.Context, userID, sourceID, targetID int) (*domain.Tag, error) {
+	if sourceID == targetID {
+		return nil, domain.ErrInvalidInput
+	}
+
+	return withinTx(ctx, s.txManager, func(tx domain.Tx) (*domain.Tag, error) {
+		tagRepo := s.tagRepo.WithTx(tx)
+
+		// Verify both tags exist, locking them in ID order so merges running
+		// the other way round can't deadlock
+		first, second := sourceID, targetID
+		if first > second {
+			first, second = second, first
+		}
+		if _, err := tagRepo.GetByID(ctx, userID, first); err != nil {
+			return nil, domain.ErrNotFound
+		}
+		if _, err := tagRepo.GetByID(ctx, userID, second); err != nil {
+			return nil, domain.ErrNotFound
+		}
+
+		if err := tagRepo.Merge(ctx, userID, sourceID, targetID); err != nil {
+			return nil, err
+		}
+
+		return tagRepo.GetByID(ctx, userID, targetID)
+	})
+}
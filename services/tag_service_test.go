@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"expense-tracker-api/domain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockTagRepository is a mock implementation of TagRepository
+type MockTagRepository struct {
+	mock.Mock
+}
+
+// WithTx returns the mock itself, so expectations hold inside and outside a transaction
+func (m *MockTagRepository) WithTx(tx domain.Tx) domain.TagRepository {
+	return m
+}
+
+func (m *MockTagRepository) GetAll(ctx context.Context, userID int) ([]*domain.Tag, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Tag), args.Error(1)
+}
+
+func (m *MockTagRepository) GetByID(ctx context.Context, userID, id int) (*domain.Tag, error) {
+	args := m.Called(userID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Tag), args.Error(1)
+}
+
+func (m *MockTagRepository) Rename(ctx context.Context, userID, id int, name string) error {
+	args := m.Called(userID, id, name)
+	return args.Error(0)
+}
+
+func (m *MockTagRepository) Merge(ctx context.Context, userID, sourceID, targetID int) error {
+	args := m.Called(userID, sourceID, targetID)
+	return args.Error(0)
+}
+
+func TestTagService_RenameTag(t *testing.T) {
+	t.Run("Name is normalized", func(t *testing.T) {
+		mockRepo := new(MockTagRepository)
+		tagService := NewTagService(fakeTxManager{}, mockRepo)
+
+		mockRepo.On("GetByID", testUserID, 1).Return(&domain.Tag{ID: 1, Name: "goa"}, nil)
+		mockRepo.On("Rename", testUserID, 1, "trip-goa").Return(nil)
+
+		tag, err := tagService.RenameTag(context.Background(), testUserID, 1, "  Trip-Goa ")
+		assert.NoError(t, err)
+		assert.Equal(t, "trip-goa", tag.Name)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Name taken by another tag", func(t *testing.T) {
+		mockRepo := new(MockTagRepository)
+		tagService := NewTagService(fakeTxManager{}, mockRepo)
+
+		mockRepo.On("GetByID", testUserID, 1).Return(&domain.Tag{ID: 1, Name: "goa"}, nil)
+		mockRepo.On("Rename", testUserID, 1, "gift").Return(domain.ErrAlreadyExists)
+
+		tag, err := tagService.RenameTag(context.Background(), testUserID, 1, "gift")
+		assert.Nil(t, tag)
+		assert.Equal(t, domain.ErrAlreadyExists, err)
+	})
+
+	t.Run("Empty name", func(t *testing.T) {
+		tagService := NewTagService(fakeTxManager{}, new(MockTagRepository))
+
+		tag, err := tagService.RenameTag(context.Background(), testUserID, 1, "   ")
+		assert.Nil(t, tag)
+		assert.Equal(t, domain.ErrInvalidInput, err)
+	})
+}
+
+func TestTagService_MergeTags(t *testing.T) {
+	t.Run("Returns the target with its new count", func(t *testing.T) {
+		mockRepo := new(MockTagRepository)
+		tagService := NewTagService(fakeTxManager{}, mockRepo)
+
+		mockRepo.On("GetByID", testUserID, 1).Return(&domain.Tag{ID: 1, Name: "reimburse", UsageCount: 2}, nil)
+		mockRepo.On("GetByID", testUserID, 2).Return(&domain.Tag{ID: 2, Name: "reimbursable", UsageCount: 5}, nil).Once()
+		mockRepo.On("Merge", testUserID, 1, 2).Return(nil)
+		mockRepo.On("GetByID", testUserID, 2).Return(&domain.Tag{ID: 2, Name: "reimbursable", UsageCount: 6}, nil).Once()
+
+		tag, err := tagService.MergeTags(context.Background(), testUserID, 1, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, 6, tag.UsageCount)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Merging a tag into itself", func(t *testing.T) {
+		tagService := NewTagService(fakeTxManager{}, new(MockTagRepository))
+
+		tag, err := tagService.MergeTags(context.Background(), testUserID, 1, 1)
+		assert.Nil(t, tag)
+		assert.Equal(t, domain.ErrInvalidInput, err)
+	})
+
+	t.Run("Unknown target", func(t *testing.T) {
+		mockRepo := new(MockTagRepository)
+		tagService := NewTagService(fakeTxManager{}, mockRepo)
+
+		mockRepo.On("GetByID", testUserID, 1).Return(&domain.Tag{ID: 1}, nil)
+		mockRepo.On("GetByID", testUserID, 9).Return(nil, domain.ErrNotFound)
+
+		tag, err := tagService.MergeTags(context.Background(), testUserID, 1, 9)
+		assert.Nil(t, tag)
+		assert.Equal(t, domain.ErrNotFound, err)
+		mockRepo.AssertNotCalled(t, "Merge", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
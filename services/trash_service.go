@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"expense-tracker-api/domain"
+	"log"
+	"time"
+)
+
+// DefaultTrashRetention is how long deleted records stay restorable when no retention is configured
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+type TrashService struct {
+	trashRepo domain.TrashRepository
+	retention time.Duration
+}
+
+// NewTrashService creates a new trash service that purges records deleted longer than retention ago
+func NewTrashService(trashRepo domain.TrashRepository, retention time.Duration) *TrashService {
+	return &TrashService{trashRepo: trashRepo, retention: retention}
+}
+
+// GetTrash retrieves the user's deleted expenses, categories and budgets
+func (s *TrashService) GetTrash(ctx context.Context, userID int) (*domain.Trash, error) {
+	return s.trashRepo.GetAll(ctx, userID)
+}
+
+// Restore brings a deleted record back. An expense or budget whose category is in
+// the trash can't be restored until the category is.
+func (s *TrashService) Restore(ctx context.Context, userID int, itemType domain.TrashItemType, id int) error {
+	if !itemType.IsValid() {
+		return domain.ErrInvalidInput
+	}
+	return s.trashRepo.Restore(ctx, userID, itemType, id)
+}
+
+// Purge permanently removes the records deleted more than the retention before now
+func (s *TrashService) Purge(ctx context.Context, now time.Time) (int64, error) {
+	return s.trashRepo.Purge(ctx, now.Add(-s.retention))
+}
+
+// StartPurger purges the trash immediately and then on every interval until ctx
+// is cancelled. It blocks, so run it in its own goroutine.
+func (s *TrashService) StartPurger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := s.Purge(ctx, time.Now())
+		if err != nil {
+			log.Printf("Trash purge failed: %v", err)
+		} else if purged > 0 {
+			log.Printf("Trash purge removed %d record(s)", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"expense-tracker-api/domain"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockTrashRepository is a mock implementation of TrashRepository
+type MockTrashRepository struct {
+	mock.Mock
+}
+
+func (m *MockTrashRepository) GetAll(ctx context.Context, userID int) (*domain.Trash, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Trash), args.Error(1)
+}
+
+func (m *MockTrashRepository) Restore(ctx context.Context, userID int, itemType domain.TrashItemType, id int) error {
+	args := m.Called(userID, itemType, id)
+	return args.Error(0)
+}
+
+func (m *MockTrashRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func TestTrashService_Restore(t *testing.T) {
+	t.Run("Expense", func(t *testing.T) {
+		mockRepo := new(MockTrashRepository)
+		trashService := NewTrashService(mockRepo, DefaultTrashRetention)
+
+		mockRepo.On("Restore", testUserID, domain.TrashItemExpense, 7).Return(nil)
+
+		err := trashService.Restore(context.Background(), testUserID, "expense", 7)
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Unknown type", func(t *testing.T) {
+		mockRepo := new(MockTrashRepository)
+		trashService := NewTrashService(mockRepo, DefaultTrashRetention)
+
+		err := trashService.Restore(context.Background(), testUserID, "income", 7)
+		assert.Equal(t, domain.ErrInvalidInput, err)
+		mockRepo.AssertNotCalled(t, "Restore", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Category still in the trash", func(t *testing.T) {
+		mockRepo := new(MockTrashRepository)
+		trashService := NewTrashService(mockRepo, DefaultTrashRetention)
+
+		mockRepo.On("Restore", testUserID, domain.TrashItemBudget, 3).Return(domain.ErrInvalidCategory)
+
+		err := trashService.Restore(context.Background(), testUserID, domain.TrashItemBudget, 3)
+		assert.Equal(t, domain.ErrInvalidCategory, err)
+	})
+}
+
+func TestTrashService_Purge(t *testing.T) {
+	mockRepo := new(MockTrashRepository)
+	trashService := NewTrashService(mockRepo, 7*24*time.Hour)
+
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	mockRepo.On("Purge", time.Date(2026, 3, 8, 12, 0, 0, 0, time.UTC)).Return(int64(4), nil)
+
+	purged, err := trashService.Purge(context.Background(), now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), purged)
+	mockRepo.AssertExpectations(t)
+}
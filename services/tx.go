@@ -0,0 +1,22 @@
+package services
+
+import (
+	"context"
+	"expense-tracker-api/domain"
+)
+
+// withinTx runs fn in a transaction from txManager and returns its result, or
+// only the error when the transaction was rolled back
+func withinTx[T any](ctx context.Context, txManager domain.TxManager, fn func(tx domain.Tx) (T, error)) (T, error) {
+	var result T
+	err := txManager.WithinTx(ctx, func(tx domain.Tx) error {
+		var err error
+		result, err = fn(tx)
+		return err
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return result, nil
+}
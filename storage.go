@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"expense-tracker-api/domain"
+	"expense-tracker-api/repository"
+	"expense-tracker-api/repository/memory"
+	"expense-tracker-api/repository/sqlite"
+	"fmt"
+	"os"
+)
+
+// storage is the set of repositories of one storage driver. The services bind
+// repositories to transactions from txManager, so all of them come from the same driver.
+type storage struct {
+	users             domain.UserRepository
+	categories        domain.CategoryRepository
+	expenses          domain.ExpenseRepository
+	budgets           domain.BudgetRepository
+	recurringExpenses domain.RecurringExpenseRepository
+	reports           domain.ReportRepository
+	tags              domain.TagRepository
+	paymentMethods    domain.PaymentMethodRepository
+	exchangeRates     domain.ExchangeRateRepository
+	incomes           domain.IncomeRepository
+	accounts          domain.AccountRepository
+	transfers         domain.TransferRepository
+	trash             domain.TrashRepository
+	audit             domain.AuditRepository
+	txManager         domain.TxManager
+
+	// migrator is nil for the in-memory storage, which has no schema
+	migrator         *repository.Migrator
+	initBaseCurrency func(currency string) error
+	// adoptLegacyData hands rows without an owner to a user; nil for the storages
+	// that never held any
+	adoptLegacyData func(ctx context.Context, email string) (int64, error)
+	// seed loads the demo data once the base currency is set; nil unless in demo mode
+	seed  func(ctx context.Context) error
+	close func() error
+}
+
+// openStorage connects to the database of the driver: postgres, configured by the
+// DB_* variables, or sqlite, a single file at SQLITE_PATH
+func openStorage(driver string) (*storage, error) {
+	switch driver {
+	case "", "postgres":
+		if err := repository.InitDB(); err != nil {
+			return nil, err
+		}
+		return &storage{
+			users:             repository.NewUserRepository(),
+			categories:        repository.NewCategoryRepository(),
+			expenses:          repository.NewExpenseRepository(),
+			budgets:           repository.NewBudgetRepository(),
+			recurringExpenses: repository.NewRecurringExpenseRepository(),
+			reports:           repository.NewReportRepository(),
+			tags:              repository.NewTagRepository(),
+			paymentMethods:    repository.NewPaymentMethodRepository(),
+			exchangeRates:     repository.NewExchangeRateRepository(),
+			incomes:           repository.NewIncomeRepository(),
+			accounts:          repository.NewAccountRepository(),
+			transfers:         repository.NewTransferRepository(),
+			trash:             repository.NewTrashRepository(),
+			audit:             repository.NewAuditRepository(),
+			txManager:         repository.NewTxManager(),
+			migrator:          repository.NewSchemaMigrator(),
+			initBaseCurrency:  repository.InitBaseCurrency,
+			adoptLegacyData:   repository.AdoptLegacyData,
+			close:             repository.CloseDB,
+		}, nil
+
+	case "sqlite":
+		path := os.Getenv("SQLITE_PATH")
+		if path == "" {
+			path = "expense_tracker.db"
+		}
+		if err := sqlite.InitDB(path); err != nil {
+			return nil, err
+		}
+		return &storage{
+			users:             sqlite.NewUserRepository(),
+			categories:        sqlite.NewCategoryRepository(),
+			expenses:          sqlite.NewExpenseRepository(),
+			budgets:           sqlite.NewBudgetRepository(),
+			recurringExpenses: sqlite.NewRecurringExpenseRepository(),
+			reports:           sqlite.NewReportRepository(),
+			tags:              sqlite.NewTagRepository(),
+			paymentMethods:    sqlite.NewPaymentMethodRepository(),
+			exchangeRates:     sqlite.NewExchangeRateRepository(),
+			incomes:           sqlite.NewIncomeRepository(),
+			accounts:          sqlite.NewAccountRepository(),
+			transfers:         sqlite.NewTransferRepository(),
+			trash:             sqlite.NewTrashRepository(),
+			audit:             sqlite.NewAuditRepository(),
+			txManager:         sqlite.NewTxManager(),
+			migrator:          sqlite.NewSchemaMigrator(),
+			initBaseCurrency:  sqlite.InitBaseCurrency,
+			close:             sqlite.CloseDB,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown storage driver %q, expected postgres or sqlite", driver)
+}
+
+// openDemoStorage keeps the records in memory, so they are gone when the server
+// stops. The demo data of seed.sql is loaded by seed.
+func openDemoStorage() *storage {
+	store := memory.NewStore()
+	return &storage{
+		users:             memory.NewUserRepository(store),
+		categories:        memory.NewCategoryRepository(store),
+		expenses:          memory.NewExpenseRepository(store),
+		budgets:           memory.NewBudgetRepository(store),
+		recurringExpenses: memory.NewRecurringExpenseRepository(store),
+		reports:           memory.NewReportRepository(store),
+		tags:              memory.NewTagRepository(store),
+		paymentMethods:    memory.NewPaymentMethodRepository(store),
+		exchangeRates:     memory.NewExchangeRateRepository(store),
+		incomes:           memory.NewIncomeRepository(store),
+		accounts:          memory.NewAccountRepository(store),
+		transfers:         memory.NewTransferRepository(store),
+		trash:             memory.NewTrashRepository(store),
+		audit:             memory.NewAuditRepository(store),
+		txManager:         memory.NewTxManager(store),
+		initBaseCurrency:  store.InitBaseCurrency,
+		seed: func(ctx context.Context) error {
+			return memory.Seed(ctx, store)
+		},
+		close: func() error { return nil },
+	}
+}
@@ -0,0 +1,152 @@
+// Package apierror writes every failed response as the same JSON envelope:
+//
+//	{"error": {"code": "INVALID_PAYMENT_MODE", "message": "...", "fields": [{"field": "payment_mode", "message": "..."}]}}
+//
+// The code is stable for clients to switch on; the message is for people and may change.
+package apierror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expense-tracker-api/domain"
+	"log"
+	"net/http"
+)
+
+// RequestIDHeader carries the correlation ID of a request, set on every response
+const RequestIDHeader = "X-Request-ID"
+
+// FieldError names a request field that was rejected and why
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is an error with the status, code and field errors to answer it with
+type Error struct {
+	Status  int          `json:"-"`
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"fields,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// New creates an error answered with the status and code
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// Invalid creates a 400 error for a single bad field, path variable or query parameter
+func Invalid(field, message string) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: "INVALID_INPUT", Message: message,
+		Fields: []FieldError{{Field: field, Message: message}}}
+}
+
+// domainErrors map the domain errors to their status and code, and to the request
+// field they are about when there is one. They are matched with errors.Is, so an
+// error wrapping one with more detail is answered the same way.
+var domainErrors = []struct {
+	err    error
+	status int
+	code   string
+	field  string
+}{
+	{domain.ErrNotFound, http.StatusNotFound, "NOT_FOUND", ""},
+	{domain.ErrInvalidCredentials, http.StatusUnauthorized, "INVALID_CREDENTIALS", ""},
+	{domain.ErrUnauthorized, http.StatusUnauthorized, "UNAUTHORIZED", ""},
+	{domain.ErrEmailAlreadyExists, http.StatusConflict, "EMAIL_ALREADY_EXISTS", "email"},
+	{domain.ErrAlreadyExists, http.StatusConflict, "ALREADY_EXISTS", ""},
+	{domain.ErrInUse, http.StatusConflict, "IN_USE", ""},
+	{domain.ErrInvalidReference, http.StatusUnprocessableEntity, "INVALID_REFERENCE", ""},
+	{domain.ErrInvalidPaymentMode, http.StatusBadRequest, "INVALID_PAYMENT_MODE", "payment_mode"},
+	{domain.ErrInvalidCategory, http.StatusBadRequest, "INVALID_CATEGORY", "category_id"},
+	{domain.ErrCategoryCycle, http.StatusBadRequest, "CATEGORY_CYCLE", "parent_id"},
+	{domain.ErrInvalidAccount, http.StatusBadRequest, "INVALID_ACCOUNT", "account_id"},
+	{domain.ErrInvalidSchedule, http.StatusBadRequest, "INVALID_SCHEDULE", ""},
+	{domain.ErrInvalidAmount, http.StatusBadRequest, "INVALID_AMOUNT", "amount"},
+	{domain.ErrAmountTooLarge, http.StatusBadRequest, "AMOUNT_TOO_LARGE", "amount"},
+	{domain.ErrInvalidCurrency, http.StatusBadRequest, "INVALID_CURRENCY", "currency"},
+	{domain.ErrInvalidRate, http.StatusBadRequest, "INVALID_RATE", "rate"},
+	{domain.ErrNoExchangeRate, http.StatusUnprocessableEntity, "NO_EXCHANGE_RATE", "currency"},
+	{domain.ErrInvalidInput, http.StatusBadRequest, "INVALID_INPUT", ""},
+}
+
+// From returns the status, code and message to answer err with. Errors the API
+// doesn't know are internal errors, and their text stays out of the response.
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	for _, known := range domainErrors {
+		if errors.Is(err, known.err) {
+			e := New(known.status, known.code, err.Error())
+			if known.field != "" {
+				e.Fields = []FieldError{{Field: known.field, Message: err.Error()}}
+			}
+			return e
+		}
+	}
+
+	switch {
+	case isUniqueViolation(err):
+		return New(http.StatusConflict, "ALREADY_EXISTS", domain.ErrAlreadyExists.Error())
+	case isForeignKeyViolation(err):
+		return New(http.StatusUnprocessableEntity, "INVALID_REFERENCE", "A referenced record does not exist")
+	case errors.Is(err, context.DeadlineExceeded):
+		return New(http.StatusGatewayTimeout, "TIMEOUT", "The request took too long")
+	case errors.Is(err, context.Canceled):
+		return New(http.StatusServiceUnavailable, "UNAVAILABLE", "The request was cancelled")
+	}
+	return New(http.StatusInternalServerError, "INTERNAL", "Internal server error")
+}
+
+// WithMessage returns the error err is answered with, worded for the request at hand
+func WithMessage(err error, message string) *Error {
+	e := *From(err)
+	e.Message = message
+	return &e
+}
+
+// Write answers the request with err in the error envelope. Internal errors and
+// timeouts are logged with the request ID, which the client gets in the X-Request-ID header.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	e := From(err)
+	// A driver may report a query stopped by the request's deadline in its own words
+	if e.Status == http.StatusInternalServerError && r.Context().Err() != nil {
+		e = From(r.Context().Err())
+	}
+	if e.Status >= http.StatusInternalServerError {
+		log.Printf("Request %s %s %s failed: %v", w.Header().Get(RequestIDHeader), r.Method, r.URL.Path, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.Status)
+	json.NewEncoder(w).Encode(struct {
+		Error *Error `json:"error"`
+	}{e})
+}
+
+// sqlStateError is implemented by Postgres errors
+type sqlStateError interface {
+	SQLState() string
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation a
+// repository passed on as it was
+func isUniqueViolation(err error) bool {
+	var pgErr sqlStateError
+	return errors.As(err, &pgErr) && pgErr.SQLState() == "23505"
+}
+
+// isForeignKeyViolation reports whether err is a foreign key violation a
+// repository passed on as it was
+func isForeignKeyViolation(err error) bool {
+	var pgErr sqlStateError
+	return errors.As(err, &pgErr) && pgErr.SQLState() == "23503"
+}
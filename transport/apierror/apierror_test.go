@@ -0,0 +1,99 @@
+package apierror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expense-tracker-api/domain"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrom(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+		fields []FieldError
+	}{
+		{"domain error", domain.ErrNotFound, http.StatusNotFound, "NOT_FOUND", nil},
+		{"wrapped domain error", fmt.Errorf("%w: category %q is archived", domain.ErrInvalidCategory, "Food"),
+			http.StatusBadRequest, "INVALID_CATEGORY",
+			[]FieldError{{Field: "category_id", Message: `invalid category: category "Food" is archived`}}},
+		{"email before resource clash", domain.ErrEmailAlreadyExists, http.StatusConflict, "EMAIL_ALREADY_EXISTS",
+			[]FieldError{{Field: "email", Message: "email already exists"}}},
+		{"postgres unique violation", &pq.Error{Code: "23505"}, http.StatusConflict, "ALREADY_EXISTS", nil},
+		{"postgres foreign key violation", fmt.Errorf("insert: %w", &pq.Error{Code: "23503"}),
+			http.StatusUnprocessableEntity, "INVALID_REFERENCE", nil},
+		{"amount too large", domain.ErrAmountTooLarge, http.StatusBadRequest, "AMOUNT_TOO_LARGE",
+			[]FieldError{{Field: "amount", Message: domain.ErrAmountTooLarge.Error()}}},
+		{"invalid reference", domain.ErrInvalidReference, http.StatusUnprocessableEntity, "INVALID_REFERENCE", nil},
+		{"deadline", fmt.Errorf("query: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, "TIMEOUT", nil},
+		{"cancelled", context.Canceled, http.StatusServiceUnavailable, "UNAVAILABLE", nil},
+		{"api error", Invalid("limit", "Invalid limit"), http.StatusBadRequest, "INVALID_INPUT",
+			[]FieldError{{Field: "limit", Message: "Invalid limit"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := From(tt.err)
+			assert.Equal(t, tt.status, e.Status)
+			assert.Equal(t, tt.code, e.Code)
+			assert.Equal(t, tt.fields, e.Fields)
+		})
+	}
+}
+
+func TestFrom_HidesUnknownErrors(t *testing.T) {
+	e := From(errors.New(`pq: relation "expenses" does not exist`))
+
+	assert.Equal(t, http.StatusInternalServerError, e.Status)
+	assert.Equal(t, "INTERNAL", e.Code)
+	assert.Equal(t, "Internal server error", e.Message)
+}
+
+func TestWithMessage(t *testing.T) {
+	e := WithMessage(domain.ErrInUse, "Account has expenses, incomes or transfers")
+
+	assert.Equal(t, http.StatusConflict, e.Status)
+	assert.Equal(t, "IN_USE", e.Code)
+	assert.Equal(t, "Account has expenses, incomes or transfers", e.Message)
+}
+
+func TestWrite(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/expenses", nil)
+
+	Write(w, r, domain.ErrInvalidPaymentMode)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var body struct {
+		Error Error `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "INVALID_PAYMENT_MODE", body.Error.Code)
+	assert.Equal(t, "invalid payment mode", body.Error.Message)
+	assert.Equal(t, []FieldError{{Field: "payment_mode", Message: "invalid payment mode"}}, body.Error.Fields)
+}
+
+func TestWrite_RequestTimedOut(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	<-ctx.Done()
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/api/expenses", nil).WithContext(ctx)
+
+	// Postgres reports a statement cancelled by the deadline as an error of its own
+	Write(w, r, &pq.Error{Code: "57014", Message: "canceling statement due to user request"})
+
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"TIMEOUT"`)
+}
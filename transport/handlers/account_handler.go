@@ -0,0 +1,364 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"expense-tracker-api/domain"
+	"expense-tracker-api/services"
+	"expense-tracker-api/transport/apierror"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type AccountHandler struct {
+	accountService *services.AccountService
+}
+
+// NewAccountHandler creates a new account handler
+func NewAccountHandler(accountService *services.AccountService) *AccountHandler {
+	return &AccountHandler{accountService: accountService}
+}
+
+type AccountRequest struct {
+	Name           string       `json:"name"`
+	Type           string       `json:"type"`
+	OpeningBalance domain.Money `json:"opening_balance"`
+	OpeningDate    string       `json:"opening_date"`
+}
+
+type ReconcileRequest struct {
+	StatementDate    string       `json:"statement_date"`
+	StatementBalance domain.Money `json:"statement_balance"`
+}
+
+type TransferRequest struct {
+	FromAccountID int          `json:"from_account_id"`
+	ToAccountID   int          `json:"to_account_id"`
+	Amount        domain.Money `json:"amount"`
+	Description   string       `json:"description"`
+	TransferDate  string       `json:"transfer_date"`
+}
+
+// toAccount converts the request into an account, reporting every invalid field at once.
+// The opening balance may be negative, as it is for a credit card.
+func (req *AccountRequest) toAccount() (*domain.Account, error) {
+	v := &validator{}
+	v.required("name", req.Name)
+	v.check(domain.AccountType(req.Type).IsValid(), "type", "Invalid type, expected bank, cash, wallet or card")
+	openingDate := v.date("opening_date", req.OpeningDate)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	return &domain.Account{
+		Name:           req.Name,
+		Type:           domain.AccountType(req.Type),
+		OpeningBalance: req.OpeningBalance,
+		OpeningDate:    openingDate,
+	}, nil
+}
+
+// toTransfer converts the request into a transfer, reporting every invalid field at once
+func (req *TransferRequest) toTransfer() (*domain.Transfer, error) {
+	v := &validator{}
+	v.check(req.FromAccountID > 0, "from_account_id", "Missing from_account_id")
+	v.check(req.ToAccountID > 0, "to_account_id", "Missing to_account_id")
+	v.check(req.FromAccountID != req.ToAccountID, "to_account_id", "Invalid to_account_id, must differ from from_account_id")
+	v.positiveAmount("amount", req.Amount)
+	v.description("description", req.Description)
+	transferDate := v.date("transfer_date", req.TransferDate)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	return &domain.Transfer{
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+		Description:   req.Description,
+		TransferDate:  transferDate,
+	}, nil
+}
+
+// GetAccounts handles getting all accounts
+func (h *AccountHandler) GetAccounts(w http.ResponseWriter, r *http.Request) {
+	accounts, err := h.accountService.GetAccounts(r.Context(), userIDFromRequest(r))
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(accounts)
+}
+
+// GetAccount handles getting a single account
+func (h *AccountHandler) GetAccount(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	accountID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid account ID"))
+		return
+	}
+
+	account, err := h.accountService.GetAccountByID(r.Context(), userIDFromRequest(r), accountID)
+	if err != nil {
+		writeAccountError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(account)
+}
+
+// CreateAccount handles creating a new account
+func (h *AccountHandler) CreateAccount(w http.ResponseWriter, r *http.Request) {
+	var req AccountRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	account, err := req.toAccount()
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	account.UserID = userIDFromRequest(r)
+
+	createdAccount, err := h.accountService.CreateAccount(r.Context(), account)
+	if err != nil {
+		writeAccountError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdAccount)
+}
+
+// UpdateAccount handles replacing an account
+func (h *AccountHandler) UpdateAccount(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	accountID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid account ID"))
+		return
+	}
+
+	var req AccountRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	account, err := req.toAccount()
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	account.ID = accountID
+	account.UserID = userIDFromRequest(r)
+
+	updatedAccount, err := h.accountService.UpdateAccount(r.Context(), account)
+	if err != nil {
+		writeAccountError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedAccount)
+}
+
+// DeleteAccount handles deleting an account
+func (h *AccountHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	accountID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid account ID"))
+		return
+	}
+
+	err = h.accountService.DeleteAccount(r.Context(), userIDFromRequest(r), accountID)
+	if err != nil {
+		writeAccountError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetBalance handles computing an account's balance, as of today or the as_of date
+func (h *AccountHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	accountID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid account ID"))
+		return
+	}
+
+	v := &validator{}
+	asOf := v.date("as_of", r.URL.Query().Get("as_of"))
+	if err := v.err(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	balance, err := h.accountService.GetBalance(r.Context(), userIDFromRequest(r), accountID, asOf)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidInput) {
+			err = apierror.Invalid("as_of", "Date is before the account's opening date")
+		}
+		writeAccountError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(balance)
+}
+
+// Reconcile handles recording a statement balance against the computed balance
+func (h *AccountHandler) Reconcile(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	accountID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid account ID"))
+		return
+	}
+
+	var req ReconcileRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	v := &validator{}
+	statementDate := v.requiredDate("statement_date", req.StatementDate)
+	if err := v.err(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	reconciliation, err := h.accountService.Reconcile(r.Context(), userIDFromRequest(r), accountID, statementDate, req.StatementBalance)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidInput) {
+			err = apierror.Invalid("statement_date", "Statement date is before the account's opening date")
+		}
+		writeAccountError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(reconciliation)
+}
+
+// GetReconciliations handles listing an account's reconciliations
+func (h *AccountHandler) GetReconciliations(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	accountID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid account ID"))
+		return
+	}
+
+	reconciliations, err := h.accountService.GetReconciliations(r.Context(), userIDFromRequest(r), accountID)
+	if err != nil {
+		writeAccountError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reconciliations)
+}
+
+// GetTransfers handles listing transfers, optionally only those of account_id
+func (h *AccountHandler) GetTransfers(w http.ResponseWriter, r *http.Request) {
+	v := &validator{}
+	accountID := v.queryID(r.URL.Query(), "account_id")
+	if err := v.err(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	transfers, err := h.accountService.GetTransfers(r.Context(), userIDFromRequest(r), accountID)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfers)
+}
+
+// GetTransfer handles getting a single transfer
+func (h *AccountHandler) GetTransfer(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	transferID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid transfer ID"))
+		return
+	}
+
+	transfer, err := h.accountService.GetTransferByID(r.Context(), userIDFromRequest(r), transferID)
+	if err != nil {
+		writeAccountError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// CreateTransfer handles moving money between two accounts
+func (h *AccountHandler) CreateTransfer(w http.ResponseWriter, r *http.Request) {
+	var req TransferRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	transfer, err := req.toTransfer()
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	transfer.UserID = userIDFromRequest(r)
+
+	createdTransfer, err := h.accountService.CreateTransfer(r.Context(), transfer)
+	if err != nil {
+		writeAccountError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdTransfer)
+}
+
+// DeleteTransfer handles deleting a transfer
+func (h *AccountHandler) DeleteTransfer(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	transferID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid transfer ID"))
+		return
+	}
+
+	err = h.accountService.DeleteTransfer(r.Context(), userIDFromRequest(r), transferID)
+	if err != nil {
+		writeAccountError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeAccountError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, domain.ErrInUse) {
+		err = apierror.WithMessage(err, "Account has expenses, incomes or transfers")
+	}
+	apierror.Write(w, r, err)
+}
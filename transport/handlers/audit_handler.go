@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"expense-tracker-api/domain"
+	"expense-tracker-api/services"
+	"expense-tracker-api/transport/apierror"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type AuditHandler struct {
+	auditService *services.AuditService
+}
+
+// NewAuditHandler creates a new audit log handler
+func NewAuditHandler(auditService *services.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+// GetAuditEvents handles listing audit events, optionally for one entity or record
+func (h *AuditHandler) GetAuditEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := domain.AuditFilter{Entity: domain.AuditEntity(query.Get("entity"))}
+
+	v := &validator{}
+	if id := v.queryID(query, "id"); id != nil {
+		filter.EntityID = *id
+	}
+	if limit := v.queryID(query, "limit"); limit != nil {
+		filter.Limit = *limit
+	}
+	if err := v.err(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	events, err := h.auditService.GetEvents(r.Context(), userIDFromRequest(r), filter)
+	if err != nil {
+		writeAuditError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+// GetHistory returns a handler listing the changes to one record of the entity
+// named by the {id} route variable
+func (h *AuditHandler) GetHistory(entity domain.AuditEntity) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Invalid("id", "Invalid ID"))
+			return
+		}
+
+		events, err := h.auditService.GetHistory(r.Context(), userIDFromRequest(r), entity, id)
+		if err != nil {
+			writeAuditError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(events)
+	}
+}
+
+func writeAuditError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, domain.ErrInvalidInput) {
+		err = apierror.WithMessage(err, "Invalid filter: entity must be expense, category, budget, income, account, transfer, recurring_expense, payment_method or tag, and id needs an entity")
+	}
+	apierror.Write(w, r, err)
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"expense-tracker-api/services"
+	"expense-tracker-api/transport/apierror"
+	"expense-tracker-api/transport/middleware"
+	"net/http"
+)
+
+type AuthHandler struct {
+	authService *services.AuthService
+}
+
+// NewAuthHandler creates a new auth handler
+func NewAuthHandler(authService *services.AuthService) *AuthHandler {
+	return &AuthHandler{authService: authService}
+}
+
+type CredentialsRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Register handles creating a new user account
+func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
+	var req CredentialsRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	user, err := h.authService.Register(r.Context(), req.Email, req.Password)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(user)
+}
+
+// Login handles exchanging credentials for an access and refresh token
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req CredentialsRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	tokens, err := h.authService.Login(r.Context(), req.Email, req.Password)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// Refresh handles exchanging a refresh token for a new token pair
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	tokens, err := h.authService.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// userIDFromRequest returns the ID of the user authenticated by the auth middleware
+func userIDFromRequest(r *http.Request) int {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	return userID
+}
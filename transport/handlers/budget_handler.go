@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"expense-tracker-api/domain"
 	"expense-tracker-api/services"
+	"expense-tracker-api/transport/apierror"
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -20,16 +22,32 @@ func NewBudgetHandler(budgetService *services.BudgetService) *BudgetHandler {
 }
 
 type CreateBudgetRequest struct {
-	Month        int     `json:"month"`
-	Year         int     `json:"year"`
-	BudgetAmount float64 `json:"budget_amount"`
+	CategoryID   *int         `json:"category_id"`
+	Month        int          `json:"month"`
+	Year         int          `json:"year"`
+	BudgetAmount domain.Money `json:"budget_amount"`
+}
+
+// validate reports every invalid field of the request at once. A budget of zero
+// is allowed, to mark a category that shouldn't be spent on.
+func (req *CreateBudgetRequest) validate() error {
+	v := &validator{}
+	v.check(req.Month >= 1 && req.Month <= 12, "month", "Invalid month, expected 1 to 12")
+	v.check(req.Year >= domain.EarliestDate.Year() && req.Year <= domain.LatestDate.Year(), "year",
+		fmt.Sprintf("Invalid year, expected %d to %d", domain.EarliestDate.Year(), domain.LatestDate.Year()))
+	v.check(req.BudgetAmount >= 0, "budget_amount", "Invalid budget_amount, must not be negative")
+	v.maxAmount("budget_amount", req.BudgetAmount)
+	if req.CategoryID != nil {
+		v.check(*req.CategoryID > 0, "category_id", "Invalid category_id")
+	}
+	return v.err()
 }
 
 // GetBudgets handles getting all budgets
 func (h *BudgetHandler) GetBudgets(w http.ResponseWriter, r *http.Request) {
-	budgets, err := h.budgetService.GetBudgets()
+	budgets, err := h.budgetService.GetBudgets(r.Context(), userIDFromRequest(r))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -40,25 +58,18 @@ func (h *BudgetHandler) GetBudgets(w http.ResponseWriter, r *http.Request) {
 // GetBudgetByMonth handles getting a budget for a specific month with status
 func (h *BudgetHandler) GetBudgetByMonth(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	month, err := strconv.Atoi(vars["month"])
-	if err != nil {
-		http.Error(w, "Invalid month", http.StatusBadRequest)
+	v := &validator{}
+	month := v.month("month", vars["month"])
+	year := v.year("year", vars["year"])
+	includeIncome := v.queryBool(r.URL.Query(), "include_income")
+	if err := v.err(); err != nil {
+		apierror.Write(w, r, err)
 		return
 	}
 
-	year, err := strconv.Atoi(vars["year"])
+	budgetStatus, err := h.budgetService.GetBudgetByMonth(r.Context(), userIDFromRequest(r), month, year, includeIncome)
 	if err != nil {
-		http.Error(w, "Invalid year", http.StatusBadRequest)
-		return
-	}
-
-	budgetStatus, err := h.budgetService.GetBudgetByMonth(month, year)
-	if err != nil {
-		if err == domain.ErrNotFound {
-			http.Error(w, err.Error(), http.StatusNotFound)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -69,14 +80,19 @@ func (h *BudgetHandler) GetBudgetByMonth(w http.ResponseWriter, r *http.Request)
 // CreateOrUpdateBudget handles creating or updating a budget
 func (h *BudgetHandler) CreateOrUpdateBudget(w http.ResponseWriter, r *http.Request) {
 	var req CreateBudgetRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	if err := req.validate(); err != nil {
+		apierror.Write(w, r, err)
 		return
 	}
 
-	budget, err := h.budgetService.CreateOrUpdateBudget(req.Month, req.Year, req.BudgetAmount)
+	budget, err := h.budgetService.CreateOrUpdateBudget(r.Context(), userIDFromRequest(r), req.Month, req.Year, req.CategoryID, req.BudgetAmount)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -90,17 +106,13 @@ func (h *BudgetHandler) DeleteBudget(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	budgetID, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, "Invalid budget ID", http.StatusBadRequest)
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid budget ID"))
 		return
 	}
 
-	err = h.budgetService.DeleteBudget(budgetID)
+	err = h.budgetService.DeleteBudget(r.Context(), userIDFromRequest(r), budgetID)
 	if err != nil {
-		if err == domain.ErrNotFound {
-			http.Error(w, err.Error(), http.StatusNotFound)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+		apierror.Write(w, r, err)
 		return
 	}
 
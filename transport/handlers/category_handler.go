@@ -2,8 +2,10 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"expense-tracker-api/domain"
 	"expense-tracker-api/services"
+	"expense-tracker-api/transport/apierror"
 	"net/http"
 	"strconv"
 
@@ -20,18 +22,33 @@ func NewCategoryHandler(categoryService *services.CategoryService) *CategoryHand
 }
 
 type CreateCategoryRequest struct {
-	Name string `json:"name"`
+	Name     string `json:"name"`
+	ParentID *int   `json:"parent_id"`
 }
 
+// UpdateCategoryRequest renames and optionally moves a category.
+// A parent_id of 0 moves it to the top level; leaving it out keeps the current parent.
 type UpdateCategoryRequest struct {
-	Name string `json:"name"`
+	Name     string `json:"name"`
+	ParentID *int   `json:"parent_id"`
 }
 
-// GetCategories handles getting all categories
+// MergeCategoryRequest names the category the merged one is folded into
+type MergeCategoryRequest struct {
+	TargetID int `json:"target_id"`
+}
+
+// GetCategories handles getting all categories, with archived ones only when asked for
 func (h *CategoryHandler) GetCategories(w http.ResponseWriter, r *http.Request) {
-	categories, err := h.categoryService.GetCategories()
+	includeArchived, err := parseIncludeArchived(r)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	categories, err := h.categoryService.GetCategories(r.Context(), userIDFromRequest(r), includeArchived)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -39,17 +56,35 @@ func (h *CategoryHandler) GetCategories(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(categories)
 }
 
+// GetCategoryTree handles getting all categories nested under their parents
+func (h *CategoryHandler) GetCategoryTree(w http.ResponseWriter, r *http.Request) {
+	includeArchived, err := parseIncludeArchived(r)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	tree, err := h.categoryService.GetCategoryTree(r.Context(), userIDFromRequest(r), includeArchived)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tree)
+}
+
 // CreateCategory handles creating a new category
 func (h *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
 	var req CreateCategoryRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, r, err)
 		return
 	}
 
-	category, err := h.categoryService.CreateCategory(req.Name)
+	category, err := h.categoryService.CreateCategory(r.Context(), userIDFromRequest(r), req.Name, req.ParentID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeCategoryError(w, r, err)
 		return
 	}
 
@@ -63,23 +98,19 @@ func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request)
 	vars := mux.Vars(r)
 	categoryID, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid category ID"))
 		return
 	}
 
 	var req UpdateCategoryRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, r, err)
 		return
 	}
 
-	category, err := h.categoryService.UpdateCategory(categoryID, req.Name)
+	category, err := h.categoryService.UpdateCategory(r.Context(), userIDFromRequest(r), categoryID, req.Name, req.ParentID)
 	if err != nil {
-		if err == domain.ErrNotFound {
-			http.Error(w, err.Error(), http.StatusNotFound)
-		} else {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		}
+		writeCategoryError(w, r, err)
 		return
 	}
 
@@ -92,19 +123,97 @@ func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request)
 	vars := mux.Vars(r)
 	categoryID, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid category ID"))
 		return
 	}
 
-	err = h.categoryService.DeleteCategory(categoryID)
-	if err != nil {
-		if err == domain.ErrNotFound {
-			http.Error(w, err.Error(), http.StatusNotFound)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+	var reassignTo *int
+	if reassignToStr := r.URL.Query().Get("reassign_to"); reassignToStr != "" {
+		targetID, err := strconv.Atoi(reassignToStr)
+		if err != nil {
+			apierror.Write(w, r, apierror.Invalid("reassign_to", "Invalid reassign_to"))
+			return
 		}
+		reassignTo = &targetID
+	}
+
+	err = h.categoryService.DeleteCategory(r.Context(), userIDFromRequest(r), categoryID, reassignTo)
+	if err != nil {
+		writeCategoryError(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// ArchiveCategory handles hiding a category and its subcategories from pickers
+func (h *CategoryHandler) ArchiveCategory(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, true)
+}
+
+// UnarchiveCategory handles making an archived category and its subcategories pickable again
+func (h *CategoryHandler) UnarchiveCategory(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, false)
+}
+
+func (h *CategoryHandler) setArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	vars := mux.Vars(r)
+	categoryID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid category ID"))
+		return
+	}
+
+	category, err := h.categoryService.SetCategoryArchived(r.Context(), userIDFromRequest(r), categoryID, archived)
+	if err != nil {
+		writeCategoryError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(category)
+}
+
+// MergeCategory handles moving everything in a category into another and deleting it
+func (h *CategoryHandler) MergeCategory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	categoryID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid category ID"))
+		return
+	}
+
+	var req MergeCategoryRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	target, err := h.categoryService.MergeCategory(r.Context(), userIDFromRequest(r), categoryID, req.TargetID)
+	if err != nil {
+		writeCategoryError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(target)
+}
+
+func parseIncludeArchived(r *http.Request) (bool, error) {
+	includeArchivedStr := r.URL.Query().Get("include_archived")
+	if includeArchivedStr == "" {
+		return false, nil
+	}
+	includeArchived, err := strconv.ParseBool(includeArchivedStr)
+	if err != nil {
+		return false, apierror.Invalid("include_archived", "Invalid include_archived")
+	}
+	return includeArchived, nil
+}
+
+func writeCategoryError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, domain.ErrInUse) {
+		err = apierror.WithMessage(err, "Category has expenses, recurring expenses or subcategories; pass reassign_to to move them")
+	}
+	apierror.Write(w, r, err)
+}
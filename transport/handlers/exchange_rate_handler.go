@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"expense-tracker-api/domain"
+	"expense-tracker-api/services"
+	"expense-tracker-api/transport/apierror"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type ExchangeRateHandler struct {
+	exchangeRateService *services.ExchangeRateService
+	// queryTimeout caps the database work of rate imports, which the router doesn't cut off as a whole
+	queryTimeout time.Duration
+}
+
+// NewExchangeRateHandler creates a new exchange rate handler
+func NewExchangeRateHandler(exchangeRateService *services.ExchangeRateService, queryTimeout time.Duration) *ExchangeRateHandler {
+	return &ExchangeRateHandler{exchangeRateService: exchangeRateService, queryTimeout: queryTimeout}
+}
+
+type ExchangeRateRequest struct {
+	Currency string      `json:"currency"`
+	Date     string      `json:"date"`
+	Rate     domain.Rate `json:"rate"`
+}
+
+type ExchangeRatesResponse struct {
+	BaseCurrency string                 `json:"base_currency"`
+	Rates        []*domain.ExchangeRate `json:"rates"`
+}
+
+type ImportRatesResponse struct {
+	Imported int `json:"imported"`
+}
+
+// GetExchangeRates handles listing the loaded rates, optionally filtered by currency
+func (h *ExchangeRateHandler) GetExchangeRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := h.exchangeRateService.GetRates(r.Context(), r.URL.Query().Get("currency"))
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	response := ExchangeRatesResponse{BaseCurrency: h.exchangeRateService.BaseCurrency(), Rates: rates}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// SaveExchangeRates handles adding or replacing a batch of rates
+func (h *ExchangeRateHandler) SaveExchangeRates(w http.ResponseWriter, r *http.Request) {
+	var req []ExchangeRateRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	// Fields are named by their position in the batch, such as [2].date
+	v := &validator{}
+	rates := make([]*domain.ExchangeRate, len(req))
+	for i, item := range req {
+		field := func(name string) string { return fmt.Sprintf("[%d].%s", i, name) }
+		v.required(field("currency"), item.Currency)
+		date := v.requiredDate(field("date"), item.Date)
+		v.check(item.Rate > 0, field("rate"), fmt.Sprintf("Invalid %s, must be more than zero", field("rate")))
+		rates[i] = &domain.ExchangeRate{Currency: item.Currency, Date: date, Rate: item.Rate}
+	}
+	if err := v.err(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	if err := h.exchangeRateService.SaveRates(r.Context(), rates); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ImportRatesResponse{Imported: len(rates)})
+}
+
+// ImportExchangeRates handles loading rates from a multipart CSV upload
+func (h *ExchangeRateHandler) ImportExchangeRates(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		apierror.Write(w, r, apierror.Invalid("file", "Invalid multipart form"))
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		apierror.Write(w, r, apierror.Invalid("file", "Missing CSV file"))
+		return
+	}
+	defer file.Close()
+
+	// The file has arrived; from here the import is held to the query timeout
+	ctx, cancel := withQueryTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+	r = r.WithContext(ctx)
+
+	imported, err := h.exchangeRateService.ImportCSV(r.Context(), file)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ImportRatesResponse{Imported: imported})
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"expense-tracker-api/domain"
+	"expense-tracker-api/transport/apierror"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportWriter writes export rows in one output format
+type exportWriter interface {
+	WriteRow(row *domain.ExpenseExportRow) error
+	Close() error
+}
+
+var exportContentTypes = map[string]string{
+	"csv":    "text/csv",
+	"json":   "application/json",
+	"ndjson": "application/x-ndjson",
+}
+
+// ExportExpenses handles streaming the filtered expenses as CSV, a JSON array or
+// newline-delimited JSON. It takes the same filters as GetExpenses.
+func (h *ExpenseHandler) ExportExpenses(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	contentType, ok := exportContentTypes[format]
+	v := &validator{}
+	v.check(ok, "format", "Invalid format, must be csv, json or ndjson")
+	filter := parseExpenseFilter(r.URL.Query(), v)
+	if err := h.checkPaymentModeFilter(r, filter, v); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	if err := v.err(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	// The query must return its first row within the query timeout. The rows after
+	// it stream for as long as the client takes to read them.
+	ctx, cancel := context.WithCancelCause(r.Context())
+	defer cancel(nil)
+	var firstRowDeadline *time.Timer
+	if h.queryTimeout > 0 {
+		firstRowDeadline = time.AfterFunc(h.queryTimeout, func() { cancel(context.DeadlineExceeded) })
+	}
+
+	var writer exportWriter
+	started := false
+	err := h.expenseService.ExportExpenses(ctx, userIDFromRequest(r), filter, func(row *domain.ExpenseExportRow) error {
+		if !started {
+			if firstRowDeadline != nil && !firstRowDeadline.Stop() {
+				return context.Cause(ctx)
+			}
+			writer = startExport(w, format, contentType)
+			started = true
+		}
+		return writer.WriteRow(row)
+	})
+	if err != nil {
+		if !started {
+			if errors.Is(context.Cause(ctx), context.DeadlineExceeded) {
+				err = context.DeadlineExceeded
+			}
+			apierror.Write(w, r, err)
+			return
+		}
+		// The status line has already been sent; all we can do is cut the response short
+		log.Printf("Expense export aborted: %v", err)
+		return
+	}
+
+	if !started {
+		writer = startExport(w, format, contentType)
+	}
+	if err := writer.Close(); err != nil {
+		log.Printf("Expense export aborted: %v", err)
+	}
+}
+
+// startExport sends the download headers and returns the writer for the format
+func startExport(w http.ResponseWriter, format, contentType string) exportWriter {
+	filename := fmt.Sprintf("expenses-%s.%s", time.Now().Format("2006-01-02"), format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	switch format {
+	case "json":
+		return &jsonArrayExportWriter{w: w}
+	case "ndjson":
+		return &ndjsonExportWriter{encoder: json.NewEncoder(w)}
+	default:
+		writer := &csvExportWriter{writer: csv.NewWriter(w)}
+		writer.writer.Write([]string{"id", "expense_date", "category_id", "category_name", "amount",
+			"currency", "original_amount", "exchange_rate", "description", "payment_mode", "tags", "recurring_expense_id", "created_at"})
+		return writer
+	}
+}
+
+type csvExportWriter struct {
+	writer *csv.Writer
+}
+
+func (e *csvExportWriter) WriteRow(row *domain.ExpenseExportRow) error {
+	recurringID := ""
+	if row.RecurringExpenseID != nil {
+		recurringID = strconv.Itoa(*row.RecurringExpenseID)
+	}
+	return e.writer.Write([]string{
+		strconv.Itoa(row.ID),
+		row.ExpenseDate.Format("2006-01-02"),
+		strconv.Itoa(row.CategoryID),
+		row.CategoryName,
+		row.Amount.String(),
+		row.Currency,
+		row.OriginalAmount.String(),
+		row.ExchangeRate.String(),
+		row.Description,
+		string(row.PaymentMode),
+		strings.Join(row.Tags, ";"),
+		recurringID,
+		row.CreatedAt.Format(time.RFC3339),
+	})
+}
+
+func (e *csvExportWriter) Close() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+type jsonArrayExportWriter struct {
+	w     http.ResponseWriter
+	count int
+}
+
+func (e *jsonArrayExportWriter) WriteRow(row *domain.ExpenseExportRow) error {
+	separator := ","
+	if e.count == 0 {
+		separator = "["
+	}
+	e.count++
+
+	data, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	if _, err := e.w.Write([]byte(separator)); err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonArrayExportWriter) Close() error {
+	closing := "]"
+	if e.count == 0 {
+		closing = "[]"
+	}
+	_, err := e.w.Write([]byte(closing + "\n"))
+	return err
+}
+
+type ndjsonExportWriter struct {
+	encoder *json.Encoder
+}
+
+func (e *ndjsonExportWriter) WriteRow(row *domain.ExpenseExportRow) error {
+	return e.encoder.Encode(row)
+}
+
+func (e *ndjsonExportWriter) Close() error {
+	return nil
+}
@@ -4,79 +4,231 @@ import (
 	"encoding/json"
 	"expense-tracker-api/domain"
 	"expense-tracker-api/services"
+	"expense-tracker-api/transport/apierror"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 )
 
+// maxImportSize caps the size of an uploaded CSV file
+const maxImportSize = 10 << 20
+
 type ExpenseHandler struct {
 	expenseService *services.ExpenseService
+	// queryTimeout caps the database work of imports and exports, which the router
+	// doesn't cut off as a whole
+	queryTimeout time.Duration
 }
 
 // NewExpenseHandler creates a new expense handler
-func NewExpenseHandler(expenseService *services.ExpenseService) *ExpenseHandler {
-	return &ExpenseHandler{expenseService: expenseService}
+func NewExpenseHandler(expenseService *services.ExpenseService, queryTimeout time.Duration) *ExpenseHandler {
+	return &ExpenseHandler{expenseService: expenseService, queryTimeout: queryTimeout}
 }
 
 type CreateExpenseRequest struct {
-	CategoryID  int     `json:"category_id"`
-	Amount      float64 `json:"amount"`
-	Description string  `json:"description"`
-	PaymentMode string  `json:"payment_mode"`
-	ExpenseDate string  `json:"expense_date"`
+	CategoryID  int          `json:"category_id"`
+	Amount      domain.Money `json:"amount"`
+	Currency    string       `json:"currency"`
+	Description string       `json:"description"`
+	PaymentMode string       `json:"payment_mode"`
+	AccountID   *int         `json:"account_id"`
+	ExpenseDate string       `json:"expense_date"`
+	Tags        []string     `json:"tags"`
 }
 
 type UpdateExpenseRequest struct {
-	CategoryID  *int     `json:"category_id"`
-	Amount      *float64 `json:"amount"`
-	Description *string  `json:"description"`
-	PaymentMode *string  `json:"payment_mode"`
-	ExpenseDate *string  `json:"expense_date"`
+	CategoryID  *int            `json:"category_id"`
+	Amount      *domain.Money   `json:"amount"`
+	Currency    *string         `json:"currency"`
+	Description *string         `json:"description"`
+	PaymentMode *string         `json:"payment_mode"`
+	AccountID   json.RawMessage `json:"account_id"`
+	ExpenseDate *string         `json:"expense_date"`
+	Tags        *[]string       `json:"tags"`
+}
+
+// toExpense converts the request into an expense, reporting every invalid field at once.
+// A body that didn't decode never gets here; see writeDecodeError.
+func (req *CreateExpenseRequest) toExpense() (*domain.Expense, error) {
+	v := &validator{}
+	v.check(req.CategoryID > 0, "category_id", "Missing category_id")
+	v.positiveAmount("amount", req.Amount)
+	v.description("description", req.Description)
+	v.required("payment_mode", req.PaymentMode)
+	expenseDate := v.date("expense_date", req.ExpenseDate)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	return &domain.Expense{
+		CategoryID:  req.CategoryID,
+		Amount:      req.Amount,
+		Currency:    req.Currency,
+		Description: req.Description,
+		PaymentMode: domain.PaymentMode(req.PaymentMode),
+		AccountID:   req.AccountID,
+		ExpenseDate: expenseDate,
+		Tags:        req.Tags,
+	}, nil
+}
+
+// toExpense converts the request into the changes to an expense; fields left out
+// stay zero and keep their current value. Every invalid field is reported at once.
+func (req *UpdateExpenseRequest) toExpense() (*domain.Expense, error) {
+	v := &validator{}
+	expense := &domain.Expense{}
+	if req.AccountID != nil {
+		// account_id is kept raw to tell null from a field left out. Null leaves
+		// accountID at 0, which detaches the expense from its account.
+		accountID := 0
+		err := json.Unmarshal(req.AccountID, &accountID)
+		v.check(err == nil && accountID >= 0, "account_id", "Invalid account_id, expected an account ID or null")
+		expense.AccountID = &accountID
+	}
+	if req.CategoryID != nil {
+		v.check(*req.CategoryID > 0, "category_id", "Invalid category_id")
+		expense.CategoryID = *req.CategoryID
+	}
+	if req.Amount != nil {
+		v.positiveAmount("amount", *req.Amount)
+		expense.Amount = *req.Amount
+	}
+	if req.Currency != nil {
+		expense.Currency = *req.Currency
+	}
+	if req.Description != nil {
+		v.description("description", *req.Description)
+		expense.Description = *req.Description
+	}
+	if req.PaymentMode != nil {
+		expense.PaymentMode = domain.PaymentMode(*req.PaymentMode)
+	}
+	if req.ExpenseDate != nil {
+		expense.ExpenseDate = v.requiredDate("expense_date", *req.ExpenseDate)
+	}
+	if req.Tags != nil {
+		expense.Tags = *req.Tags
+		if expense.Tags == nil {
+			expense.Tags = []string{}
+		}
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	return expense, nil
 }
 
 // GetExpenses handles getting expenses with optional filters
 func (h *ExpenseHandler) GetExpenses(w http.ResponseWriter, r *http.Request) {
-	filter := &domain.ExpenseFilter{}
+	v := &validator{}
+	filter := parseExpenseFilter(r.URL.Query(), v)
+	parseExpensePaging(r.URL.Query(), filter, v)
+	if err := h.checkPaymentModeFilter(r, filter, v); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	if err := v.err(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
 
-	// Parse query parameters
-	if categoryIDStr := r.URL.Query().Get("category_id"); categoryIDStr != "" {
-		categoryID, err := strconv.Atoi(categoryIDStr)
-		if err == nil {
-			filter.CategoryID = &categoryID
-		}
+	page, err := h.expenseService.GetExpenses(r.Context(), userIDFromRequest(r), filter)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
 	}
 
-	if paymentModeStr := r.URL.Query().Get("payment_mode"); paymentModeStr != "" {
-		pm := domain.PaymentMode(paymentModeStr)
-		if pm.IsValid() {
-			filter.PaymentMode = &pm
-		}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// parseExpensePaging reads sort, order, limit and cursor from the query parameters
+func parseExpensePaging(query url.Values, filter *domain.ExpenseFilter, v *validator) {
+	filter.Sort = domain.ExpenseSort(query.Get("sort"))
+	v.check(filter.Sort == "" || filter.Sort.IsValid(), "sort", "Invalid sort, expected date, amount, created_at or relevance")
+
+	switch query.Get("order") {
+	case "", "desc":
+		filter.Descending = true
+	case "asc":
+		filter.Descending = false
+	default:
+		v.fail("order", "Invalid order, expected asc or desc")
 	}
 
-	if startDateStr := r.URL.Query().Get("start_date"); startDateStr != "" {
-		startDate, err := time.Parse("2006-01-02", startDateStr)
-		if err == nil {
-			filter.StartDate = &startDate
-		}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		v.check(err == nil && limit >= 1, "limit", "Invalid limit, expected a positive whole number")
+		filter.Limit = limit
 	}
 
-	if endDateStr := r.URL.Query().Get("end_date"); endDateStr != "" {
-		endDate, err := time.Parse("2006-01-02", endDateStr)
-		if err == nil {
-			filter.EndDate = &endDate
-		}
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		cursor, err := domain.ParseExpenseCursor(cursorStr)
+		v.check(err == nil, "cursor", "Invalid cursor")
+		filter.After = cursor
+	}
+}
+
+// parseExpenseFilter reads the expense filter from the query parameters. Invalid
+// values are rejected rather than ignored, so a client never gets unfiltered data by mistake.
+func parseExpenseFilter(query url.Values, v *validator) *domain.ExpenseFilter {
+	filter := &domain.ExpenseFilter{
+		CategoryID:           v.queryID(query, "category_id"),
+		IncludeSubcategories: v.queryBool(query, "include_subcategories"),
+		AccountID:            v.queryID(query, "account_id"),
+		StartDate:            v.optionalDate("start_date", query.Get("start_date")),
+		EndDate:              v.optionalDate("end_date", query.Get("end_date")),
+		Query:                query.Get("q"),
 	}
+	v.dateOrder("start_date", filter.StartDate, "end_date", filter.EndDate)
 
-	expenses, err := h.expenseService.GetExpenses(filter)
+	if query.Has("payment_mode") {
+		paymentMode := domain.PaymentMode(query.Get("payment_mode"))
+		v.check(paymentMode != "", "payment_mode", "Invalid payment_mode, must not be empty")
+		filter.PaymentMode = &paymentMode
+	}
+
+	parseTagFilter(query, filter, v)
+	return filter
+}
+
+// checkPaymentModeFilter checks that the payment_mode filter names one of the user's
+// payment methods, as create and update do, so a misspelled one isn't answered with
+// no expenses. Unlike them it accepts inactive methods. Only a failed lookup is returned.
+func (h *ExpenseHandler) checkPaymentModeFilter(r *http.Request, filter *domain.ExpenseFilter, v *validator) error {
+	if filter.PaymentMode == nil || *filter.PaymentMode == "" {
+		return nil
+	}
+	known, err := h.expenseService.HasPaymentMethod(r.Context(), userIDFromRequest(r), *filter.PaymentMode)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return err
+	}
+	v.check(known, "payment_mode", "Invalid payment_mode, must name one of your payment methods")
+	return nil
+}
+
+// parseTagFilter reads the tags filter, "any:a,b" or "all:a,b". Without a prefix any tag matches.
+func parseTagFilter(query url.Values, filter *domain.ExpenseFilter, v *validator) {
+	tagsStr := query.Get("tags")
+	if tagsStr == "" {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(expenses)
+	switch {
+	case strings.HasPrefix(tagsStr, "all:"):
+		filter.AllTags = true
+		tagsStr = strings.TrimPrefix(tagsStr, "all:")
+	case strings.HasPrefix(tagsStr, "any:"):
+		tagsStr = strings.TrimPrefix(tagsStr, "any:")
+	}
+
+	tags, err := domain.NormalizeTagNames(strings.Split(tagsStr, ","))
+	v.check(err == nil, "tags", "Invalid tags, expected any:tag1,tag2 or all:tag1,tag2")
+	filter.Tags = tags
 }
 
 // GetExpense handles getting a single expense
@@ -84,17 +236,13 @@ func (h *ExpenseHandler) GetExpense(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	expenseID, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, "Invalid expense ID", http.StatusBadRequest)
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid expense ID"))
 		return
 	}
 
-	expense, err := h.expenseService.GetExpenseByID(expenseID)
+	expense, err := h.expenseService.GetExpenseByID(r.Context(), userIDFromRequest(r), expenseID)
 	if err != nil {
-		if err == domain.ErrNotFound {
-			http.Error(w, err.Error(), http.StatusNotFound)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -105,34 +253,84 @@ func (h *ExpenseHandler) GetExpense(w http.ResponseWriter, r *http.Request) {
 // CreateExpense handles creating a new expense
 func (h *ExpenseHandler) CreateExpense(w http.ResponseWriter, r *http.Request) {
 	var req CreateExpenseRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, r, err)
 		return
 	}
 
-	expense := &domain.Expense{
-		CategoryID:  req.CategoryID,
-		Amount:      req.Amount,
-		Description: req.Description,
-		PaymentMode: domain.PaymentMode(req.PaymentMode),
+	expense, err := req.toExpense()
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
 	}
+	expense.UserID = userIDFromRequest(r)
 
-	if req.ExpenseDate != "" {
-		expenseDate, err := time.Parse("2006-01-02", req.ExpenseDate)
-		if err == nil {
-			expense.ExpenseDate = expenseDate
+	createdExpense, err := h.expenseService.CreateExpense(r.Context(), expense)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdExpense)
+}
+
+// ImportExpenses handles bulk loading expenses from a multipart CSV upload.
+// Column names can be overridden with the *_column form fields, and
+// dry_run=true returns the row report without writing anything.
+func (h *ExpenseHandler) ImportExpenses(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		apierror.Write(w, r, apierror.Invalid("file", "Invalid multipart form"))
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		apierror.Write(w, r, apierror.Invalid("file", "Missing CSV file"))
+		return
+	}
+	defer file.Close()
+
+	mapping := domain.DefaultImportMapping()
+	overrides := map[string]*string{
+		"date_column":         &mapping.Date,
+		"amount_column":       &mapping.Amount,
+		"currency_column":     &mapping.Currency,
+		"description_column":  &mapping.Description,
+		"category_column":     &mapping.Category,
+		"payment_mode_column": &mapping.PaymentMode,
+		"date_format":         &mapping.DateFormat,
+	}
+	for field, target := range overrides {
+		if value := r.FormValue(field); value != "" {
+			*target = value
 		}
 	}
 
-	createdExpense, err := h.expenseService.CreateExpense(expense)
+	dryRun := false
+	if dryRunStr := r.FormValue("dry_run"); dryRunStr != "" {
+		dryRun, err = strconv.ParseBool(dryRunStr)
+		if err != nil {
+			apierror.Write(w, r, apierror.Invalid("dry_run", "Invalid dry_run"))
+			return
+		}
+	}
+
+	// The file has arrived; from here the import is held to the query timeout
+	ctx, cancel := withQueryTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+	r = r.WithContext(ctx)
+
+	report, err := h.expenseService.ImportCSV(r.Context(), userIDFromRequest(r), file, mapping, dryRun)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		apierror.Write(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(createdExpense)
+	json.NewEncoder(w).Encode(report)
 }
 
 // UpdateExpense handles updating an expense
@@ -140,43 +338,27 @@ func (h *ExpenseHandler) UpdateExpense(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	expenseID, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, "Invalid expense ID", http.StatusBadRequest)
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid expense ID"))
 		return
 	}
 
 	var req UpdateExpenseRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, r, err)
 		return
 	}
 
-	expense := &domain.Expense{ID: expenseID}
-	if req.CategoryID != nil {
-		expense.CategoryID = *req.CategoryID
-	}
-	if req.Amount != nil {
-		expense.Amount = *req.Amount
-	}
-	if req.Description != nil {
-		expense.Description = *req.Description
-	}
-	if req.PaymentMode != nil {
-		expense.PaymentMode = domain.PaymentMode(*req.PaymentMode)
-	}
-	if req.ExpenseDate != nil {
-		expenseDate, err := time.Parse("2006-01-02", *req.ExpenseDate)
-		if err == nil {
-			expense.ExpenseDate = expenseDate
-		}
+	expense, err := req.toExpense()
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
 	}
+	expense.ID = expenseID
+	expense.UserID = userIDFromRequest(r)
 
-	updatedExpense, err := h.expenseService.UpdateExpense(expense)
+	updatedExpense, err := h.expenseService.UpdateExpense(r.Context(), expense)
 	if err != nil {
-		if err == domain.ErrNotFound {
-			http.Error(w, err.Error(), http.StatusNotFound)
-		} else {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		}
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -189,17 +371,13 @@ func (h *ExpenseHandler) DeleteExpense(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	expenseID, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, "Invalid expense ID", http.StatusBadRequest)
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid expense ID"))
 		return
 	}
 
-	err = h.expenseService.DeleteExpense(expenseID)
+	err = h.expenseService.DeleteExpense(r.Context(), userIDFromRequest(r), expenseID)
 	if err != nil {
-		if err == domain.ErrNotFound {
-			http.Error(w, err.Error(), http.StatusNotFound)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+		apierror.Write(w, r, err)
 		return
 	}
 
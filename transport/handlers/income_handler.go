@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"encoding/json"
+	"expense-tracker-api/domain"
+	"expense-tracker-api/services"
+	"expense-tracker-api/transport/apierror"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type IncomeHandler struct {
+	incomeService *services.IncomeService
+}
+
+// NewIncomeHandler creates a new income handler
+func NewIncomeHandler(incomeService *services.IncomeService) *IncomeHandler {
+	return &IncomeHandler{incomeService: incomeService}
+}
+
+type IncomeRequest struct {
+	Source      string       `json:"source"`
+	Amount      domain.Money `json:"amount"`
+	Description string       `json:"description"`
+	PaymentMode *string      `json:"payment_mode"`
+	AccountID   *int         `json:"account_id"`
+	CategoryID  *int         `json:"category_id"`
+	IncomeDate  string       `json:"income_date"`
+}
+
+// toIncome converts the request into an income, reporting every invalid field at once
+func (req *IncomeRequest) toIncome() (*domain.Income, error) {
+	v := &validator{}
+	v.required("source", req.Source)
+	v.positiveAmount("amount", req.Amount)
+	v.description("description", req.Description)
+	incomeDate := v.date("income_date", req.IncomeDate)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	income := &domain.Income{
+		Source:      req.Source,
+		Amount:      req.Amount,
+		Description: req.Description,
+		AccountID:   req.AccountID,
+		CategoryID:  req.CategoryID,
+		IncomeDate:  incomeDate,
+	}
+
+	if req.PaymentMode != nil && *req.PaymentMode != "" {
+		paymentMode := domain.PaymentMode(*req.PaymentMode)
+		income.PaymentMode = &paymentMode
+	}
+	return income, nil
+}
+
+// GetIncomes handles listing incomes, optionally between start_date and end_date
+func (h *IncomeHandler) GetIncomes(w http.ResponseWriter, r *http.Request) {
+	v := &validator{}
+	filter := &domain.IncomeFilter{
+		StartDate: v.optionalDate("start_date", r.URL.Query().Get("start_date")),
+		EndDate:   v.optionalDate("end_date", r.URL.Query().Get("end_date")),
+	}
+	v.dateOrder("start_date", filter.StartDate, "end_date", filter.EndDate)
+	if err := v.err(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	incomes, err := h.incomeService.GetIncomes(r.Context(), userIDFromRequest(r), filter)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(incomes)
+}
+
+// GetIncome handles getting a single income
+func (h *IncomeHandler) GetIncome(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	incomeID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid income ID"))
+		return
+	}
+
+	income, err := h.incomeService.GetIncomeByID(r.Context(), userIDFromRequest(r), incomeID)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(income)
+}
+
+// CreateIncome handles recording a new income
+func (h *IncomeHandler) CreateIncome(w http.ResponseWriter, r *http.Request) {
+	var req IncomeRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	income, err := req.toIncome()
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	income.UserID = userIDFromRequest(r)
+
+	createdIncome, err := h.incomeService.CreateIncome(r.Context(), income)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdIncome)
+}
+
+// UpdateIncome handles replacing an income
+func (h *IncomeHandler) UpdateIncome(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	incomeID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid income ID"))
+		return
+	}
+
+	var req IncomeRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	income, err := req.toIncome()
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	income.ID = incomeID
+	income.UserID = userIDFromRequest(r)
+
+	updatedIncome, err := h.incomeService.UpdateIncome(r.Context(), income)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedIncome)
+}
+
+// DeleteIncome handles deleting an income
+func (h *IncomeHandler) DeleteIncome(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	incomeID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid income ID"))
+		return
+	}
+
+	err = h.incomeService.DeleteIncome(r.Context(), userIDFromRequest(r), incomeID)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
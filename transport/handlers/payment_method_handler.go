@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"expense-tracker-api/domain"
+	"expense-tracker-api/services"
+	"expense-tracker-api/transport/apierror"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type PaymentMethodHandler struct {
+	paymentMethodService *services.PaymentMethodService
+}
+
+// NewPaymentMethodHandler creates a new payment method handler
+func NewPaymentMethodHandler(paymentMethodService *services.PaymentMethodService) *PaymentMethodHandler {
+	return &PaymentMethodHandler{paymentMethodService: paymentMethodService}
+}
+
+type PaymentMethodRequest struct {
+	Name   string  `json:"name"`
+	Type   string  `json:"type"`
+	Last4  *string `json:"last4"`
+	VPA    *string `json:"vpa"`
+	Active *bool   `json:"active"`
+}
+
+// toPaymentMethod converts the request into a payment method, active unless stated otherwise
+func (req *PaymentMethodRequest) toPaymentMethod() *domain.PaymentMethod {
+	return &domain.PaymentMethod{
+		Name:   req.Name,
+		Type:   domain.PaymentMethodType(req.Type),
+		Last4:  req.Last4,
+		VPA:    req.VPA,
+		Active: req.Active == nil || *req.Active,
+	}
+}
+
+// GetPaymentMethods handles listing payment methods
+func (h *PaymentMethodHandler) GetPaymentMethods(w http.ResponseWriter, r *http.Request) {
+	methods, err := h.paymentMethodService.GetPaymentMethods(r.Context(), userIDFromRequest(r))
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(methods)
+}
+
+// GetPaymentMethod handles getting a single payment method
+func (h *PaymentMethodHandler) GetPaymentMethod(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	methodID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid payment method ID"))
+		return
+	}
+
+	method, err := h.paymentMethodService.GetPaymentMethodByID(r.Context(), userIDFromRequest(r), methodID)
+	if err != nil {
+		writePaymentMethodError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(method)
+}
+
+// CreatePaymentMethod handles creating a new payment method
+func (h *PaymentMethodHandler) CreatePaymentMethod(w http.ResponseWriter, r *http.Request) {
+	var req PaymentMethodRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	method := req.toPaymentMethod()
+	method.UserID = userIDFromRequest(r)
+
+	createdMethod, err := h.paymentMethodService.CreatePaymentMethod(r.Context(), method)
+	if err != nil {
+		writePaymentMethodError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdMethod)
+}
+
+// UpdatePaymentMethod handles replacing a payment method
+func (h *PaymentMethodHandler) UpdatePaymentMethod(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	methodID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid payment method ID"))
+		return
+	}
+
+	var req PaymentMethodRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	method := req.toPaymentMethod()
+	method.ID = methodID
+	method.UserID = userIDFromRequest(r)
+
+	updatedMethod, err := h.paymentMethodService.UpdatePaymentMethod(r.Context(), method)
+	if err != nil {
+		writePaymentMethodError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedMethod)
+}
+
+// DeletePaymentMethod handles deleting a payment method
+func (h *PaymentMethodHandler) DeletePaymentMethod(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	methodID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid payment method ID"))
+		return
+	}
+
+	err = h.paymentMethodService.DeletePaymentMethod(r.Context(), userIDFromRequest(r), methodID)
+	if err != nil {
+		writePaymentMethodError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writePaymentMethodError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, domain.ErrInUse) {
+		err = apierror.WithMessage(err, "Payment method is used by expenses or incomes; deactivate it instead")
+	}
+	apierror.Write(w, r, err)
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"encoding/json"
+	"expense-tracker-api/domain"
+	"expense-tracker-api/services"
+	"expense-tracker-api/transport/apierror"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultPreviewCount is how many occurrences a preview returns when count is not given
+const defaultPreviewCount = 5
+
+type RecurringExpenseHandler struct {
+	recurringService *services.RecurringExpenseService
+}
+
+// NewRecurringExpenseHandler creates a new recurring expense handler
+func NewRecurringExpenseHandler(recurringService *services.RecurringExpenseService) *RecurringExpenseHandler {
+	return &RecurringExpenseHandler{recurringService: recurringService}
+}
+
+type RecurringExpenseRequest struct {
+	CategoryID  int          `json:"category_id"`
+	Amount      domain.Money `json:"amount"`
+	Description string       `json:"description"`
+	PaymentMode string       `json:"payment_mode"`
+	Frequency   string       `json:"frequency"`
+	DayOfMonth  *int         `json:"day_of_month"`
+	StartDate   string       `json:"start_date"`
+	EndDate     string       `json:"end_date"`
+	Active      *bool        `json:"active"`
+}
+
+type PreviewResponse struct {
+	RecurringExpenseID int      `json:"recurring_expense_id"`
+	Occurrences        []string `json:"occurrences"`
+}
+
+// toRecurringExpense converts the request into a template, reporting every invalid field at once
+func (req *RecurringExpenseRequest) toRecurringExpense() (*domain.RecurringExpense, error) {
+	v := &validator{}
+	v.check(req.CategoryID > 0, "category_id", "Missing category_id")
+	v.positiveAmount("amount", req.Amount)
+	v.description("description", req.Description)
+	v.required("payment_mode", req.PaymentMode)
+	v.check(domain.Frequency(req.Frequency).IsValid(), "frequency", "Invalid frequency, expected daily, weekly, monthly or yearly")
+	if req.DayOfMonth != nil {
+		v.check(*req.DayOfMonth >= 1 && *req.DayOfMonth <= 31, "day_of_month", "Invalid day_of_month, expected 1 to 31")
+	}
+	startDate := v.optionalDate("start_date", req.StartDate)
+	endDate := v.optionalDate("end_date", req.EndDate)
+	v.dateOrder("start_date", startDate, "end_date", endDate)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	recurring := &domain.RecurringExpense{
+		CategoryID:  req.CategoryID,
+		Amount:      req.Amount,
+		Description: req.Description,
+		PaymentMode: domain.PaymentMode(req.PaymentMode),
+		Frequency:   domain.Frequency(req.Frequency),
+		DayOfMonth:  req.DayOfMonth,
+		EndDate:     endDate,
+		Active:      req.Active == nil || *req.Active,
+	}
+	if startDate != nil {
+		recurring.StartDate = *startDate
+	}
+	return recurring, nil
+}
+
+// GetRecurringExpenses handles getting all recurring expense templates
+func (h *RecurringExpenseHandler) GetRecurringExpenses(w http.ResponseWriter, r *http.Request) {
+	recurringExpenses, err := h.recurringService.GetRecurringExpenses(r.Context(), userIDFromRequest(r))
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recurringExpenses)
+}
+
+// GetRecurringExpense handles getting a single recurring expense template
+func (h *RecurringExpenseHandler) GetRecurringExpense(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	recurringID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid recurring expense ID"))
+		return
+	}
+
+	recurring, err := h.recurringService.GetRecurringExpenseByID(r.Context(), userIDFromRequest(r), recurringID)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recurring)
+}
+
+// CreateRecurringExpense handles creating a new recurring expense template
+func (h *RecurringExpenseHandler) CreateRecurringExpense(w http.ResponseWriter, r *http.Request) {
+	var req RecurringExpenseRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	recurring, err := req.toRecurringExpense()
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	recurring.UserID = userIDFromRequest(r)
+
+	createdRecurring, err := h.recurringService.CreateRecurringExpense(r.Context(), recurring)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdRecurring)
+}
+
+// UpdateRecurringExpense handles replacing a recurring expense template
+func (h *RecurringExpenseHandler) UpdateRecurringExpense(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	recurringID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid recurring expense ID"))
+		return
+	}
+
+	var req RecurringExpenseRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	recurring, err := req.toRecurringExpense()
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	recurring.ID = recurringID
+	recurring.UserID = userIDFromRequest(r)
+
+	updatedRecurring, err := h.recurringService.UpdateRecurringExpense(r.Context(), recurring)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedRecurring)
+}
+
+// DeleteRecurringExpense handles deleting a recurring expense template
+func (h *RecurringExpenseHandler) DeleteRecurringExpense(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	recurringID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid recurring expense ID"))
+		return
+	}
+
+	err = h.recurringService.DeleteRecurringExpense(r.Context(), userIDFromRequest(r), recurringID)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PreviewRecurringExpense handles listing the next occurrences of a recurring expense template
+func (h *RecurringExpenseHandler) PreviewRecurringExpense(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	recurringID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid recurring expense ID"))
+		return
+	}
+
+	count := defaultPreviewCount
+	if countStr := r.URL.Query().Get("count"); countStr != "" {
+		count, err = strconv.Atoi(countStr)
+		if err != nil || count < 1 {
+			apierror.Write(w, r, apierror.Invalid("count", "Invalid count, expected a positive whole number"))
+			return
+		}
+	}
+
+	occurrences, err := h.recurringService.PreviewOccurrences(r.Context(), userIDFromRequest(r), recurringID, count)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	response := PreviewResponse{RecurringExpenseID: recurringID, Occurrences: make([]string, len(occurrences))}
+	for i, occurrence := range occurrences {
+		response.Occurrences[i] = occurrence.Format("2006-01-02")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"expense-tracker-api/domain"
+	"expense-tracker-api/services"
+	"expense-tracker-api/transport/apierror"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type ReportHandler struct {
+	reportService *services.ReportService
+}
+
+// NewReportHandler creates a new report handler
+func NewReportHandler(reportService *services.ReportService) *ReportHandler {
+	return &ReportHandler{reportService: reportService}
+}
+
+// GetMonthlyReport handles getting the spending report for a month
+func (h *ReportHandler) GetMonthlyReport(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	v := &validator{}
+	year := v.year("year", vars["year"])
+	month := v.month("month", vars["month"])
+	if err := v.err(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	report, err := h.reportService.GetMonthlyReport(r.Context(), userIDFromRequest(r), year, month)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetCashflow handles getting income, expenses and net savings per month.
+// from and to are optional YYYY-MM-DD dates.
+func (h *ReportHandler) GetCashflow(w http.ResponseWriter, r *http.Request) {
+	v := &validator{}
+	from := v.date("from", r.URL.Query().Get("from"))
+	to := v.date("to", r.URL.Query().Get("to"))
+	if err := v.err(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	report, err := h.reportService.GetCashflow(r.Context(), userIDFromRequest(r), from, to)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidInput) {
+			err = apierror.WithMessage(err, "Invalid date range")
+		}
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expense-tracker-api/domain"
+	"expense-tracker-api/transport/apierror"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// writeDecodeError reports a request body that could not be decoded. A malformed
+// amount gets its own message, and a value of the wrong type or a field the request
+// doesn't have is named; anything else is just an invalid body.
+//
+// Decoding stops at the first such problem, before the validator sees any field, so
+// this error names only that one. Every other problem is reported together once the
+// body decodes.
+func writeDecodeError(w http.ResponseWriter, r *http.Request, err error) {
+	var typeErr *json.UnmarshalTypeError
+	unknownField, isUnknownField := strings.CutPrefix(err.Error(), "json: unknown field ")
+	switch {
+	case errors.Is(err, domain.ErrInvalidAmount):
+		apierror.Write(w, r, err)
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		apierror.Write(w, r, apierror.Invalid(typeErr.Field, "Invalid "+typeErr.Field+", expected "+jsonTypeName(typeErr.Type)))
+	case isUnknownField:
+		field := strings.Trim(unknownField, `"`)
+		apierror.Write(w, r, apierror.Invalid(field, "Unknown field "+field))
+	default:
+		apierror.Write(w, r, apierror.New(http.StatusBadRequest, "INVALID_BODY", "Invalid request body"))
+	}
+}
+
+// jsonTypeName describes the JSON value a Go type is decoded from
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Pointer:
+		return jsonTypeName(t.Elem())
+	}
+	return "an object"
+}
+
+// withQueryTimeout bounds the database work of a request the router doesn't cut off
+// as a whole, from the moment it is called. A zero timeout leaves it unbounded.
+func withQueryTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"expense-tracker-api/services"
+	"expense-tracker-api/transport/apierror"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type TagHandler struct {
+	tagService *services.TagService
+}
+
+// NewTagHandler creates a new tag handler
+func NewTagHandler(tagService *services.TagService) *TagHandler {
+	return &TagHandler{tagService: tagService}
+}
+
+type RenameTagRequest struct {
+	Name string `json:"name"`
+}
+
+type MergeTagRequest struct {
+	TargetID int `json:"target_id"`
+}
+
+// GetTags handles listing tags with their usage counts
+func (h *TagHandler) GetTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.tagService.GetTags(r.Context(), userIDFromRequest(r))
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
+
+// RenameTag handles renaming a tag
+func (h *TagHandler) RenameTag(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tagID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid tag ID"))
+		return
+	}
+
+	var req RenameTagRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	tag, err := h.tagService.RenameTag(r.Context(), userIDFromRequest(r), tagID, req.Name)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tag)
+}
+
+// MergeTag handles merging a tag into another one
+func (h *TagHandler) MergeTag(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tagID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid tag ID"))
+		return
+	}
+
+	var req MergeTagRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	tag, err := h.tagService.MergeTags(r.Context(), userIDFromRequest(r), tagID, req.TargetID)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tag)
+}
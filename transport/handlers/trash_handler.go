@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"expense-tracker-api/domain"
+	"expense-tracker-api/services"
+	"expense-tracker-api/transport/apierror"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type TrashHandler struct {
+	trashService *services.TrashService
+}
+
+// NewTrashHandler creates a new trash handler
+func NewTrashHandler(trashService *services.TrashService) *TrashHandler {
+	return &TrashHandler{trashService: trashService}
+}
+
+// GetTrash handles listing the deleted expenses, categories and budgets
+func (h *TrashHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	trash, err := h.trashService.GetTrash(r.Context(), userIDFromRequest(r))
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trash)
+}
+
+// RestoreItem handles bringing a deleted expense, category or budget back
+func (h *TrashHandler) RestoreItem(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.Invalid("id", "Invalid ID"))
+		return
+	}
+
+	err = h.trashService.Restore(r.Context(), userIDFromRequest(r), domain.TrashItemType(vars["type"]), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			err = apierror.WithMessage(err, "Not found in the trash")
+		case errors.Is(err, domain.ErrInvalidInput):
+			err = apierror.Invalid("type", "Invalid type, expected expense, category or budget")
+		case errors.Is(err, domain.ErrInvalidCategory):
+			err = apierror.New(http.StatusConflict, "CATEGORY_IN_TRASH", "Its category is in the trash; restore the category first")
+		case errors.Is(err, domain.ErrAlreadyExists):
+			err = apierror.WithMessage(err, "A category with the same name or a budget for the same month already exists")
+		}
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
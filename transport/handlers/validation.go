@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"expense-tracker-api/domain"
+	"expense-tracker-api/transport/apierror"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errTrailingData reports a request body with more after its JSON value
+var errTrailingData = errors.New("unexpected data after the JSON value")
+
+// decodeJSON decodes the request body into dst, refusing fields dst doesn't have
+// and anything following the JSON value
+func decodeJSON(r *http.Request, dst interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dst); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return errTrailingData
+	}
+	return nil
+}
+
+// validator collects everything wrong with a request, so the client hears about all of it at once
+type validator struct {
+	fields []apierror.FieldError
+	// tooLarge counts the problems that are amounts above domain.MaxAmount
+	tooLarge int
+}
+
+// fail records a problem with a field
+func (v *validator) fail(field, message string) {
+	v.fields = append(v.fields, apierror.FieldError{Field: field, Message: message})
+}
+
+// check records the problem unless ok holds
+func (v *validator) check(ok bool, field, message string) {
+	if !ok {
+		v.fail(field, message)
+	}
+}
+
+// err returns the problems found as a single 400 error, or nil when there are none.
+// When every problem is an amount that is too large the code says so, as it does
+// for domain.ErrAmountTooLarge.
+func (v *validator) err() error {
+	code := "VALIDATION_FAILED"
+	if v.tooLarge == len(v.fields) {
+		code = "AMOUNT_TOO_LARGE"
+	}
+	switch len(v.fields) {
+	case 0:
+		return nil
+	case 1:
+		return &apierror.Error{Status: http.StatusBadRequest, Code: code, Message: v.fields[0].Message, Fields: v.fields}
+	}
+	return &apierror.Error{Status: http.StatusBadRequest, Code: code,
+		Message: fmt.Sprintf("The request has %d invalid fields", len(v.fields)), Fields: v.fields}
+}
+
+// required checks that a text field isn't empty or blank
+func (v *validator) required(field, value string) {
+	v.check(strings.TrimSpace(value) != "", field, "Missing "+field)
+}
+
+// positiveAmount checks that an amount is more than zero and fits the amount columns
+func (v *validator) positiveAmount(field string, amount domain.Money) {
+	v.check(amount > 0, field, fmt.Sprintf("Invalid %s, must be more than zero", field))
+	v.maxAmount(field, amount)
+}
+
+// maxAmount checks that an amount is at most domain.MaxAmount
+func (v *validator) maxAmount(field string, amount domain.Money) {
+	if amount > domain.MaxAmount {
+		v.fail(field, fmt.Sprintf("Invalid %s, must be at most %s", field, domain.MaxAmount))
+		v.tooLarge++
+	}
+}
+
+// description checks the length of a description
+func (v *validator) description(field, description string) {
+	v.check(len([]rune(description)) <= domain.MaxDescriptionLength, field,
+		fmt.Sprintf("Invalid %s, must be at most %d characters", field, domain.MaxDescriptionLength))
+}
+
+// date parses a YYYY-MM-DD date within the accepted range. An empty value is the
+// zero time, for the services to default.
+func (v *validator) date(field, value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		v.fail(field, fmt.Sprintf("Invalid %s, expected YYYY-MM-DD", field))
+		return time.Time{}
+	}
+	if date.Before(domain.EarliestDate) || date.After(domain.LatestDate) {
+		v.fail(field, fmt.Sprintf("Invalid %s, must be between %s and %s", field,
+			domain.EarliestDate.Format("2006-01-02"), domain.LatestDate.Format("2006-01-02")))
+		return time.Time{}
+	}
+	return date
+}
+
+// requiredDate parses a YYYY-MM-DD date that must be given
+func (v *validator) requiredDate(field, value string) time.Time {
+	if value == "" {
+		v.fail(field, fmt.Sprintf("Missing %s, expected YYYY-MM-DD", field))
+		return time.Time{}
+	}
+	return v.date(field, value)
+}
+
+// optionalDate parses a YYYY-MM-DD date, nil when it isn't given or is invalid
+func (v *validator) optionalDate(field, value string) *time.Time {
+	date := v.date(field, value)
+	if date.IsZero() {
+		return nil
+	}
+	return &date
+}
+
+// dateOrder checks that a range doesn't end before it starts
+func (v *validator) dateOrder(startField string, start *time.Time, endField string, end *time.Time) {
+	if start != nil && end != nil {
+		v.check(!end.Before(*start), endField, fmt.Sprintf("Invalid %s, must not be before %s", endField, startField))
+	}
+}
+
+// month parses a month number, 1 to 12, from a path variable
+func (v *validator) month(field, value string) int {
+	month, err := strconv.Atoi(value)
+	v.check(err == nil && month >= 1 && month <= 12, field, fmt.Sprintf("Invalid %s, expected 1 to 12", field))
+	return month
+}
+
+// year parses a year within the accepted dates from a path variable
+func (v *validator) year(field, value string) int {
+	year, err := strconv.Atoi(value)
+	v.check(err == nil && year >= domain.EarliestDate.Year() && year <= domain.LatestDate.Year(), field,
+		fmt.Sprintf("Invalid %s, expected %d to %d", field, domain.EarliestDate.Year(), domain.LatestDate.Year()))
+	return year
+}
+
+// queryID parses an optional query parameter naming a record, nil when it isn't given
+func (v *validator) queryID(query url.Values, name string) *int {
+	value := query.Get(name)
+	if value == "" {
+		return nil
+	}
+	id, err := strconv.Atoi(value)
+	if err != nil || id < 1 {
+		v.fail(name, fmt.Sprintf("Invalid %s, expected a positive whole number", name))
+		return nil
+	}
+	return &id
+}
+
+// queryBool parses an optional true or false query parameter
+func (v *validator) queryBool(query url.Values, name string) bool {
+	value := query.Get(name)
+	if value == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		v.fail(name, fmt.Sprintf("Invalid %s, expected true or false", name))
+	}
+	return b
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"expense-tracker-api/domain"
+	"expense-tracker-api/transport/apierror"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{"known fields", `{"category_id": 1, "amount": 10}`, false},
+		{"unknown field", `{"category_id": 1, "amount": 10, "note": "x"}`, true},
+		{"trailing value", `{"category_id": 1} {"category_id": 2}`, true},
+		{"trailing whitespace", "{\"category_id\": 1}\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/expenses", strings.NewReader(tt.body))
+			var req CreateExpenseRequest
+			err := decodeJSON(r, &req)
+			assert.Equal(t, tt.wantErr, err != nil, "err = %v", err)
+		})
+	}
+}
+
+func TestCreateExpenseRequest_ReportsEveryInvalidField(t *testing.T) {
+	req := CreateExpenseRequest{
+		Amount:      -5,
+		Description: strings.Repeat("a", domain.MaxDescriptionLength+1),
+		ExpenseDate: "3024-01-01",
+	}
+
+	_, err := req.toExpense()
+
+	e := apierror.From(err)
+	assert.Equal(t, http.StatusBadRequest, e.Status)
+	assert.Equal(t, "VALIDATION_FAILED", e.Code)
+	var fields []string
+	for _, f := range e.Fields {
+		fields = append(fields, f.Field)
+	}
+	assert.Equal(t, []string{"category_id", "amount", "description", "payment_mode", "expense_date"}, fields)
+}
+
+func TestCreateExpenseRequest_AmountTooLarge(t *testing.T) {
+	req := CreateExpenseRequest{CategoryID: 1, Amount: domain.MaxAmount + 1, PaymentMode: "UPI", ExpenseDate: "2024-01-15"}
+
+	_, err := req.toExpense()
+
+	e := apierror.From(err)
+	assert.Equal(t, http.StatusBadRequest, e.Status)
+	assert.Equal(t, "AMOUNT_TOO_LARGE", e.Code)
+	require.Len(t, e.Fields, 1)
+	assert.Equal(t, "amount", e.Fields[0].Field)
+
+	// Alongside other problems it is one more validation failure
+	req.CategoryID = 0
+	_, err = req.toExpense()
+	assert.Equal(t, "VALIDATION_FAILED", apierror.From(err).Code)
+}
+
+func TestCreateExpenseRequest_Valid(t *testing.T) {
+	req := CreateExpenseRequest{CategoryID: 1, Amount: 10, PaymentMode: "UPI", ExpenseDate: "2024-01-15"}
+
+	expense, err := req.toExpense()
+
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01-15", expense.ExpenseDate.Format("2006-01-02"))
+}
+
+func TestParseExpenseFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		fields []string
+	}{
+		{"valid", "category_id=2&start_date=2024-01-01&end_date=2024-01-31&include_subcategories=true", nil},
+		{"bad values", "category_id=x&start_date=bad&include_subcategories=maybe", []string{"category_id", "include_subcategories", "start_date"}},
+		{"reversed range", "start_date=2024-02-01&end_date=2024-01-01", []string{"end_date"}},
+		{"empty payment mode", "payment_mode=", []string{"payment_mode"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			require.NoError(t, err)
+			v := &validator{}
+
+			parseExpenseFilter(query, v)
+
+			var fields []string
+			for _, f := range v.fields {
+				fields = append(fields, f.Field)
+			}
+			assert.Equal(t, tt.fields, fields)
+		})
+	}
+}
+
+func TestUpdateExpenseRequest_AccountID(t *testing.T) {
+	detach, account := 0, 5
+	tests := []struct {
+		name string
+		body string
+		want *int
+	}{
+		{"left out keeps the account", `{"description": "Lunch"}`, nil},
+		{"null detaches", `{"account_id": null}`, &detach},
+		{"zero detaches", `{"account_id": 0}`, &detach},
+		{"new account", `{"account_id": 5}`, &account},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/api/expenses/1", strings.NewReader(tt.body))
+			var req UpdateExpenseRequest
+			require.NoError(t, decodeJSON(r, &req))
+
+			expense, err := req.toExpense()
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, expense.AccountID)
+		})
+	}
+
+	t.Run("not an ID", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPut, "/api/expenses/1", strings.NewReader(`{"account_id": "savings", "amount": -1}`))
+		var req UpdateExpenseRequest
+		require.NoError(t, decodeJSON(r, &req))
+
+		_, err := req.toExpense()
+
+		e := apierror.From(err)
+		assert.Equal(t, "VALIDATION_FAILED", e.Code)
+		var fields []string
+		for _, f := range e.Fields {
+			fields = append(fields, f.Field)
+		}
+		assert.ElementsMatch(t, []string{"account_id", "amount"}, fields)
+	})
+}
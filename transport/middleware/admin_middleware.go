@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"expense-tracker-api/transport/apierror"
+	"net/http"
+)
+
+// AdminMiddleware only lets through requests carrying the configured admin token in
+// the X-Admin-Token header. With no token configured every admin request is refused.
+func AdminMiddleware(adminToken string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.Header.Get("X-Admin-Token")
+			if adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+				apierror.Write(w, r, apierror.New(http.StatusForbidden, "FORBIDDEN", "Forbidden"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
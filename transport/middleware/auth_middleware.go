@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"context"
+	"expense-tracker-api/domain"
+	"expense-tracker-api/services"
+	"expense-tracker-api/transport/apierror"
+	"net/http"
+	"strings"
+)
+
+type contextKey string
+
+const userIDKey contextKey = "user_id"
+
+// AuthMiddleware rejects requests without a valid bearer access token and
+// puts the authenticated user's ID into the request context
+func AuthMiddleware(authService *services.AuthService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			token, found := strings.CutPrefix(header, "Bearer ")
+			if !found || token == "" {
+				apierror.Write(w, r, apierror.New(http.StatusUnauthorized, "UNAUTHORIZED", "Missing bearer token"))
+				return
+			}
+
+			userID, err := authService.Authenticate(token)
+			if err != nil {
+				apierror.Write(w, r, apierror.WithMessage(domain.ErrUnauthorized, err.Error()))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithUserID(r.Context(), userID)))
+		})
+	}
+}
+
+// WithUserID returns a copy of ctx carrying the authenticated user's ID
+func WithUserID(ctx context.Context, userID int) context.Context {
+	return context.WithValue(ctx, userIDKey, userID)
+}
+
+// UserIDFromContext returns the authenticated user's ID stored by AuthMiddleware
+func UserIDFromContext(ctx context.Context) (int, bool) {
+	userID, ok := ctx.Value(userIDKey).(int)
+	return userID, ok
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"expense-tracker-api/transport/apierror"
+	"net/http"
+)
+
+// maxRequestIDLength caps the length of a request ID taken from the client
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware sets the X-Request-ID response header to the ID the client
+// sent, or to a new random one, so a failed request can be found in the logs
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(apierror.RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		w.Header().Set(apierror.RequestIDHeader, requestID)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validRequestID reports whether a client's request ID is short printable ASCII,
+// safe to echo back and to write to the logs
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
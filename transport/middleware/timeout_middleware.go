@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// TimeoutMiddleware cancels the request context once the timeout passes, which
+// stops any database query the request still has running. The context is also
+// cancelled when the client goes away. A zero timeout leaves requests unbounded.
+func TimeoutMiddleware(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if timeout <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
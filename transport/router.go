@@ -1,48 +1,174 @@
 package transport
 
 import (
+	"expense-tracker-api/domain"
 	"expense-tracker-api/services"
+	"expense-tracker-api/transport/apierror"
 	"expense-tracker-api/transport/handlers"
 	"expense-tracker-api/transport/middleware"
+	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 )
 
 // SetupRouter sets up all routes
-func SetupRouter(categoryService *services.CategoryService,
-	expenseService *services.ExpenseService, budgetService *services.BudgetService) *mux.Router {
+func SetupRouter(authService *services.AuthService, categoryService *services.CategoryService,
+	expenseService *services.ExpenseService, budgetService *services.BudgetService,
+	recurringExpenseService *services.RecurringExpenseService,
+	reportService *services.ReportService, tagService *services.TagService,
+	paymentMethodService *services.PaymentMethodService, exchangeRateService *services.ExchangeRateService,
+	incomeService *services.IncomeService, accountService *services.AccountService, trashService *services.TrashService,
+	auditService *services.AuditService, adminToken string, queryTimeout time.Duration) *mux.Router {
 
 	router := mux.NewRouter()
 
 	// Initialize handlers
+	authHandler := handlers.NewAuthHandler(authService)
 	categoryHandler := handlers.NewCategoryHandler(categoryService)
-	expenseHandler := handlers.NewExpenseHandler(expenseService)
+	expenseHandler := handlers.NewExpenseHandler(expenseService, queryTimeout)
 	budgetHandler := handlers.NewBudgetHandler(budgetService)
+	recurringExpenseHandler := handlers.NewRecurringExpenseHandler(recurringExpenseService)
+	reportHandler := handlers.NewReportHandler(reportService)
+	tagHandler := handlers.NewTagHandler(tagService)
+	paymentMethodHandler := handlers.NewPaymentMethodHandler(paymentMethodService)
+	exchangeRateHandler := handlers.NewExchangeRateHandler(exchangeRateService, queryTimeout)
+	incomeHandler := handlers.NewIncomeHandler(incomeService)
+	accountHandler := handlers.NewAccountHandler(accountService)
+	trashHandler := handlers.NewTrashHandler(trashService)
+	auditHandler := handlers.NewAuditHandler(auditService)
 
-	// Apply CORS middleware
-	router.Use(middleware.CORSMiddleware)
+	// Apply CORS middleware, and tag every response with its request ID
+	router.Use(middleware.RequestIDMiddleware, middleware.CORSMiddleware)
+
+	// Unknown routes get the same error envelope as the rest of the API
+	router.NotFoundHandler = middleware.RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apierror.Write(w, r, apierror.New(http.StatusNotFound, "ROUTE_NOT_FOUND", "No such route"))
+	}))
+	router.MethodNotAllowedHandler = middleware.RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apierror.Write(w, r, apierror.New(http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Method not allowed"))
+	}))
 
 	// API routes
 	api := router.PathPrefix("/api").Subrouter()
 
+	// Uploads last as long as the file takes to arrive, so they aren't under timed
+	adminUploads := api.PathPrefix("/admin").Subrouter()
+	adminUploads.Use(middleware.AdminMiddleware(adminToken))
+	adminUploads.HandleFunc("/exchange-rates/import", exchangeRateHandler.ImportExchangeRates).Methods("POST", "OPTIONS")
+
+	// Imports and exports stream for as long as the client takes, so they aren't under timed either
+	streaming := api.NewRoute().Subrouter()
+	streaming.Use(middleware.AuthMiddleware(authService))
+	streaming.HandleFunc("/expenses/export", expenseHandler.ExportExpenses).Methods("GET", "OPTIONS")
+	streaming.HandleFunc("/expenses/import", expenseHandler.ImportExpenses).Methods("POST", "OPTIONS")
+
+	// Any other request is cut off once it has run for queryTimeout
+	timed := api.NewRoute().Subrouter()
+	timed.Use(middleware.TimeoutMiddleware(queryTimeout))
+
+	// Auth routes (public)
+	timed.HandleFunc("/auth/register", authHandler.Register).Methods("POST", "OPTIONS")
+	timed.HandleFunc("/auth/login", authHandler.Login).Methods("POST", "OPTIONS")
+	timed.HandleFunc("/auth/refresh", authHandler.Refresh).Methods("POST", "OPTIONS")
+
+	// Admin routes require the X-Admin-Token header instead of a user token
+	admin := timed.PathPrefix("/admin").Subrouter()
+	admin.Use(middleware.AdminMiddleware(adminToken))
+	admin.HandleFunc("/exchange-rates", exchangeRateHandler.SaveExchangeRates).Methods("POST", "OPTIONS")
+
+	// Everything else requires a bearer access token
+	protected := timed.NewRoute().Subrouter()
+	protected.Use(middleware.AuthMiddleware(authService))
+
 	// Category routes
-	api.HandleFunc("/categories", categoryHandler.GetCategories).Methods("GET", "OPTIONS")
-	api.HandleFunc("/categories", categoryHandler.CreateCategory).Methods("POST", "OPTIONS")
-	api.HandleFunc("/categories/{id}", categoryHandler.UpdateCategory).Methods("PUT", "OPTIONS")
-	api.HandleFunc("/categories/{id}", categoryHandler.DeleteCategory).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/categories", categoryHandler.GetCategories).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/categories", categoryHandler.CreateCategory).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/categories/tree", categoryHandler.GetCategoryTree).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/categories/{id}", categoryHandler.UpdateCategory).Methods("PUT", "OPTIONS")
+	protected.HandleFunc("/categories/{id}", categoryHandler.DeleteCategory).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/categories/{id}/archive", categoryHandler.ArchiveCategory).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/categories/{id}/unarchive", categoryHandler.UnarchiveCategory).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/categories/{id}/merge", categoryHandler.MergeCategory).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/categories/{id}/history", auditHandler.GetHistory(domain.AuditEntityCategory)).Methods("GET", "OPTIONS")
 
 	// Expense routes
-	api.HandleFunc("/expenses", expenseHandler.GetExpenses).Methods("GET", "OPTIONS")
-	api.HandleFunc("/expenses/{id}", expenseHandler.GetExpense).Methods("GET", "OPTIONS")
-	api.HandleFunc("/expenses", expenseHandler.CreateExpense).Methods("POST", "OPTIONS")
-	api.HandleFunc("/expenses/{id}", expenseHandler.UpdateExpense).Methods("PUT", "OPTIONS")
-	api.HandleFunc("/expenses/{id}", expenseHandler.DeleteExpense).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/expenses", expenseHandler.GetExpenses).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/expenses/{id}", expenseHandler.GetExpense).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/expenses", expenseHandler.CreateExpense).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/expenses/{id}", expenseHandler.UpdateExpense).Methods("PUT", "OPTIONS")
+	protected.HandleFunc("/expenses/{id}", expenseHandler.DeleteExpense).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/expenses/{id}/history", auditHandler.GetHistory(domain.AuditEntityExpense)).Methods("GET", "OPTIONS")
 
 	// Budget routes
-	api.HandleFunc("/budgets", budgetHandler.GetBudgets).Methods("GET", "OPTIONS")
-	api.HandleFunc("/budgets/{month}/{year}", budgetHandler.GetBudgetByMonth).Methods("GET", "OPTIONS")
-	api.HandleFunc("/budgets", budgetHandler.CreateOrUpdateBudget).Methods("POST", "OPTIONS")
-	api.HandleFunc("/budgets/{id}", budgetHandler.DeleteBudget).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/budgets", budgetHandler.GetBudgets).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/budgets/{month}/{year}", budgetHandler.GetBudgetByMonth).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/budgets", budgetHandler.CreateOrUpdateBudget).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/budgets/{id}", budgetHandler.DeleteBudget).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/budgets/{id}/history", auditHandler.GetHistory(domain.AuditEntityBudget)).Methods("GET", "OPTIONS")
+
+	// Income routes
+	protected.HandleFunc("/incomes", incomeHandler.GetIncomes).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/incomes/{id}", incomeHandler.GetIncome).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/incomes", incomeHandler.CreateIncome).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/incomes/{id}", incomeHandler.UpdateIncome).Methods("PUT", "OPTIONS")
+	protected.HandleFunc("/incomes/{id}", incomeHandler.DeleteIncome).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/incomes/{id}/history", auditHandler.GetHistory(domain.AuditEntityIncome)).Methods("GET", "OPTIONS")
+
+	// Account routes
+	protected.HandleFunc("/accounts", accountHandler.GetAccounts).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/accounts/{id}", accountHandler.GetAccount).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/accounts/{id}/balance", accountHandler.GetBalance).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/accounts/{id}/reconciliations", accountHandler.GetReconciliations).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/accounts/{id}/reconciliations", accountHandler.Reconcile).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/accounts", accountHandler.CreateAccount).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/accounts/{id}", accountHandler.UpdateAccount).Methods("PUT", "OPTIONS")
+	protected.HandleFunc("/accounts/{id}", accountHandler.DeleteAccount).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/accounts/{id}/history", auditHandler.GetHistory(domain.AuditEntityAccount)).Methods("GET", "OPTIONS")
+
+	// Transfer routes
+	protected.HandleFunc("/transfers", accountHandler.GetTransfers).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/transfers/{id}", accountHandler.GetTransfer).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/transfers", accountHandler.CreateTransfer).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/transfers/{id}", accountHandler.DeleteTransfer).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/transfers/{id}/history", auditHandler.GetHistory(domain.AuditEntityTransfer)).Methods("GET", "OPTIONS")
+
+	// Recurring expense routes
+	protected.HandleFunc("/recurring-expenses", recurringExpenseHandler.GetRecurringExpenses).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/recurring-expenses/{id}", recurringExpenseHandler.GetRecurringExpense).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/recurring-expenses/{id}/preview", recurringExpenseHandler.PreviewRecurringExpense).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/recurring-expenses", recurringExpenseHandler.CreateRecurringExpense).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/recurring-expenses/{id}", recurringExpenseHandler.UpdateRecurringExpense).Methods("PUT", "OPTIONS")
+	protected.HandleFunc("/recurring-expenses/{id}", recurringExpenseHandler.DeleteRecurringExpense).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/recurring-expenses/{id}/history", auditHandler.GetHistory(domain.AuditEntityRecurringExpense)).Methods("GET", "OPTIONS")
+
+	// Payment method routes
+	protected.HandleFunc("/payment-methods", paymentMethodHandler.GetPaymentMethods).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/payment-methods/{id}", paymentMethodHandler.GetPaymentMethod).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/payment-methods", paymentMethodHandler.CreatePaymentMethod).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/payment-methods/{id}", paymentMethodHandler.UpdatePaymentMethod).Methods("PUT", "OPTIONS")
+	protected.HandleFunc("/payment-methods/{id}", paymentMethodHandler.DeletePaymentMethod).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/payment-methods/{id}/history", auditHandler.GetHistory(domain.AuditEntityPaymentMethod)).Methods("GET", "OPTIONS")
+
+	// Tag routes
+	protected.HandleFunc("/tags", tagHandler.GetTags).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/tags/{id}", tagHandler.RenameTag).Methods("PUT", "OPTIONS")
+	protected.HandleFunc("/tags/{id}/merge", tagHandler.MergeTag).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/tags/{id}/history", auditHandler.GetHistory(domain.AuditEntityTag)).Methods("GET", "OPTIONS")
+
+	// Exchange rate routes
+	protected.HandleFunc("/exchange-rates", exchangeRateHandler.GetExchangeRates).Methods("GET", "OPTIONS")
+
+	// Report routes
+	protected.HandleFunc("/reports/monthly/{year}/{month}", reportHandler.GetMonthlyReport).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/cashflow", reportHandler.GetCashflow).Methods("GET", "OPTIONS")
+
+	// Trash routes
+	protected.HandleFunc("/trash", trashHandler.GetTrash).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/trash/{type}/{id}/restore", trashHandler.RestoreItem).Methods("POST", "OPTIONS")
+
+	// Audit log routes
+	protected.HandleFunc("/audit", auditHandler.GetAuditEvents).Methods("GET", "OPTIONS")
 
 	return router
 }